@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// operationsLogName is the audit trail of executed transactions, kept in the
+// config directory
+const operationsLogName = "operations.log"
+
+// operationsLogMaxSize is the size past which the log is rotated to
+// operations.log.1, replacing the previous rotation
+const operationsLogMaxSize = 1 << 20
+
+// operationsLogPath is where transactions are recorded. It is set at startup
+// from the config directory; when empty nothing is logged.
+var operationsLogPath string
+
+// operationsLogMu keeps transactions finishing at the same time (parallel
+// link-all) from interleaving their entries or rotating the log twice
+var operationsLogMu sync.Mutex
+
+// Outcomes of a logged transaction
+const (
+	operationsLogCommitted      = "committed"
+	operationsLogRolledBack     = "rolled back"
+	operationsLogRollbackFailed = "rollback failed"
+)
+
+// operationLogEntry is one transaction in the operations log: a header line
+// with the time, transaction id and outcome, followed by its operations
+type operationLogEntry struct {
+	Header     string
+	Operations []string
+}
+
+// logTransaction appends a transaction to the operations log. failed is the
+// index of the operation that failed (-1 when none did); the ones before it
+// were undone, or stranded when the rollback failed too, and the ones after it
+// never ran. Failing to write the log never fails the transaction itself.
+func logTransaction(t *Transaction, outcome string, failed int, cause error) {
+	if operationsLogPath == "" || len(t.operations) == 0 {
+		return
+	}
+	
+	var b strings.Builder
+	header := fmt.Sprintf("%s %s %s", time.Now().UTC().Format(time.RFC3339), t.id, outcome)
+	if cause != nil {
+		header += ": " + strings.ReplaceAll(cause.Error(), "\n", "; ")
+	}
+	b.WriteString(header + "\n")
+	
+	for i, op := range t.operations {
+		status := "done"
+		switch {
+		case failed < 0:
+		case i < failed && outcome == operationsLogRolledBack:
+			status = "undone"
+		case i < failed:
+			status = "stranded"
+		case i == failed:
+			status = "failed"
+		default:
+			status = "not run"
+		}
+		b.WriteString(fmt.Sprintf("    %-8s %s\n", status, op.Description()))
+	}
+	
+	if err := appendOperationsLog(b.String()); err != nil {
+		logger.Debugf("%s: could not write operations log: %v", t.id, err)
+	}
+}
+
+// appendOperationsLog writes text to the end of the log, rotating it first
+// when it has grown past operationsLogMaxSize
+func appendOperationsLog(text string) error {
+	operationsLogMu.Lock()
+	defer operationsLogMu.Unlock()
+	
+	if info, err := os.Stat(operationsLogPath); err == nil && info.Size() > operationsLogMaxSize {
+		if err := os.Rename(operationsLogPath, operationsLogPath+".1"); err != nil {
+			return NewConfigError("rotate operations log", operationsLogPath, err)
+		}
+	}
+	
+	logFile, err := os.OpenFile(operationsLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return NewConfigError("open operations log", operationsLogPath, err)
+	}
+	defer logFile.Close()
+	
+	if _, err := logFile.WriteString(text); err != nil {
+		return NewConfigError("write operations log", operationsLogPath, err)
+	}
+	return nil
+}
+
+// readOperationsLog returns the last limit logged transactions, oldest first,
+// including those in the rotated log. A limit of 0 returns all of them.
+func readOperationsLog(limit int) ([]operationLogEntry, error) {
+	var entries []operationLogEntry
+	for _, path := range []string{operationsLogPath + ".1", operationsLogPath} {
+		logFile, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, NewConfigError("open operations log", path, err)
+		}
+		
+		scanner := bufio.NewScanner(logFile)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+			case strings.HasPrefix(line, " "):
+				if len(entries) > 0 {
+					last := &entries[len(entries)-1]
+					last.Operations = append(last.Operations, strings.TrimSpace(line))
+				}
+			default:
+				entries = append(entries, operationLogEntry{Header: line})
+			}
+		}
+		err = scanner.Err()
+		logFile.Close()
+		if err != nil {
+			return nil, NewConfigError("read operations log", path, err)
+		}
+	}
+	
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
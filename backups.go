@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultBackupTimeFormat is used when backup_time_format isn't configured
+const defaultBackupTimeFormat = "2006-01-02_15-04-05"
+
+// legacyBackupTimeFormat was used for .backup.<timestamp> files before the
+// format became configurable; such names are still recognized
+const legacyBackupTimeFormat = "20060102-150405"
+
+// Backup styles for Config.BackupStyle
+const (
+	BackupStyleTimestamped = "timestamped" // a new .backup.<timestamp> each time a target is replaced
+	BackupStyleRolling     = "rolling"     // a single .backup per target, overwritten each time
+)
+
+// rollingBackupSuffix names the one backup a target keeps in rolling style
+const rollingBackupSuffix = ".backup"
+
+// noBackupOverride is set from --no-backup and takes precedence over the
+// config file without being persisted to it
+var noBackupOverride bool
+
+// redirectedBackupsDirName is the directory in the backup directory holding
+// backups kept out of the dotfiles and config directories
+const redirectedBackupsDirName = "redirected"
+
+// backupPolicy is how a transaction backs up the targets it replaces. It is
+// worked out from the config when the transaction is created, so nothing
+// carries over from a config loaded earlier and files linked in parallel
+// each read their own transaction's copy.
+type backupPolicy struct {
+	// skip deletes replaced targets instead (no_backup or --no-backup), for
+	// disposable environments. Nothing can be restored on rollback then.
+	skip       bool
+	style      string   // BackupStyleTimestamped or BackupStyleRolling
+	timeFormat string   // timestamp of backup directories and .backup.<timestamp> files
+	protected  []string // directories no backup is created in, where it could be committed
+	backupDir  string   // where backups of what is inside them go instead
+}
+
+// defaultBackupPolicy backs up next to the target with timestamped names,
+// protecting no directory; only --no-backup applies
+func defaultBackupPolicy() *backupPolicy {
+	return &backupPolicy{
+		skip:       noBackupOverride,
+		style:      BackupStyleTimestamped,
+		timeFormat: defaultBackupTimeFormat,
+	}
+}
+
+// backupPolicy returns how c's transactions back up replaced targets. An
+// unset or unknown style keeps timestamped backups (Validate reports unknown
+// ones). The dotfiles and config directories are protected as given and with
+// symlinks resolved. A nil config gets the default policy.
+func (c *Config) backupPolicy() *backupPolicy {
+	policy := defaultBackupPolicy()
+	if c == nil {
+		return policy
+	}
+	
+	policy.skip = c.NoBackup || noBackupOverride
+	if c.BackupStyle == BackupStyleRolling {
+		policy.style = BackupStyleRolling
+	}
+	policy.timeFormat = c.backupTimeFormat()
+	for _, dir := range []string{c.DotfilesDir, c.ConfigDir} {
+		if dir == "" {
+			continue
+		}
+		policy.protected = append(policy.protected, dir)
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil && resolved != filepath.Clean(dir) {
+			policy.protected = append(policy.protected, resolved)
+		}
+	}
+	policy.backupDir = c.GetBackupDir()
+	return policy
+}
+
+// backupTimeFormat is the timestamp format of c's backup directories and
+// .backup.<timestamp> files: the configured one, or the default when it is
+// unset or invalid (Validate reports invalid ones)
+func (c *Config) backupTimeFormat() string {
+	if c.BackupTimeFormat != "" && validateBackupTimeFormat(c.BackupTimeFormat) == nil {
+		return c.BackupTimeFormat
+	}
+	return defaultBackupTimeFormat
+}
+
+// discardTarget deletes a target that is about to be replaced, for when
+// backups are off
+func discardTarget(target string, removeAll func(string) error) error {
+	logger.Debugf("backups are off, deleting %s instead of backing it up", target)
+	return removeAll(target)
+}
+
+// redirected is where a backup that would land at backupPath goes when that
+// is inside a protected directory: under redirected/ in the backup directory,
+// at its full path. ok is false when backupPath is fine as it is.
+func (p *backupPolicy) redirected(backupPath string) (string, bool) {
+	if p.backupDir == "" || isWithinDir(backupPath, p.backupDir) {
+		return "", false
+	}
+	for _, dir := range p.protected {
+		if isWithinDir(backupPath, dir) {
+			return filepath.Join(p.backupDir, redirectedBackupsDirName, backupPath), true
+		}
+	}
+	return "", false
+}
+
+// targetPath names the backup a replaced target is moved to: next to the
+// target, unless that would put it in the dotfiles or config directory
+func (p *backupPolicy) targetPath(target string) string {
+	backupPath := target + ".backup." + p.timestamp()
+	if p.style == BackupStyleRolling {
+		backupPath = target + rollingBackupSuffix
+	}
+	if redirected, ok := p.redirected(backupPath); ok {
+		logger.Debugf("%s is inside the dotfiles or config directory, backing it up to %s", target, redirected)
+		return redirected
+	}
+	return backupPath
+}
+
+// makeRoom deletes the previous rolling backup at backupPath so the target
+// can be moved there, and creates the directory of a redirected backup.
+// Timestamped backup paths are always new.
+func (p *backupPolicy) makeRoom(backupPath string, removeAll func(string) error) error {
+	if p.backupDir != "" && isWithinDir(backupPath, filepath.Join(p.backupDir, redirectedBackupsDirName)) {
+		if err := mkdirAllSerialized(filepath.Dir(backupPath)); err != nil {
+			return err
+		}
+	}
+	if p.style != BackupStyleRolling {
+		return nil
+	}
+	if _, err := os.Lstat(backupPath); err != nil {
+		return nil
+	}
+	logger.Debugf("replacing previous rolling backup %s", backupPath)
+	return removeAll(backupPath)
+}
+
+// timestamp names a backup taken now
+func (p *backupPolicy) timestamp() string {
+	return time.Now().Format(p.timeFormat)
+}
+
+// parseBackupTimestamp reads the time back out of a backup name, accepting
+// format and the legacy one
+func parseBackupTimestamp(format, stamp string) (time.Time, error) {
+	parsed, err := time.ParseInLocation(format, stamp, time.Local)
+	if err == nil {
+		return parsed, nil
+	}
+	if legacy, legacyErr := time.ParseInLocation(legacyBackupTimeFormat, stamp, time.Local); legacyErr == nil {
+		return legacy, nil
+	}
+	return time.Time{}, err
+}
+
+// validateBackupTimeFormat checks that a Go time layout produces names that
+// are safe on any filesystem and parse back to the same second, so backups
+// can be listed, pruned and restored by time
+func validateBackupTimeFormat(format string) error {
+	reference := time.Date(2024, time.March, 5, 14, 7, 9, 0, time.Local)
+	name := reference.Format(format)
+	
+	for _, r := range name {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>| `, r) {
+			return fmt.Errorf("format produces %q, which contains a character not allowed in file names", name)
+		}
+	}
+	
+	parsed, err := time.ParseInLocation(format, name, time.Local)
+	if err != nil || !parsed.Equal(reference) {
+		return fmt.Errorf("format must include the date and time down to the second (e.g. %q)", defaultBackupTimeFormat)
+	}
+	return nil
+}
+
+// backupInfo is a timestamped backup directory
+type backupInfo struct {
+	Name    string
+	Path    string
+	Created time.Time
+}
+
+// listBackups returns the backups in the backup directory, newest first. A
+// non-zero since keeps only backups taken within that long. Directories whose
+// names aren't backup timestamps are skipped.
+func listBackups(config *Config, since time.Duration) ([]backupInfo, error) {
+	backupDir := config.GetBackupDir()
+	entries, err := os.ReadDir(backupDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewConfigError("list backups", backupDir, err)
+	}
+	
+	var cutoff time.Time
+	if since > 0 {
+		cutoff = time.Now().Add(-since)
+	}
+	
+	var backups []backupInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		created, err := parseBackupTimestamp(config.backupTimeFormat(), entry.Name())
+		if err != nil || created.Before(cutoff) {
+			continue
+		}
+		backups = append(backups, backupInfo{
+			Name:    entry.Name(),
+			Path:    filepath.Join(backupDir, entry.Name()),
+			Created: created,
+		})
+	}
+	
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Created.After(backups[j].Created)
+	})
+	return backups, nil
+}
+
+// parseSinceDuration parses a --since value such as "7d", "12h" or "30m"
+func parseSinceDuration(value string) (time.Duration, error) {
+	if len(value) < 2 {
+		return 0, NewValidationError("since", value, "expected a number followed by d, h or m (e.g. 7d)", "")
+	}
+	
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'd':
+		unit = 24 * time.Hour
+	case 'h':
+		unit = time.Hour
+	case 'm':
+		unit = time.Minute
+	default:
+		return 0, NewValidationError("since", value, "unknown unit; use d, h or m (e.g. 7d)", "")
+	}
+	
+	count, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || count <= 0 {
+		return 0, NewValidationError("since", value, "expected a positive number before the unit (e.g. 7d)", "")
+	}
+	
+	return time.Duration(count) * unit, nil
+}
+
+// snapshotsSince keeps the snapshots taken within since; zero keeps all
+func snapshotsSince(snapshots []snapshotInfo, since time.Duration) []snapshotInfo {
+	if since <= 0 {
+		return snapshots
+	}
+	
+	cutoff := time.Now().Add(-since)
+	var recent []snapshotInfo
+	for _, snapshot := range snapshots {
+		if !snapshot.Created.Before(cutoff) {
+			recent = append(recent, snapshot)
+		}
+	}
+	return recent
+}
+
+// formatAge describes how long ago t was, e.g. "3d ago"
+func formatAge(t time.Time) string {
+	age := time.Since(t)
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// linkOverExisting links source over a regular file at target in a
+// transaction of config's and returns the transaction
+func linkOverExisting(t *testing.T, config *Config, source, target string) *Transaction {
+	t.Helper()
+	writeTestFile(t, source, "managed\n")
+	writeTestFile(t, target, "existing\n")
+	tx := NewTransaction(config)
+	tx.AddOperation(NewLinkOperation(source, target, nil))
+	if err := tx.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestNoBackupDeletesReplacedTarget(t *testing.T) {
+	config, home := newTestConfig(t)
+	config.NoBackup = true
+	target := filepath.Join(home, ".zshrc")
+
+	tx := linkOverExisting(t, config, filepath.Join(config.DotfilesDir, "shell", "zshrc"), target)
+	if backups, _ := filepath.Glob(target + ".backup*"); len(backups) != 0 {
+		t.Errorf("backups were made: %q", backups)
+	}
+	if names := readDirNames(t, config.GetBackupDir()); len(names) != 0 {
+		t.Errorf("backup directory holds %q", names)
+	}
+
+	// Rollback can only remove the link; the replaced file is gone
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if fileExistsNoFollow(target) {
+		t.Error("rollback left something at the target")
+	}
+}
+
+func TestBackupPolicyFollowsEachConfig(t *testing.T) {
+	disposable, home := newTestConfig(t)
+	disposable.NoBackup = true
+	rolling := createMinimalConfig(filepath.Join(home, ".config", "other"))
+	rolling.BackupStyle = BackupStyleRolling
+
+	// Transactions of both configs are created before either runs, as
+	// parallel workers would
+	first := filepath.Join(home, ".first")
+	second := filepath.Join(home, ".second")
+	writeTestFile(t, first, "existing\n")
+	writeTestFile(t, second, "existing\n")
+	source := filepath.Join(disposable.DotfilesDir, "source")
+	writeTestFile(t, source, "managed\n")
+	txRolling := NewTransaction(rolling)
+	txRolling.AddOperation(NewLinkOperation(source, second, nil))
+	txDisposable := NewTransaction(disposable)
+	txDisposable.AddOperation(NewLinkOperation(source, first, nil))
+
+	if err := txDisposable.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if err := txRolling.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := filepath.Glob(first + ".backup*"); len(backups) != 0 {
+		t.Errorf("no_backup config made backups: %q", backups)
+	}
+	if data, err := os.ReadFile(second + rollingBackupSuffix); err != nil || string(data) != "existing\n" {
+		t.Errorf("rolling config's backup holds %q (%v), want the replaced file", data, err)
+	}
+}
+
+func TestBackupTimeFormatRoundTrip(t *testing.T) {
+	when := time.Date(2024, time.November, 3, 9, 8, 7, 0, time.Local)
+	for _, format := range []string{defaultBackupTimeFormat, legacyBackupTimeFormat, "20060102T150405", "2006.01.02-15.04.05"} {
+		if err := validateBackupTimeFormat(format); err != nil {
+			t.Errorf("%q: %v", format, err)
+			continue
+		}
+		parsed, err := parseBackupTimestamp(format, when.Format(format))
+		if err != nil || !parsed.Equal(when) {
+			t.Errorf("%q: parsed %v (%v), want %v", format, parsed, err, when)
+		}
+	}
+
+	// Names in the legacy format are still understood under another format
+	if parsed, err := parseBackupTimestamp(defaultBackupTimeFormat, when.Format(legacyBackupTimeFormat)); err != nil || !parsed.Equal(when) {
+		t.Errorf("legacy name: parsed %v (%v), want %v", parsed, err, when)
+	}
+}
+
+func TestBackupTimeFormatRejected(t *testing.T) {
+	for _, format := range []string{
+		"2006-01-02 15:04:05", // space and colons
+		"2006/01/02_150405",   // slashes
+		"2006-01-02",          // no time
+		"15-04-05",            // no date
+		"2006-01-02_15-04",    // no seconds
+	} {
+		if validateBackupTimeFormat(format) == nil {
+			t.Errorf("%q was accepted", format)
+		}
+	}
+}
+
+func TestInvalidBackupTimeFormatFallsBack(t *testing.T) {
+	config := createMinimalConfig(t.TempDir())
+	config.BackupTimeFormat = "2006/01/02"
+	if got := config.backupTimeFormat(); got != defaultBackupTimeFormat {
+		t.Errorf("backupTimeFormat() = %q, want the default", got)
+	}
+	config.BackupTimeFormat = "20060102T150405"
+	if got := config.backupPolicy().timeFormat; got != "20060102T150405" {
+		t.Errorf("policy time format = %q, want the configured one", got)
+	}
+}
+
+func TestBackupsRedirectedOutOfDotfiles(t *testing.T) {
+	config, _ := newTestConfig(t)
+	target := filepath.Join(config.DotfilesDir, "nested", "target")
+
+	tx := linkOverExisting(t, config, filepath.Join(t.TempDir(), "source"), target)
+	if backups, _ := filepath.Glob(target + ".backup*"); len(backups) != 0 {
+		t.Errorf("backup left in the dotfiles directory: %q", backups)
+	}
+	redirected, _ := filepath.Glob(filepath.Join(config.GetBackupDir(), redirectedBackupsDirName, target+".backup.*"))
+	if len(redirected) != 1 {
+		t.Fatalf("redirected backups %q, want one", redirected)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "existing\n" {
+		t.Errorf("rollback restored %q (%v), want the redirected backup back in place", data, err)
+	}
+}
+
+func TestBackupsRedirectedOutOfConfigDir(t *testing.T) {
+	config, _ := newTestConfig(t)
+	target := filepath.Join(config.ConfigDir, "templates", "gitconfig.tmpl")
+
+	linkOverExisting(t, config, filepath.Join(t.TempDir(), "source"), target)
+	if backups, _ := filepath.Glob(target + ".backup*"); len(backups) != 0 {
+		t.Errorf("backup left in the config directory: %q", backups)
+	}
+	redirected, _ := filepath.Glob(filepath.Join(config.GetBackupDir(), redirectedBackupsDirName, target+".backup.*"))
+	if len(redirected) != 1 {
+		t.Errorf("redirected backups %q, want one", redirected)
+	}
+}
+
+func TestStrandedRedirectedBackupRecovered(t *testing.T) {
+	config, _ := newTestConfig(t)
+	policy := config.backupPolicy()
+	target := filepath.Join(config.DotfilesDir, "nested", "target")
+	writeTestFile(t, target, "existing\n")
+	stem, ok := policy.redirected(target)
+	if !ok {
+		t.Fatalf("%s isn't redirected", target)
+	}
+	entry := journalEntry{Kind: journalKindLink, Target: target, BackupStem: stem, Existed: true, Started: time.Now()}
+
+	// Crash after the target was moved to its backup and linked
+	backupPath := policy.targetPath(target)
+	if err := policy.makeRoom(backupPath, os.RemoveAll); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveFile(target, backupPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(t.TempDir(), "source"), target); err != nil {
+		t.Fatal(err)
+	}
+
+	if found := findStrandedBackup(entry, policy.timeFormat); found != backupPath {
+		t.Errorf("findStrandedBackup = %q, want %q", found, backupPath)
+	}
+	if err := recoverJournalEntry(entry, policy.timeFormat); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "existing\n" {
+		t.Errorf("recovery restored %q (%v), want the redirected backup back in place", data, err)
+	}
+}
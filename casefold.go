@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isCaseInsensitiveDir probes whether dir is on a case-insensitive filesystem
+// (the macOS and Windows defaults) by looking it up with its case flipped.
+// Nothing is written. A path without letters can't be probed and counts as
+// case-sensitive.
+func isCaseInsensitiveDir(dir string) bool {
+	if dir == "" {
+		return false
+	}
+	
+	flipped := strings.ToUpper(dir)
+	if flipped == dir {
+		flipped = strings.ToLower(dir)
+	}
+	if flipped == dir {
+		return false
+	}
+	
+	info, err := os.Stat(dir)
+	if err != nil {
+		return false
+	}
+	flippedInfo, err := os.Stat(flipped)
+	return err == nil && os.SameFile(info, flippedInfo)
+}
+
+// sameTargetPath reports whether two targets are the same file: identical,
+// or differing only by case in a directory that is case-insensitive
+func sameTargetPath(a, b string) bool {
+	if a == b {
+		return true
+	}
+	return strings.EqualFold(a, b) && isCaseInsensitiveDir(nearestExistingDir(filepath.Dir(a)))
+}
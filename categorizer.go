@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// categorizerTimeout bounds each call to the external categorizer
+const categorizerTimeout = 5 * time.Second
+
+// externalCategory asks config.CategorizerCmd for filename's category. The
+// command gets the filename on stdin and prints the category on stdout. An
+// answer that isn't a known category is added as a new one; failures, empty
+// output and unusable names report ok=false so the caller can fall back.
+func externalCategory(config *Config, filename string) (string, bool) {
+	args := strings.Fields(config.CategorizerCmd)
+	if len(args) == 0 {
+		return "", false
+	}
+	
+	category, err := runCategorizer(args, filename)
+	if err != nil {
+		logger.Debugf("%s: categorizer failed, using built-in rules: %v", filename, err)
+		return "", false
+	}
+	if category == "" {
+		logger.Debugf("%s: categorizer had no answer, using built-in rules", filename)
+		return "", false
+	}
+	if err := validateCategoryName(category); err != nil {
+		logger.Debugf("%s: categorizer returned unusable category %q: %v", filename, category, err)
+		return "", false
+	}
+	
+	for _, existing := range config.Categories {
+		if existing == category {
+			return category, true
+		}
+	}
+	if err := config.AddCategory(category); err != nil {
+		logger.Debugf("%s: could not add category %q from categorizer: %v", filename, category, err)
+		return "", false
+	}
+	logger.Debugf("%s: categorizer added new category %q", filename, category)
+	return category, true
+}
+
+// runCategorizer runs the categorizer command with a timeout and returns the
+// first line it printed
+func runCategorizer(args []string, filename string) (string, error) {
+	timeout := toolTimeout(categorizerTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	name := args[0]
+	if strings.HasPrefix(name, "~/") {
+		name = absPath(name)
+	}
+	
+	output, err := runner.Filter(ctx, []byte(filename+"\n"), name, args[1:]...)
+	if errors.Is(err, errCommandTimeout) {
+		return "", fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		return "", err
+	}
+	
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+// validateCategoryName rejects names that can't be used as a category
+// directory inside the dotfiles directory
+func validateCategoryName(category string) error {
+	if category == "." || category == ".." || strings.ContainsAny(category, `/\`) {
+		return fmt.Errorf("category names can't contain slashes or be . or ..")
+	}
+	for _, r := range category {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("category names can't contain control characters")
+		}
+	}
+	return nil
+}
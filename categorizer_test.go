@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExternalCategoryRunsThroughRunner(t *testing.T) {
+	config, _ := newTestConfig(t)
+	config.CategorizerCmd = "categorize --strict"
+	fake := &scriptedRunner{Responses: []scriptedResponse{{Output: "editor\nignored\n"}, {Output: "window-manager\n"}}}
+	useRunner(t, fake)
+
+	if category, ok := externalCategory(config, ".vimrc"); !ok || category != "editor" {
+		t.Errorf("externalCategory(.vimrc) = %q, %v; want editor", category, ok)
+	}
+	if category, ok := externalCategory(config, ".xinitrc"); !ok || category != "window-manager" {
+		t.Errorf("externalCategory(.xinitrc) = %q, %v; want the new category", category, ok)
+	}
+	want := [][]string{{"categorize", "--strict"}, {"categorize", "--strict"}}
+	if !reflect.DeepEqual(fake.Calls, want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+}
+
+func TestRunCategorizerTimeout(t *testing.T) {
+	useRunner(t, &scriptedRunner{Responses: []scriptedResponse{{Err: fmt.Errorf("categorize %w", errCommandTimeout)}}})
+
+	_, err := runCategorizer([]string{"categorize"}, ".vimrc")
+	if err == nil || !strings.Contains(err.Error(), "timed out after "+categorizerTimeout.String()) {
+		t.Errorf("runCategorizer = %v, want a timeout error", err)
+	}
+}
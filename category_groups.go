@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// uncategorizedLabel heads the group of files without a category
+const uncategorizedLabel = "uncategorized"
+
+// categoryItem is the header row above a category's files in the file list
+type categoryItem struct {
+	category  string
+	count     int
+	collapsed bool
+}
+
+// Headers never match a filter, so filtering shows files only
+func (i categoryItem) FilterValue() string {
+	return ""
+}
+
+func (i categoryItem) Title() string {
+	glyph := glyphExpanded
+	if i.collapsed {
+		glyph = glyphCollapsed
+	}
+	name := i.category
+	if name == "" {
+		name = uncategorizedLabel
+	}
+	return fmt.Sprintf("%s %s (%d)", glyph, name, i.count)
+}
+
+func (i categoryItem) Description() string {
+	if i.collapsed {
+		return "collapsed - press z to show its files"
+	}
+	return ""
+}
+
+// groupByCategory orders file items by category - the configured categories
+// in order, then any others alphabetically, then files without one - with a
+// header before each category. Files of collapsed categories are left out;
+// within a category files keep their order.
+func groupByCategory(items []list.Item, categories []string, collapsed map[string]bool) []list.Item {
+	groups := make(map[string][]list.Item)
+	for _, item := range items {
+		category := item.(fileItem).file.Category
+		groups[category] = append(groups[category], item)
+	}
+	
+	var order []string
+	listed := make(map[string]bool)
+	for _, category := range categories {
+		if len(groups[category]) > 0 && !listed[category] {
+			order = append(order, category)
+			listed[category] = true
+		}
+	}
+	var others []string
+	for category := range groups {
+		if !listed[category] && category != "" {
+			others = append(others, category)
+		}
+	}
+	sort.Strings(others)
+	order = append(order, others...)
+	if len(groups[""]) > 0 {
+		order = append(order, "")
+	}
+	
+	grouped := make([]list.Item, 0, len(items)+len(order))
+	for _, category := range order {
+		header := categoryItem{category: category, count: len(groups[category]), collapsed: collapsed[category]}
+		grouped = append(grouped, header)
+		if !header.collapsed {
+			grouped = append(grouped, groups[category]...)
+		}
+	}
+	return grouped
+}
+
+// groupedDelegate draws category headers flush left and the files beneath
+// them indented, otherwise like the default delegate
+type groupedDelegate struct {
+	list.DefaultDelegate
+	headerStyles list.DefaultItemStyles
+}
+
+func newGroupedDelegate() groupedDelegate {
+	d := groupedDelegate{
+		DefaultDelegate: list.NewDefaultDelegate(),
+		headerStyles:    list.NewDefaultItemStyles(),
+	}
+	d.headerStyles.NormalTitle = activeStyle.Copy().Padding(0, 0, 0, 2)
+	
+	// Styles share their rules until copied
+	styles := &d.DefaultDelegate.Styles
+	styles.NormalTitle = styles.NormalTitle.Copy().MarginLeft(2)
+	styles.NormalDesc = styles.NormalDesc.Copy().MarginLeft(2)
+	styles.SelectedTitle = styles.SelectedTitle.Copy().MarginLeft(2)
+	styles.SelectedDesc = styles.SelectedDesc.Copy().MarginLeft(2)
+	styles.DimmedTitle = styles.DimmedTitle.Copy().MarginLeft(2)
+	styles.DimmedDesc = styles.DimmedDesc.Copy().MarginLeft(2)
+	return d
+}
+
+func (d groupedDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	header, ok := item.(categoryItem)
+	if !ok {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+	
+	title, desc := d.headerStyles.NormalTitle, d.headerStyles.NormalDesc
+	if index == m.Index() {
+		title, desc = d.headerStyles.SelectedTitle, d.headerStyles.SelectedDesc
+	}
+	fmt.Fprintf(w, "%s\n%s", title.Render(header.Title()), desc.Render(header.Description()))
+}
+
+// highlightedCategory is the category of the highlighted header or file
+func (m model) highlightedCategory() (string, bool) {
+	switch item := m.fileList.SelectedItem().(type) {
+	case categoryItem:
+		return item.category, true
+	case fileItem:
+		return item.file.Category, true
+	}
+	return "", false
+}
+
+// selectCategoryHeader highlights the header of category
+func (m model) selectCategoryHeader(category string) model {
+	for i, item := range m.fileList.Items() {
+		if header, ok := item.(categoryItem); ok && header.category == category {
+			m.fileList.Select(i)
+			break
+		}
+	}
+	return m
+}
+
+// selectFile highlights config.Files[index], expanding its category first if
+// it is collapsed
+func (m model) selectFile(index int) model {
+	file := m.config.Files[index]
+	if m.collapsedCategories[file.Category] {
+		delete(m.collapsedCategories, file.Category)
+		m.fileList.SetItems(m.listItems())
+	}
+	for i, item := range m.fileList.Items() {
+		if listed, ok := item.(fileItem); ok && listed.file.Target == file.Target {
+			m.fileList.Select(i)
+			break
+		}
+	}
+	return m
+}
+
+// handleToggleCategory collapses the highlighted category, or expands it
+func (m model) handleToggleCategory() (tea.Model, tea.Cmd) {
+	category, ok := m.highlightedCategory()
+	if !ok {
+		m.message = "No category to collapse"
+		m.messageType = "warning"
+		return m, nil
+	}
+	
+	if m.collapsedCategories == nil {
+		m.collapsedCategories = make(map[string]bool)
+	}
+	name := category
+	if name == "" {
+		name = uncategorizedLabel
+	}
+	if m.collapsedCategories[category] {
+		delete(m.collapsedCategories, category)
+		m.message = fmt.Sprintf("Expanded %s", name)
+	} else {
+		m.collapsedCategories[category] = true
+		m.message = fmt.Sprintf("Collapsed %s", name)
+	}
+	m.messageType = "success"
+	
+	m.fileList.SetItems(m.listItems())
+	return m.selectCategoryHeader(category), nil
+}
+
+// handleToggleAllCategories collapses every category, or expands them all
+// when every one is collapsed already
+func (m model) handleToggleAllCategories() (tea.Model, tea.Cmd) {
+	category, _ := m.highlightedCategory()
+	
+	allCollapsed := true
+	for _, item := range m.fileList.Items() {
+		if header, ok := item.(categoryItem); ok && !header.collapsed {
+			allCollapsed = false
+			break
+		}
+	}
+	
+	if allCollapsed {
+		m.collapsedCategories = nil
+		m.message = "Expanded all categories"
+	} else {
+		m.collapsedCategories = make(map[string]bool)
+		for _, file := range m.config.Files {
+			m.collapsedCategories[file.Category] = true
+		}
+		m.message = "Collapsed all categories"
+	}
+	m.messageType = "success"
+	
+	m.fileList.SetItems(m.listItems())
+	return m.selectCategoryHeader(category), nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// commandTimeout bounds gum prompts and external tools such as diff, so an
+// unattended run can't hang on a prompt nobody answers. It is set from
+// --timeout; 0 waits forever. Editor sessions are never bounded.
+var commandTimeout time.Duration
+
+// errCommandTimeout is returned for a command that ran past commandTimeout
+var errCommandTimeout = errors.New("timed out")
+
+// gumTimedOut is set once a gum prompt has timed out. Nobody is answering, so
+// later prompts take the text path as if gum weren't installed.
+var gumTimedOut atomic.Bool
+
+// commandRunner starts the external programs interactive flows depend on
+// (gum prompts, editors, diff tools, git). It is a variable so tests can replace
+// it with scripted answers instead of driving a real terminal.
+type commandRunner interface {
+	// LookPath searches PATH for a program, like exec.LookPath
+	LookPath(file string) (string, error)
+	// Output runs a prompt reading the terminal and drawing on stderr, and
+	// returns what it printed to stdout (the user's answer)
+	Output(name string, args ...string) ([]byte, error)
+	// Run runs a program attached to the terminal, e.g. gum confirm or diff
+	Run(name string, args ...string) error
+	// Session runs a program attached to the terminal for as long as the user
+	// works in it, e.g. an editor; unlike Run it is never timed out
+	Session(name string, args ...string) error
+	// Capture runs a tool off the terminal under ctx and returns its stdout;
+	// what it prints on stderr goes into the error, e.g. git
+	Capture(ctx context.Context, name string, args ...string) ([]byte, error)
+	// Filter is Capture with input fed to the tool's stdin, e.g. a template
+	// formatter or the categorizer
+	Filter(ctx context.Context, input []byte, name string, args ...string) ([]byte, error)
+	// Command builds a program for the TUI to hand the terminal to with
+	// tea.ExecProcess, e.g. a shell or pager. A failed PATH lookup is left
+	// in the command's Err, as exec.Command does.
+	Command(name string, args ...string) *exec.Cmd
+}
+
+// runner is the commandRunner used by prompts, editors and the setup wizard
+var runner commandRunner = execRunner{}
+
+// execRunner runs real programs on the current terminal
+type execRunner struct{}
+
+func (execRunner) LookPath(file string) (string, error) {
+	if file == "gum" && gumTimedOut.Load() {
+		return "", &exec.Error{Name: file, Err: errCommandTimeout}
+	}
+	return exec.LookPath(file)
+}
+
+func (execRunner) Output(name string, args ...string) ([]byte, error) {
+	ctx, cancel := commandContext()
+	defer cancel()
+	
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = time.Second // don't wait on children still holding stdout after the kill
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	return output, timeoutError(ctx, name, err)
+}
+
+func (execRunner) Run(name string, args ...string) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+	
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return timeoutError(ctx, name, cmd.Run())
+}
+
+func (execRunner) Session(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (execRunner) Capture(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return execRunner{}.Filter(ctx, nil, name, args...)
+}
+
+func (execRunner) Filter(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = time.Second
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("%s %w", name, errCommandTimeout)
+	}
+	if err != nil && stderr.Len() > 0 {
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, err
+}
+
+func (execRunner) Command(name string, args ...string) *exec.Cmd {
+	return exec.Command(name, args...)
+}
+
+// commandContext is the context a prompt or tool runs under: bounded by
+// commandTimeout when one is set
+func commandContext() (context.Context, context.CancelFunc) {
+	if commandTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), commandTimeout)
+}
+
+// toolTimeout is how long a tool with its own limit may run: that limit, or
+// commandTimeout when it is shorter
+func toolTimeout(limit time.Duration) time.Duration {
+	if commandTimeout > 0 && commandTimeout < limit {
+		return commandTimeout
+	}
+	return limit
+}
+
+// timeoutError turns the error of a command killed for running past
+// commandTimeout into one wrapping errCommandTimeout, and stops offering gum
+// if it was gum that timed out
+func timeoutError(ctx context.Context, name string, err error) error {
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	if name == "gum" {
+		gumTimedOut.Store(true)
+	}
+	return fmt.Errorf("%s %w after %s", name, errCommandTimeout, commandTimeout)
+}
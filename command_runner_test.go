@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedRunner is a fake commandRunner for tests. Programs listed in
+// Available are found on the "PATH"; each Output, Run, Session, Capture or
+// Filter call consumes the next response in order and is recorded in Calls.
+// Command only records the call, as nothing runs until the TUI hands over.
+type scriptedRunner struct {
+	Available map[string]bool
+	Responses []scriptedResponse
+	Calls     [][]string
+}
+
+// scriptedResponse is what one scripted program call prints and returns
+type scriptedResponse struct {
+	Output string
+	Err    error
+}
+
+// useRunner swaps in r as the package runner for the rest of the test
+func useRunner(t *testing.T, r commandRunner) {
+	t.Helper()
+	previous := runner
+	runner = r
+	t.Cleanup(func() { runner = previous })
+}
+
+func (r *scriptedRunner) LookPath(file string) (string, error) {
+	if r.Available[file] {
+		return "/fake/bin/" + file, nil
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+func (r *scriptedRunner) Output(name string, args ...string) ([]byte, error) {
+	response, err := r.next(name, args)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(response.Output), response.Err
+}
+
+func (r *scriptedRunner) Run(name string, args ...string) error {
+	response, err := r.next(name, args)
+	if err != nil {
+		return err
+	}
+	return response.Err
+}
+
+func (r *scriptedRunner) Session(name string, args ...string) error {
+	return r.Run(name, args...)
+}
+
+func (r *scriptedRunner) Capture(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return r.Output(name, args...)
+}
+
+func (r *scriptedRunner) Filter(ctx context.Context, input []byte, name string, args ...string) ([]byte, error) {
+	return r.Output(name, args...)
+}
+
+func (r *scriptedRunner) Command(name string, args ...string) *exec.Cmd {
+	r.Calls = append(r.Calls, append([]string{name}, args...))
+	cmd := &exec.Cmd{Path: "/fake/bin/" + name, Args: append([]string{name}, args...)}
+	if _, err := r.LookPath(name); err != nil {
+		cmd.Err = err
+	}
+	return cmd
+}
+
+// next records a call and pops its scripted response
+func (r *scriptedRunner) next(name string, args []string) (scriptedResponse, error) {
+	r.Calls = append(r.Calls, append([]string{name}, args...))
+	if len(r.Responses) == 0 {
+		return scriptedResponse{}, fmt.Errorf("unexpected call: %s %s", name, strings.Join(args, " "))
+	}
+	response := r.Responses[0]
+	r.Responses = r.Responses[1:]
+	return response, nil
+}
+
+// callNames returns the program of each recorded call
+func (r *scriptedRunner) callNames() []string {
+	names := make([]string, len(r.Calls))
+	for i, call := range r.Calls {
+		names[i] = call[0]
+	}
+	return names
+}
+
+func TestExecRunnerCaptureIncludesStderr(t *testing.T) {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	output, err := execRunner{}.Capture(ctx, "sh", "-c", "echo out; echo broken >&2; exit 3")
+	if string(output) != "out\n" {
+		t.Errorf("output = %q, want %q", output, "out\n")
+	}
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("err = %v, want the command's stderr in it", err)
+	}
+}
+
+func TestToolTimeout(t *testing.T) {
+	previous := commandTimeout
+	t.Cleanup(func() { commandTimeout = previous })
+
+	for _, test := range []struct {
+		commandTimeout, limit, want time.Duration
+	}{
+		{0, 5 * time.Second, 5 * time.Second},
+		{time.Second, 5 * time.Second, time.Second},
+		{time.Minute, 5 * time.Second, 5 * time.Second},
+	} {
+		commandTimeout = test.commandTimeout
+		if got := toolTimeout(test.limit); got != test.want {
+			t.Errorf("toolTimeout(%s) with --timeout %s = %s, want %s", test.limit, test.commandTimeout, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,796 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// command is a CLI subcommand run instead of the TUI
+type command struct {
+	name        string
+	usage       string
+	description string
+	mutates     bool // takes the instance lock before running
+	noConfig    bool // runs without loading config.json (config is the minimal default for the config directory)
+	run         func(config *Config, args []string) error
+}
+
+// commands lists the available subcommands
+var commands = []command{
+	{
+		name:        "setup",
+		usage:       "setup [--non-interactive]",
+		description: "create the configuration with the setup wizard, or from --editor, --shell and --adopt with --non-interactive",
+		mutates:     true,
+		noConfig:    true,
+		run:         runSetup,
+	},
+	{
+		name:        "doctor",
+		usage:       "doctor [--fix]",
+		description: "check for dangling and orphaned symlinks and offer to remove them; --fix first repairs what it safely can",
+		mutates:     true,
+		run:         runDoctor,
+	},
+	{
+		name:        "prune-sources",
+		usage:       "prune-sources",
+		description: "list files in the dotfiles directory no managed file uses and offer to delete them",
+		mutates:     true,
+		run:         runPruneSources,
+	},
+	{
+		name:        "refresh",
+		usage:       "refresh",
+		description: "re-scan sources and update which files are treated as templates",
+		mutates:     true,
+		run:         runRefresh,
+	},
+	{
+		name:        "lint-templates",
+		usage:       "lint-templates",
+		description: "report template parse errors, undefined variables and unused variables",
+		run:         runLintTemplates,
+	},
+	{
+		name:        "render",
+		usage:       "render [--trace] <name|target>",
+		description: "print a template file's rendered output; --trace also lists which conditions were taken",
+		run:         runRender,
+	},
+	{
+		name:        "apply",
+		usage:       "apply [--only <glob>] [--tag <tag>]",
+		description: "link files that aren't linked yet in one transaction; --only and --tag limit it to files whose name or target matches, or that carry the tag",
+		mutates:     true,
+		run:         runApply,
+	},
+	{
+		name:        "verify",
+		usage:       "verify [--fix]",
+		description: "report files whose target or rendered template is out of sync; exits non-zero if any are",
+		mutates:     true,
+		run:         runVerify,
+	},
+	{
+		name:        "relink",
+		usage:       "relink [name|target...]",
+		description: "re-point links left dangling or at an old source (e.g. after sources moved) without backing anything up",
+		mutates:     true,
+		run:         runRelink,
+	},
+	{
+		name:        "backups",
+		usage:       "backups [--since 7d]",
+		description: "list backups, newest first",
+		run:         runBackups,
+	},
+	{
+		name:        "snapshots",
+		usage:       "snapshots [--since 7d]",
+		description: "list snapshots, newest first",
+		run:         runSnapshots,
+	},
+	{
+		name:        "suggest",
+		usage:       "suggest [-n 20] [--all]",
+		description: "rank unmanaged dotfiles and config directories worth adopting, with the category each would get",
+		run:         runSuggest,
+	},
+	{
+		name:        "log",
+		usage:       "log [-n 20]",
+		description: "show the most recent transactions from the operations log",
+		noConfig:    true,
+		run:         runLog,
+	},
+	{
+		name:        "import",
+		usage:       "import [--replace] <file|url>",
+		description: "preview and merge an exported config from a file, http(s) URL or git repository",
+		mutates:     true,
+		run:         runImport,
+	},
+	{
+		name:        "inventory",
+		usage:       "inventory",
+		description: "record this machine's link status in inventory.json in the dotfiles directory",
+		mutates:     true,
+		run:         runInventory,
+	},
+	{
+		name:        "serve",
+		usage:       "serve [--port N]",
+		description: "serve a token-protected HTTP API on 127.0.0.1 for editor integration",
+		mutates:     true,
+		run:         runServe,
+	},
+	{
+		name:        "diff-config",
+		usage:       "diff-config <fileA> <fileB>",
+		description: "compare two exported configs (categories, variables and files)",
+		noConfig:    true,
+		run:         runDiffConfig,
+	},
+}
+
+// findCommand looks up a subcommand by name
+func findCommand(name string) (*command, bool) {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i], true
+		}
+	}
+	return nil, false
+}
+
+// runCommand executes a subcommand and returns the process exit code
+func runCommand(configDir string, args []string) int {
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		errorf("Unknown command: %s\n\n", args[0])
+		printUsage()
+		return 2
+	}
+	
+	if cmd.mutates {
+		lock, err := acquireInstanceLock(configDir)
+		if err != nil {
+			errorf("Error: %v\n", err)
+			return 1
+		}
+		defer lock.Release()
+		
+		offerJournalRecovery(configDir)
+	}
+	
+	config := createMinimalConfig(configDir)
+	if !cmd.noConfig {
+		var err error
+		config, err = loadConfigForCommand(configDir)
+		if err != nil {
+			errorf("Error: %v\n", err)
+			return 1
+		}
+	}
+	
+	if err := cmd.run(config, args[1:]); err != nil {
+		errorf("Error: %v\n", err)
+		return 1
+	}
+	
+	return 0
+}
+
+// loadConfigForCommand loads an existing config without running the setup wizard
+func loadConfigForCommand(configDir string) (*Config, error) {
+	configFile := filepath.Join(configDir, "config.json")
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		return nil, NewConfigError("load config", configFile, 
+			fmt.Errorf("no configuration found; run config-manager without a command to set up"))
+	}
+	
+	config, err := loadConfigFile(configFile, configDir)
+	if err != nil {
+		return nil, err
+	}
+	
+	updateFileStatuses(config)
+	return config, nil
+}
+
+// printUsage describes global flags and subcommands
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: config-manager [flags] [command]\n\n")
+	fmt.Fprintf(out, "Without a command, the interactive TUI is started.\n\n")
+	fmt.Fprintf(out, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-24s %s\n", cmd.usage, cmd.description)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// runDoctor reports dangling and orphaned symlinks and offers to clean them up
+func runDoctor(config *Config, args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fix := flags.Bool("fix", false, "re-render missing template sources, relink dangling links, drop empty backups and create missing category directories")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	
+	if *fix {
+		if err := runDoctorRepairs(config); err != nil {
+			return err
+		}
+	}
+	
+	dangling := findDanglingLinks(config)
+	orphaned := findOrphanedLinks(config)
+	
+	infoln(decoration("🩺") + "Checking managed links...")
+	if len(dangling) == 0 {
+		infoln(glyphSuccess + " No dangling links")
+	} else {
+		fmt.Printf("%s %d dangling links (source was deleted):\n", glyphWarning, len(dangling))
+		for _, path := range dangling {
+			fmt.Printf("  - %s\n", path)
+		}
+	}
+	
+	if len(orphaned) == 0 {
+		infoln(glyphSuccess + " No orphaned links")
+	} else {
+		fmt.Printf("%s %d orphaned links (point into %s but aren't managed):\n", glyphWarning, len(orphaned), config.DotfilesDir)
+		for _, path := range orphaned {
+			fmt.Printf("  - %s\n", path)
+		}
+	}
+	
+	if isGitManaged(config.DotfilesDir) {
+		if problems := findUnexecutableInGit(config); len(problems) == 0 {
+			infoln(glyphSuccess + " Executable sources are recorded as executable in git")
+		} else {
+			fmt.Printf("%s %d executable sources git would check out without the executable bit:\n", glyphWarning, len(problems))
+			for _, problem := range problems {
+				fmt.Printf("  - %s\n", problem)
+			}
+		}
+	}
+	
+	if len(dangling) > 0 {
+		confirmed, err := confirmAction(fmt.Sprintf("Remove %d dangling links?", len(dangling)))
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			if err := removeLinks(dangling); err != nil {
+				return err
+			}
+			fmt.Printf("%s Removed %d dangling links\n", glyphSuccess, len(dangling))
+		}
+	}
+	
+	if len(orphaned) > 0 {
+		confirmed, err := confirmAction(fmt.Sprintf("Remove %d orphaned links?", len(orphaned)))
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			if err := removeLinks(orphaned); err != nil {
+				return err
+			}
+			fmt.Printf("%s Removed %d orphaned links\n", glyphSuccess, len(orphaned))
+		}
+	}
+	
+	return nil
+}
+
+// runDoctorRepairs lists what doctor --fix would repair and, once confirmed,
+// applies it all in one transaction
+func runDoctorRepairs(config *Config) error {
+	repairs, err := planDoctorRepairs(config)
+	if err != nil {
+		return err
+	}
+	
+	infoln(decoration("🔧") + "Looking for problems that can be repaired...")
+	if len(repairs.manual) > 0 {
+		fmt.Printf("%s %d problems need your attention:\n", glyphWarning, len(repairs.manual))
+		for _, problem := range repairs.manual {
+			fmt.Printf("  - %s\n", problem)
+		}
+	}
+	if len(repairs.fixes) == 0 {
+		infoln(glyphSuccess + " Nothing to repair")
+		return nil
+	}
+	
+	fmt.Printf("%d repairs:\n", len(repairs.fixes))
+	for _, fix := range repairs.fixes {
+		fmt.Printf("  - %s\n", fix)
+	}
+	confirmed, err := confirmAction(fmt.Sprintf("Apply %d repairs?", len(repairs.fixes)))
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return nil
+	}
+	
+	if err := repairs.tx.Execute(); err != nil {
+		return NewConfigError("repair", config.DotfilesDir, err)
+	}
+	for _, target := range repairs.relinks {
+		config.markLinked(target)
+	}
+	if len(repairs.relinks) > 0 {
+		saveLinkTimes(config)
+	}
+	updateFileStatuses(config)
+	fmt.Printf("%s Applied %d repairs\n", glyphSuccess, len(repairs.fixes))
+	return nil
+}
+
+// runPruneSources lists sources left behind by removed files and deletes
+// them after confirmation
+func runPruneSources(config *Config, args []string) error {
+	flags := flag.NewFlagSet("prune-sources", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	
+	orphaned, err := findOrphanedSources(config)
+	if err != nil {
+		return err
+	}
+	
+	if len(orphaned) == 0 {
+		infoln(glyphSuccess + " No unreferenced sources")
+		return nil
+	}
+	
+	fmt.Printf("%s %d entries in %s aren't used by any managed file:\n", glyphWarning, len(orphaned), config.DotfilesDir)
+	for _, path := range orphaned {
+		relPath, _ := filepath.Rel(config.DotfilesDir, path)
+		if info, err := os.Lstat(path); err == nil && info.IsDir() {
+			relPath += string(filepath.Separator)
+		}
+		fmt.Printf("  - %s\n", relPath)
+	}
+	
+	confirmed, err := confirmAction(fmt.Sprintf("Delete %d unreferenced sources?", len(orphaned)))
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return nil
+	}
+	
+	if err := removeSources(config, orphaned); err != nil {
+		return err
+	}
+	fmt.Printf("%s Deleted %d unreferenced sources\n", glyphSuccess, len(orphaned))
+	return nil
+}
+
+// runRefresh re-detects templates from the current sources and saves the
+// config if anything changed
+func runRefresh(config *Config, args []string) error {
+	flags := flag.NewFlagSet("refresh", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	
+	changes := config.RefreshFileMetadata()
+	if len(changes) == 0 {
+		infoln(glyphSuccess + " Template flags are up to date")
+		return nil
+	}
+	
+	for _, change := range changes {
+		fmt.Printf("  - %s\n", change)
+	}
+	if err := saveConfigSafe(config); err != nil {
+		return err
+	}
+	fmt.Printf("%s Updated %d files\n", glyphSuccess, len(changes))
+	return nil
+}
+
+// runLintTemplates reports problems found by lintTemplates, failing on errors
+func runLintTemplates(config *Config, args []string) error {
+	flags := flag.NewFlagSet("lint-templates", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	
+	issues, err := lintTemplates(config)
+	if err != nil {
+		return err
+	}
+	
+	infoln(decoration("🔍") + "Linting templates...")
+	if len(issues) == 0 {
+		infoln(glyphSuccess + " No problems found")
+		return nil
+	}
+	
+	errorCount := 0
+	for _, issue := range issues {
+		marker := glyphWarning
+		if issue.IsError {
+			marker = glyphError
+			errorCount++
+		}
+		fmt.Printf("%s %s: %s\n", marker, issue.Template, issue.Message)
+	}
+	
+	if errorCount > 0 {
+		return fmt.Errorf("%d template errors, %d warnings", errorCount, len(issues)-errorCount)
+	}
+	infof("%d warnings\n", len(issues))
+	return nil
+}
+
+// runRender renders a template file to stdout without writing its source,
+// optionally tracing the conditions the template evaluated
+func runRender(config *Config, args []string) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	trace := flags.Bool("trace", false, "list the if/with/range conditions evaluated and whether each was taken")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: config-manager render [--trace] <name|target>")
+	}
+	
+	file, err := findFileForCommand(config, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !file.Template {
+		return NewConfigError("render", file.Name, fmt.Errorf("file is not marked as template"))
+	}
+	
+	templatePath := findTemplateFile(config, file.Name, file.Source, file.Category)
+	if templatePath == "" {
+		return NewConfigError("render", file.Name, fmt.Errorf("template file not found"))
+	}
+	
+	context, err := createTemplateContext(config, file)
+	if err != nil {
+		return err
+	}
+	
+	output, err := renderTemplate(templatePath, context, file.Formatter)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(output)
+	
+	if *trace {
+		branches, err := traceTemplateBranches(templatePath, context)
+		if err != nil {
+			return err
+		}
+		printTemplateTrace(branches)
+	}
+	return nil
+}
+
+// findFileForCommand looks up a managed file by target path or, failing
+// that, by name, which must then be unambiguous
+func findFileForCommand(config *Config, nameOrTarget string) (*ConfigFile, error) {
+	if file, err := config.GetConfigFileByTarget(absPath(nameOrTarget)); err == nil {
+		return file, nil
+	}
+	
+	matches := config.GetConfigFilesByName(nameOrTarget)
+	switch len(matches) {
+	case 0:
+		return nil, NewConfigError("find config file", nameOrTarget, fmt.Errorf("file not found in configuration"))
+	case 1:
+		return matches[0], nil
+	default:
+		targets := make([]string, len(matches))
+		for i, match := range matches {
+			targets[i] = match.Target
+		}
+		return nil, NewConfigError("find config file", nameOrTarget,
+			fmt.Errorf("several files have this name; use the target instead: %s", strings.Join(targets, ", ")))
+	}
+}
+
+// runVerify prints an OK/DRIFT/MISSING line per file and fails if anything is
+// out of sync. With --fix, symlinks pointing elsewhere are relinked first.
+func runVerify(config *Config, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fix := flags.Bool("fix", false, "relink symlinks that point somewhere other than their source")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	
+	results := verifyConfig(config)
+	
+	if *fix {
+		fixed := 0
+		for _, result := range results {
+			if !result.Fixable {
+				continue
+			}
+			if err := atomicLinkSingleConfig(config, result.File); err != nil {
+				errorf("Failed to relink %s: %v\n", result.File.Name, err)
+				continue
+			}
+			fixed++
+		}
+		if fixed > 0 {
+			saveLinkTimes(config)
+			infof("%sRelinked %d files\n", decoration("🔗"), fixed)
+			results = verifyConfig(config)
+		}
+	}
+	
+	outOfSync := 0
+	for _, result := range results {
+		if result.Status == verifyOK {
+			fmt.Printf("%-8s %s\n", result.Status, result.File.Name)
+			continue
+		}
+		outOfSync++
+		fmt.Printf("%-8s %s (%s): %s\n", result.Status, result.File.Name, result.File.Target, result.Detail)
+	}
+	
+	if outOfSync > 0 {
+		return fmt.Errorf("%d of %d files out of sync", outOfSync, len(results))
+	}
+	return nil
+}
+
+// runImport merges (or with --replace, replaces the config with) an exported
+// config read from a local file or fetched from a URL, after confirmation
+func runImport(config *Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	replace := flags.Bool("replace", false, "replace the current config instead of merging into it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: config-manager import [--replace] <file|url>")
+	}
+	
+	ref := flags.Arg(0)
+	if isRemoteImport(ref) {
+		return importFromURL(config, ref, !*replace)
+	}
+	
+	data, err := os.ReadFile(resolveHomePath(ref))
+	if err != nil {
+		return NewConfigError("read import file", ref, err)
+	}
+	return confirmAndImport(config, data, ref, !*replace)
+}
+
+// runDiffConfig prints the structural differences between two exported configs
+func runDiffConfig(config *Config, args []string) error {
+	flags := flag.NewFlagSet("diff-config", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("usage: config-manager diff-config <fileA> <fileB>")
+	}
+	
+	dataA, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return NewConfigError("read config", flags.Arg(0), err)
+	}
+	dataB, err := os.ReadFile(flags.Arg(1))
+	if err != nil {
+		return NewConfigError("read config", flags.Arg(1), err)
+	}
+	
+	diff, err := diffConfigs(dataA, dataB)
+	if err != nil {
+		return err
+	}
+	
+	if diff == "" {
+		infoln(glyphSuccess + " No differences")
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// runApply links the files that aren't linked yet, optionally only those
+// matching --only. Conflicted and drifted files are left for the TUI.
+func runApply(config *Config, args []string) error {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	only := flags.String("only", "", "only link files whose name or target matches this glob, e.g. '.config/nvim*'")
+	tag := flags.String("tag", "", "only link files carrying this tag, e.g. minimal")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("usage: config-manager apply [--only <glob>] [--tag <tag>]")
+	}
+	
+	include := func(*ConfigFile) bool { return true }
+	if *only != "" || *tag != "" {
+		candidates := config.Files
+		var selection []string
+		if *only != "" {
+			if _, err := filepath.Match(*only, ""); err != nil {
+				return fmt.Errorf("invalid --only pattern %q: %v", *only, err)
+			}
+			candidates = filterFiles(config, *only)
+			selection = append(selection, *only)
+		}
+		if *tag != "" {
+			selection = append(selection, "tag "+*tag)
+		}
+		
+		matched := make(map[string]bool)
+		for _, file := range candidates {
+			if *tag == "" || file.HasTag(*tag) {
+				matched[file.Target] = true
+			}
+		}
+		infof("%d of %d files match %s\n", len(matched), len(config.Files), strings.Join(selection, " and "))
+		if len(matched) == 0 {
+			return nil
+		}
+		include = func(file *ConfigFile) bool { return matched[file.Target] }
+	}
+	
+	linked, left, err := linkUnlinkedWhere(config, include)
+	if err != nil {
+		return err
+	}
+	if linked > 0 {
+		saveLinkTimes(config)
+	}
+	
+	infof("%s Linked %d files\n", glyphSuccess, linked)
+	if left > 0 {
+		warnf("%s %d conflicted or drifted files were left alone; run config-manager and press c to resolve conflicts\n", glyphWarning, left)
+	}
+	return nil
+}
+
+// runLog prints the last transactions from the operations log, oldest first
+func runLog(config *Config, args []string) error {
+	flags := flag.NewFlagSet("log", flag.ContinueOnError)
+	limit := flags.Int("n", 20, "number of transactions to show (0 for all)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	
+	entries, err := readOperationsLog(*limit)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		infof("No operations logged in %s\n", operationsLogPath)
+		return nil
+	}
+	
+	for _, entry := range entries {
+		fmt.Println(entry.Header)
+		for _, op := range entry.Operations {
+			fmt.Printf("    %s\n", op)
+		}
+	}
+	return nil
+}
+
+// runInventory updates this machine's section of the shared inventory and
+// lists every host recorded in it
+func runInventory(config *Config, args []string) error {
+	flags := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	
+	data, err := exportInventory(config)
+	if err != nil {
+		return err
+	}
+	if err := atomicWrite(config.inventoryPath(), data, 0644); err != nil {
+		return err
+	}
+	
+	var inventory map[string]inventoryHost
+	if err := json.Unmarshal(data, &inventory); err != nil {
+		return NewConfigError("parse inventory", config.inventoryPath(), err)
+	}
+	hosts := make([]string, 0, len(inventory))
+	for host := range inventory {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	
+	infof("%s Updated %s\n", glyphSuccess, config.inventoryPath())
+	for _, host := range hosts {
+		entry := inventory[host]
+		infof("  %s (%s, %s): %s\n", host, entry.Version, entry.Updated.Local().Format("2006-01-02 15:04"), summarizeInventoryHost(entry))
+	}
+	return nil
+}
+
+// parseSinceFlag registers --since on flags and returns a function reading it
+func parseSinceFlag(flags *flag.FlagSet) func() (time.Duration, error) {
+	since := flags.String("since", "", "only list entries newer than this, e.g. 7d, 12h or 30m")
+	return func() (time.Duration, error) {
+		if *since == "" {
+			return 0, nil
+		}
+		return parseSinceDuration(*since)
+	}
+}
+
+// runBackups lists backups, optionally only recent ones
+func runBackups(config *Config, args []string) error {
+	flags := flag.NewFlagSet("backups", flag.ContinueOnError)
+	sinceValue := parseSinceFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	since, err := sinceValue()
+	if err != nil {
+		return err
+	}
+	
+	backups, err := listBackups(config, since)
+	if err != nil {
+		return err
+	}
+	
+	if len(backups) == 0 {
+		infof("No backups found in %s\n", config.GetBackupDir())
+		return nil
+	}
+	for _, backup := range backups {
+		fmt.Printf("%s  %-8s %s\n", backup.Name, formatAge(backup.Created), backup.Path)
+	}
+	return nil
+}
+
+// runSnapshots lists snapshots, optionally only recent ones
+func runSnapshots(config *Config, args []string) error {
+	flags := flag.NewFlagSet("snapshots", flag.ContinueOnError)
+	sinceValue := parseSinceFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	since, err := sinceValue()
+	if err != nil {
+		return err
+	}
+	
+	snapshots, err := listSnapshots(config)
+	if err != nil {
+		return err
+	}
+	snapshots = snapshotsSince(snapshots, since)
+	
+	if len(snapshots) == 0 {
+		infoln("No snapshots found")
+		return nil
+	}
+	for _, snapshot := range snapshots {
+		fmt.Printf("%-24s %s  %-8s %d targets\n", snapshot.Name, snapshot.Created.Format(config.backupTimeFormat()), 
+			formatAge(snapshot.Created), len(snapshot.Entries))
+	}
+	return nil
+}
@@ -2,58 +2,162 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 )
 
+// configHomeEnv overrides the default config directory when set
+const configHomeEnv = "CONFIG_MANAGER_HOME"
+
+// resolveConfigDir picks the config directory with precedence
+// --config flag > $CONFIG_MANAGER_HOME > $XDG_CONFIG_HOME/config-manager
+func resolveConfigDir(flagValue string) string {
+	if flagValue != "" {
+		return absPath(flagValue)
+	}
+	if envValue := os.Getenv(configHomeEnv); envValue != "" {
+		return absPath(envValue)
+	}
+	
+	configDir := filepath.Join(xdgConfigHome(), "config-manager")
+	
+	// Keep using an existing ~/.config/config-manager created before XDG_CONFIG_HOME was honored
+	homeDir, _ := mustHome()
+	legacyDir := filepath.Join(homeDir, ".config", "config-manager")
+	if configDir != legacyDir && !fileExists(configDir) && fileExists(legacyDir) {
+		return legacyDir
+	}
+	return configDir
+}
+
+// mustHome returns the home directory, or an error when it can't be
+// determined. Targets, discovery and the default config directory are all
+// relative to it, so an empty or relative $HOME would put files in the wrong
+// place; main refuses to start without one.
+func mustHome() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", NewConfigError("find home directory", "", err)
+	}
+	if !filepath.IsAbs(homeDir) {
+		return "", NewConfigError("find home directory", homeDir, fmt.Errorf("home directory must be an absolute path"))
+	}
+	return filepath.Clean(homeDir), nil
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME, falling back to ~/.config. Relative
+// values are ignored, as the XDG spec requires.
+func xdgConfigHome() string {
+	if xdgDir := os.Getenv("XDG_CONFIG_HOME"); xdgDir != "" && filepath.IsAbs(xdgDir) {
+		return filepath.Clean(xdgDir)
+	}
+	
+	homeDir, _ := mustHome()
+	return filepath.Join(homeDir, ".config")
+}
+
+// localBinDir returns ~/.local/bin, where user scripts on $PATH live
+func localBinDir() string {
+	homeDir, _ := mustHome()
+	return filepath.Join(homeDir, ".local", "bin")
+}
+
+// xdgConfigDisplayPath is how the XDG config directory appears in selection
+// lists: relative to home (".config") when inside it, absolute otherwise
+func xdgConfigDisplayPath() string {
+	homeDir, _ := mustHome()
+	xdgDir := xdgConfigHome()
+	if relPath, err := filepath.Rel(homeDir, xdgDir); err == nil && isWithinDir(xdgDir, homeDir) {
+		return relPath
+	}
+	return xdgDir
+}
+
+// resolveHomePath turns a path from a selection list into a target path.
+// Absolute paths are kept, ".config/..." follows $XDG_CONFIG_HOME and anything
+// else is relative to the home directory.
+func resolveHomePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if path == ".config" || strings.HasPrefix(path, ".config/") {
+		return filepath.Join(xdgConfigHome(), strings.TrimPrefix(strings.TrimPrefix(path, ".config"), "/"))
+	}
+	
+	homeDir, _ := mustHome()
+	return filepath.Join(homeDir, path)
+}
+
+// absPath expands a leading ~/ and makes path absolute, returning it unchanged on failure
+func absPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		homeDir, _ := mustHome()
+		path = filepath.Join(homeDir, path[2:])
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
 // Enhanced configuration loading with validation and error handling
-func loadConfig() *Config {
-	homeDir, _ := os.UserHomeDir()
-	configDir := filepath.Join(homeDir, ".config", "config-manager")
+func loadConfig(configDir string) *Config {
 	configFile := filepath.Join(configDir, "config.json")
 	
 	// Check if this is first run (no config file exists)
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
 		// Run setup wizard
-		config, err := runSetupWizard()
+		config, err := runSetupWizard(configDir)
 		if err != nil {
-			fmt.Printf("Setup wizard failed: %v\n", err)
-			fmt.Println("Creating minimal configuration...")
+			warnf("Setup wizard failed: %v\n", err)
+			infoln("Creating minimal configuration...")
 			
 			// Fallback to minimal config
 			config = createMinimalConfig(configDir)
 			
 			// Ensure directories exist
 			if err := os.MkdirAll(configDir, 0755); err != nil {
-				fmt.Printf("Failed to create config directory: %v\n", err)
+				errorf("Failed to create config directory: %v\n", err)
 				return config // Return config anyway, let user handle errors
 			}
 			
 			// Try to save config
 			if err := saveConfigSafe(config); err != nil {
-				fmt.Printf("Failed to save minimal config: %v\n", err)
+				errorf("Failed to save minimal config: %v\n", err)
 			}
+			return config
 		}
+		infoln("Starting Config Manager...")
+		infoln()
 		return config
 	}
 	
 	// Load existing config
 	config, err := loadConfigFile(configFile, configDir)
+	if errors.Is(err, errSchemaTooNew) {
+		// Never fall back to a minimal config here - saving it would clobber the newer file
+		errorf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
 	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
-		fmt.Println("Creating minimal configuration...")
+		errorf("Error loading config: %v\n", err)
+		infoln("Creating minimal configuration...")
 		return createMinimalConfig(configDir)
 	}
 	
 	// Validate loaded config
 	if errors := config.Validate(); len(errors) > 0 {
-		fmt.Printf("Configuration validation warnings:\n")
+		warnf("Configuration validation warnings:\n")
 		for _, err := range errors {
-			fmt.Printf("  - %v\n", err)
+			warnf("  - %v\n", err)
 		}
-		fmt.Println("Continuing with current configuration...")
+		infoln("Continuing with current configuration...")
 	}
 	
 	return config
@@ -70,6 +174,7 @@ func createMinimalConfig(configDir string) *Config {
 		Editor:       "vim",
 		Shell:        "bash",
 		Files:        []ConfigFile{},
+		DiscoveryDepth: defaultDiscoveryDepth,
 	}
 }
 
@@ -80,6 +185,12 @@ func loadConfigFile(configFile, configDir string) (*Config, error) {
 		return nil, NewConfigError("read config file", configFile, err)
 	}
 	
+	// Upgrade older configs and refuse ones written by a newer version
+	data, err = migrateConfigData(data)
+	if err != nil {
+		return nil, NewConfigError("migrate config file", configFile, err)
+	}
+	
 	config := &Config{}
 	if err := json.Unmarshal(data, config); err != nil {
 		return nil, NewConfigError("parse config file", configFile, err)
@@ -108,6 +219,14 @@ func loadConfigFile(configFile, configDir string) (*Config, error) {
 	if config.Files == nil {
 		config.Files = []ConfigFile{}
 	}
+	if config.DiscoveryDepth == 0 {
+		config.DiscoveryDepth = defaultDiscoveryDepth
+	}
+	useOperationRetries(config.OperationRetries)
+	
+	if err := applyLocalOverlay(config, filepath.Join(filepath.Dir(configFile), localConfigName)); err != nil {
+		return nil, err
+	}
 	
 	return config, nil
 }
@@ -115,7 +234,7 @@ func loadConfigFile(configFile, configDir string) (*Config, error) {
 // Enhanced save configuration with validation and backup
 func saveConfig(config *Config) {
 	if err := saveConfigSafe(config); err != nil {
-		fmt.Printf("Failed to save configuration: %v\n", err)
+		errorf("Failed to save configuration: %v\n", err)
 	}
 }
 
@@ -126,14 +245,30 @@ func saveConfigSafe(config *Config) error {
 		return NewConfigError("config validation", config.ConfigDir, err)
 	}
 	
+	// Always write the schema this binary understands
+	config.SchemaVersion = currentSchemaVersion
+	
+	// What the local overlay contributed stays out of config.json; with
+	// --local, only what differs from config.json is written, to the overlay
 	configFile := filepath.Join(config.ConfigDir, "config.json")
+	var saved interface{}
+	if saveToLocal && config.local != nil {
+		configFile = config.localConfigPath()
+		saved = config.savedLocal()
+	} else {
+		base, shadowed := config.savedBase()
+		if len(shadowed) > 0 {
+			logger.Debugf("%s still overrides changed %s; save with --local to change them there", localConfigName, strings.Join(shadowed, ", "))
+		}
+		saved = base
+	}
 	
 	// Create backup of existing config if it exists
 	if _, err := os.Stat(configFile); err == nil {
 		backupFile := configFile + ".backup"
 		if err := copyFile(configFile, backupFile); err != nil {
 			// Log warning but continue
-			fmt.Printf("Warning: failed to backup config file: %v\n", err)
+			warnf("Warning: failed to backup config file: %v\n", err)
 		}
 	}
 	
@@ -143,7 +278,7 @@ func saveConfigSafe(config *Config) error {
 	}
 	
 	// Marshal config to JSON with nice formatting
-	data, err := json.MarshalIndent(config, "", "  ")
+	data, err := json.MarshalIndent(saved, "", "  ")
 	if err != nil {
 		return NewConfigError("marshal config", configFile, err)
 	}
@@ -161,6 +296,13 @@ func saveConfigSafe(config *Config) error {
 		return NewConfigError("replace config file", configFile, err)
 	}
 	
+	// Later saves are split against what is now on disk
+	if local, ok := saved.(*localOverlay); ok {
+		config.local.overlay = local
+	} else if config.local != nil {
+		config.local.base = copyConfig(saved.(*Config))
+	}
+	
 	return nil
 }
 
@@ -171,7 +313,7 @@ func updateFileStatuses(config *Config) {
 	}
 	
 	// Remove duplicates and update statuses
-	config.Files = removeDuplicateFiles(config.Files)
+	config.Files = removeDuplicateFiles(config, config.Files)
 	
 	// Update statuses for all files
 	for i := range config.Files {
@@ -180,17 +322,68 @@ func updateFileStatuses(config *Config) {
 	}
 }
 
-// removeDuplicateFiles removes duplicate entries based on target path
-func removeDuplicateFiles(files []ConfigFile) []ConfigFile {
+// SnapshotFiles returns a deep copy of Files. updateFileStatuses and the
+// Config methods change entries in place, so anything holding on to files
+// for display should take a snapshot rather than share them.
+func (c *Config) SnapshotFiles() []ConfigFile {
+	files := make([]ConfigFile, len(c.Files))
+	for i, file := range c.Files {
+		files[i] = cloneConfigFile(file)
+	}
+	return files
+}
+
+// cloneConfigFile copies file along with its Variables map and slices
+func cloneConfigFile(file ConfigFile) ConfigFile {
+	if file.Variables != nil {
+		variables := make(map[string]string, len(file.Variables))
+		for k, v := range file.Variables {
+			variables[k] = v
+		}
+		file.Variables = variables
+	}
+	if file.StructuredVariables != nil {
+		file.StructuredVariables = mergeStructuredVariables(nil, file.StructuredVariables)
+	}
+	if file.ExcludePatterns != nil {
+		file.ExcludePatterns = append([]string{}, file.ExcludePatterns...)
+	}
+	if file.Formatter != nil {
+		file.Formatter = append([]string{}, file.Formatter...)
+	}
+	if file.Tags != nil {
+		file.Tags = append([]string{}, file.Tags...)
+	}
+	return file
+}
+
+// removeDuplicateFiles removes duplicate entries based on target path,
+// compared as resolved on disk like validateFiles does. On case-insensitive
+// filesystems targets differing only by case are duplicates too.
+func removeDuplicateFiles(config *Config, files []ConfigFile) []ConfigFile {
 	seen := make(map[string]bool)
+	seenFolded := make(map[string]string) // lowercased target -> first target kept
 	uniqueFiles := make([]ConfigFile, 0, len(files))
 	
 	for _, file := range files {
-		// Use target path as the unique identifier
-		if !seen[file.Target] {
-			seen[file.Target] = true
-			uniqueFiles = append(uniqueFiles, file)
+		// Unresolvable targets are kept for validation to report
+		target, err := resolveTarget(config, &file)
+		if err != nil {
+			target = file.Target
+		}
+		if seen[target] {
+			continue
+		}
+		folded := strings.ToLower(target)
+		if kept, ok := seenFolded[folded]; ok && sameTargetPath(kept, target) {
+			continue
 		}
+		
+		seen[target] = true
+		if _, ok := seenFolded[folded]; !ok {
+			seenFolded[folded] = target
+		}
+		uniqueFiles = append(uniqueFiles, file)
 	}
 	
 	return uniqueFiles
@@ -201,15 +394,80 @@ func updateSingleFileStatus(config *Config, file *ConfigFile) {
 	// Reset status flags
 	file.IsLinked = false
 	file.HasConflict = false
+	file.Drifted = false
+	
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		logger.Debugf("%s: conflict (%v)", file.Name, err)
+		file.HasConflict = !file.Disabled
+		return
+	}
+	
+	updateLinkStatus(config, file, target)
+	file.NeedsRelink = sourceModifiedSinceLink(config, file, target)
+	
+	// Whatever is at a disabled file's target isn't in the way of anything
+	if file.Disabled && file.HasConflict {
+		logger.Debugf("%s: disabled, not reporting the conflict", file.Name)
+		file.HasConflict = false
+	}
+}
+
+// sourceModifiedSinceLink reports whether a linked (or drifted copy-mode)
+// file's source was modified after it was last linked, going by LinkedAt or,
+// for files linked before that was recorded, the target's own mtime. Symlinks
+// already show the change, so there it's a hint that a verify is due; a
+// drifted copy needs relinking to pick it up. Directory sources are skipped.
+func sourceModifiedSinceLink(config *Config, file *ConfigFile, target string) bool {
+	isCopy := file.LinkStrategy == LinkStrategyCopy
+	if !(file.IsLinked && !isCopy) && !(file.Drifted && isCopy) {
+		return false
+	}
+	
+	sourceInfo, err := os.Stat(filepath.Join(config.DotfilesDir, file.Source))
+	if err != nil || sourceInfo.IsDir() {
+		return false
+	}
+	
+	var linkedAt time.Time
+	if file.LinkedAt != nil {
+		linkedAt = *file.LinkedAt
+	} else if targetInfo, err := os.Lstat(target); err == nil {
+		linkedAt = targetInfo.ModTime()
+	} else {
+		return false
+	}
+	
+	if sourceInfo.ModTime().After(linkedAt) {
+		logger.Debugf("%s: source modified %s, after it was linked %s", file.Name,
+			sourceInfo.ModTime().Format(time.RFC3339), linkedAt.Format(time.RFC3339))
+		return true
+	}
+	return false
+}
+
+// updateLinkStatus sets IsLinked, HasConflict and Drifted from what's on disk
+// at target, file's resolved target
+func updateLinkStatus(config *Config, file *ConfigFile, target string) {
+	if file.effectiveLinkStrategy() == LinkStrategyTree {
+		updateTreeFileStatus(config, file, target)
+		return
+	}
+	if file.LinkStrategy == LinkStrategyCopy {
+		updateCopyFileStatus(config, file, target)
+		return
+	}
 	
 	// Check if target exists and its status
-	info, err := os.Lstat(file.Target)
+	info, err := os.Lstat(target)
 	if os.IsNotExist(err) {
 		// File doesn't exist - no conflict, not linked
+		logger.Debugf("%s: not linked, no conflict (target %s does not exist)", file.Name, target)
 		return
 	}
 	if err != nil {
 		// Some other error - treat as conflict
+		logger.Debugf("%s: conflict (cannot stat target %s: %v)", file.Name, target, err)
 		file.HasConflict = true
 		return
 	}
@@ -217,27 +475,116 @@ func updateSingleFileStatus(config *Config, file *ConfigFile) {
 	// Check if it's a symlink
 	if info.Mode()&os.ModeSymlink != 0 {
 		// It's a symlink - check where it points
-		linkTarget, err := os.Readlink(file.Target)
+		linkTarget, err := os.Readlink(target)
 		if err != nil {
+			logger.Debugf("%s: conflict (cannot read symlink %s: %v)", file.Name, target, err)
 			file.HasConflict = true
 			return
 		}
 		
 		expectedSource := filepath.Join(config.DotfilesDir, file.Source)
-		file.IsLinked = (linkTarget == expectedSource)
+		resolved := resolveLinkTarget(target, linkTarget)
+		file.IsLinked = sameLinkDestination(resolved, expectedSource)
 		
 		// If it's a symlink but points somewhere else, it's a conflict
 		if !file.IsLinked {
+			logger.Debugf("%s: conflict (symlink %s points to %s, expected %s)", file.Name, target, resolved, expectedSource)
 			file.HasConflict = true
+		} else {
+			logger.Debugf("%s: linked (symlink %s points to %s)", file.Name, target, resolved)
 		}
 	} else {
 		// File exists but is not a symlink - conflict
+		logger.Debugf("%s: conflict (target %s is a regular %s, not a symlink)", file.Name, target, fileKind(info))
+		file.HasConflict = true
+	}
+}
+
+// fileKind describes a non-symlink file for tracing
+func fileKind(info os.FileInfo) string {
+	if info.IsDir() {
+		return "directory"
+	}
+	return "file"
+}
+
+// updateCopyFileStatus compares a copy-mode target with its source by content.
+// A copy that no longer matches is drifted rather than conflicted, since the
+// target is ours and can be synced in either direction.
+func updateCopyFileStatus(config *Config, file *ConfigFile, target string) {
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		logger.Debugf("%s: conflict (copy-mode target %s is a symlink or unreadable)", file.Name, target)
 		file.HasConflict = true
+		return
+	}
+	
+	if contentsMatch(filepath.Join(config.DotfilesDir, file.Source), target) {
+		logger.Debugf("%s: in sync (copy at %s matches source)", file.Name, target)
+		file.IsLinked = true
+	} else {
+		logger.Debugf("%s: drifted (copy at %s differs from source)", file.Name, target)
+		file.Drifted = true
 	}
 }
 
+// updateTreeFileStatus checks every leaf link of a tree-linked directory. Files
+// that exist only in the target (added after linking) are ignored.
+func updateTreeFileStatus(config *Config, file *ConfigFile, target string) {
+	sourceRoot := filepath.Join(config.DotfilesDir, file.Source)
+	
+	// A symlink at the root means the directory is still linked as a whole
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		logger.Debugf("%s: conflict (tree target %s is a single symlink, not a directory)", file.Name, target)
+		file.HasConflict = true
+		return
+	}
+	
+	leaves, err := treeLinkPaths(sourceRoot, file.ExcludePatterns)
+	if err != nil || len(leaves) == 0 {
+		return
+	}
+	
+	linked := 0
+	for _, relPath := range leaves {
+		targetPath := filepath.Join(target, relPath)
+		linkTarget, err := os.Readlink(targetPath)
+		if err != nil {
+			if _, statErr := os.Lstat(targetPath); statErr == nil {
+				// Exists but isn't a symlink
+				logger.Debugf("%s: conflict (%s is not a symlink)", file.Name, targetPath)
+				file.HasConflict = true
+			}
+			continue
+		}
+		
+		expected := filepath.Join(sourceRoot, relPath)
+		if resolved := resolveLinkTarget(targetPath, linkTarget); sameLinkDestination(resolved, expected) {
+			linked++
+		} else {
+			logger.Debugf("%s: conflict (symlink %s points to %s, expected %s)", file.Name, targetPath, resolved, expected)
+			file.HasConflict = true
+		}
+	}
+	
+	file.IsLinked = linked == len(leaves)
+}
+
+// categorizeDotfile picks a category for filename. A configured
+// categorizer_cmd is asked first; when it fails or has no answer the
+// built-in heuristics decide. Categories it invents are added to config.
+func categorizeDotfile(config *Config, filename string) string {
+	if category, ok := externalCategory(config, filename); ok {
+		return category
+	}
+	return builtinCategory(filename, config.Categories)
+}
+
 // Enhanced file categorization with better heuristics
-func categorizeDotfile(filename string, categories []string) string {
+func builtinCategory(filename string, categories []string) string {
 	filename = strings.ToLower(filename)
 	
 	// Shell configuration files
@@ -246,7 +593,7 @@ func categorizeDotfile(filename string, categories []string) string {
 		"bash_profile", "bash_aliases", "aliases", "functions",
 	}
 	for _, pattern := range shellPatterns {
-		if strings.Contains(filename, pattern) {
+		if matchesWord(filename, pattern) {
 			return "shell"
 		}
 	}
@@ -254,7 +601,7 @@ func categorizeDotfile(filename string, categories []string) string {
 	// Git configuration files
 	gitPatterns := []string{"git", "gitconfig", "gitignore", "gitmessage"}
 	for _, pattern := range gitPatterns {
-		if strings.Contains(filename, pattern) {
+		if matchesWord(filename, pattern) {
 			return "git"
 		}
 	}
@@ -265,7 +612,7 @@ func categorizeDotfile(filename string, categories []string) string {
 		"vimrc", "init.vim", "init.lua", "emacs.d",
 	}
 	for _, pattern := range editorPatterns {
-		if strings.Contains(filename, pattern) {
+		if matchesWord(filename, pattern) {
 			return "editor"
 		}
 	}
@@ -276,7 +623,7 @@ func categorizeDotfile(filename string, categories []string) string {
 		"terminator", "gnome-terminal", "konsole",
 	}
 	for _, pattern := range terminalPatterns {
-		if strings.Contains(filename, pattern) {
+		if matchesWord(filename, pattern) {
 			return "terminal"
 		}
 	}
@@ -288,13 +635,15 @@ func categorizeDotfile(filename string, categories []string) string {
 		"picom", "compton", "xorg", "wayland",
 	}
 	for _, pattern := range wmPatterns {
-		if strings.Contains(filename, pattern) {
-			// Check if WM category exists, otherwise use misc
+		if matchesWord(filename, pattern) {
+			// Use the WM category if one exists. Otherwise return misc right away
+			// rather than letting a later, less specific pattern claim the file.
 			for _, cat := range categories {
 				if cat == "wm" || cat == "desktop" {
 					return cat
 				}
 			}
+			return "misc"
 		}
 	}
 	
@@ -304,13 +653,14 @@ func categorizeDotfile(filename string, categories []string) string {
 		"makefile", "cmake", "cargo", "npm", "yarn",
 	}
 	for _, pattern := range devPatterns {
-		if strings.Contains(filename, pattern) {
-			// Check if dev category exists, otherwise use misc
+		if matchesWord(filename, pattern) {
+			// Use the dev category if one exists, otherwise fall back to misc
 			for _, cat := range categories {
 				if cat == "dev" || cat == "development" {
 					return cat
 				}
 			}
+			return "misc"
 		}
 	}
 	
@@ -318,6 +668,52 @@ func categorizeDotfile(filename string, categories []string) string {
 	return "misc"
 }
 
+// matchesWord reports whether pattern occurs in name on word boundaries, so
+// "screen" matches ".screenrc" but not "screenshots". A pattern may be directly
+// followed by a common config suffix (i3status, zshrc, gitconfig).
+func matchesWord(name, pattern string) bool {
+	start := 0
+	for {
+		idx := strings.Index(name[start:], pattern)
+		if idx < 0 {
+			return false
+		}
+		idx += start
+		end := idx + len(pattern)
+		
+		startsWord := idx == 0 || !isAlphanumeric(name[idx-1])
+		endsWord := end == len(name) || !isAlphanumeric(name[end]) || hasConfigSuffix(name[end:])
+		if startsWord && endsWord {
+			return true
+		}
+		
+		start = idx + 1
+	}
+}
+
+// hasConfigSuffix reports whether rest begins with a word that commonly
+// follows a program name in config file names
+func hasConfigSuffix(rest string) bool {
+	end := 0
+	for end < len(rest) && isAlphanumeric(rest[end]) {
+		end++
+	}
+	
+	suffixes := []string{"rc", "env", "status", "blocks", "bar", "conf", "config", "cfg", "ignore", "message"}
+	for _, suffix := range suffixes {
+		if rest[:end] == suffix {
+			return true
+		}
+	}
+	
+	return false
+}
+
+// isAlphanumeric reports whether c is an ASCII letter or digit
+func isAlphanumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
 // Enhanced system file detection
 func isSystemFile(filename string) bool {
 	// macOS system files
@@ -387,7 +783,7 @@ func (c *Config) AddConfigFile(file ConfigFile) error {
 	
 	// Check for duplicates
 	for _, existing := range c.Files {
-		if existing.Target == file.Target {
+		if sameTargetPath(existing.Target, file.Target) {
 			return NewValidationError("target", file.Target, 
 				fmt.Sprintf("target already managed by %s", existing.Name), "")
 		}
@@ -413,7 +809,10 @@ func (c *Config) AddConfigFile(file ConfigFile) error {
 		}
 	}
 	
-	// Add the file
+	// Add the file, last in apply order
+	if file.Order == 0 {
+		file.Order = c.nextOrder()
+	}
 	c.Files = append(c.Files, file)
 	
 	// Update file status
@@ -436,6 +835,199 @@ func (c *Config) RemoveConfigFile(targetPath string) error {
 		fmt.Errorf("file not found in configuration"))
 }
 
+// RenameFile changes the display name of the file managing targetPath. The
+// name must stay unique within the file's category.
+func (c *Config) RenameFile(targetPath, newName string) error {
+	file, err := c.GetConfigFileByTarget(targetPath)
+	if err != nil {
+		return err
+	}
+	
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return NewValidationError("name", "", "file name cannot be empty", targetPath)
+	}
+	for _, existing := range c.Files {
+		if existing.Target != targetPath && existing.Name == newName && existing.Category == file.Category {
+			return NewValidationError("name", newName, 
+				fmt.Sprintf("file with same name already exists in category %s", file.Category), targetPath)
+		}
+	}
+	
+	file.Name = newName
+	return nil
+}
+
+// SetDescription replaces the note on the file managing targetPath. An empty
+// description removes it.
+func (c *Config) SetDescription(targetPath, description string) error {
+	file, err := c.GetConfigFileByTarget(targetPath)
+	if err != nil {
+		return err
+	}
+	
+	description = strings.TrimSpace(description)
+	if strings.ContainsAny(description, "\r\n") {
+		return NewValidationError("description", description, "description must be a single line", targetPath)
+	}
+	
+	file.Description = description
+	return nil
+}
+
+// RefreshFileMetadata re-runs the template detection done when a file is
+// added against its current source and updates the Template flag, so sources
+// edited since they were added are picked up. A file keeps its flag while its
+// template still exists; files marked template_manual, directories and
+// missing sources are left alone. It returns one line per file it changed.
+func (c *Config) RefreshFileMetadata() []string {
+	var changes []string
+	for i := range c.Files {
+		file := &c.Files[i]
+		if file.TemplateManual {
+			continue
+		}
+		
+		sourcePath := filepath.Join(c.DotfilesDir, file.Source)
+		info, err := os.Stat(sourcePath)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		
+		// The source of a template file is its rendered output, which has no
+		// template markers left; the template itself still counts
+		detected := looksLikeTemplate(sourcePath) ||
+			(file.Template && c.findTemplateFile(file.Name, file.Source, file.Category) != "")
+		if detected == file.Template {
+			continue
+		}
+		
+		file.Template = detected
+		if detected {
+			changes = append(changes, fmt.Sprintf("%s: now a template", file.Name))
+		} else {
+			changes = append(changes, fmt.Sprintf("%s: no longer a template", file.Name))
+		}
+	}
+	return changes
+}
+
+// cleanSource checks a source given for the file managing targetPath: any
+// path relative to DotfilesDir that stays inside it, wherever its category
+// would put it
+func (c *Config) cleanSource(source, targetPath string) (string, error) {
+	source = filepath.Clean(strings.TrimSpace(source))
+	if source == "." || filepath.IsAbs(source) {
+		return "", NewValidationError("source", source, "source must be a path inside the dotfiles directory", targetPath)
+	}
+	if !isWithinDir(filepath.Join(c.DotfilesDir, source), c.DotfilesDir) {
+		return "", NewValidationError("source", source, "source path escapes dotfiles directory", targetPath)
+	}
+	return source, nil
+}
+
+// sharedSourceOwner returns the first file other than the one managing
+// targetPath whose source is exactly source
+func (c *Config) sharedSourceOwner(source, targetPath string) (*ConfigFile, bool) {
+	for i, existing := range c.Files {
+		if existing.Target != targetPath && existing.Source != "" && filepath.Clean(existing.Source) == source {
+			return &c.Files[i], true
+		}
+	}
+	return nil, false
+}
+
+// MoveSource moves the source of the file managing targetPath to newSource
+// (relative to DotfilesDir) and re-points its links, in one transaction.
+// When newSource is exactly another file's source, the two share it instead:
+// nothing is moved, and the old source is left for prune-sources. The entry
+// is only updated once everything on disk has changed.
+func (c *Config) MoveSource(targetPath, newSource string) error {
+	file, err := c.GetConfigFileByTarget(targetPath)
+	if err != nil {
+		return err
+	}
+	
+	newSource, err = c.cleanSource(newSource, targetPath)
+	if err != nil {
+		return err
+	}
+	newSourcePath := filepath.Join(c.DotfilesDir, newSource)
+	if newSource == filepath.Clean(file.Source) {
+		return nil
+	}
+	if owner, shared := c.sharedSourceOwner(newSource, targetPath); shared {
+		return c.shareSource(file, owner, newSource)
+	}
+	if other, shared := c.sharedSourceOwner(filepath.Clean(file.Source), targetPath); shared {
+		return NewValidationError("source", file.Source, 
+			fmt.Sprintf("source is shared with %s, so moving it would break that file's links", other.Name), targetPath)
+	}
+	
+	// The new source can't overlap another file's source, in either direction
+	for _, existing := range c.Files {
+		if existing.Target == targetPath || existing.Source == "" {
+			continue
+		}
+		existingPath := filepath.Join(c.DotfilesDir, existing.Source)
+		if isWithinDir(newSourcePath, existingPath) || isWithinDir(existingPath, newSourcePath) {
+			return NewValidationError("source", newSource, 
+				fmt.Sprintf("overlaps the source of %s", existing.Name), targetPath)
+		}
+	}
+	if fileExistsNoFollow(newSourcePath) {
+		return NewValidationError("source", newSource, "a file already exists at the new source", targetPath)
+	}
+	
+	oldSourcePath := filepath.Join(c.DotfilesDir, file.Source)
+	if _, err := os.Lstat(oldSourcePath); err == nil {
+		tx, err := createMoveSourceOperation(c, file, newSourcePath)
+		if err != nil {
+			return err
+		}
+		if err := tx.Execute(); err != nil {
+			return NewConfigError("move source", file.Name, err)
+		}
+	}
+	
+	file.Source = newSource
+	updateSingleFileStatus(c, file)
+	return nil
+}
+
+// shareSource points file at source, which owner already uses, so one source
+// links to both targets. A template renders to its source, so templates
+// can't share one, and the source must already exist with the same kind.
+func (c *Config) shareSource(file, owner *ConfigFile, source string) error {
+	if file.Template || owner.Template {
+		return NewValidationError("source", source, 
+			fmt.Sprintf("a template's source is its rendered output and can't be shared with %s", owner.Name), file.Target)
+	}
+	sourcePath := filepath.Join(c.DotfilesDir, source)
+	sharedInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return NewValidationError("source", source, 
+			fmt.Sprintf("the source of %s doesn't exist yet; link %s first", owner.Name, owner.Name), file.Target)
+	}
+	if oldInfo, err := os.Stat(filepath.Join(c.DotfilesDir, file.Source)); err == nil && oldInfo.IsDir() != sharedInfo.IsDir() {
+		return NewValidationError("source", source, 
+			fmt.Sprintf("can't share the source of %s: one is a directory and the other a file", owner.Name), file.Target)
+	}
+	
+	tx, err := createShareSourceOperation(c, file, sourcePath)
+	if err != nil {
+		return err
+	}
+	if err := tx.Execute(); err != nil {
+		return NewConfigError("share source", file.Name, err)
+	}
+	
+	logger.Debugf("%s: now shares %s with %s; %s is left in place", file.Name, source, owner.Name, file.Source)
+	file.Source = source
+	updateSingleFileStatus(c, file)
+	return nil
+}
+
 // getConfigFileByTarget finds a config file by its target path
 func (c *Config) GetConfigFileByTarget(targetPath string) (*ConfigFile, error) {
 	for i, file := range c.Files {
@@ -465,7 +1057,7 @@ func (c *Config) EnsureDirectoriesExist() error {
 		c.ConfigDir,
 		c.DotfilesDir,
 		filepath.Join(c.ConfigDir, "templates"),
-		filepath.Join(c.ConfigDir, "backups"),
+		c.GetBackupDir(),
 	}
 	
 	for _, dir := range dirs {
@@ -476,20 +1068,133 @@ func (c *Config) EnsureDirectoriesExist() error {
 	
 	// Create category subdirectories in dotfiles
 	for _, category := range c.Categories {
-		categoryDir := filepath.Join(c.DotfilesDir, category)
-		if err := os.MkdirAll(categoryDir, 0755); err != nil {
-			return NewConfigError("create category directory", categoryDir, err)
+		if err := c.ensureCategoryDir(category); err != nil {
+			return err
 		}
 	}
 	
 	return nil
 }
 
+// ensureCategoryDir creates the directory for category in DotfilesDir. When
+// DotfilesDir is inside a git repository, empty category directories would
+// only clutter the checkout (git doesn't track them), so they are left to be
+// created when a file is first linked into them, unless gitkeep is set, in
+// which case they are created with a .gitkeep so git tracks them.
+func (c *Config) ensureCategoryDir(category string) error {
+	if isGitManaged(c.DotfilesDir) && !c.GitKeep {
+		return nil
+	}
+	
+	categoryDir := filepath.Join(c.DotfilesDir, category)
+	if err := os.MkdirAll(categoryDir, 0755); err != nil {
+		return NewConfigError("create category directory", categoryDir, err)
+	}
+	
+	if !c.GitKeep || !isGitManaged(c.DotfilesDir) {
+		return nil
+	}
+	if entries, err := os.ReadDir(categoryDir); err != nil || len(entries) > 0 {
+		return nil
+	}
+	gitkeep := filepath.Join(categoryDir, ".gitkeep")
+	if err := os.WriteFile(gitkeep, nil, 0644); err != nil {
+		return NewConfigError("create .gitkeep", gitkeep, err)
+	}
+	return nil
+}
+
+// isGitManaged reports whether dir is inside a git repository: a regular
+// checkout has a .git directory above it, while submodules and worktrees
+// have a .git file pointing at the real repository
+func isGitManaged(dir string) bool {
+	dir = filepath.Clean(dir)
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// backupDirOverride is set from --backup-dir and takes precedence over the
+// config file without being persisted to it
+var backupDirOverride string
+
+// GetBackupDir returns where backups are stored: --backup-dir, then the
+// configured BackupDir, then ConfigDir/backups
+func (c *Config) GetBackupDir() string {
+	if backupDirOverride != "" {
+		return backupDirOverride
+	}
+	if c.BackupDir != "" {
+		return c.BackupDir
+	}
+	return filepath.Join(c.ConfigDir, "backups")
+}
+
+// targetRootOverride is set from --target-root and takes precedence over the
+// config file without being persisted to it
+var targetRootOverride string
+
+// targetRoot returns the sandbox directory targets are rebased under:
+// --target-root, then the configured TargetRoot. Empty means the real paths.
+func (c *Config) targetRoot() string {
+	if targetRootOverride != "" {
+		return targetRootOverride
+	}
+	return c.TargetRoot
+}
+
+// resolveTarget turns file's stored target into the absolute path it has on
+// disk. Everything that touches a target goes through it. Targets are stored
+// as their real paths; with a target root they're placed under it, so
+// ~/.bashrc becomes <root>/home/user/.bashrc.
+func resolveTarget(config *Config, file *ConfigFile) (string, error) {
+	if file.Target == "" {
+		return "", NewConfigError("resolve target", file.Name, fmt.Errorf("target path is empty"))
+	}
+	if !filepath.IsAbs(file.Target) {
+		return "", NewConfigError("resolve target", file.Target, fmt.Errorf("target must be an absolute path"))
+	}
+	
+	return config.underTargetRoot(filepath.Clean(file.Target)), nil
+}
+
+// underTargetRoot places an absolute path under the target root, if any.
+// Besides resolveTarget it's for directories scanned for links.
+func (c *Config) underTargetRoot(path string) string {
+	if root := c.targetRoot(); root != "" {
+		return filepath.Join(root, path)
+	}
+	return path
+}
+
+// checkDirWritable creates dir if needed and verifies files can be written to it
+func checkDirWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return NewConfigError("create directory", dir, err)
+	}
+	
+	probe, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return NewConfigError("check directory writable", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	
+	return nil
+}
+
 // getUnlinkedFiles returns files that are not currently linked
 func (c *Config) GetUnlinkedFiles() []ConfigFile {
 	var unlinked []ConfigFile
 	for _, file := range c.Files {
-		if !file.IsLinked {
+		if !file.IsLinked && !file.Disabled {
 			unlinked = append(unlinked, file)
 		}
 	}
@@ -540,6 +1245,53 @@ func (c *Config) GetFilesByCategory(category string) []ConfigFile {
 	return files
 }
 
+// GetFilesByTag returns the files carrying tag
+func (c *Config) GetFilesByTag(tag string) []ConfigFile {
+	var files []ConfigFile
+	for _, file := range c.Files {
+		if file.HasTag(tag) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Tags returns every tag used by a file, sorted
+func (c *Config) Tags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, file := range c.Files {
+		for _, tag := range file.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// HasTag reports whether the file carries tag
+func (f *ConfigFile) HasTag(tag string) bool {
+	for _, t := range f.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDisabled disables or re-enables the file managing targetPath
+func (c *Config) SetDisabled(targetPath string, disabled bool) error {
+	file, err := c.GetConfigFileByTarget(targetPath)
+	if err != nil {
+		return err
+	}
+	file.Disabled = disabled
+	return nil
+}
+
 // addCategory safely adds a new category
 func (c *Config) AddCategory(category string) error {
 	if category == "" {
@@ -556,12 +1308,7 @@ func (c *Config) AddCategory(category string) error {
 	c.Categories = append(c.Categories, category)
 	
 	// Create directory for the new category
-	categoryDir := filepath.Join(c.DotfilesDir, category)
-	if err := os.MkdirAll(categoryDir, 0755); err != nil {
-		return NewConfigError("create category directory", categoryDir, err)
-	}
-	
-	return nil
+	return c.ensureCategoryDir(category)
 }
 
 // removeCategory safely removes a category (only if no files use it)
@@ -646,62 +1393,171 @@ func (c *Config) GetStats() map[string]int {
 
 // exportConfig exports configuration to a portable format
 func (c *Config) ExportConfig() ([]byte, error) {
-	// Create a clean copy for export (remove runtime fields)
+	return json.MarshalIndent(c.portable(), "", "  ")
+}
+
+// portable returns the settings of c that are exported and imported. Paths
+// and machine settings such as commands, privileges and backups are left
+// out: they differ between machines, and an imported config must not be
+// able to run commands or change how files are replaced.
+func (c *Config) portable() *Config {
 	export := &Config{
+		SchemaVersion: currentSchemaVersion,
 		Files:        make([]ConfigFile, len(c.Files)),
-		ConfigDir:    "", // Don't export absolute paths
-		DotfilesDir:  "", // Don't export absolute paths
 		Variables:    c.Variables,
 		Categories:   c.Categories,
 		TemplateExts: c.TemplateExts,
 		Editor:       c.Editor,
 		Shell:        c.Shell,
+		DiscoveryDepth: c.DiscoveryDepth,
+		TargetSymlinks: c.TargetSymlinks,
 	}
 	
 	// Copy files without runtime status
 	for i, file := range c.Files {
-		export.Files[i] = ConfigFile{
-			Name:      file.Name,
-			Source:    file.Source,
-			Target:    file.Target,
-			Category:  file.Category,
-			Template:  file.Template,
-			Variables: file.Variables,
-			// Exclude IsLinked and HasConflict (runtime fields)
-		}
+		export.Files[i] = exportFile(file)
+	}
+	return export
+}
+
+// exportFile copies the fields of a file that are exported and compared
+// between configs, leaving out runtime status
+func exportFile(file ConfigFile) ConfigFile {
+	return ConfigFile{
+		Name:      file.Name,
+		Description: file.Description,
+		Source:    file.Source,
+		Target:    file.Target,
+		Category:  file.Category,
+		Tags:      file.Tags,
+		Disabled:  file.Disabled,
+		Order:     file.Order,
+		Template:  file.Template,
+		TemplateManual: file.TemplateManual,
+		Variables: file.Variables,
+		StructuredVariables: file.StructuredVariables,
+		LinkStrategy: file.LinkStrategy,
+		ExcludePatterns: file.ExcludePatterns,
+		Formatter: file.Formatter,
+		Perms:     file.Perms,
+		OriginalLink: file.OriginalLink,
+		// Exclude IsLinked, HasConflict and Drifted (runtime fields)
 	}
-	
-	return json.MarshalIndent(export, "", "  ")
+}
+
+// ImportResult describes what a merge import did with each imported file
+type ImportResult struct {
+	Added     []ConfigFile     // files that were new and have been added
+	Skipped   []ImportSkip     // files left out, e.g. identical to an existing one
+	Conflicts []ImportConflict // files that clash with an existing one; the existing one is kept
+}
+
+// ImportSkip is an imported file that wasn't added and why
+type ImportSkip struct {
+	File   ConfigFile
+	Reason string
+}
+
+// ImportConflict is an imported file whose target or name/category is already
+// managed by a different entry
+type ImportConflict struct {
+	Existing ConfigFile
+	Imported ConfigFile
+	Reason   string
 }
 
 // importConfig imports configuration from exported data
 func (c *Config) ImportConfig(data []byte, mergeMode bool) error {
-	imported := &Config{}
-	if err := json.Unmarshal(data, imported); err != nil {
-		return NewConfigError("import config", "", fmt.Errorf("invalid JSON: %v", err))
+	result, err := c.ImportConfigWithResult(data, mergeMode)
+	if result != nil {
+		for _, skipped := range result.Skipped {
+			infof("Skipping duplicate file %s: %s\n", skipped.File.Name, skipped.Reason)
+		}
+		for _, conflict := range result.Conflicts {
+			infof("Skipping duplicate file %s: %s\n", conflict.Imported.Name, conflict.Reason)
+		}
+	}
+	return err
+}
+
+// ImportConfigWithResult imports configuration from exported data. In merge
+// mode the returned result lists added, skipped and conflicting files; for a
+// replacing import the result is nil.
+func (c *Config) ImportConfigWithResult(data []byte, mergeMode bool) (*ImportResult, error) {
+	data, err := migrateConfigData(data)
+	if err != nil {
+		return nil, NewConfigError("import config", "", err)
+	}
+	
+	parsed := &Config{}
+	if err := json.Unmarshal(data, parsed); err != nil {
+		return nil, NewConfigError("import config", "", fmt.Errorf("invalid JSON: %v", err))
 	}
+	// Only what an export holds is taken; anything else in the data is ignored
+	imported := parsed.portable()
 	
 	if mergeMode {
 		// Merge imported configuration with existing
 		return c.mergeConfig(imported)
 	} else {
-		// Replace current configuration (keeping paths)
-		configDir := c.ConfigDir
-		dotfilesDir := c.DotfilesDir
-		
-		*c = *imported
-		c.ConfigDir = configDir
-		c.DotfilesDir = dotfilesDir
+		// Replace the portable settings and files, keeping this machine's
+		// paths and settings
+		c.Files = imported.Files
+		c.Variables = imported.Variables
+		c.Categories = imported.Categories
+		c.TemplateExts = imported.TemplateExts
+		c.Editor = imported.Editor
+		c.Shell = imported.Shell
+		c.DiscoveryDepth = imported.DiscoveryDepth
+		c.TargetSymlinks = imported.TargetSymlinks
 		
 		// Update file statuses
 		updateFileStatuses(c)
 		
-		return nil
+		return nil, nil
+	}
+}
+
+// ResolveImportConflict replaces whichever side of conflict is currently
+// managed with the imported file (takeImported) or the existing one
+func (c *Config) ResolveImportConflict(conflict ImportConflict, takeImported bool) error {
+	chosen := conflict.Existing
+	if takeImported {
+		chosen = conflict.Imported
+	}
+	
+	index := -1
+	for i, file := range c.Files {
+		if sameManagedFile(file, conflict.Existing) || sameManagedFile(file, conflict.Imported) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return NewValidationError("target", conflict.Existing.Target, "conflicting file is no longer managed", "")
 	}
+	
+	// The chosen side must not clash with some other managed file
+	for i, file := range c.Files {
+		if i != index && file.Target == chosen.Target {
+			return NewValidationError("target", chosen.Target,
+				fmt.Sprintf("target already managed by %s", file.Name), "")
+		}
+	}
+	
+	c.Files[index] = chosen
+	updateSingleFileStatus(c, &c.Files[index])
+	return nil
+}
+
+// sameManagedFile reports whether two entries describe the same managed file
+func sameManagedFile(a, b ConfigFile) bool {
+	return a.Target == b.Target && a.Name == b.Name && a.Category == b.Category
 }
 
 // mergeConfig merges imported configuration with current configuration
-func (c *Config) mergeConfig(imported *Config) error {
+func (c *Config) mergeConfig(imported *Config) (*ImportResult, error) {
+	result := &ImportResult{}
 	var multiErr MultiError
 	multiErr.Op = "merge configuration"
 	
@@ -743,13 +1599,25 @@ func (c *Config) mergeConfig(imported *Config) error {
 	
 	// Merge files (skip duplicates based on target)
 	for _, importedFile := range imported.Files {
-		if err := c.AddConfigFile(importedFile); err != nil {
-			if IsValidationError(err) {
-				// Skip duplicate files but log the issue
-				fmt.Printf("Skipping duplicate file %s: %v\n", importedFile.Name, err)
-			} else {
-				multiErr.Add(err)
-			}
+		err := c.AddConfigFile(importedFile)
+		if err == nil {
+			result.Added = append(result.Added, importedFile)
+			continue
+		}
+		if !IsValidationError(err) {
+			multiErr.Add(err)
+			continue
+		}
+		
+		// Same target or name/category: identical entries are skipped, the rest conflict
+		existing, found := c.findClashingFile(importedFile)
+		switch {
+		case !found:
+			result.Skipped = append(result.Skipped, ImportSkip{File: importedFile, Reason: err.Error()})
+		case reflect.DeepEqual(exportFile(existing), exportFile(importedFile)):
+			result.Skipped = append(result.Skipped, ImportSkip{File: importedFile, Reason: "already managed with the same settings"})
+		default:
+			result.Conflicts = append(result.Conflicts, ImportConflict{Existing: existing, Imported: importedFile, Reason: err.Error()})
 		}
 	}
 	
@@ -762,8 +1630,20 @@ func (c *Config) mergeConfig(imported *Config) error {
 	}
 	
 	if multiErr.HasErrors() {
-		return &multiErr
+		return result, &multiErr
 	}
 	
-	return nil
+	return result, nil
+}
+
+// findClashingFile returns the managed file an imported one would duplicate,
+// matching AddConfigFile's duplicate checks
+func (c *Config) findClashingFile(file ConfigFile) (ConfigFile, bool) {
+	for _, existing := range c.Files {
+		if existing.Target == file.Target ||
+			(existing.Name == file.Name && existing.Category == file.Category) {
+			return existing, true
+		}
+	}
+	return ConfigFile{}, false
 }
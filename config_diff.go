@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// diffConfigs compares two exported configs and describes what changed from a
+// to b: editor and shell, categories, template extensions, global variables,
+// and managed files matched by target. It returns "" when they're equivalent.
+func diffConfigs(a, b []byte) (string, error) {
+	configA, err := parseExportedConfig(a)
+	if err != nil {
+		return "", NewConfigError("diff config", "first config", err)
+	}
+	configB, err := parseExportedConfig(b)
+	if err != nil {
+		return "", NewConfigError("diff config", "second config", err)
+	}
+	
+	var out strings.Builder
+	
+	if configA.Editor != configB.Editor {
+		fmt.Fprintf(&out, "Editor: %q → %q\n", configA.Editor, configB.Editor)
+	}
+	if configA.Shell != configB.Shell {
+		fmt.Fprintf(&out, "Shell: %q → %q\n", configA.Shell, configB.Shell)
+	}
+	
+	writeListDiff(&out, "Categories", configA.Categories, configB.Categories)
+	writeListDiff(&out, "Template extensions", configA.TemplateExts, configB.TemplateExts)
+	
+	if lines := diffVariables(configA.Variables, configB.Variables); len(lines) > 0 {
+		out.WriteString("Global variables:\n")
+		writeIndented(&out, lines)
+	}
+	
+	if lines := diffFiles(configA.Files, configB.Files); len(lines) > 0 {
+		out.WriteString("Files:\n")
+		writeIndented(&out, lines)
+	}
+	
+	return out.String(), nil
+}
+
+// parseExportedConfig unmarshals an exported config, migrating older schemas
+func parseExportedConfig(data []byte) (*Config, error) {
+	data, err := migrateConfigData(data)
+	if err != nil {
+		return nil, err
+	}
+	
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return config, nil
+}
+
+// writeListDiff writes added and removed entries of a string list
+func writeListDiff(out *strings.Builder, title string, a, b []string) {
+	inA := make(map[string]bool)
+	for _, value := range a {
+		inA[value] = true
+	}
+	inB := make(map[string]bool)
+	for _, value := range b {
+		inB[value] = true
+	}
+	
+	var lines []string
+	for _, value := range b {
+		if !inA[value] {
+			lines = append(lines, "+ "+value)
+		}
+	}
+	for _, value := range a {
+		if !inB[value] {
+			lines = append(lines, "- "+value)
+		}
+	}
+	
+	if len(lines) > 0 {
+		out.WriteString(title + ":\n")
+		writeIndented(out, lines)
+	}
+}
+
+// diffVariables lists added (+), removed (-) and changed (~) variables
+func diffVariables(a, b map[string]string) []string {
+	var lines []string
+	
+	for _, name := range sortedKeys(b) {
+		oldValue, existed := a[name]
+		if !existed {
+			lines = append(lines, fmt.Sprintf("+ %s = %q", name, b[name]))
+		} else if oldValue != b[name] {
+			lines = append(lines, fmt.Sprintf("~ %s: %q → %q", name, oldValue, b[name]))
+		}
+	}
+	for _, name := range sortedKeys(a) {
+		if _, exists := b[name]; !exists {
+			lines = append(lines, fmt.Sprintf("- %s", name))
+		}
+	}
+	
+	return lines
+}
+
+// diffFiles matches files by target and lists added, removed and changed ones.
+// Only fields kept by exportFile are compared.
+func diffFiles(a, b []ConfigFile) []string {
+	filesA := make(map[string]ConfigFile)
+	for _, file := range a {
+		filesA[file.Target] = exportFile(file)
+	}
+	filesB := make(map[string]ConfigFile)
+	for _, file := range b {
+		filesB[file.Target] = exportFile(file)
+	}
+	
+	var lines []string
+	for _, target := range sortedFileTargets(filesB) {
+		fileB := filesB[target]
+		fileA, existed := filesA[target]
+		if !existed {
+			// A formatter is a command that will run, so it is shown up front
+			if len(fileB.Formatter) > 0 {
+				lines = append(lines, fmt.Sprintf("+ %s (%s): formatter %s", fileB.Name, target, formatField(fileB.Formatter)))
+			} else {
+				lines = append(lines, fmt.Sprintf("+ %s (%s)", fileB.Name, target))
+			}
+			continue
+		}
+		if changes := diffFileFields(fileA, fileB); len(changes) > 0 {
+			lines = append(lines, fmt.Sprintf("~ %s (%s): %s", fileB.Name, target, strings.Join(changes, "; ")))
+		}
+	}
+	for _, target := range sortedFileTargets(filesA) {
+		if _, exists := filesB[target]; !exists {
+			lines = append(lines, fmt.Sprintf("- %s (%s)", filesA[target].Name, target))
+		}
+	}
+	
+	return lines
+}
+
+// diffFileFields describes each JSON field that differs between two files
+func diffFileFields(a, b ConfigFile) []string {
+	fieldsA := fileFields(a)
+	fieldsB := fileFields(b)
+	
+	names := make(map[string]bool)
+	for name := range fieldsA {
+		names[name] = true
+	}
+	for name := range fieldsB {
+		names[name] = true
+	}
+	
+	var changes []string
+	for _, name := range sortedRefNames(names) {
+		if name == "target" || reflect.DeepEqual(fieldsA[name], fieldsB[name]) {
+			continue
+		}
+		if name == "variables" {
+			changes = append(changes, "variables "+strings.Join(diffVariables(a.Variables, b.Variables), ", "))
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s %s → %s", name, formatField(fieldsA[name]), formatField(fieldsB[name])))
+	}
+	
+	return changes
+}
+
+// fileFields returns a file's fields keyed by their JSON names
+func fileFields(file ConfigFile) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+// formatField renders a JSON field value for diff output
+func formatField(value interface{}) string {
+	if value == nil {
+		return "(unset)"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// sortedFileTargets returns the keys of a target-indexed file map in order
+func sortedFileTargets(files map[string]ConfigFile) []string {
+	targets := make([]string, 0, len(files))
+	for target := range files {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// writeIndented writes each line indented under a section heading
+func writeIndented(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString("  " + line + "\n")
+	}
+}
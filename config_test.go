@@ -0,0 +1,386 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestConfig returns a minimal config in a fresh home directory, with
+// $HOME pointing at it and the dotfiles directory created
+func newTestConfig(t *testing.T) (*Config, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	config := createMinimalConfig(filepath.Join(home, ".config", "config-manager"))
+	if err := os.MkdirAll(config.DotfilesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return config, home
+}
+
+func TestMatchesWord(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    bool
+	}{
+		{".vimrc", "vim", true},
+		{".vim-plug", "vim", true},
+		{".vimiumrc", "vim", false},
+		{".gitconfig", "git", true},
+		{".digital", "git", false},
+		{"legit.conf", "git", false},
+		{".github-token", "git", false},
+		{".screenrc", "screen", true},
+		{"screenshots", "screen", false},
+		{"i3status", "i3", true},
+		{"fishing.txt", "fish", false},
+		{"codecov.yml", "code", false},
+		{"init.lua", "init.lua", true},
+	}
+	for _, tt := range tests {
+		if got := matchesWord(tt.name, tt.pattern); got != tt.want {
+			t.Errorf("matchesWord(%q, %q) = %v, want %v", tt.name, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestCategorizeDotfile(t *testing.T) {
+	defaults := createMinimalConfig(t.TempDir())
+	withExtras := createMinimalConfig(t.TempDir())
+	withExtras.Categories = append(withExtras.Categories, "wm", "dev")
+
+	tests := []struct {
+		filename string
+		config   *Config
+		want     string
+	}{
+		{".vimrc", defaults, "editor"},
+		{".vim-plug", defaults, "editor"},
+		{".vimiumrc", defaults, "misc"},
+		{".gitconfig", defaults, "git"},
+		{".digital", defaults, "misc"},
+		{"legit.conf", defaults, "misc"},
+		{".bash_profile", defaults, "shell"},
+		{".zshenv", defaults, "shell"},
+		{"fishing.txt", defaults, "misc"},
+		{".screenrc", defaults, "terminal"},
+		{"screenshots", defaults, "misc"},
+		{"codecov.yml", defaults, "misc"},
+		{"i3status", defaults, "misc"},
+		{"i3status", withExtras, "wm"},
+		{".npmrc", defaults, "misc"},
+		{".npmrc", withExtras, "dev"},
+		{"docker-compose.yml", withExtras, "dev"},
+	}
+	for _, tt := range tests {
+		if got := categorizeDotfile(tt.config, tt.filename); got != tt.want {
+			t.Errorf("categorizeDotfile(%q) with categories %v = %q, want %q", tt.filename, tt.config.Categories, got, tt.want)
+		}
+	}
+}
+
+func TestTargetRootCallSitesAgree(t *testing.T) {
+	config, home := newTestConfig(t)
+	config.TargetRoot = t.TempDir()
+	target := filepath.Join(home, ".vimrc")
+	onDisk := filepath.Join(config.TargetRoot, target)
+	file := ConfigFile{Name: "vimrc", Source: "editor/vimrc", Target: target, Category: "editor"}
+
+	// The real path holds an unrelated file; the sandboxed one links elsewhere
+	writeTestFile(t, target, "not managed\n")
+	elsewhere := filepath.Join(t.TempDir(), "vimrc")
+	writeTestFile(t, elsewhere, "set number\n")
+	if err := os.MkdirAll(filepath.Dir(onDisk), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(elsewhere, onDisk); err != nil {
+		t.Fatal(err)
+	}
+
+	if resolved, err := resolveTarget(config, &file); err != nil || resolved != onDisk {
+		t.Fatalf("resolveTarget = %q (%v), want %q", resolved, err, onDisk)
+	}
+	if linkTarget, _, ok := externalSymlink(config, &file); !ok || linkTarget != elsewhere {
+		t.Fatalf("externalSymlink = %q, %v; want the link under the target root", linkTarget, ok)
+	}
+	if conflict, err := detectConflict(config, &file, filepath.Join(config.DotfilesDir, file.Source)); err != nil || conflict == nil || conflict.TargetPath != onDisk {
+		t.Fatalf("detectConflict = %+v (%v), want a conflict at %q", conflict, err, onDisk)
+	}
+
+	if err := absorbSymlinkTarget(config, &file); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(config.DotfilesDir, file.Source)); err != nil || string(data) != "set number\n" {
+		t.Errorf("absorbed source holds %q (%v), want the linked file's contents", data, err)
+	}
+
+	config.Files = []ConfigFile{file}
+	if err := atomicLinkSingleConfig(config, &config.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	updateSingleFileStatus(config, &config.Files[0])
+	if !config.Files[0].IsLinked {
+		t.Error("status check doesn't see the link made under the target root")
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "not managed\n" {
+		t.Errorf("the real path was touched: %q (%v)", data, err)
+	}
+}
+
+func TestEquivalentLinkValuesAreLinked(t *testing.T) {
+	config, home := newTestConfig(t)
+	source := filepath.Join(config.DotfilesDir, "editor", "nvim")
+	writeTestFile(t, filepath.Join(source, "init.lua"), "")
+	relSource := filepath.Join(".config", "config-manager", "dotfiles", "editor", "nvim")
+	// A symlinked path to the dotfiles directory resolves to the same source
+	viaLink := filepath.Join(home, "dotfiles-link")
+	if err := os.Symlink(config.DotfilesDir, viaLink); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, linkValue := range []string{
+		source,
+		source + "/",
+		filepath.Dir(source) + "//nvim",
+		filepath.Join(config.DotfilesDir, "editor", "..", "editor", "nvim"),
+		relSource,
+		"./" + relSource,
+		filepath.Join("..", filepath.Base(home), relSource),
+		filepath.Join(viaLink, "editor", "nvim"),
+	} {
+		target := filepath.Join(home, ".nvim")
+		os.Remove(target)
+		if err := os.Symlink(linkValue, target); err != nil {
+			t.Fatal(err)
+		}
+		file := ConfigFile{Name: "nvim", Source: "editor/nvim", Target: target, Category: "editor"}
+
+		updateSingleFileStatus(config, &file)
+		if !file.IsLinked || file.HasConflict {
+			t.Errorf("link to %q: linked=%v conflict=%v, want linked", linkValue, file.IsLinked, file.HasConflict)
+		}
+		if conflict, err := detectConflict(config, &file, source); err != nil || conflict != nil {
+			t.Errorf("link to %q: detectConflict = %+v (%v), want no conflict", linkValue, conflict, err)
+		}
+	}
+}
+
+func TestMustHome(t *testing.T) {
+	for _, test := range []struct {
+		home    string
+		want    string
+		wantErr bool
+	}{
+		{"", "", true},
+		{"relative/home", "", true},
+		{"/home/user/", "/home/user", false},
+	} {
+		t.Setenv("HOME", test.home)
+		got, err := mustHome()
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("HOME=%q: mustHome() = %q, %v; want %q, error %v", test.home, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestUnsetHomeFailsInsteadOfGuessing(t *testing.T) {
+	config, _ := newTestConfig(t)
+	t.Setenv("HOME", "")
+
+	if path, err := validateAndNormalizePath("~/.zshrc"); err == nil {
+		t.Errorf("validateAndNormalizePath = %q, want an error", path)
+	}
+	if file, err := createConfigFileFromPath(".zshrc", config, false); err == nil {
+		t.Errorf("createConfigFileFromPath = %+v, want an error", file)
+	}
+	for _, root := range config.allowedTargetRoots() {
+		if !filepath.IsAbs(root) {
+			t.Errorf("allowed target root %q isn't absolute", root)
+		}
+	}
+}
+
+func TestSharedSourceLinksManyTargets(t *testing.T) {
+	config, home := newTestConfig(t)
+	shared := filepath.Join(config.DotfilesDir, "vendor", "shared", "gitconfig")
+	writeTestFile(t, shared, "[user]\n")
+	config.Files = []ConfigFile{
+		{Name: "gitconfig", Source: "vendor/shared/gitconfig", Target: filepath.Join(home, ".gitconfig"), Category: "git"},
+		{Name: "config", Source: "vendor/shared/gitconfig", Target: filepath.Join(home, ".config", "git", "config"), Category: "git"},
+	}
+	if errs := config.validateFiles(); len(errs) != 0 {
+		t.Fatalf("validateFiles = %v, want a shared source outside the categories accepted", errs)
+	}
+
+	for i := range config.Files {
+		file := &config.Files[i]
+		if err := atomicLinkSingleConfig(config, file); err != nil {
+			t.Fatalf("link %s: %v", file.Target, err)
+		}
+		if value, err := os.Readlink(file.Target); err != nil || value != shared {
+			t.Errorf("%s links to %q (%v), want %q", file.Target, value, err, shared)
+		}
+	}
+}
+
+func TestMoveSourceOntoAnotherSharesIt(t *testing.T) {
+	config, home := newTestConfig(t)
+	shared := filepath.Join(config.DotfilesDir, "vendor", "shared", "gitconfig")
+	own := filepath.Join(config.DotfilesDir, "git", "gitconfig")
+	writeTestFile(t, shared, "[user]\n")
+	writeTestFile(t, own, "[core]\n")
+	config.Files = []ConfigFile{
+		{Name: "gitconfig", Source: "vendor/shared/gitconfig", Target: filepath.Join(home, ".gitconfig"), Category: "git"},
+		{Name: "config", Source: "git/gitconfig", Target: filepath.Join(home, ".config", "git", "config"), Category: "git"},
+	}
+	for i := range config.Files {
+		if err := atomicLinkSingleConfig(config, &config.Files[i]); err != nil {
+			t.Fatal(err)
+		}
+		updateSingleFileStatus(config, &config.Files[i])
+	}
+
+	if err := config.MoveSource(config.Files[1].Target, "vendor/shared/gitconfig/"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Files[1].Source != "vendor/shared/gitconfig" {
+		t.Errorf("source = %q, want the shared one", config.Files[1].Source)
+	}
+	for _, file := range config.Files {
+		if value, err := os.Readlink(file.Target); err != nil || value != shared {
+			t.Errorf("%s links to %q (%v), want %q", file.Target, value, err, shared)
+		}
+	}
+	// The old source is left for prune-sources rather than deleted
+	if data, err := os.ReadFile(own); err != nil || string(data) != "[core]\n" {
+		t.Errorf("old source = %q, %v; want it left in place", data, err)
+	}
+
+	// A shared source can't be moved away from under the other file
+	err := config.MoveSource(config.Files[0].Target, "git/moved")
+	if err == nil || !strings.Contains(err.Error(), "shared with config") {
+		t.Errorf("moving a shared source = %v, want it refused", err)
+	}
+}
+
+func TestSharingTemplateSourceRefused(t *testing.T) {
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "vendor", "gitconfig"), "[user]\n")
+	config.Files = []ConfigFile{
+		{Name: "gitconfig", Source: "vendor/gitconfig", Target: filepath.Join(home, ".gitconfig"), Category: "git", Template: true},
+		{Name: "config", Source: "git/config", Target: filepath.Join(home, ".config", "git", "config"), Category: "git"},
+	}
+
+	if err := config.MoveSource(config.Files[1].Target, "vendor/gitconfig"); err == nil {
+		t.Error("sharing a template's rendered source was accepted")
+	}
+	if config.Files[1].Source != "git/config" {
+		t.Errorf("source = %q, want it unchanged", config.Files[1].Source)
+	}
+}
+
+func TestRemoveDuplicateFilesMatchesValidation(t *testing.T) {
+	config, home := newTestConfig(t)
+	files := []ConfigFile{
+		{Name: "zshrc", Source: "shell/zshrc", Target: filepath.Join(home, ".zshrc"), Category: "shell"},
+		{Name: "zshrc-slash", Source: "shell/zshrc", Target: filepath.Join(home, ".zshrc") + "/", Category: "shell"},
+		{Name: "zshrc-dot", Source: "shell/zshrc", Target: home + "/./.zshrc", Category: "shell"},
+		{Name: "bashrc", Source: "shell/bashrc", Target: filepath.Join(home, ".bashrc"), Category: "shell"},
+	}
+
+	config.Files = files
+	duplicates := 0
+	for _, issue := range config.validateFiles() {
+		if strings.Contains(issue.Message, "duplicate target") {
+			duplicates++
+		}
+	}
+	unique := removeDuplicateFiles(config, files)
+	if duplicates != 2 || len(unique) != 2 || unique[0].Name != "zshrc" || unique[1].Name != "bashrc" {
+		t.Errorf("validation found %d duplicates and %d files were kept (%+v), want both to drop the same 2", duplicates, len(unique), unique)
+	}
+}
+
+func TestCaseCollisionIsAWarning(t *testing.T) {
+	config, home := newTestConfig(t)
+	if !isCaseInsensitiveDir(home) {
+		t.Skip("the temporary directory is on a case-sensitive filesystem")
+	}
+	config.Files = []ConfigFile{
+		{Name: "zshrc", Source: "shell/zshrc", Target: filepath.Join(home, ".zshrc"), Category: "shell"},
+		{Name: "ZSHRC", Source: "shell/ZSHRC", Target: filepath.Join(home, ".ZSHRC"), Category: "shell"},
+	}
+	found := false
+	for _, issue := range config.validateFiles() {
+		if strings.Contains(issue.Message, "only by case") {
+			found = true
+			if issue.Severity != SeverityWarning {
+				t.Errorf("case collision reported as %v, want a warning", issue.Severity)
+			}
+		}
+	}
+	if !found {
+		t.Error("case collision wasn't reported")
+	}
+}
+
+func TestResolveConfigDir(t *testing.T) {
+	home := t.TempDir()
+	xdg := filepath.Join(t.TempDir(), "xdg")
+	legacy := filepath.Join(home, ".config", "config-manager")
+	tests := []struct {
+		name    string
+		xdg     string
+		legacy  bool // ~/.config/config-manager already exists
+		wantXDG string
+		wantDir string
+	}{
+		{"unset", "", false, filepath.Join(home, ".config"), legacy},
+		{"absolute", xdg, false, xdg, filepath.Join(xdg, "config-manager")},
+		{"absolute uncleaned", xdg + "/", false, xdg, filepath.Join(xdg, "config-manager")},
+		{"relative is ignored", "xdg", false, filepath.Join(home, ".config"), legacy},
+		{"legacy directory kept", xdg, true, xdg, legacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", home)
+			t.Setenv(configHomeEnv, "")
+			t.Setenv("XDG_CONFIG_HOME", tt.xdg)
+			os.RemoveAll(legacy)
+			if tt.legacy {
+				if err := os.MkdirAll(legacy, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := xdgConfigHome(); got != tt.wantXDG {
+				t.Errorf("xdgConfigHome() = %q, want %q", got, tt.wantXDG)
+			}
+			if got := resolveConfigDir(""); got != tt.wantDir {
+				t.Errorf("resolveConfigDir() = %q, want %q", got, tt.wantDir)
+			}
+		})
+	}
+}
+
+func TestResolveConfigDirPrefersXDGOverLegacy(t *testing.T) {
+	home := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(configHomeEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	for _, dir := range []string{filepath.Join(home, ".config", "config-manager"), filepath.Join(xdg, "config-manager")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := resolveConfigDir(""), filepath.Join(xdg, "config-manager"); got != want {
+		t.Errorf("resolveConfigDir() = %q, want %q once it exists", got, want)
+	}
+}
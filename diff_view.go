@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// diffContextLines is how many unchanged lines are shown around a change
+const diffContextLines = 3
+
+// diffMaxFileSize is the largest file the diff view compares itself; larger
+// ones are shown with the external diff tool
+const diffMaxFileSize = 1 << 20
+
+// diffMaxCells bounds the line-by-line comparison: the lines of one file
+// times the lines of the other, once common leading and trailing lines are
+// set aside
+const diffMaxCells = 4 << 20
+
+// diffOp marks a line of a diff as kept, removed from the old file or added by the new one
+type diffOp byte
+
+const (
+	diffEqual  diffOp = ' '
+	diffDelete diffOp = '-'
+	diffInsert diffOp = '+'
+)
+
+// diffLine is one line of a line-by-line diff
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// lineDiff compares from and to line by line, keeping the longest run of
+// common lines. ok is false when the differing part is too large to compare.
+func lineDiff(from, to []string) (lines []diffLine, ok bool) {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	a, b := from[prefix:len(from)-suffix], to[prefix:len(to)-suffix]
+	if len(a)*len(b) > diffMaxCells {
+		return nil, false
+	}
+	
+	// common[i*(len(b)+1)+j] is the length of the longest common subsequence of a[i:] and b[j:]
+	width := len(b) + 1
+	common := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				common[i*width+j] = common[(i+1)*width+j+1] + 1
+			case common[(i+1)*width+j] >= common[i*width+j+1]:
+				common[i*width+j] = common[(i+1)*width+j]
+			default:
+				common[i*width+j] = common[i*width+j+1]
+			}
+		}
+	}
+	
+	for _, text := range from[:prefix] {
+		lines = append(lines, diffLine{diffEqual, text})
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{diffEqual, a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && common[(i+1)*width+j] >= common[i*width+j+1]):
+			lines = append(lines, diffLine{diffDelete, a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{diffInsert, b[j]})
+			j++
+		}
+	}
+	for _, text := range from[len(from)-suffix:] {
+		lines = append(lines, diffLine{diffEqual, text})
+	}
+	return lines, true
+}
+
+// formatDiff renders a diff as unified hunks with diffContextLines of
+// context, colored for the diff view
+func formatDiff(lines []diffLine) string {
+	var b strings.Builder
+	oldLine, newLine := 0, 0 // lines of each file before lines[i]
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffEqual {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+		
+		// Changes with no more than twice the context between them share a
+		// hunk, which ends diffContextLines past the last of them
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for {
+			for end < len(lines) && lines[end].op != diffEqual {
+				end++
+			}
+			next := end
+			for next < len(lines) && lines[next].op == diffEqual {
+				next++
+			}
+			if next == len(lines) || next-end > 2*diffContextLines {
+				break
+			}
+			end = next
+		}
+		end += diffContextLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		
+		oldStart, newStart := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+		for _, line := range lines[start:end] {
+			if line.op != diffInsert {
+				oldCount++
+			}
+			if line.op != diffDelete {
+				newCount++
+			}
+		}
+		b.WriteString(diffHunkStyle.Render(fmt.Sprintf("@@ -%s +%s @@", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))) + "\n")
+		for _, line := range lines[start:end] {
+			text := string(line.op) + line.text
+			switch line.op {
+			case diffDelete:
+				text = diffDeleteStyle.Render(text)
+			case diffInsert:
+				text = diffInsertStyle.Render(text)
+			}
+			b.WriteString(text + "\n")
+		}
+		
+		oldLine, newLine = oldStart+oldCount, newStart+newCount
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats where a hunk is in one file, as unified diffs do
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// readDiffLines reads a file for the diff view; a missing file reads as empty.
+// ok is false for directories, binary files and files over diffMaxFileSize.
+func readDiffLines(path string) (lines []string, ok bool, err error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, NewConfigError("read file", path, err)
+	}
+	if info.IsDir() || info.Size() > diffMaxFileSize {
+		return nil, false, nil
+	}
+	
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, NewConfigError("read file", path, err)
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return nil, false, nil
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil, true, nil
+	}
+	return strings.Split(text, "\n"), true, nil
+}
+
+// buildDiff renders the differences between target and source for the diff
+// view. ok is false when they can't be compared there - directories, binary
+// and large files - and the external diff tool should be used instead.
+func buildDiff(target, source string) (content string, ok bool, err error) {
+	targetLines, ok, err := readDiffLines(target)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	sourceLines, ok, err := readDiffLines(source)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	lines, ok := lineDiff(targetLines, sourceLines)
+	if !ok {
+		return "", false, nil
+	}
+	
+	header := diffDeleteStyle.Render("--- "+target) + "\n" + diffInsertStyle.Render("+++ "+source) + "\n"
+	body := formatDiff(lines)
+	if body == "" {
+		body = inactiveStyle.Render("The files are identical") + "\n"
+	}
+	return header + body, true, nil
+}
+
+// showDiff opens the diff view comparing target with source, returning to
+// the current view when it is closed. Directories, binary and large files
+// are shown in the external diff tool instead.
+func (m model) showDiff(title, target, source string) (tea.Model, tea.Cmd) {
+	content, ok, err := buildDiff(target, source)
+	if err != nil {
+		m.message = fmt.Sprintf("Can't show diff: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	if !ok {
+		return m, tea.ExecProcess(diffPagerCommand(target, source), func(err error) tea.Msg {
+			return conflictDiffDoneMsg{err: err}
+		})
+	}
+	
+	width, height := m.diffViewportSize()
+	m.diffViewport = viewport.New(width, height)
+	m.diffViewport.SetContent(strings.TrimSuffix(content, "\n"))
+	m.diffTitle = title
+	m.diffPaths = [2]string{target, source}
+	m.diffReturn = m.currentView
+	m.currentView = "diff"
+	return m, nil
+}
+
+// diffViewportSize is how much of the screen the diff gets: what the file
+// list gets, less the title line
+func (m model) diffViewportSize() (width, height int) {
+	width, height = m.width-4, m.height-9
+	if width < 40 {
+		width = 40
+	}
+	if height < 5 {
+		height = 5
+	}
+	return width, height
+}
+
+// updateDiffView handles key presses while the diff view is shown; anything
+// but our own keys scrolls the viewport
+func (m model) updateDiffView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+		
+	case key.Matches(msg, keys.Back):
+		m.currentView = m.diffReturn
+		return m, nil
+		
+	case key.Matches(msg, keys.Pager):
+		return m, tea.ExecProcess(diffPagerCommand(m.diffPaths[0], m.diffPaths[1]), func(err error) tea.Msg {
+			return conflictDiffDoneMsg{err: err}
+		})
+	}
+	
+	var cmd tea.Cmd
+	m.diffViewport, cmd = m.diffViewport.Update(msg)
+	return m, cmd
+}
+
+// diffView renders the diff view: the title and scroll position above the diff
+func (m model) diffView() string {
+	position := fmt.Sprintf("%3.f%%", m.diffViewport.ScrollPercent()*100)
+	return activeStyle.Render(m.diffTitle) + " " + inactiveStyle.Render(position) + "\n\n" + m.diffViewport.View()
+}
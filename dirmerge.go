@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// directoryMerge is the plan for adopting an existing target directory into
+// its source: entries only the target has are moved into the source, and
+// entries both have are left where they are. Those stay in the target, which
+// linking then backs up as usual, so nothing is discarded.
+type directoryMerge struct {
+	moves      [][2]string // target entry -> source entry
+	identical  []string    // relative paths the source already has with the same contents
+	collisions []string    // relative paths the source has with different contents
+}
+
+// canMergeDirectory reports whether a conflict can be resolved by merging: the
+// target must be a real directory and the source a directory too
+func canMergeDirectory(conflict *ConflictInfo) bool {
+	if conflict.IsSymlink {
+		return false
+	}
+	targetInfo, err := os.Lstat(conflict.TargetPath)
+	if err != nil || !targetInfo.IsDir() {
+		return false
+	}
+	sourceInfo, err := os.Stat(conflict.SourcePath)
+	return err == nil && sourceInfo.IsDir()
+}
+
+// planDirectoryMerge compares target with source entry by entry. Directories
+// present on both sides are descended into; a file on one side and a
+// directory on the other is a collision like any other.
+func planDirectoryMerge(target, source string) (*directoryMerge, error) {
+	merge := &directoryMerge{}
+	err := filepath.Walk(target, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == target {
+			return nil
+		}
+		
+		rel, err := filepath.Rel(target, path)
+		if err != nil {
+			return err
+		}
+		sourceEntry := filepath.Join(source, rel)
+		sourceInfo, err := os.Lstat(sourceEntry)
+		switch {
+		case os.IsNotExist(err):
+			merge.moves = append(merge.moves, [2]string{path, sourceEntry})
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case err != nil:
+			return err
+		case info.IsDir() && sourceInfo.IsDir():
+			return nil
+		case !info.IsDir() && !sourceInfo.IsDir() && contentsMatch(path, sourceEntry):
+			merge.identical = append(merge.identical, rel)
+		default:
+			merge.collisions = append(merge.collisions, rel)
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, NewConfigError("plan directory merge", target, err)
+	}
+	return merge, nil
+}
+
+// summary describes the merge in one line, naming the colliding entries
+func (d *directoryMerge) summary() string {
+	text := fmt.Sprintf("adopted %d entries", len(d.moves))
+	if len(d.identical) > 0 {
+		text += fmt.Sprintf(", %d already in source", len(d.identical))
+	}
+	if len(d.collisions) > 0 {
+		text += fmt.Sprintf(", kept source version of %v (existing copies are in the backup)", d.collisions)
+	}
+	return text
+}
+
+// createMergeDirectoryOperation plans merging file's existing target
+// directory into its source and then linking it. The moves and the link run
+// in one transaction, so a failure puts every entry back.
+func createMergeDirectoryOperation(config *Config, file *ConfigFile) (*Transaction, *directoryMerge, error) {
+	sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	conflict := &ConflictInfo{File: file, TargetPath: target, SourcePath: sourcePath}
+	if !canMergeDirectory(conflict) {
+		return nil, nil, NewConfigError("merge directory", file.Name,
+			fmt.Errorf("both %s and %s must be directories", target, sourcePath))
+	}
+	
+	merge, err := planDirectoryMerge(target, sourcePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The differing copies are only kept by backing up what's left of the target
+	if config.backupPolicy().skip && len(merge.collisions) > 0 {
+		return nil, nil, NewConfigError("merge directory", file.Name,
+			fmt.Errorf("backups are off and %v differ from the source; they would be lost", merge.collisions))
+	}
+	
+	tx := NewTransaction(config)
+	for _, move := range merge.moves {
+		tx.AddOperation(NewMoveOperation(move[0], move[1], file))
+	}
+	linkTx, err := createAtomicLinkOperation(config, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, op := range linkTx.GetOperations() {
+		tx.AddOperation(op)
+	}
+	return tx, merge, nil
+}
+
+// mergeDirectoryWithResult merges and links a single config in its own
+// transaction and reports the outcome instead of returning an error
+func mergeDirectoryWithResult(config *Config, file *ConfigFile) OperationResult {
+	tx, merge, err := createMergeDirectoryOperation(config, file)
+	if err != nil {
+		return OperationResult{
+			File:    file.Name,
+			Success: false,
+			Message: "Failed to create transaction",
+			Error:   err,
+		}
+	}
+	
+	if err := tx.Execute(); err != nil {
+		return OperationResult{
+			File:    file.Name,
+			Success: false,
+			Message: "Transaction failed",
+			Error:   err,
+		}
+	}
+	
+	return OperationResult{
+		File:    file.Name,
+		Success: true,
+		Message: "Merged and linked: " + merge.summary(),
+	}
+}
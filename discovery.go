@@ -9,9 +9,11 @@ import (
 
 // Find unmanaged dotfiles in home directory
 func findUnmanagedDotfiles(config *Config) []string {
-	homeDir, _ := os.UserHomeDir()
+	homeDir, _ := mustHome()
 	var unmanaged []string
 	
+	ignorePatterns := loadIgnorePatterns(config)
+	
 	// Get list of currently managed files (check both name and target path)
 	managed := make(map[string]bool)
 	managedPaths := make(map[string]bool)
@@ -31,7 +33,7 @@ func findUnmanagedDotfiles(config *Config) []string {
 	
 	for _, dotfile := range commonDotfiles {
 		targetPath := filepath.Join(homeDir, dotfile)
-		if !managed[dotfile] && !managedPaths[targetPath] {
+		if !managed[dotfile] && !managedPaths[targetPath] && !matchesIgnore(dotfile, ignorePatterns) {
 			if _, err := os.Stat(targetPath); err == nil {
 				unmanaged = append(unmanaged, dotfile)
 			}
@@ -45,8 +47,8 @@ func findUnmanagedDotfiles(config *Config) []string {
 			targetPath := filepath.Join(homeDir, name)
 			if strings.HasPrefix(name, ".") && !entry.IsDir() && 
 			   !managed[name] && !managedPaths[targetPath] {
-				// Skip common non-config files
-				if !isSystemFile(name) {
+				// Skip common non-config files and user-ignored names
+				if !isSystemFile(name) && !matchesIgnore(name, ignorePatterns) {
 					unmanaged = append(unmanaged, name)
 				}
 			}
@@ -56,12 +58,21 @@ func findUnmanagedDotfiles(config *Config) []string {
 	return unmanaged
 }
 
+// defaultDiscoveryDepth only lists the direct children of ~/.config
+const defaultDiscoveryDepth = 1
+
 // Discover all possible configuration files and directories
-func discoverAllConfigs() []string {
-	homeDir, _ := os.UserHomeDir()
+func discoverAllConfigs(config *Config) []string {
+	homeDir, _ := mustHome()
 	var configs []string
 	
-	fmt.Printf("Scanning home directory: %s\n", homeDir)
+	ignorePatterns := loadIgnorePatterns(config)
+	depth := config.DiscoveryDepth
+	if depth <= 0 {
+		depth = defaultDiscoveryDepth
+	}
+	
+	infof("Scanning home directory: %s\n", homeDir)
 	
 	// Common dotfiles in home directory
 	commonDotfiles := []string{
@@ -72,35 +83,29 @@ func discoverAllConfigs() []string {
 		".editorconfig", ".prettierrc", ".eslintrc",
 	}
 	
-	fmt.Print("Checking common dotfiles... ")
+	infof("Checking common dotfiles... ")
 	found := 0
 	for _, dotfile := range commonDotfiles {
+		if matchesIgnore(dotfile, ignorePatterns) {
+			continue
+		}
 		path := filepath.Join(homeDir, dotfile)
 		if _, err := os.Stat(path); err == nil {
 			configs = append(configs, fmt.Sprintf("%s (file)", dotfile))
 			found++
 		}
 	}
-	fmt.Printf("found %d\n", found)
+	infof("found %d\n", found)
 	
-	// Check .config directory for subdirectories
-	configDir := filepath.Join(homeDir, ".config")
-	fmt.Printf("Checking .config directory: %s... ", configDir)
-	configFound := 0
-	if entries, err := os.ReadDir(configDir); err == nil {
-		for _, entry := range entries {
-			if entry.IsDir() {
-				// Skip some system directories
-				name := entry.Name()
-				if !isSystemConfigDir(name) {
-					configs = append(configs, fmt.Sprintf(".config/%s (directory)", name))
-					configFound++
-				}
-			}
-		}
-		fmt.Printf("found %d directories\n", configFound)
+	// Check the XDG config directory (usually ~/.config) for subdirectories
+	configDir := xdgConfigHome()
+	infof("Checking config directory (depth %d): %s... ", depth, configDir)
+	if _, err := os.ReadDir(configDir); err == nil {
+		configDirs := discoverConfigSubdirs(configDir, xdgConfigDisplayPath(), 1, depth, ignorePatterns)
+		configs = append(configs, configDirs...)
+		infof("found %d directories\n", len(configDirs))
 	} else {
-		fmt.Printf("not accessible (%v)\n", err)
+		infof("not accessible (%v)\n", err)
 	}
 	
 	// Other important directories
@@ -109,47 +114,50 @@ func discoverAllConfigs() []string {
 		".fonts", ".themes", ".icons",
 	}
 	
-	fmt.Print("Checking special directories... ")
+	infof("Checking special directories... ")
 	specialFound := 0
 	for _, dir := range specialDirs {
+		if matchesIgnore(dir, ignorePatterns) {
+			continue
+		}
 		path := filepath.Join(homeDir, dir)
 		if info, err := os.Stat(path); err == nil && info.IsDir() {
 			configs = append(configs, fmt.Sprintf("%s (directory)", dir))
 			specialFound++
 		}
 	}
-	fmt.Printf("found %d\n", specialFound)
+	infof("found %d\n", specialFound)
 	
-	fmt.Printf("Total configurations discovered: %d\n", len(configs))
+	infof("Total configurations discovered: %d\n", len(configs))
 	
 	// Debug: print first few found configs
 	if len(configs) > 0 {
-		fmt.Println("Examples found:")
+		infoln("Examples found:")
 		for i, config := range configs {
 			if i >= 5 { // Only show first 5
-				fmt.Printf("  ... and %d more\n", len(configs)-5)
+				infof("  ... and %d more\n", len(configs)-5)
 				break
 			}
-			fmt.Printf("  - %s\n", config)
+			infof("  - %s\n", config)
 		}
 	} else {
-		fmt.Println("❌ No configurations found!")
-		fmt.Println("This might be because:")
-		fmt.Println("  - You don't have common dotfiles yet")
-		fmt.Println("  - Your configs are in non-standard locations")
-		fmt.Println("  - Permission issues accessing directories")
-		fmt.Printf("  - Home directory: %s\n", homeDir)
+		infoln(glyphError + " No configurations found!")
+		infoln("This might be because:")
+		infoln("  - You don't have common dotfiles yet")
+		infoln("  - Your configs are in non-standard locations")
+		infoln("  - Permission issues accessing directories")
+		infof("  - Home directory: %s\n", homeDir)
 		
 		// Let's check what's actually in the home directory
 		if entries, err := os.ReadDir(homeDir); err == nil {
-			fmt.Println("Files in home directory:")
+			infoln("Files in home directory:")
 			for i, entry := range entries {
 				if i >= 10 { // Only show first 10
-					fmt.Printf("  ... and %d more\n", len(entries)-10)
+					infof("  ... and %d more\n", len(entries)-10)
 					break
 				}
 				if strings.HasPrefix(entry.Name(), ".") {
-					fmt.Printf("  %s\n", entry.Name())
+					infof("  %s\n", entry.Name())
 				}
 			}
 		}
@@ -158,6 +166,57 @@ func discoverAllConfigs() []string {
 	return configs
 }
 
+// discoverConfigSubdirs walks dir up to maxDepth levels, emitting one entry per
+// directory. Nested entries are indented by depth so the list stays readable.
+func discoverConfigSubdirs(dir, relPath string, depth, maxDepth int, ignorePatterns []string) []string {
+	var configs []string
+	
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return configs
+	}
+	
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		
+		// Skip system, noisy and user-ignored directories without descending into them
+		name := entry.Name()
+		entryRelPath := relPath + "/" + name
+		if isSystemConfigDir(name) || isNoisyDir(name) || matchesIgnore(entryRelPath, ignorePatterns) {
+			continue
+		}
+		
+		indent := strings.Repeat("  ", depth-1)
+		configs = append(configs, fmt.Sprintf("%s%s (directory)", indent, entryRelPath))
+		
+		if depth < maxDepth {
+			configs = append(configs, discoverConfigSubdirs(filepath.Join(dir, name), entryRelPath, depth+1, maxDepth, ignorePatterns)...)
+		}
+	}
+	
+	return configs
+}
+
+// isNoisyDir reports directories that are large or hold generated data and
+// are never worth descending into during discovery
+func isNoisyDir(name string) bool {
+	noisyDirs := []string{
+		"node_modules", ".git", "cache", "Cache", "CachedData", "GPUCache",
+		"Code Cache", "Crashpad", "logs", "log", "tmp", "History",
+		"plugged", "pack", "site-packages", "__pycache__",
+	}
+	
+	for _, noisy := range noisyDirs {
+		if name == noisy {
+			return true
+		}
+	}
+	
+	return false
+}
+
 // Check if a config directory should be skipped (system directories)
 func isSystemConfigDir(name string) bool {
 	systemDirs := []string{
@@ -177,3 +236,59 @@ func isSystemConfigDir(name string) bool {
 	
 	return false
 }
+
+// loadIgnorePatterns reads user-defined ignore patterns from ConfigDir/ignore.
+// The file uses .gitignore-style syntax: one pattern per line, blank lines
+// and lines starting with '#' are skipped.
+func loadIgnorePatterns(config *Config) []string {
+	if config == nil || config.ConfigDir == "" {
+		return nil
+	}
+	
+	data, err := os.ReadFile(filepath.Join(config.ConfigDir, "ignore"))
+	if err != nil {
+		return nil
+	}
+	
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	
+	return patterns
+}
+
+// matchesIgnore reports whether a home-relative path matches any ignore pattern.
+// Patterns support '*' globs; a pattern matching a leading directory (e.g.
+// ".config/foo" or ".config/foo/") ignores everything beneath it, and a pattern
+// without a slash matches any single path component.
+func matchesIgnore(name string, patterns []string) bool {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+	if name == "" {
+		return false
+	}
+	components := strings.Split(name, "/")
+	
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "/"), "/")
+		if pattern == "" {
+			continue
+		}
+		
+		for i := range components {
+			candidate := strings.Join(components[:i+1], "/")
+			if !strings.Contains(pattern, "/") {
+				candidate = components[i]
+			}
+			if matched, _ := filepath.Match(pattern, candidate); matched {
+				return true
+			}
+		}
+	}
+	
+	return false
+}
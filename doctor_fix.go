@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// doctorRepairs is what doctor --fix can repair, as one transaction, and the
+// problems it leaves for the user
+type doctorRepairs struct {
+	tx      *Transaction
+	fixes   []string // one line per planned repair
+	manual  []string // problems that need the user
+	relinks []string // targets linked by the repairs, for recording link times
+}
+
+// planDoctorRepairs works out the repairs that are safe to make: sources of
+// templates that went missing are rendered again, managed links left dangling
+// are re-pointed at their (possibly re-rendered) source, empty backup
+// directories left by interrupted backups are removed and missing category
+// directories are created. Nothing holding user data is deleted; anything
+// else that's wrong is reported in manual.
+func planDoctorRepairs(config *Config) (*doctorRepairs, error) {
+	repairs := &doctorRepairs{tx: NewTransaction(config)}
+	
+	danglingLinks := findDanglingLinks(config)
+	dangling := make(map[string]bool)
+	for _, path := range danglingLinks {
+		dangling[filepath.Clean(path)] = true
+	}
+	
+	for i := range config.Files {
+		file := &config.Files[i]
+		sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+		_, statErr := os.Stat(sourcePath)
+		sourceMissing := os.IsNotExist(statErr)
+		
+		templatePath := ""
+		if file.Template {
+			templatePath = findTemplateFile(config, file.Name, file.Source, file.Category)
+		}
+		canRender := sourceMissing && templatePath != ""
+		
+		if sourceMissing && !canRender {
+			if file.Template {
+				repairs.manual = append(repairs.manual, fmt.Sprintf("%s: source %s is missing and no template was found to render it from", file.Name, file.Source))
+			} else {
+				repairs.manual = append(repairs.manual, fmt.Sprintf("%s: source %s is missing; restore it from a backup or remove the file from the config", file.Name, file.Source))
+			}
+			continue
+		}
+		
+		if target, err := resolveTarget(config, file); err == nil && dangling[target] {
+			// Linking renders a missing template source first
+			fileTx, err := createAtomicLinkOperation(config, file)
+			if err != nil {
+				repairs.manual = append(repairs.manual, fmt.Sprintf("%s: dangling link %s can't be relinked: %v", file.Name, file.Target, err))
+				continue
+			}
+			for _, op := range fileTx.GetOperations() {
+				repairs.tx.AddOperation(op)
+			}
+			repairs.relinks = append(repairs.relinks, file.Target)
+			if canRender {
+				repairs.fixes = append(repairs.fixes, fmt.Sprintf("render %s from its template and relink %s", file.Source, file.Target))
+			} else {
+				repairs.fixes = append(repairs.fixes, fmt.Sprintf("relink dangling %s to %s", file.Target, file.Source))
+			}
+			continue
+		}
+		
+		if canRender {
+			repairs.tx.AddOperation(NewTemplateOperation(config, file, templatePath, sourcePath))
+			repairs.fixes = append(repairs.fixes, fmt.Sprintf("render missing source %s from its template", file.Source))
+		}
+	}
+	
+	// Dangling leaves of tree-linked directories belong to source files that
+	// were deleted on purpose more often than not
+	for _, path := range danglingLinks {
+		if isManagedTreeLeaf(config, path) {
+			repairs.manual = append(repairs.manual, fmt.Sprintf("dangling link %s: its source was deleted (plain doctor offers to remove it)", path))
+		}
+	}
+	
+	backups, err := listBackups(config, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, backup := range backups {
+		if entries, err := os.ReadDir(backup.Path); err == nil && len(entries) == 0 {
+			repairs.tx.AddOperation(&removeEmptyDirOperation{path: backup.Path})
+			repairs.fixes = append(repairs.fixes, fmt.Sprintf("remove empty backup %s", backup.Name))
+		}
+	}
+	
+	// In a git-managed dotfiles directory without gitkeep, category
+	// directories are created on demand and aren't missing
+	if !isGitManaged(config.DotfilesDir) || config.GitKeep {
+		for _, category := range config.Categories {
+			categoryDir := filepath.Join(config.DotfilesDir, category)
+			if _, err := os.Stat(categoryDir); os.IsNotExist(err) {
+				repairs.tx.AddOperation(&createDirOperation{path: categoryDir})
+				repairs.fixes = append(repairs.fixes, fmt.Sprintf("create missing category directory %s", category))
+			}
+		}
+	}
+	
+	return repairs, nil
+}
+
+// createDirOperation creates a directory; rollback removes it again
+type createDirOperation struct {
+	path    string
+	created bool
+}
+
+func (op *createDirOperation) Execute() error {
+	if err := os.Mkdir(op.path, 0755); err != nil {
+		return NewConfigError("create directory", op.path, err)
+	}
+	op.created = true
+	return nil
+}
+
+func (op *createDirOperation) Rollback() error {
+	if !op.created {
+		return nil
+	}
+	if err := os.Remove(op.path); err != nil && !os.IsNotExist(err) {
+		return NewConfigError("remove directory", op.path, err)
+	}
+	return nil
+}
+
+func (op *createDirOperation) Description() string {
+	return fmt.Sprintf("create directory %s", op.path)
+}
+
+func (op *createDirOperation) GetFile() string {
+	return filepath.Base(op.path)
+}
+
+// removeEmptyDirOperation removes a directory only while it is empty, so it
+// can never delete data; rollback recreates it
+type removeEmptyDirOperation struct {
+	path    string
+	removed bool
+}
+
+func (op *removeEmptyDirOperation) Execute() error {
+	if err := os.Remove(op.path); err != nil {
+		return NewConfigError("remove empty directory", op.path, err)
+	}
+	op.removed = true
+	return nil
+}
+
+func (op *removeEmptyDirOperation) Rollback() error {
+	if !op.removed {
+		return nil
+	}
+	if err := os.MkdirAll(op.path, 0755); err != nil {
+		return NewConfigError("restore directory", op.path, err)
+	}
+	return nil
+}
+
+func (op *removeEmptyDirOperation) Description() string {
+	return fmt.Sprintf("remove empty directory %s", op.path)
+}
+
+func (op *removeEmptyDirOperation) GetFile() string {
+	return filepath.Base(op.path)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -25,19 +26,46 @@ func (e *ConfigError) Unwrap() error {
 	return e.Err
 }
 
+// Severity says how much a validation issue matters. Only errors stop the
+// configuration from being saved or linked.
+type Severity int
+
+const (
+	SeverityError   Severity = iota // blocks saving and linking
+	SeverityWarning                 // likely a mistake, but nothing is blocked
+	SeverityInfo                    // worth knowing, harmless as it is
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "error"
+	}
+}
+
 // ValidationError represents configuration validation failures
 type ValidationError struct {
-	Field   string
-	Value   string
-	Message string
-	File    string
+	Field    string
+	Value    string
+	Message  string
+	File     string
+	Severity Severity
 }
 
 func (e *ValidationError) Error() string {
 	if e.File != "" {
-		return fmt.Sprintf("validation error in %s: %s (%s=%s)", e.File, e.Message, e.Field, e.Value)
+		return fmt.Sprintf("validation %s in %s: %s (%s=%s)", e.Severity, e.File, e.Message, e.Field, e.Value)
 	}
-	return fmt.Sprintf("validation error: %s (%s=%s)", e.Message, e.Field, e.Value)
+	return fmt.Sprintf("validation %s: %s (%s=%s)", e.Severity, e.Message, e.Field, e.Value)
+}
+
+// IsBlocking reports whether the issue stops the configuration from being saved or linked
+func (e *ValidationError) IsBlocking() bool {
+	return e.Severity == SeverityError
 }
 
 // OperationResult represents the result of a file operation
@@ -111,9 +139,18 @@ func NewValidationError(field, value, message, file string) *ValidationError {
 	}
 }
 
+// NewValidationIssue is a validation issue of the given severity;
+// NewValidationError always makes a blocking one
+func NewValidationIssue(severity Severity, field, value, message, file string) *ValidationError {
+	err := NewValidationError(field, value, message, file)
+	err.Severity = severity
+	return err
+}
+
 // Error classification helpers
 func IsRecoverable(err error) bool {
-	if configErr, ok := err.(*ConfigError); ok {
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
 		return configErr.Recoverable
 	}
 	return false
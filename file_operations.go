@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +20,7 @@ const (
 	ConflictViewDiff
 	ConflictMerge
 	ConflictCancel
+	ConflictMergeDirectory
 )
 
 // ConflictInfo provides details about a file conflict
@@ -28,6 +31,7 @@ type ConflictInfo struct {
 	TargetExists bool
 	IsSymlink   bool
 	LinkTarget  string
+	ResolvedPath string // final destination of a symlink target after following every link
 	BackupPath  string // Add backup path field
 }
 
@@ -36,13 +40,11 @@ type ConflictInfo struct {
 // Use Gum to select files/directories to add with better error handling
 func selectFileToAdd(config *Config) (string, error) {
 	// Check if gum is available
-	if _, err := exec.LookPath("gum"); err != nil {
+	if _, err := runner.LookPath("gum"); err != nil {
 		// Fallback to text-based selection
 		return selectFileToAddText(config)
 	}
 	
-	homeDir, _ := os.UserHomeDir()
-	
 	// Find all potential config files and directories
 	candidates := []string{}
 	
@@ -53,16 +55,8 @@ func selectFileToAdd(config *Config) (string, error) {
 	}
 	
 	// Add common config directories
-	configDirs := []string{
-		".config/nvim", ".config/alacritty", ".config/kitty", ".config/tmux",
-		".config/fish", ".config/starship", ".config/rofi", ".config/i3",
-		".config/polybar", ".config/dunst", ".config/picom", ".config/sway",
-		".config/waybar", ".config/hypr", ".config/wezterm", ".config/helix",
-		".ssh", ".gnupg", ".local/bin",
-	}
-	
-	for _, dir := range configDirs {
-		fullPath := filepath.Join(homeDir, dir)
+	for _, dir := range commonConfigDirs() {
+		fullPath := resolveHomePath(dir)
 		if info, err := os.Stat(fullPath); err == nil && info.IsDir() {
 			// Check if not already managed
 			if !isFileAlreadyManaged(config, fullPath) {
@@ -80,12 +74,8 @@ func selectFileToAdd(config *Config) (string, error) {
 	}
 	
 	// Use gum choose to select
-	cmd := exec.Command("gum", "choose", "--header", "Select config file or directory to add:")
-	cmd.Args = append(cmd.Args, candidates...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	
-	output, err := cmd.Output()
+	args := append([]string{"choose", "--header", "Select config file or directory to add:"}, candidates...)
+	output, err := runner.Output("gum", args...)
 	if err != nil {
 		return "", NewConfigError("file selection", "", fmt.Errorf("selection cancelled or failed: %v", err))
 	}
@@ -121,21 +111,26 @@ func isFileAlreadyManaged(config *Config, fullPath string) bool {
 }
 
 // Enhanced conflict detection and resolution
-func detectConflict(file *ConfigFile, sourcePath string) (*ConflictInfo, error) {
+func detectConflict(config *Config, file *ConfigFile, sourcePath string) (*ConflictInfo, error) {
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return nil, err
+	}
 	conflict := &ConflictInfo{
 		File:       file,
-		TargetPath: file.Target,
+		TargetPath: target,
 		SourcePath: sourcePath,
 	}
 	
 	// Check if target exists
-	info, err := os.Lstat(file.Target)
+	info, err := os.Lstat(target)
 	if os.IsNotExist(err) {
 		// No conflict - target doesn't exist
+		logger.Debugf("%s: no conflict (target %s does not exist)", file.Name, target)
 		return nil, nil
 	}
 	if err != nil {
-		return nil, NewConfigError("stat target", file.Target, err)
+		return nil, NewConfigError("stat target", target, err)
 	}
 	
 	conflict.TargetExists = true
@@ -143,136 +138,376 @@ func detectConflict(file *ConfigFile, sourcePath string) (*ConflictInfo, error)
 	// Check if target is a symlink
 	if info.Mode()&fs.ModeSymlink != 0 {
 		conflict.IsSymlink = true
-		linkTarget, err := os.Readlink(file.Target)
+		linkTarget, err := os.Readlink(target)
 		if err != nil {
-			return nil, NewConfigError("read symlink", file.Target, err)
+			return nil, NewConfigError("read symlink", target, err)
 		}
 		conflict.LinkTarget = linkTarget
+		if finalPath, err := filepath.EvalSymlinks(target); err == nil {
+			conflict.ResolvedPath = finalPath
+		}
 		
-		// Check if it points to our source
-		if linkTarget == sourcePath {
+		// Check if it points to our source (relative links are resolved first)
+		resolved := resolveLinkTarget(target, linkTarget)
+		if sameLinkDestination(resolved, sourcePath) {
 			// Already linked correctly - no conflict
+			logger.Debugf("%s: no conflict (already linked: %s points to %s)", file.Name, target, resolved)
 			return nil, nil
 		}
+		logger.Debugf("%s: conflict (symlink %s points to %s, expected %s)", file.Name, target, resolved, sourcePath)
+		if conflict.ResolvedPath != "" && conflict.ResolvedPath != resolved {
+			logger.Debugf("%s: symlink chain from %s ends at %s", file.Name, target, conflict.ResolvedPath)
+		}
+	} else {
+		logger.Debugf("%s: conflict (target %s is a regular %s, not a symlink)", file.Name, target, fileKind(info))
 	}
 	
 	// There is a conflict
 	return conflict, nil
 }
 
-// resolveConflictInteractive presents options to user for conflict resolution
-func resolveConflictInteractive(conflict *ConflictInfo) (ConflictResolution, error) {
-	// Check if gum is available
-	if _, err := exec.LookPath("gum"); err != nil {
-		return resolveConflictText(conflict)
-	}
-	
-	// Build gum options
-	options := []string{
-		"Backup existing and replace",
-		"View diff",
-		"Skip this file", 
-		"Cancel operation",
-	}
-	
-	// Add merge option for text files
-	if isTextFile(conflict.TargetPath) {
-		options = append(options[:2], append([]string{"Merge interactively"}, options[2:]...)...)
-	}
-	
-	// Show conflict information
-	fmt.Printf("🚨 Conflict detected for %s\n", conflict.File.Name)
+// conflictOption is one answer offered when resolving a conflict
+type conflictOption struct {
+	label      string
+	resolution ConflictResolution
+	applyToAll bool // in a batch, use this answer for every remaining conflict too
+}
+
+// conflictOptions lists the answers for a conflict. Batches (link-all) can
+// apply an answer to every remaining conflict; merging is only offered for
+// single text files, and adopting a directory's contents only when both the
+// target and the source are directories.
+func conflictOptions(conflict *ConflictInfo, batch bool) []conflictOption {
+	options := []conflictOption{{label: "Backup existing and replace", resolution: ConflictBackupAndReplace}}
+	if batch {
+		options = append(options, conflictOption{label: "Backup and replace all remaining", resolution: ConflictBackupAndReplace, applyToAll: true})
+	}
+	options = append(options, conflictOption{label: "View diff", resolution: ConflictViewDiff})
+	if !batch && isTextFile(conflict.TargetPath) {
+		options = append(options, conflictOption{label: "Merge interactively", resolution: ConflictMerge})
+	}
+	if canMergeDirectory(conflict) {
+		options = append(options, conflictOption{label: "Merge existing contents into source", resolution: ConflictMergeDirectory})
+	}
+	options = append(options, conflictOption{label: "Skip this file", resolution: ConflictSkip})
+	if batch {
+		options = append(options, conflictOption{label: "Skip all remaining conflicts", resolution: ConflictSkip, applyToAll: true})
+	}
+	return append(options, conflictOption{label: "Cancel operation", resolution: ConflictCancel})
+}
+
+// printConflict describes what is in the way of a link
+func printConflict(conflict *ConflictInfo) {
+	fmt.Printf("%s Conflict detected for %s\n", glyphWarning, conflict.File.Name)
 	fmt.Printf("Target: %s\n", conflict.TargetPath)
 	if conflict.IsSymlink {
 		fmt.Printf("Current symlink points to: %s\n", conflict.LinkTarget)
+		if conflict.ResolvedPath != "" && conflict.ResolvedPath != resolveLinkTarget(conflict.TargetPath, conflict.LinkTarget) {
+			fmt.Printf("Which resolves to: %s\n", conflict.ResolvedPath)
+		}
 		fmt.Printf("Would point to: %s\n", conflict.SourcePath)
 	} else {
 		fmt.Printf("Target exists as regular file/directory\n")
 		fmt.Printf("Would be replaced with symlink to: %s\n", conflict.SourcePath)
 	}
-	fmt.Println()
+}
+
+// resolveConflictInteractive presents options to user for conflict resolution.
+// With batch set it also offers to apply the answer to all remaining conflicts,
+// reported by the returned bool.
+func resolveConflictInteractive(conflict *ConflictInfo, batch bool) (ConflictResolution, bool, error) {
+	// Check if gum is available
+	if _, err := runner.LookPath("gum"); err != nil {
+		return resolveConflictText(conflict, batch)
+	}
+	
+	options := conflictOptions(conflict, batch)
+	labels := make([]string, len(options))
+	for i, option := range options {
+		labels[i] = option.label
+	}
 	
-	cmd := exec.Command("gum", "choose", "--header", "How would you like to resolve this conflict?")
-	cmd.Args = append(cmd.Args, options...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
+	// Show conflict information
+	printConflict(conflict)
+	fmt.Println()
 	
-	output, err := cmd.Output()
+	args := append([]string{"choose", "--header", "How would you like to resolve this conflict?"}, labels...)
+	output, err := runner.Output("gum", args...)
 	if err != nil {
-		return ConflictCancel, NewConfigError("conflict resolution", conflict.File.Name, 
+		return ConflictCancel, false, NewConfigError("conflict resolution", conflict.File.Name, 
 			fmt.Errorf("selection cancelled: %v", err))
 	}
 	
 	choice := strings.TrimSpace(string(output))
-	switch {
-	case strings.Contains(choice, "Backup"):
-		return ConflictBackupAndReplace, nil
-	case strings.Contains(choice, "View diff"):
-		return ConflictViewDiff, nil
-	case strings.Contains(choice, "Merge"):
-		return ConflictMerge, nil
-	case strings.Contains(choice, "Skip"):
-		return ConflictSkip, nil
-	default:
-		return ConflictCancel, nil
+	for _, option := range options {
+		if option.label == choice {
+			return option.resolution, option.applyToAll, nil
+		}
 	}
+	return ConflictCancel, false, nil
 }
 
-// resolveConflictText provides text-based conflict resolution
-func resolveConflictText(conflict *ConflictInfo) (ConflictResolution, error) {
-	fmt.Printf("\n🚨 Conflict detected for %s\n", conflict.File.Name)
-	fmt.Printf("Target: %s\n", conflict.TargetPath)
-	if conflict.IsSymlink {
-		fmt.Printf("Current symlink points to: %s\n", conflict.LinkTarget)
-		fmt.Printf("Would point to: %s\n", conflict.SourcePath)
-	} else {
-		fmt.Printf("Target exists as regular file/directory\n")
-		fmt.Printf("Would be replaced with symlink to: %s\n", conflict.SourcePath)
+// resolveConflictText resolves a conflict without gum: with the built-in
+// picker on a terminal, or a numbered prompt when input is piped
+func resolveConflictText(conflict *ConflictInfo, batch bool) (ConflictResolution, bool, error) {
+	fmt.Println()
+	printConflict(conflict)
+	
+	options := conflictOptions(conflict, batch)
+	if stdinIsTerminal() {
+		labels := make([]string, len(options))
+		for i, option := range options {
+			labels[i] = option.label
+		}
+		fmt.Println()
+		index, err := chooseOption("How would you like to resolve this conflict?", labels, 0)
+		if err != nil {
+			return ConflictCancel, false, NewConfigError("conflict resolution", conflict.File.Name, err)
+		}
+		return options[index].resolution, options[index].applyToAll, nil
 	}
 	
 	fmt.Println("\nOptions:")
-	fmt.Println("1. Backup existing and replace")
-	fmt.Println("2. View diff")
-	if isTextFile(conflict.TargetPath) {
-		fmt.Println("3. Merge interactively")
-		fmt.Println("4. Skip this file")
-		fmt.Println("5. Cancel operation")
-	} else {
-		fmt.Println("3. Skip this file")
-		fmt.Println("4. Cancel operation")
+	for i, option := range options {
+		fmt.Printf("%d. %s\n", i+1, option.label)
 	}
 	
 	fmt.Print("Enter choice: ")
 	var choice int
 	if _, err := fmt.Scanf("%d", &choice); err != nil {
-		return ConflictCancel, NewConfigError("read choice", "", err)
-	}
-	
-	if isTextFile(conflict.TargetPath) {
-		switch choice {
-		case 1:
-			return ConflictBackupAndReplace, nil
-		case 2:
-			return ConflictViewDiff, nil
-		case 3:
-			return ConflictMerge, nil
-		case 4:
-			return ConflictSkip, nil
-		default:
-			return ConflictCancel, nil
+		return ConflictCancel, false, NewConfigError("read choice", "", err)
+	}
+	
+	if choice < 1 || choice > len(options) {
+		return ConflictCancel, false, nil
+	}
+	return options[choice-1].resolution, options[choice-1].applyToAll, nil
+}
+
+// conflictBatch carries conflict answers across the files of one link-all
+// run, so an "all remaining" answer isn't asked for again
+type conflictBatch struct {
+	sticky bool
+	choice ConflictResolution
+}
+
+// resolve returns how to handle conflict, asking unless an earlier answer
+// applies to all remaining conflicts. Viewing the diff asks again afterwards.
+func (b *conflictBatch) resolve(conflict *ConflictInfo) (ConflictResolution, error) {
+	if b.sticky {
+		logger.Debugf("%s: using earlier answer for all remaining conflicts", conflict.File.Name)
+		return b.choice, nil
+	}
+	
+	for {
+		resolution, applyToAll, err := resolveConflictInteractive(conflict, true)
+		if err != nil {
+			return ConflictCancel, err
 		}
-	} else {
-		switch choice {
-		case 1:
-			return ConflictBackupAndReplace, nil
-		case 2:
-			return ConflictViewDiff, nil
-		case 3:
-			return ConflictSkip, nil
-		default:
-			return ConflictCancel, nil
+		if resolution == ConflictViewDiff {
+			if err := viewDiff(conflict.TargetPath, conflict.SourcePath); err != nil {
+				warnf("Warning: %v\n", err)
+			}
+			continue
+		}
+		
+		if applyToAll {
+			b.sticky = true
+			b.choice = resolution
 		}
+		return resolution, nil
+	}
+}
+
+// batchConflict reports what is in the way of linking file as part of a
+// batch. Only whole-file symlinks over an existing source are asked about:
+// a missing source is seeded from the target, and tree and copy targets are
+// reconciled entry by entry.
+func batchConflict(config *Config, file *ConfigFile) (*ConflictInfo, error) {
+	strategy := file.effectiveLinkStrategy()
+	if strategy != "" && strategy != LinkStrategySymlink {
+		return nil, nil
+	}
+	
+	sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	if _, err := os.Stat(sourcePath); err != nil {
+		return nil, nil
 	}
+	return detectConflict(config, file, sourcePath)
+}
+
+// resolveBatchConflicts asks how to handle every conflicting target before a
+// link-all run starts and returns the answers that change how a file is
+// linked, by index: ConflictSkip leaves the file alone and
+// ConflictMergeDirectory adopts its target's contents first. Files without
+// an answer are linked as usual. Cancelling returns an error so nothing gets
+// linked. When linking in parallel, or with nobody to ask (serve), nothing is
+// asked: every conflict is skipped, to be resolved afterwards from the
+// conflicts view.
+func resolveBatchConflicts(config *Config) (map[int]ConflictResolution, error) {
+	answers := make(map[int]ConflictResolution)
+	var batch conflictBatch
+	if !config.canPrompt() {
+		batch = conflictBatch{sticky: true, choice: ConflictSkip}
+	}
+	
+	for i := range config.Files {
+		file := &config.Files[i]
+		if file.Disabled {
+			continue
+		}
+		conflict, err := batchConflict(config, file)
+		if err != nil {
+			return nil, err
+		}
+		if conflict == nil {
+			continue
+		}
+		
+		resolution, err := batch.resolve(conflict)
+		if err != nil {
+			return nil, err
+		}
+		switch resolution {
+		case ConflictSkip, ConflictMergeDirectory:
+			answers[i] = resolution
+		case ConflictCancel:
+			return nil, NewConfigError("link all configs", file.Name, fmt.Errorf("cancelled at conflict"))
+		}
+	}
+	
+	return answers, nil
+}
+
+// replaceConflictedTargets links files over whatever is in their way, backing
+// each target up first. The directories in merge have their contents adopted
+// into the source before they are linked; one summary line is returned for
+// each. Everything runs as one transaction, so a failure leaves every target
+// as it was.
+func replaceConflictedTargets(config *Config, files, merge []ConfigFile) ([]string, error) {
+	if err := validateForApply(config); err != nil {
+		return nil, err
+	}
+	
+	tx := NewTransaction(config)
+	var summaries []string
+	all := append(append([]ConfigFile{}, files...), merge...)
+	for i, conflicted := range all {
+		file, err := config.GetConfigFileByTarget(conflicted.Target)
+		if err != nil {
+			return nil, err
+		}
+		
+		var fileTx *Transaction
+		if i >= len(files) {
+			var plan *directoryMerge
+			fileTx, plan, err = createMergeDirectoryOperation(config, file)
+			if err == nil {
+				summaries = append(summaries, file.Name+": "+plan.summary())
+			}
+		} else {
+			fileTx, err = createAtomicLinkOperation(config, file)
+		}
+		if err != nil {
+			return nil, NewConfigError("resolve conflicts", file.Name, err)
+		}
+		for _, op := range fileTx.GetOperations() {
+			tx.AddOperation(op)
+		}
+	}
+	
+	if err := tx.Execute(); err != nil {
+		return nil, err
+	}
+	for _, file := range all {
+		config.markLinked(file.Target)
+	}
+	return summaries, nil
+}
+
+// linkUnlinked links every file whose target doesn't exist yet, in apply
+// order and one transaction, so a failure leaves nothing half-linked. Files
+// already linked aren't touched (or backed up again), and conflicted or
+// drifted files, whose targets would be replaced, are left for the conflict
+// and sync actions. It returns how many files were linked and how many were
+// left alone.
+func linkUnlinked(config *Config) (int, int, error) {
+	return linkUnlinkedWhere(config, func(*ConfigFile) bool { return true })
+}
+
+// linkUnlinkedWhere is linkUnlinked for only the files include accepts
+func linkUnlinkedWhere(config *Config, include func(file *ConfigFile) bool) (int, int, error) {
+	if err := validateForApply(config); err != nil {
+		return 0, 0, err
+	}
+	updateFileStatuses(config)
+	
+	tx := NewTransaction(config)
+	var linked []string
+	left := 0
+	for _, i := range config.applyOrder() {
+		file := &config.Files[i]
+		if file.IsLinked || file.Disabled || !include(file) {
+			continue
+		}
+		if file.HasConflict || file.Drifted {
+			left++
+			continue
+		}
+		
+		fileTx, err := createAtomicLinkOperation(config, file)
+		if err != nil {
+			return 0, 0, NewConfigError("link unlinked", file.Name, err)
+		}
+		for _, op := range fileTx.GetOperations() {
+			tx.AddOperation(op)
+		}
+		linked = append(linked, file.Target)
+	}
+	
+	if len(linked) == 0 {
+		return 0, left, nil
+	}
+	if err := tx.Execute(); err != nil {
+		return 0, 0, err
+	}
+	for _, target := range linked {
+		config.markLinked(target)
+	}
+	return len(linked), left, nil
+}
+
+// filterFiles returns the files whose name or target matches the glob
+// pattern. Targets are also tried relative to the home directory, so
+// ".config/nvim*" matches ~/.config/nvim.
+func filterFiles(config *Config, pattern string) []ConfigFile {
+	homeDir, _ := mustHome()
+	
+	var matched []ConfigFile
+	for _, file := range config.Files {
+		candidates := []string{file.Name, file.Target}
+		if relPath, err := filepath.Rel(homeDir, file.Target); err == nil && isWithinDir(file.Target, homeDir) {
+			candidates = append(candidates, relPath)
+		}
+		
+		for _, candidate := range candidates {
+			if ok, _ := filepath.Match(pattern, candidate); ok {
+				matched = append(matched, file)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// diffPagerCommand shows the differences between a target and its source in a
+// pager, for running from the TUI
+func diffPagerCommand(target, source string) *exec.Cmd {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less -R"
+	}
+	return runner.Command("sh", "-c", `diff -ru -- "$1" "$2" | `+pager, "sh", target, source)
 }
 
 // viewDiff shows differences between files
@@ -285,18 +520,16 @@ func viewDiff(file1, file2 string) error {
 	}
 	
 	for _, tool := range diffTools {
-		if _, err := exec.LookPath(tool[0]); err == nil {
-			cmd := exec.Command(tool[0], tool[1:]...)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			cmd.Stdin = os.Stdin
-			
+		if _, err := runner.LookPath(tool[0]); err == nil {
 			// Run and don't treat exit code 1 as error (diff found differences)
-			err := cmd.Run()
+			err := runner.Run(tool[0], tool[1:]...)
 			if err != nil {
 				if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
 					return nil // diff found differences, this is normal
 				}
+				if errors.Is(err, errCommandTimeout) {
+					return NewConfigError("view diff", file1, err)
+				}
 				continue // Try next tool
 			}
 			return nil
@@ -314,8 +547,12 @@ func linkConfigFile(config *Config, file *ConfigFile) (string, error) {
 
 // linkConfigFileAtomic uses atomic operations for safe linking
 func linkConfigFileAtomic(config *Config, file *ConfigFile) (string, error) {
+	if file.Disabled {
+		return "", NewConfigError("link", file.Name, fmt.Errorf("file is disabled; enable it first"))
+	}
+	
 	// Validate configuration before proceeding
-	if errors := config.Validate(); len(errors) > 0 {
+	if errors := config.BlockingErrors(); len(errors) > 0 {
 		return "", NewConfigError("config validation", file.Name, 
 			fmt.Errorf("configuration has validation errors"))
 	}
@@ -325,33 +562,82 @@ func linkConfigFileAtomic(config *Config, file *ConfigFile) (string, error) {
 		return "", err
 	}
 	
-	return fmt.Sprintf("✅ Successfully linked %s", file.Name), nil
+	return fmt.Sprintf("%s Successfully linked %s", glyphSuccess, file.Name), nil
 }
 
-// Apply all configuration files using atomic operations
-func applyAllConfigs(config *Config) ([]string, error) {
-	// Validate configuration first
-	if errors := config.Validate(); len(errors) > 0 {
+// validateForApply checks the configuration before linking everything
+func validateForApply(config *Config) error {
+	if errors := config.BlockingErrors(); len(errors) > 0 {
 		var messages []string
 		for _, err := range errors {
 			messages = append(messages, err.Error())
 		}
-		return nil, NewConfigError("config validation", "", 
+		return NewConfigError("config validation", "", 
 			fmt.Errorf("configuration validation failed: %s", strings.Join(messages, "; ")))
 	}
+	return nil
+}
+
+// Apply all configuration files using atomic operations. Each file is linked
+// on its own, so the result of every file is returned even when some failed;
+// the error is only set when nothing was attempted (invalid config, cancelled).
+func applyAllConfigs(config *Config) ([]OperationResult, error) {
+	// Validate configuration first
+	if err := validateForApply(config); err != nil {
+		return nil, err
+	}
 	
 	// Use atomic operations for all configs
-	if err := atomicLinkAllConfigs(config); err != nil {
+	results, err := atomicLinkAllConfigs(config)
+	if results == nil && err != nil {
 		return nil, err
 	}
 	
-	// Generate success messages
-	var messages []string
-	for _, file := range config.Files {
-		messages = append(messages, fmt.Sprintf("✅ %s", file.Name))
+	return results, nil
+}
+
+// summarizeLinkResults describes a link-all run for the status bar, e.g.
+// "8 linked, 2 failed: nvim, tmux", and the message type to show it with
+func summarizeLinkResults(results []OperationResult) (string, string) {
+	linked, skipped := 0, 0
+	var failed []OperationResult
+	for _, result := range results {
+		switch {
+		case !result.Success:
+			failed = append(failed, result)
+		case result.Skipped:
+			skipped++
+		default:
+			linked++
+		}
 	}
 	
-	return messages, nil
+	if len(failed) == 0 {
+		if skipped > 0 {
+			return fmt.Sprintf("%s Linked %d configuration files, skipped %d with conflicts", glyphSuccess, linked, skipped), "success"
+		}
+		return fmt.Sprintf("%s Successfully linked %d configuration files", glyphSuccess, linked), "success"
+	}
+	
+	summary := fmt.Sprintf("%d linked, %d failed", linked, len(failed))
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	if len(failed) == 1 {
+		summary += fmt.Sprintf(": %s (%v)", failed[0].File, failed[0].Error)
+	} else {
+		names := make([]string, len(failed))
+		for i, result := range failed {
+			names[i] = result.File
+			logger.Debugf("%s: link failed: %v", result.File, result.Error)
+		}
+		summary += ": " + strings.Join(names, ", ")
+	}
+	
+	if linked == 0 && skipped == 0 {
+		return glyphError + " " + summary, "error"
+	}
+	return glyphWarning + " " + summary, "warning"
 }
 
 // Enhanced file type detection
@@ -407,9 +693,13 @@ func createBackupInDir(config *Config, backupDir string) int {
 	
 	backedUp := 0
 	for _, file := range config.Files {
-		if _, err := os.Stat(file.Target); err == nil {
+		target, err := resolveTarget(config, &file)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(target); err == nil {
 			// Determine backup filename
-			backupName := filepath.Base(file.Target)
+			backupName := filepath.Base(target)
 			if strings.HasPrefix(backupName, ".") {
 				backupName = strings.TrimPrefix(backupName, ".")
 			}
@@ -417,13 +707,13 @@ func createBackupInDir(config *Config, backupDir string) int {
 			backupPath := filepath.Join(backupDir, backupName)
 			
 			// Handle directories
-			if info, err := os.Stat(file.Target); err == nil && info.IsDir() {
-				if err := copyDirectory(file.Target, backupPath); err == nil {
+			if info, err := os.Stat(target); err == nil && info.IsDir() {
+				if err := copyDirectory(target, backupPath); err == nil {
 					backedUp++
 				}
 			} else {
 				// Handle files
-				if data, err := os.ReadFile(file.Target); err == nil {
+				if data, err := os.ReadFile(target); err == nil {
 					if err := os.WriteFile(backupPath, data, 0644); err == nil {
 						backedUp++
 					}
@@ -445,9 +735,8 @@ func openInEditor(config *Config, file *ConfigFile) error {
 	}
 	
 	// Validate editor is available
-	if _, err := exec.LookPath(config.Editor); err != nil {
-		return NewConfigError("open editor", config.Editor, 
-			fmt.Errorf("editor not found in PATH: %v", err))
+	if err := config.checkEditor(); err != nil {
+		return err
 	}
 	
 	// Check if it's a directory or file
@@ -456,7 +745,7 @@ func openInEditor(config *Config, file *ConfigFile) error {
 		return openDirectoryInEditor(config, sourcePath)
 	} else {
 		// It's a single file - open it directly
-		return openFileInEditor(config.Editor, sourcePath)
+		return openFileInEditor(config, sourcePath)
 	}
 }
 
@@ -502,27 +791,23 @@ func openDirectoryInEditor(config *Config, dirPath string) error {
 	
 	// Open the selected file
 	fullPath := filepath.Join(dirPath, selectedFile)
-	return openFileInEditor(config.Editor, fullPath)
+	return openFileInEditor(config, fullPath)
 }
 
 // Enhanced file selection with better error handling
 func selectFileToEdit(files []string) (string, error) {
 	// Check if gum is available
-	if _, err := exec.LookPath("gum"); err != nil {
+	if _, err := runner.LookPath("gum"); err != nil {
 		// Fallback to text-based selection
 		return selectFileToEditText(files)
 	}
 	
 	// Add cancel option to the files list
-	options := append([]string{"🚫 Cancel (Esc)"}, files...)
+	options := append([]string{decoration("🚫") + "Cancel (Esc)"}, files...)
 	
 	// Use gum choose to select
-	cmd := exec.Command("gum", "choose", "--header", "Select file to edit (Esc to cancel):")
-	cmd.Args = append(cmd.Args, options...)
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	
-	output, err := cmd.Output()
+	args := append([]string{"choose", "--header", "Select file to edit (Esc to cancel):"}, options...)
+	output, err := runner.Output("gum", args...)
 	if err != nil {
 		return "", NewConfigError("file selection", "", 
 			fmt.Errorf("file selection cancelled or failed: %v", err))
@@ -537,9 +822,19 @@ func selectFileToEdit(files []string) (string, error) {
 	return selected, nil
 }
 
-// Text-based file selection fallback with better error handling
+// File selection without gum: the built-in picker on a terminal, or a
+// numbered prompt when input is piped
 func selectFileToEditText(files []string) (string, error) {
-	fmt.Println("\n📝 Select file to edit:")
+	if stdinIsTerminal() {
+		index, err := chooseOption("Select file to edit (Esc to cancel):", files, 0)
+		if err != nil {
+			return "", NewConfigError("file selection", "", 
+				fmt.Errorf("file selection cancelled"))
+		}
+		return files[index], nil
+	}
+	
+	fmt.Println("\n" + decoration("📝") + "Select file to edit:")
 	
 	// Show cancel option first
 	fmt.Println("0. Cancel")
@@ -569,63 +864,138 @@ func selectFileToEditText(files []string) (string, error) {
 }
 
 // Enhanced file editor opening with better error handling
-func openFileInEditor(editor, filePath string) error {
+func openFileInEditor(config *Config, filePath string) error {
 	// Validate file exists and is readable
 	if _, err := os.Stat(filePath); err != nil {
 		return NewConfigError("open file", filePath, err)
 	}
 	
-	// Prepare the command
-	var cmd *exec.Cmd
+	// Terminal and GUI editors alike get the terminal and are waited for
+	args := config.editorInvocation(filePath)
+	if err := runner.Session(args[0], args[1:]...); err != nil {
+		if status, exited := editorExitStatus(err); exited {
+			logger.Debugf("%s %s", args[0], status)
+			return nil
+		}
+		return NewConfigError("run editor", args[0], err)
+	}
+	
+	return nil
+}
+
+// editorExitStatus tells an editor that started and then exited unsuccessfully
+// apart from one that couldn't be started at all (not found, not executable).
+// Many editors exit non-zero when quitting without saving, so only the latter
+// is an error; for the former it describes how the editor exited.
+func editorExitStatus(err error) (string, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return "", false
+	}
+	if code := exitErr.ExitCode(); code >= 0 {
+		return fmt.Sprintf("exited with status %d", code), true
+	}
+	// ExitCode is -1 when the process was killed by a signal
+	return "was interrupted", true
+}
+
+// editorFilePlaceholder in editor_args is replaced with the path being edited
+const editorFilePlaceholder = "{file}"
+
+// editorOverride is set from --editor and takes precedence over $VISUAL,
+// $EDITOR and the configured editor without being persisted
+var editorOverride string
+
+// editorCommand returns the editor to open files with, the arguments given
+// along with it and where it came from: --editor, then $VISUAL, then $EDITOR,
+// then the configured editor. All but the last may carry arguments, as in
+// EDITOR="code --wait".
+func (c *Config) editorCommand() (editor string, args []string, origin string) {
+	candidates := []struct{ value, origin string }{
+		{editorOverride, "--editor"},
+		{os.Getenv("VISUAL"), "$VISUAL"},
+		{os.Getenv("EDITOR"), "$EDITOR"},
+	}
+	for _, candidate := range candidates {
+		if fields := strings.Fields(candidate.value); len(fields) > 0 {
+			return fields[0], fields[1:], candidate.origin
+		}
+	}
+	return c.Editor, nil, "config.json"
+}
+
+// checkEditor fails with a clear error when the editor to use isn't installed
+func (c *Config) checkEditor() error {
+	editor, _, origin := c.editorCommand()
+	return checkEditorCommand(editor, origin)
+}
+
+// checkEditorCommand fails when editor, chosen by origin, isn't in PATH
+func checkEditorCommand(editor, origin string) error {
+	if editor == "" {
+		return NewConfigError("open editor", "", fmt.Errorf("no editor configured; set editor in config.json, $EDITOR or --editor"))
+	}
+	if _, err := runner.LookPath(editor); err != nil {
+		return NewConfigError("open editor", editor, 
+			fmt.Errorf("editor from %s not found in PATH: %v", origin, err))
+	}
+	return nil
+}
+
+// editorInvocation returns the command that opens filePath in the editor to
+// use. Arguments that came with the editor are used as given; otherwise the
+// configured editor_args apply, as for the configured editor.
+func (c *Config) editorInvocation(filePath string) []string {
+	editor, args, _ := c.editorCommand()
+	if len(args) > 0 {
+		command := append([]string{editor}, args...)
+		return append(command, filePath)
+	}
+	return editorCommandLine(editor, c.EditorArgs, filePath)
+}
+
+// editorCommandLine returns the command that opens filePath in editor. Args
+// configured for the editor (by name or by the base name of its path) are
+// used when present, with the path appended unless one of them contains
+// {file}. Otherwise GUI editors get their flag for blocking until the file
+// is closed.
+func editorCommandLine(editor string, editorArgs map[string][]string, filePath string) []string {
+	args, ok := editorArgs[editor]
+	if !ok {
+		args, ok = editorArgs[filepath.Base(editor)]
+	}
+	if ok {
+		command := []string{editor}
+		placed := false
+		for _, arg := range args {
+			if strings.Contains(arg, editorFilePlaceholder) {
+				arg = strings.ReplaceAll(arg, editorFilePlaceholder, filePath)
+				placed = true
+			}
+			command = append(command, arg)
+		}
+		if !placed {
+			command = append(command, filePath)
+		}
+		return command
+	}
 	
-	// Handle different editors that might need special arguments
 	switch editor {
 	case "code", "vscode":
-		cmd = exec.Command("code", "--wait", filePath)
+		return []string{"code", "--wait", filePath}
 	case "subl", "sublime":
-		cmd = exec.Command("subl", "--wait", filePath)
+		return []string{"subl", "--wait", filePath}
 	case "atom":
-		cmd = exec.Command("atom", "--wait", filePath)
+		return []string{"atom", "--wait", filePath}
 	default:
 		// For vim, nvim, emacs, nano, etc.
-		cmd = exec.Command(editor, filePath)
-	}
-	
-	// For terminal editors, we need to properly handle the terminal state
-	switch editor {
-	case "vim", "nvim", "emacs", "nano", "micro", "helix":
-		// Set up the command to use the current terminal properly
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		
-		// Run the editor and wait for it to complete
-		if err := cmd.Run(); err != nil {
-			return NewConfigError("run editor", editor, err)
-		}
-		
-		return nil
-	default:
-		// For GUI editors, just run them
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		
-		if err := cmd.Run(); err != nil {
-			return NewConfigError("run editor", editor, err)
-		}
-		
-		return nil
+		return []string{editor, filePath}
 	}
 }
 
-// Text-based fallback for file selection with enhanced error handling
+// File selection without gum: the built-in picker on a terminal, or a
+// numbered prompt when input is piped
 func selectFileToAddText(config *Config) (string, error) {
-	homeDir, _ := os.UserHomeDir()
-	
-	fmt.Println("\n📁 Add Configuration File/Directory")
-	fmt.Println("Available options:")
-	
 	candidates := []string{}
 	
 	// Add common dotfiles that exist but aren't managed
@@ -635,16 +1005,8 @@ func selectFileToAddText(config *Config) (string, error) {
 	}
 	
 	// Add common config directories
-	configDirs := []string{
-		".config/nvim", ".config/alacritty", ".config/kitty", ".config/tmux",
-		".config/fish", ".config/starship", ".config/rofi", ".config/i3",
-		".config/polybar", ".config/dunst", ".config/picom", ".config/sway",
-		".config/waybar", ".config/hypr", ".config/wezterm", ".config/helix",
-		".ssh", ".gnupg", ".local/bin",
-	}
-	
-	for _, dir := range configDirs {
-		fullPath := filepath.Join(homeDir, dir)
+	for _, dir := range commonConfigDirs() {
+		fullPath := resolveHomePath(dir)
 		if info, err := os.Stat(fullPath); err == nil && info.IsDir() {
 			// Check if not already managed
 			if !isFileAlreadyManaged(config, fullPath) {
@@ -658,7 +1020,21 @@ func selectFileToAddText(config *Config) (string, error) {
 			fmt.Errorf("no unmanaged config files or directories found"))
 	}
 	
+	if stdinIsTerminal() {
+		options := append(append([]string{}, candidates...), "Browse for other file/directory...")
+		index, err := chooseOption("Select config file or directory to add:", options, 0)
+		if err != nil {
+			return "", NewConfigError("file selection", "", fmt.Errorf("selection cancelled or failed: %v", err))
+		}
+		if index == len(candidates) {
+			return browseForFileText()
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(candidates[index], " (file)"), " (directory)"), nil
+	}
+	
 	// Display options
+	fmt.Println("\n" + decoration("📁") + "Add Configuration File/Directory")
+	fmt.Println("Available options:")
 	for i, candidate := range candidates {
 		fmt.Printf("%d. %s\n", i+1, candidate)
 	}
@@ -699,20 +1075,32 @@ func selectFileToAddText(config *Config) (string, error) {
 	return selected, nil
 }
 
+// commonConfigDirs lists well-known config directories offered when adding
+// files. Application directories live under $XDG_CONFIG_HOME.
+func commonConfigDirs() []string {
+	apps := []string{
+		"nvim", "alacritty", "kitty", "tmux", "fish", "starship", "rofi", "i3",
+		"polybar", "dunst", "picom", "sway", "waybar", "hypr", "wezterm", "helix",
+	}
+	
+	xdgDir := xdgConfigDisplayPath()
+	dirs := make([]string, 0, len(apps)+3)
+	for _, app := range apps {
+		dirs = append(dirs, filepath.Join(xdgDir, app))
+	}
+	return append(dirs, ".ssh", ".gnupg", ".local/bin")
+}
+
 // Enhanced browse for file with better error handling
 func browseForFile() (string, error) {
 	// Check if gum is available for the selection, but use text input for path
-	if _, err := exec.LookPath("gum"); err != nil {
+	if _, err := runner.LookPath("gum"); err != nil {
 		// Fallback to text input
 		return browseForFileText()
 	}
 	
 	// Ask what type, but then use text input for the path
-	typeCmd := exec.Command("gum", "choose", "--header", "What do you want to add?", "File", "Directory", "Cancel")
-	typeCmd.Stdin = os.Stdin
-	typeCmd.Stderr = os.Stderr
-	
-	typeOutput, err := typeCmd.Output()
+	typeOutput, err := runner.Output("gum", "choose", "--header", "What do you want to add?", "File", "Directory", "Cancel")
 	if err != nil {
 		return "", NewConfigError("browse type selection", "", 
 			fmt.Errorf("selection cancelled: %v", err))
@@ -737,13 +1125,9 @@ func browseForFileGumInput(fileType string) (string, error) {
 		placeholder = ".config/nvim, ~/.ssh, ~/Documents, etc."
 	}
 	
-	inputCmd := exec.Command("gum", "input", 
+	output, err := runner.Output("gum", "input", 
 		"--placeholder", placeholder,
 		"--prompt", fmt.Sprintf("Enter %s path: ", strings.ToLower(fileType)))
-	inputCmd.Stdin = os.Stdin
-	inputCmd.Stderr = os.Stderr
-	
-	output, err := inputCmd.Output()
 	if err != nil {
 		return "", NewConfigError("path input", "", 
 			fmt.Errorf("input cancelled: %v", err))
@@ -758,9 +1142,94 @@ func browseForFileGumInput(fileType string) (string, error) {
 	return validateAndNormalizePath(path)
 }
 
-// Text-based file browsing fallback with enhanced error handling
+// promptForInput asks the user for a single line of text, pre-filled with value
+func promptForInput(prompt, placeholder, value string) (string, error) {
+	if _, err := runner.LookPath("gum"); err != nil {
+		if stdinIsTerminal() {
+			return readInput(prompt, placeholder, value)
+		}
+		fmt.Printf("%s", prompt)
+		if value != "" {
+			fmt.Printf("[%s] ", value)
+		}
+		var input string
+		if _, err := fmt.Scanln(&input); err != nil && input == "" {
+			if value != "" {
+				return value, nil
+			}
+			return "", NewConfigError("read input", "", err)
+		}
+		return strings.TrimSpace(input), nil
+	}
+	
+	output, err := runner.Output("gum", "input",
+		"--placeholder", placeholder,
+		"--prompt", prompt,
+		"--value", value)
+	if err != nil {
+		return "", NewConfigError("input", "", 
+			fmt.Errorf("input cancelled: %v", err))
+	}
+	
+	return strings.TrimSpace(string(output)), nil
+}
+
+// promptForChoice asks the user to pick one of options
+func promptForChoice(header string, options []string) (string, error) {
+	if _, err := runner.LookPath("gum"); err != nil {
+		if stdinIsTerminal() {
+			index, err := chooseOption(header, options, 0)
+			if err != nil {
+				return "", err
+			}
+			return options[index], nil
+		}
+		fmt.Printf("\n%s\n", header)
+		for i, option := range options {
+			fmt.Printf("%d. %s\n", i+1, option)
+		}
+		fmt.Print("Enter choice: ")
+		
+		var choice int
+		if _, err := fmt.Scanf("%d", &choice); err != nil {
+			return "", NewConfigError("read choice", "", err)
+		}
+		if choice < 1 || choice > len(options) {
+			return "", NewConfigError("choice", "", fmt.Errorf("invalid choice: %d", choice))
+		}
+		return options[choice-1], nil
+	}
+	
+	args := append([]string{"choose", "--header", header}, options...)
+	output, err := runner.Output("gum", args...)
+	if err != nil {
+		return "", NewConfigError("choice", "", fmt.Errorf("selection cancelled: %v", err))
+	}
+	
+	selected := strings.TrimSpace(string(output))
+	if selected == "" {
+		return "", NewConfigError("choice", "", fmt.Errorf("selection cancelled"))
+	}
+	
+	return selected, nil
+}
+
+// Path entry without gum: a text input on a terminal, or a plain prompt when
+// input is piped
 func browseForFileText() (string, error) {
-	fmt.Println("\n📁 Enter file or directory path")
+	if stdinIsTerminal() {
+		path, err := readInput("Enter path (relative to home, or use ~/): ", ".gitconfig, .config/nvim, ~/.ssh, etc.", "")
+		if err != nil {
+			return "", NewConfigError("path input", "", fmt.Errorf("input cancelled: %v", err))
+		}
+		if path == "" {
+			return "", NewConfigError("path input", "", 
+				fmt.Errorf("no path entered"))
+		}
+		return validateAndNormalizePath(path)
+	}
+	
+	fmt.Println("\n" + decoration("📁") + "Enter file or directory path")
 	fmt.Println("Examples of common config files:")
 	fmt.Println("  .gitconfig          (file)")
 	fmt.Println("  .zshrc              (file)")
@@ -785,8 +1254,11 @@ func browseForFileText() (string, error) {
 // validateAndNormalizePath validates and normalizes the entered path
 func validateAndNormalizePath(path string) (string, error) {
 	// Expand home directory if path starts with ~/
+	homeDir, err := mustHome()
+	if err != nil {
+		return "", err
+	}
 	if strings.HasPrefix(path, "~/") {
-		homeDir, _ := os.UserHomeDir()
 		path = filepath.Join(homeDir, path[2:]) // Remove ~/ and join with home
 	}
 	
@@ -797,12 +1269,11 @@ func validateAndNormalizePath(path string) (string, error) {
 		fullPath = path
 	} else {
 		// Relative path - relative to home directory
-		homeDir, _ := os.UserHomeDir()
 		fullPath = filepath.Join(homeDir, path)
 	}
 	
-	// Validate the path exists
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+	// Validate the path exists (a dangling symlink does; adding it offers to remove it)
+	if _, err := os.Lstat(fullPath); os.IsNotExist(err) {
 		// Ask for confirmation
 		confirmed, err := confirmNonExistentPath(path)
 		if err != nil {
@@ -815,7 +1286,6 @@ func validateAndNormalizePath(path string) (string, error) {
 	}
 	
 	// Convert back to relative path if it was within home directory
-	homeDir, _ := os.UserHomeDir()
 	if strings.HasPrefix(fullPath, homeDir) {
 		relativePath := strings.TrimPrefix(fullPath, homeDir)
 		if strings.HasPrefix(relativePath, "/") {
@@ -829,16 +1299,37 @@ func validateAndNormalizePath(path string) (string, error) {
 	return path, nil
 }
 
+// confirmAction asks a yes/no question, defaulting to no
+func confirmAction(prompt string) (bool, error) {
+	// Try gum first
+	if _, err := runner.LookPath("gum"); err == nil {
+		if err := runner.Run("gum", "confirm", prompt); err != nil {
+			return false, nil // User said no or cancelled
+		}
+		return true, nil
+	}
+	
+	if stdinIsTerminal() {
+		index, err := chooseOption(prompt, []string{"Yes", "No"}, 1)
+		return err == nil && index == 0, nil
+	}
+	
+	// Fallback to text input
+	fmt.Printf("%s (y/N): ", prompt)
+	var confirm string
+	if _, err := fmt.Scanln(&confirm); err != nil && confirm == "" {
+		return false, nil
+	}
+	
+	return strings.ToLower(confirm) == "y" || strings.ToLower(confirm) == "yes", nil
+}
+
 // confirmNonExistentPath asks user to confirm adding a non-existent path
 func confirmNonExistentPath(path string) (bool, error) {
 	// Try gum first
-	if _, err := exec.LookPath("gum"); err == nil {
-		confirmCmd := exec.Command("gum", "confirm", 
-			fmt.Sprintf("Path '%s' does not exist. Add anyway?", path))
-		confirmCmd.Stdin = os.Stdin
-		confirmCmd.Stderr = os.Stderr
-		
-		if err := confirmCmd.Run(); err != nil {
+	if _, err := runner.LookPath("gum"); err == nil {
+		if err := runner.Run("gum", "confirm", 
+			fmt.Sprintf("Path '%s' does not exist. Add anyway?", path)); err != nil {
 			return false, nil // User said no or cancelled
 		}
 		return true, nil
@@ -854,9 +1345,114 @@ func confirmNonExistentPath(path string) (bool, error) {
 	return strings.ToLower(confirm) == "y" || strings.ToLower(confirm) == "yes", nil
 }
 
-// Enhanced createConfigFileFromPath with better error handling
-func createConfigFileFromPath(selectedPath string, config *Config) (ConfigFile, error) {
-	homeDir, _ := os.UserHomeDir()
+// newCategoryOption is the picker entry for creating a category on the fly
+const newCategoryOption = "+ New category..."
+
+// selectCategory lets the user pick a category for fileName, highlighting the suggestion
+func selectCategory(config *Config, fileName, suggested string) (string, error) {
+	options := append([]string{}, config.Categories...)
+	options = append(options, newCategoryOption)
+	
+	var choice string
+	if _, err := runner.LookPath("gum"); err == nil {
+		args := append([]string{"choose",
+			"--header", fmt.Sprintf("Category for %s (suggested: %s):", fileName, suggested),
+			"--selected", suggested}, options...)
+		output, err := runner.Output("gum", args...)
+		if err != nil {
+			return "", NewConfigError("category selection", fileName, 
+				fmt.Errorf("selection cancelled: %v", err))
+		}
+		choice = strings.TrimSpace(string(output))
+	} else {
+		fmt.Printf("\n%sCategory for %s:\n", decoration("🗂️ "), fileName)
+		for i, option := range options {
+			marker := ""
+			if option == suggested {
+				marker = " (suggested)"
+			}
+			fmt.Printf("%d. %s%s\n", i+1, option, marker)
+		}
+		fmt.Print("Enter choice (blank for suggested): ")
+		
+		var input string
+		fmt.Scanln(&input)
+		if strings.TrimSpace(input) == "" {
+			return suggested, nil
+		}
+		index, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil || index < 1 || index > len(options) {
+			return "", NewConfigError("category selection", fileName, 
+				fmt.Errorf("invalid choice: %s", input))
+		}
+		choice = options[index-1]
+	}
+	
+	if choice == "" {
+		return suggested, nil
+	}
+	
+	if choice != newCategoryOption {
+		return choice, nil
+	}
+	
+	name, err := promptForInput("New category name: ", "wm, dev, work...", "")
+	if err != nil {
+		return "", err
+	}
+	if err := config.AddCategory(name); err != nil {
+		return "", err
+	}
+	
+	return name, nil
+}
+
+// defaultTargetRoot is where system-wide configs live, allowed as a target
+// directory alongside the home directory
+const defaultTargetRoot = "/etc"
+
+// allowedTargetRoots lists the directories managed targets may be in: the
+// home directory, the XDG config directory, /etc and allowed_target_roots
+func (c *Config) allowedTargetRoots() []string {
+	var candidates []string
+	if homeDir, err := mustHome(); err == nil {
+		candidates = append(candidates, homeDir)
+	}
+	candidates = append(candidates, xdgConfigHome(), defaultTargetRoot)
+	candidates = append(candidates, c.AllowedTargetRoots...)
+	
+	// Roots already covered by an earlier one are left out of the list
+	var roots []string
+	for _, root := range candidates {
+		if filepath.IsAbs(root) && !withinAnyDir(root, roots) {
+			roots = append(roots, filepath.Clean(root))
+		}
+	}
+	return roots
+}
+
+// isAllowedTarget reports whether targetPath is inside an allowed root
+func (c *Config) isAllowedTarget(targetPath string) bool {
+	return withinAnyDir(targetPath, c.allowedTargetRoots())
+}
+
+// withinAnyDir reports whether path is inside any of dirs
+func withinAnyDir(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if isWithinDir(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enhanced createConfigFileFromPath with better error handling.
+// When interactive is set the user confirms or overrides the suggested category.
+func createConfigFileFromPath(selectedPath string, config *Config, interactive bool) (ConfigFile, error) {
+	homeDir, err := mustHome()
+	if err != nil {
+		return ConfigFile{}, err
+	}
 	
 	var targetPath string
 	var fileName string
@@ -871,8 +1467,8 @@ func createConfigFileFromPath(selectedPath string, config *Config) (ConfigFile,
 		targetPath = strings.Replace(selectedPath, "~", homeDir, 1)
 		fileName = filepath.Base(targetPath)
 	} else {
-		// Relative to home directory
-		targetPath = filepath.Join(homeDir, selectedPath)
+		// Relative to home directory (.config/... follows $XDG_CONFIG_HOME)
+		targetPath = resolveHomePath(selectedPath)
 		fileName = selectedPath
 		if strings.HasPrefix(fileName, ".") {
 			fileName = filepath.Base(fileName)
@@ -880,9 +1476,30 @@ func createConfigFileFromPath(selectedPath string, config *Config) (ConfigFile,
 	}
 	
 	// Validate target path is within reasonable bounds
-	if !strings.HasPrefix(targetPath, homeDir) && !strings.HasPrefix(targetPath, "/etc") {
+	if !config.isAllowedTarget(targetPath) {
 		return ConfigFile{}, NewConfigError("create config file", selectedPath,
-			fmt.Errorf("target path outside of home directory or /etc"))
+			fmt.Errorf("target path is outside the allowed directories (%s); add its directory to allowed_target_roots in config.json to manage it",
+				strings.Join(config.allowedTargetRoots(), ", ")))
+	}
+	
+	// A dangling symlink has nothing behind it to manage, and following it
+	// would report the target as missing
+	if linkValue, ok := danglingSymlink(targetPath); ok {
+		if !interactive {
+			return ConfigFile{}, NewConfigError("create config file", targetPath,
+				fmt.Errorf("target is a dangling symlink to %s; remove it first", linkValue))
+		}
+		
+		confirmed, err := confirmAction(fmt.Sprintf("%s is a dangling symlink to %s. Remove the link and manage %s?", 
+			targetPath, linkValue, targetPath))
+		if err != nil || !confirmed {
+			return ConfigFile{}, NewConfigError("create config file", targetPath,
+				fmt.Errorf("add cancelled: dangling symlink left in place"))
+		}
+		if err := os.Remove(targetPath); err != nil {
+			return ConfigFile{}, NewConfigError("remove dangling symlink", targetPath, err)
+		}
+		logger.Debugf("%s: removed dangling symlink to %s", targetPath, linkValue)
 	}
 	
 	// Check if it's a directory
@@ -893,38 +1510,189 @@ func createConfigFileFromPath(selectedPath string, config *Config) (ConfigFile,
 	}
 	
 	// Auto-categorize with validation
-	category := categorizeDotfile(fileName, config.Categories)
+	category := categorizeDotfile(config, fileName)
 	if category == "" {
 		category = "misc" // Default fallback
 	}
 	
-	// Check if it might be a template
-	isTemplate := false
-	if !isDirectory {
-		if data, err := os.ReadFile(targetPath); err == nil {
-			content := strings.ToLower(string(data))
-			// Look for template patterns
-			if strings.Contains(content, "{{") || 
-			   strings.Contains(content, "$user") || 
-			   strings.Contains(content, "$email") ||
-			   strings.Contains(content, "$editor") {
-				isTemplate = true
+	if interactive {
+		chosen, err := selectCategory(config, fileName, category)
+		if err != nil {
+			return ConfigFile{}, err
+		}
+		category = chosen
+	}
+	
+	newFile := buildConfigFile(targetPath, fileName, category, isDirectory)
+	if interactive {
+		// Optional, so a cancelled or empty answer just leaves it unset
+		if description, err := promptForInput("Description (optional): ", "what this file is for", ""); err == nil {
+			newFile.Description = strings.TrimSpace(description)
+		}
+		
+		// Any path in the dotfiles directory will do, including a file already
+		// in the repository or another file's source to share
+		if source, err := promptForInput("Source (relative to dotfiles): ", newFile.Source, newFile.Source); err == nil && strings.TrimSpace(source) != "" {
+			cleaned, err := config.cleanSource(source, targetPath)
+			if err != nil {
+				return ConfigFile{}, err
+			}
+			if owner, shared := config.sharedSourceOwner(cleaned, targetPath); shared && (owner.Template || newFile.Template) {
+				return ConfigFile{}, NewValidationError("source", cleaned, 
+					fmt.Sprintf("a template's source is its rendered output and can't be shared with %s", owner.Name), targetPath)
 			}
+			newFile.Source = cleaned
 		}
 	}
+	return newFile, nil
+}
+
+// danglingSymlink reports whether path is a symlink whose destination is
+// missing, and returns where it points
+func danglingSymlink(path string) (string, bool) {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return "", false
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return "", false
+	}
+	
+	linkValue, err := os.Readlink(path)
+	if err != nil {
+		return "", false
+	}
+	return linkValue, true
+}
+
+// externalSymlink reports whether file's target is a symlink (possibly through
+// a chain of links) to something outside the dotfiles directory, such as a
+// link made by another dotfiles manager. It returns the link's own destination
+// and the fully resolved path.
+func externalSymlink(config *Config, file *ConfigFile) (linkTarget, resolved string, ok bool) {
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return "", "", false
+	}
+	linkValue, err := os.Readlink(target)
+	if err != nil {
+		return "", "", false
+	}
+	
+	resolved, err = filepath.EvalSymlinks(target)
+	if err != nil {
+		// Dangling links have nothing to absorb
+		return "", "", false
+	}
+	if dotfilesDir, err := filepath.EvalSymlinks(config.DotfilesDir); err == nil && isWithinDir(resolved, dotfilesDir) {
+		return "", "", false
+	}
+	
+	return resolveLinkTarget(target, linkValue), resolved, true
+}
+
+// absorbSymlinkTarget copies the real file behind a symlinked target into the
+// dotfiles directory, so linking replaces the link instead of pointing at it,
+// and records where the link pointed in OriginalLink
+func absorbSymlinkTarget(config *Config, file *ConfigFile) error {
+	linkTarget, resolved, ok := externalSymlink(config, file)
+	if !ok {
+		return NewValidationError("target", file.Target, "target is not a symlink to a file outside the dotfiles directory", "")
+	}
+	
+	sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	if fileExistsNoFollow(sourcePath) {
+		return NewConfigError("absorb symlink target", sourcePath, fmt.Errorf("source already exists"))
+	}
+	if err := ensureDir(filepath.Dir(sourcePath)); err != nil {
+		return err
+	}
 	
-	// Determine source path in dotfiles directory
-	sourcePath := filepath.Join(category, strings.TrimPrefix(fileName, "."))
-	if isDirectory {
-		sourcePath = filepath.Join(category, fileName)
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return NewConfigError("absorb symlink target", resolved, err)
+	}
+	if info.IsDir() {
+		err = copyDirectory(resolved, sourcePath)
+	} else {
+		err = copyFile(resolved, sourcePath)
+	}
+	if err != nil {
+		os.RemoveAll(sourcePath)
+		return NewConfigError("absorb symlink target", resolved, err)
 	}
 	
-	return ConfigFile{
+	file.OriginalLink = linkTarget
+	logger.Debugf("%s: absorbed %s (via %s) into %s", file.Name, resolved, linkTarget, sourcePath)
+	return nil
+}
+
+// restoreOriginalLink points an adopted target back where it linked before
+// adoption, replacing our link to the source
+func restoreOriginalLink(config *Config, file *ConfigFile) error {
+	if file.OriginalLink == "" {
+		return NewValidationError("original_link", "", "file has no original link to restore", "")
+	}
+	
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return err
+	}
+	
+	tx := NewTransaction(config)
+	sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	if linkTarget, err := os.Readlink(target); err == nil && 
+		sameLinkDestination(resolveLinkTarget(target, linkTarget), sourcePath) {
+		tx.AddOperation(NewUnlinkOperation(target, file))
+	}
+	tx.AddOperation(NewLinkOperation(file.OriginalLink, target, file))
+	
+	return tx.Execute()
+}
+
+// buildConfigFile assembles the ConfigFile shared by the add flow and the setup
+// wizard so both derive the same source path and template flag
+func buildConfigFile(targetPath, fileName, category string, isDirectory bool) ConfigFile {
+	file := ConfigFile{
 		Name:      fileName,
-		Source:    sourcePath,
+		Source:    deriveSourcePath(targetPath, fileName, category),
 		Target:    targetPath,
 		Category:  category,
-		Template:  isTemplate,
+		Template:  !isDirectory && looksLikeTemplate(targetPath),
 		Variables: make(map[string]string),
-	}, nil
+	}
+	
+	// Scripts in ~/.local/bin only run when executable, so the copy made into
+	// the dotfiles directory must be too
+	if !isDirectory && isWithinDir(targetPath, localBinDir()) {
+		file.Perms = "755"
+	}
+	return file
+}
+
+// deriveSourcePath picks where a target lives in the dotfiles directory.
+// Anything under $XDG_CONFIG_HOME (usually ~/.config) keeps its structure
+// beneath "config/" (so .config/nvim becomes config/nvim); everything else
+// goes in its category directory without the leading dot.
+func deriveSourcePath(targetPath, fileName, category string) string {
+	xdgDir := xdgConfigHome()
+	if relPath, err := filepath.Rel(xdgDir, targetPath); err == nil && relPath != "." && isWithinDir(targetPath, xdgDir) {
+		return filepath.Join("config", relPath)
+	}
+	
+	return filepath.Join(category, strings.TrimPrefix(fileName, "."))
+}
+
+// looksLikeTemplate checks file content for template-style placeholders
+func looksLikeTemplate(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	
+	content := strings.ToLower(string(data))
+	return strings.Contains(content, "{{") || 
+		strings.Contains(content, "$user") || 
+		strings.Contains(content, "$email") ||
+		strings.Contains(content, "$editor")
 }
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveConflictInteractiveWithGum(t *testing.T) {
+	fake := &scriptedRunner{
+		Available: map[string]bool{"gum": true},
+		Responses: []scriptedResponse{{Output: "Skip all remaining conflicts\n"}},
+	}
+	useRunner(t, fake)
+
+	conflict := &ConflictInfo{File: &ConfigFile{Name: "zshrc"}, TargetPath: "/nonexistent/.zshrc"}
+	resolution, applyToAll, err := resolveConflictInteractive(conflict, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolution != ConflictSkip || !applyToAll {
+		t.Errorf("got (%v, %v), want (ConflictSkip, true)", resolution, applyToAll)
+	}
+}
+
+func TestResolveConflictInteractiveCancelledGum(t *testing.T) {
+	useRunner(t, &scriptedRunner{
+		Available: map[string]bool{"gum": true},
+		Responses: []scriptedResponse{{Err: errors.New("exit status 130")}},
+	})
+
+	conflict := &ConflictInfo{File: &ConfigFile{Name: "zshrc"}, TargetPath: "/nonexistent/.zshrc"}
+	resolution, _, err := resolveConflictInteractive(conflict, false)
+	if err == nil || resolution != ConflictCancel {
+		t.Errorf("got (%v, %v), want ConflictCancel and an error", resolution, err)
+	}
+}
+
+func TestSelectFileToEditCancel(t *testing.T) {
+	useRunner(t, &scriptedRunner{
+		Available: map[string]bool{"gum": true},
+		Responses: []scriptedResponse{{Output: "Cancel (Esc)\n"}},
+	})
+
+	if _, err := selectFileToEdit([]string{"init.lua"}); err == nil {
+		t.Error("choosing Cancel should be an error")
+	}
+}
+
+func TestOpenFileInEditor(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	path := filepath.Join(t.TempDir(), "init.lua")
+	writeTestFile(t, path, "")
+	config := &Config{Editor: "nvim"}
+
+	// An editor quitting with a non-zero status isn't an error
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	fake := &scriptedRunner{Responses: []scriptedResponse{{}, {Err: exitErr}, {Err: exec.ErrNotFound}}}
+	useRunner(t, fake)
+
+	if err := openFileInEditor(config, path); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Calls) != 1 || fake.Calls[0][0] != "nvim" || fake.Calls[0][1] != path {
+		t.Errorf("calls = %q, want nvim %s", fake.Calls, path)
+	}
+	if err := openFileInEditor(config, path); err != nil {
+		t.Errorf("editor exiting with status 1: %v", err)
+	}
+	if err := openFileInEditor(config, path); err == nil {
+		t.Error("an editor that can't start should be an error")
+	}
+}
+
+func TestEditorExitStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus string
+		wantExited bool
+	}{
+		{"not found", &exec.Error{Name: "nvim", Err: exec.ErrNotFound}, "", false},
+		{"not executable", &os.PathError{Op: "fork/exec", Path: "/usr/bin/nvim", Err: os.ErrPermission}, "", false},
+		{"non-zero exit", exec.Command("sh", "-c", "exit 3").Run(), "exited with status 3", true},
+		{"killed by a signal", exec.Command("sh", "-c", "kill -KILL $$").Run(), "was interrupted", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, exited := editorExitStatus(tt.err)
+			if status != tt.wantStatus || exited != tt.wantExited {
+				t.Errorf("editorExitStatus(%v) = (%q, %v), want (%q, %v)", tt.err, status, exited, tt.wantStatus, tt.wantExited)
+			}
+
+			// Only an editor that couldn't start fails opening the file
+			t.Setenv("VISUAL", "")
+			t.Setenv("EDITOR", "")
+			path := filepath.Join(t.TempDir(), "init.lua")
+			writeTestFile(t, path, "")
+			useRunner(t, &scriptedRunner{Responses: []scriptedResponse{{Err: tt.err}}})
+			if err := openFileInEditor(&Config{Editor: "nvim"}, path); (err != nil) == tt.wantExited {
+				t.Errorf("openFileInEditor() = %v, want an error: %v", err, !tt.wantExited)
+			}
+		})
+	}
+}
+
+func TestEditorInvocation(t *testing.T) {
+	path := "/home/me/My Notes/todo.md"
+	tests := []struct {
+		name       string
+		env        string // $EDITOR
+		override   string // --editor
+		editor     string
+		editorArgs map[string][]string
+		want       []string
+	}{
+		{"plain editor", "", "", "nvim", nil, []string{"nvim", path}},
+		{"gui editor waits", "", "", "code", nil, []string{"code", "--wait", path}},
+		{"args by name, path last", "", "", "nvim", map[string][]string{"nvim": {"-c", "set ft=markdown"}},
+			[]string{"nvim", "-c", "set ft=markdown", path}},
+		{"args by base name", "", "", "/opt/bin/hx", map[string][]string{"hx": {"--vsplit"}},
+			[]string{"/opt/bin/hx", "--vsplit", path}},
+		{"empty args drop the default flag", "", "", "code", map[string][]string{"code": {}}, []string{"code", path}},
+		{"file placeholder", "", "", "emacsclient", map[string][]string{"emacsclient": {"-c", "--eval=(find-file \"{file}\")"}},
+			[]string{"emacsclient", "-c", "--eval=(find-file \"" + path + "\")"}},
+		{"args from $EDITOR win", "code --wait --new-window", "", "nvim", map[string][]string{"code": {"-r"}},
+			[]string{"code", "--wait", "--new-window", path}},
+		{"$EDITOR without args uses editor_args", "nvim", "", "vim", map[string][]string{"nvim": {"-p"}},
+			[]string{"nvim", "-p", path}},
+		{"--editor beats $EDITOR", "vim", "nano -l", "vim", nil, []string{"nano", "-l", path}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", "")
+			t.Setenv("EDITOR", tt.env)
+			previous := editorOverride
+			editorOverride = tt.override
+			t.Cleanup(func() { editorOverride = previous })
+			config := &Config{Editor: tt.editor, EditorArgs: tt.editorArgs}
+
+			if got := config.editorInvocation(path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("editorInvocation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewDiffUsesFirstAvailableTool(t *testing.T) {
+	fake := &scriptedRunner{
+		Available: map[string]bool{"git": true},
+		Responses: []scriptedResponse{{}},
+	}
+	useRunner(t, fake)
+
+	if err := viewDiff("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Calls) != 1 || fake.Calls[0][0] != "git" {
+		t.Errorf("calls = %q, want one git diff", fake.Calls)
+	}
+}
+
+func TestDiffPagerCommandUsesPager(t *testing.T) {
+	t.Setenv("PAGER", "more")
+	fake := &scriptedRunner{}
+	useRunner(t, fake)
+
+	cmd := diffPagerCommand("/target", "/source")
+	if cmd.Args[0] != "sh" || cmd.Args[2] != `diff -ru -- "$1" "$2" | more` {
+		t.Errorf("args = %q, want diff piped into $PAGER", cmd.Args)
+	}
+	if len(fake.Calls) != 1 {
+		t.Errorf("calls = %q, want the command built through the runner", fake.Calls)
+	}
+}
+
+func TestAddAndSetupBuildTheSameFile(t *testing.T) {
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "")
+	writeTestFile(t, filepath.Join(home, ".config", "nvim", "init.lua"), "")
+	writeTestFile(t, filepath.Join(home, ".config", "starship.toml"), "")
+
+	tests := []struct {
+		path       string
+		kind       string
+		wantSource string
+	}{
+		{".bashrc", "file", "shell/bashrc"},
+		{".config/nvim", "directory", "config/nvim"},
+		{".config/starship.toml", "file", "config/starship.toml"},
+	}
+	for _, tt := range tests {
+		added, err := createConfigFileFromPath(tt.path, config, false)
+		if err != nil {
+			t.Fatalf("%s: add: %v", tt.path, err)
+		}
+		setUp, err := createConfigFileFromSelection(tt.path+" ("+tt.kind+")", config)
+		if err != nil {
+			t.Fatalf("%s: setup: %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(added, setUp) {
+			t.Errorf("%s: add built %+v, setup built %+v", tt.path, added, setUp)
+		}
+		if added.Source != tt.wantSource {
+			t.Errorf("%s: source %q, want %q", tt.path, added.Source, tt.wantSource)
+		}
+	}
+}
+
+func TestAddDanglingSymlink(t *testing.T) {
+	declined := errors.New("exit status 1")
+	tests := []struct {
+		name        string
+		interactive bool
+		responses   []scriptedResponse // gum confirm, then category, description and source
+		wantAdded   bool
+	}{
+		{"non-interactive refuses", false, nil, false},
+		{"declined leaves the link", true, []scriptedResponse{{Err: declined}}, false},
+		{"accepted removes the link", true, []scriptedResponse{{}, {Output: "shell\n"}, {}, {}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, home := newTestConfig(t)
+			gone := filepath.Join(home, ".old-dotfiles", "zshrc")
+			target := filepath.Join(home, ".zshrc")
+			if err := os.Symlink(gone, target); err != nil {
+				t.Fatal(err)
+			}
+			useRunner(t, &scriptedRunner{Available: map[string]bool{"gum": true}, Responses: tt.responses})
+
+			file, err := createConfigFileFromPath(".zshrc", config, tt.interactive)
+
+			if !tt.wantAdded {
+				if err == nil {
+					t.Fatalf("added %+v, want the dangling symlink refused", file)
+				}
+				if value, err := os.Readlink(target); err != nil || value != gone {
+					t.Errorf("target links to %q (%v), want the dangling link left alone", value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Lstat(target); !os.IsNotExist(err) {
+				t.Errorf("dangling link still there (%v)", err)
+			}
+			if file.Target != target || file.Source != "shell/zshrc" || file.Category != "shell" {
+				t.Errorf("added %+v, want a shell file for %s", file, target)
+			}
+		})
+	}
+}
+
+func TestAbsorbChainedSymlink(t *testing.T) {
+	config, home := newTestConfig(t)
+	other := filepath.Join(home, ".other-dotfiles")
+	realFile := filepath.Join(other, "tmux.conf")
+	writeTestFile(t, realFile, "set -g mouse on\n")
+	// ~/.tmux.conf -> ~/.other-dotfiles/current -> ~/.other-dotfiles/tmux.conf
+	hop := filepath.Join(other, "current")
+	if err := os.Symlink(realFile, hop); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(home, ".tmux.conf")
+	if err := os.Symlink(hop, target); err != nil {
+		t.Fatal(err)
+	}
+	file := ConfigFile{Name: "tmux.conf", Source: "terminal/tmux.conf", Target: target, Category: "terminal"}
+
+	linkTarget, resolved, ok := externalSymlink(config, &file)
+	if !ok || linkTarget != hop || resolved != realFile {
+		t.Fatalf("externalSymlink = (%q, %q, %v), want the first hop and the real file", linkTarget, resolved, ok)
+	}
+	if err := absorbSymlinkTarget(config, &file); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(config.DotfilesDir, file.Source)); err != nil || string(data) != "set -g mouse on\n" {
+		t.Errorf("source holds %q (%v), want the real file's contents", data, err)
+	}
+	if file.OriginalLink != hop {
+		t.Errorf("OriginalLink = %q, want %q", file.OriginalLink, hop)
+	}
+
+	// Once linked, the target resolves into the dotfiles directory: nothing to absorb
+	config.Files = []ConfigFile{file}
+	if err := atomicLinkSingleConfig(config, &config.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := externalSymlink(config, &config.Files[0]); ok {
+		t.Error("a link into the dotfiles directory was taken for an external one")
+	}
+
+	if err := restoreOriginalLink(config, &config.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if linkValue, err := os.Readlink(target); err != nil || linkValue != hop {
+		t.Errorf("restored link stores %q (%v), want %q", linkValue, err, hop)
+	}
+}
+
+func TestLocalBinScriptStaysExecutable(t *testing.T) {
+	config, home := newTestConfig(t)
+	target := filepath.Join(home, ".local", "bin", "deploy")
+	writeTestFile(t, target, "#!/bin/sh\necho deploying\n")
+	if err := os.Chmod(target, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	file := buildConfigFile(target, "deploy", "misc", false)
+	if file.Perms != "755" {
+		t.Fatalf("Perms = %q, want 755 for a ~/.local/bin script", file.Perms)
+	}
+	config.Files = []ConfigFile{file}
+	if err := atomicLinkSingleConfig(config, &config.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	source := filepath.Join(config.DotfilesDir, file.Source)
+	if info, err := os.Stat(source); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0755 {
+		t.Errorf("adopted source mode = %v, want -rwxr-xr-x", info.Mode().Perm())
+	}
+	if value, err := os.Readlink(target); err != nil || value != source {
+		t.Fatalf("target links to %q (%v), want %q", value, err, source)
+	}
+	if info, err := os.Stat(target); err != nil {
+		t.Fatal(err)
+	} else if info.Mode()&0111 == 0 {
+		t.Errorf("linked script mode = %v, want it executable", info.Mode())
+	}
+}
+
+func TestCopiedLocalBinScriptIsExecutable(t *testing.T) {
+	config, home := newTestConfig(t)
+	target := filepath.Join(home, ".local", "bin", "deploy")
+	file := buildConfigFile(target, "deploy", "misc", false)
+	file.LinkStrategy = LinkStrategyCopy
+	// A checkout that dropped the executable bit
+	writeTestFile(t, filepath.Join(config.DotfilesDir, file.Source), "#!/bin/sh\necho deploying\n")
+	config.Files = []ConfigFile{file}
+
+	if err := atomicLinkSingleConfig(config, &config.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm() != 0755 {
+		t.Errorf("copied script mode = %v, want a -rwxr-xr-x copy", info.Mode())
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// applyOrder returns the indexes of c.Files in the order link-all starts
+// them: by Order, with files of equal order in config order
+func (c *Config) applyOrder() []int {
+	order := make([]int, len(c.Files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return c.Files[order[a]].Order < c.Files[order[b]].Order
+	})
+	return order
+}
+
+// nextOrder is the Order a newly added file gets: after every file once any
+// file has one, so it doesn't jump ahead of them, and otherwise unset
+func (c *Config) nextOrder() int {
+	last := 0
+	for _, file := range c.Files {
+		if file.Order > last {
+			last = file.Order
+		}
+	}
+	if last == 0 {
+		return 0
+	}
+	return last + 1
+}
+
+// MoveFile moves the file managing targetPath one place earlier (offset -1)
+// or later (offset 1) in apply order, past the nearest file of its own
+// category, as the file list shows it. Every file is then numbered in apply
+// order, so the order is kept in config.json.
+func (c *Config) MoveFile(targetPath string, offset int) error {
+	file, err := c.GetConfigFileByTarget(targetPath)
+	if err != nil {
+		return err
+	}
+	
+	order := c.applyOrder()
+	from := -1
+	for position, index := range order {
+		if c.Files[index].Target == targetPath {
+			from = position
+			break
+		}
+	}
+	to := from + offset
+	for to >= 0 && to < len(order) && c.Files[order[to]].Category != file.Category {
+		to += offset
+	}
+	if to < 0 || to >= len(order) {
+		edge := "first"
+		if offset > 0 {
+			edge = "last"
+		}
+		return NewValidationError("order", file.Name, fmt.Sprintf("already %s in its category", edge), targetPath)
+	}
+	
+	order[from], order[to] = order[to], order[from]
+	for position, index := range order {
+		c.Files[index].Order = position + 1
+	}
+	return nil
+}
+
+// handleMoveFile moves the selected file up (offset -1) or down (offset 1)
+// within its category, changing the order link-all applies files in
+func (m model) handleMoveFile(offset int) (tea.Model, tea.Cmd) {
+	index := m.selectedFileIndex()
+	if index < 0 {
+		m.message = "No file selected to move"
+		m.messageType = "warning"
+		return m, nil
+	}
+	file := m.config.Files[index]
+	
+	if err := m.config.MoveFile(file.Target, offset); err != nil {
+		m.message = fmt.Sprintf("Can't move %s: %v", file.Name, err)
+		m.messageType = "warning"
+		return m, nil
+	}
+	m.fileList.SetItems(m.listItems())
+	m = m.selectFile(index)
+	
+	if err := saveConfigSafe(m.config); err != nil {
+		m.message = fmt.Sprintf("Moved %s but failed to save config: %v", file.Name, err)
+		m.messageType = "error"
+		return m, nil
+	}
+	direction := "up"
+	if offset > 0 {
+		direction = "down"
+	}
+	m.message = fmt.Sprintf("Moved %s %s; link-all applies files in this order", file.Name, direction)
+	m.messageType = "success"
+	return m, nil
+}
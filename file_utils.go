@@ -1,10 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 // copyFile copies a single file from src to dst
@@ -16,7 +25,7 @@ func copyFile(src, dst string) error {
 	}
 	defer srcFile.Close()
 	
-	// Get source file info for permissions
+	// Get source file info for permissions, ownership and times
 	srcInfo, err := srcFile.Stat()
 	if err != nil {
 		return NewConfigError("stat source file", src, err)
@@ -34,16 +43,37 @@ func copyFile(src, dst string) error {
 		return NewConfigError("copy file contents", src, err)
 	}
 	
+	// Ownership first: changing the owner clears setuid and setgid bits
+	preserveOwner(dst, srcInfo)
+	
 	// Set permissions to match source
 	if err := dstFile.Chmod(srcInfo.Mode()); err != nil {
 		return NewConfigError("set file permissions", dst, err)
 	}
 	
+	// Keep the modification time; the access time is now, as the source's
+	// just became by reading it
+	if err := os.Chtimes(dst, time.Now(), srcInfo.ModTime()); err != nil {
+		return NewConfigError("set file times", dst, err)
+	}
+	
 	return nil
 }
 
 // copyDirectory recursively copies a directory from src to dst
 func copyDirectory(src, dst string) error {
+	return copyDirectoryExcluding(src, dst, nil)
+}
+
+// copyDirectoryExcluding copies src to dst, skipping files and directories
+// whose path relative to src matches one of the exclude patterns
+func copyDirectoryExcluding(src, dst string, exclude []string) error {
+	return copyDirectoryRel(src, dst, "", exclude)
+}
+
+// copyDirectoryRel does the work of copyDirectoryExcluding; rel is the path of
+// src relative to the top-level directory being copied
+func copyDirectoryRel(src, dst, rel string, exclude []string) error {
 	// Get source directory info
 	srcInfo, err := os.Stat(src)
 	if err != nil {
@@ -54,6 +84,7 @@ func copyDirectory(src, dst string) error {
 	if err := os.MkdirAll(dst, srcInfo.Mode()); err != nil {
 		return NewConfigError("create destination directory", dst, err)
 	}
+	preserveOwner(dst, srcInfo)
 	
 	// Read source directory
 	entries, err := os.ReadDir(src)
@@ -65,10 +96,15 @@ func copyDirectory(src, dst string) error {
 	for _, entry := range entries {
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
+		entryRel := filepath.Join(rel, entry.Name())
+		
+		if isExcludedPath(exclude, entryRel) {
+			continue
+		}
 		
 		if entry.IsDir() {
 			// Recursively copy subdirectory
-			if err := copyDirectory(srcPath, dstPath); err != nil {
+			if err := copyDirectoryRel(srcPath, dstPath, entryRel, exclude); err != nil {
 				return err
 			}
 		} else {
@@ -82,34 +118,272 @@ func copyDirectory(src, dst string) error {
 	return nil
 }
 
-// moveFile moves a file from src to dst (rename with cross-filesystem support)
+// rename is os.Rename; tests swap it to simulate renames across filesystems
+var rename = os.Rename
+
+// moveFile moves a file, directory or symlink from src to dst. A rename
+// across filesystems (e.g. out of a bind mount) fails with EXDEV, so then src
+// is copied and removed instead; any other rename error is returned as is.
 func moveFile(src, dst string) error {
 	// Try simple rename first (works if on same filesystem)
-	if err := os.Rename(src, dst); err == nil {
-		return nil
+	err := rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
 	}
+	logger.Debugf("rename %s -> %s crosses filesystems, copying instead", src, dst)
 	
-	// If rename fails, copy and delete
-	if err := copyFile(src, dst); err != nil {
+	if err := copyPath(src, dst); err != nil {
+		os.RemoveAll(dst) // don't leave a partial copy behind
 		return err
 	}
 	
-	// Remove source file
-	if err := os.Remove(src); err != nil {
+	// Remove the original
+	if err := os.RemoveAll(src); err != nil {
 		return NewConfigError("remove source file", src, err)
 	}
 	
 	return nil
 }
 
+// copyPath copies a file or directory tree; a symlink is recreated rather
+// than followed
+func copyPath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return NewConfigError("stat source file", src, err)
+	}
+	
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		linkTarget, err := os.Readlink(src)
+		if err != nil {
+			return NewConfigError("read symlink", src, err)
+		}
+		if err := os.Symlink(linkTarget, dst); err != nil {
+			return NewConfigError("create symlink", dst, err)
+		}
+		return nil
+	case info.IsDir():
+		return copyDirectory(src, dst)
+	default:
+		return copyFile(src, dst)
+	}
+}
+
+// ensureDirMu serializes directory creation, so files linked in parallel that
+// share a missing parent don't race to create it
+var ensureDirMu sync.Mutex
+
 // ensureDir creates directory if it doesn't exist
 func ensureDir(dir string) error {
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := mkdirAllSerialized(dir); err != nil {
 		return NewConfigError("create directory", dir, err)
 	}
 	return nil
 }
 
+// mkdirAllSerialized creates dir and its parents while holding ensureDirMu.
+// Link operations create target directories through it.
+func mkdirAllSerialized(dir string) error {
+	ensureDirMu.Lock()
+	defer ensureDirMu.Unlock()
+	return os.MkdirAll(dir, 0755)
+}
+
+// resolveLinkTarget turns the value of a symlink at linkPath into an absolute,
+// cleaned path so relative and absolute links can be compared
+func resolveLinkTarget(linkPath, linkTarget string) string {
+	if !filepath.IsAbs(linkTarget) {
+		linkTarget = filepath.Join(filepath.Dir(linkPath), linkTarget)
+	}
+	return filepath.Clean(linkTarget)
+}
+
+// sameLinkDestination reports whether a symlink's resolved value (see
+// resolveLinkTarget) names the expected path. Cleaned paths are compared
+// first; failing that, the parent directories are compared with their
+// symlinks followed, so a link into /data/dotfiles still matches a dotfiles
+// directory configured as ~/dotfiles when one is a symlink to the other. The
+// last component is never followed: a link to another link isn't a link to
+// the source.
+func sameLinkDestination(resolved, expected string) bool {
+	resolved = filepath.Clean(resolved)
+	expected = filepath.Clean(expected)
+	if resolved == expected {
+		return true
+	}
+	if filepath.Base(resolved) != filepath.Base(expected) {
+		return false
+	}
+	
+	resolvedDir, err := filepath.EvalSymlinks(filepath.Dir(resolved))
+	if err != nil {
+		return false
+	}
+	expectedDir, err := filepath.EvalSymlinks(filepath.Dir(expected))
+	if err != nil {
+		return false
+	}
+	return resolvedDir == expectedDir
+}
+
+// parentLeavesDir reports whether path, inside dir as written, ends up outside
+// it once symlinks in its parent directories are followed. The nearest parent
+// that exists is resolved; path itself may be a symlink (preserved target
+// symlinks are stored as links in the dotfiles directory).
+func parentLeavesDir(path, dir string) bool {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	parent := nearestExistingDir(filepath.Dir(path))
+	if parent == "" || !isWithinDir(parent, dir) {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(parent)
+	return err == nil && !isWithinDir(resolved, realDir)
+}
+
+// isWithinDir reports whether path is dir itself or lies beneath it. It
+// compares cleaned paths component-wise, so "/a/bc" is not within "/a/b".
+func isWithinDir(path, dir string) bool {
+	relPath, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// sameFile reports whether both paths resolve to the same file on disk
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// filesEqual reports whether two files have identical contents
+func filesEqual(a, b string) bool {
+	dataA, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	dataB, err := os.ReadFile(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(dataA, dataB)
+}
+
+// contentHash returns a SHA-256 over a file's contents, or over every file's
+// relative path and contents for a directory
+func contentHash(path string) (string, error) {
+	hash := sha256.New()
+	
+	err := filepath.Walk(path, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		
+		relPath, err := filepath.Rel(path, walkPath)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(walkPath)
+		if err != nil {
+			return err
+		}
+		
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(relPath), len(data))
+		hash.Write(data)
+		return nil
+	})
+	if err != nil {
+		return "", NewConfigError("hash contents", path, err)
+	}
+	
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contentsMatch reports whether two files or directories have identical contents
+func contentsMatch(a, b string) bool {
+	hashA, err := contentHash(a)
+	if err != nil {
+		return false
+	}
+	hashB, err := contentHash(b)
+	if err != nil {
+		return false
+	}
+	return hashA == hashB
+}
+
+// parsePerms parses an octal permission string such as "600" or "0755"
+func parsePerms(perms string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(perms, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid permissions %q (use an octal mode such as \"644\")", perms)
+	}
+	return os.FileMode(mode), nil
+}
+
+// applyPerms sets the file's configured permissions on path. Files without
+// Perms keep whatever mode they were written with.
+func applyPerms(file *ConfigFile, path string) error {
+	if file == nil || file.Perms == "" {
+		return nil
+	}
+	
+	mode, err := parsePerms(file.Perms)
+	if err != nil {
+		return NewConfigError("apply permissions", path, err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return NewConfigError("apply permissions", path, err)
+	}
+	return nil
+}
+
+// writeGeneratedFile writes content generated for file, such as a rendered
+// template, to path. With Perms it goes through a temporary file that has
+// that mode before anything is written, so a secret is never readable with a
+// looser mode, not even briefly.
+func writeGeneratedFile(file *ConfigFile, path string, data []byte) error {
+	if file == nil || file.Perms == "" {
+		return os.WriteFile(path, data, 0644)
+	}
+	mode, err := parsePerms(file.Perms)
+	if err != nil {
+		return err
+	}
+	
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // only still there if something failed
+	if err := temp.Chmod(mode); err != nil {
+		temp.Close()
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
 // fileExists checks if a file exists
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
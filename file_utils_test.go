@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeTestFile writes content to path, creating its parent directories
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failRenames makes every rename fail with errno, as renames across
+// filesystems do with EXDEV
+func failRenames(t *testing.T, errno syscall.Errno) {
+	t.Helper()
+	previous := rename
+	rename = func(oldPath, newPath string) error {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: errno}
+	}
+	t.Cleanup(func() { rename = previous })
+}
+
+func TestMoveFileCrossDevice(t *testing.T) {
+	failRenames(t, syscall.EXDEV)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "nvim")
+	writeTestFile(t, filepath.Join(src, "lua", "init.lua"), "vim.o.number = true\n")
+	link := filepath.Join(dir, "vimrc")
+	if err := os.Symlink("nvim/lua/init.lua", link); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "moved")
+	if err := moveFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("source still exists after the copy fallback: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "lua", "init.lua")); err != nil || string(data) != "vim.o.number = true\n" {
+		t.Errorf("copied file = %q, %v", data, err)
+	}
+
+	movedLink := filepath.Join(dir, "vimrc.moved")
+	if err := moveFile(link, movedLink); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := os.Readlink(movedLink); err != nil || value != "nvim/lua/init.lua" {
+		t.Errorf("moved symlink = %q, %v; want it recreated, not followed", value, err)
+	}
+}
+
+func TestMoveFileOtherRenameErrors(t *testing.T) {
+	failRenames(t, syscall.EACCES)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "zshrc")
+	writeTestFile(t, src, "existing\n")
+
+	err := moveFile(src, filepath.Join(dir, "moved"))
+	if !errors.Is(err, syscall.EACCES) {
+		t.Fatalf("moveFile = %v, want the rename error", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "moved")); !os.IsNotExist(err) {
+		t.Errorf("copied despite a non cross-device error: %v", err)
+	}
+}
+
+func TestBackupAcrossDevicesRollsBack(t *testing.T) {
+	config, home := newTestConfig(t)
+	failRenames(t, syscall.EXDEV)
+	source := filepath.Join(config.DotfilesDir, "shell", "zshrc")
+	target := filepath.Join(home, ".zshrc")
+
+	tx := linkOverExisting(t, config, source, target)
+	if value, err := os.Readlink(target); err != nil || value != source {
+		t.Fatalf("target links to %q (%v), want %q", value, err, source)
+	}
+	backups, _ := filepath.Glob(target + ".backup*")
+	if len(backups) != 1 {
+		t.Fatalf("backups = %q, want one copied aside", backups)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	assertUntouched(t, target, "existing\n")
+}
+
+func TestCopyFileKeepsModeAndTimes(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "deploy.sh")
+	writeTestFile(t, src, "#!/bin/sh\n")
+	if err := os.Chmod(src, 0750); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(src, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "deploy.sh.copy")
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("mode = %v, want -rwxr-x---", info.Mode().Perm())
+	}
+	if !info.ModTime().Equal(modified) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), modified)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// findDanglingLinks returns managed targets that are symlinks into DotfilesDir
+// whose source no longer exists
+func findDanglingLinks(config *Config) []string {
+	var dangling []string
+	
+	for _, file := range config.Files {
+		resolved, err := resolveTarget(config, &file)
+		if err != nil {
+			continue
+		}
+		targets := []string{resolved}
+		
+		// Tree-linked directories hold one link per file
+		if info, err := os.Lstat(resolved); err == nil && info.IsDir() && file.effectiveLinkStrategy() == LinkStrategyTree {
+			targets = nil
+			filepath.Walk(resolved, func(path string, info os.FileInfo, err error) error {
+				if err == nil && info.Mode()&os.ModeSymlink != 0 {
+					targets = append(targets, path)
+				}
+				return nil
+			})
+		}
+		
+		for _, target := range targets {
+			if isDanglingDotfilesLink(config, target) {
+				dangling = append(dangling, target)
+			}
+		}
+	}
+	
+	return dangling
+}
+
+// findOrphanedLinks returns symlinks in the usual dotfile locations that point
+// into DotfilesDir but don't belong to any managed file
+func findOrphanedLinks(config *Config) []string {
+	homeDir, _ := mustHome()
+	
+	managed := make(map[string]bool)
+	for _, file := range config.Files {
+		if target, err := resolveTarget(config, &file); err == nil {
+			managed[target] = true
+		}
+	}
+	
+	scanDirs := []string{
+		config.underTargetRoot(homeDir),
+		config.underTargetRoot(xdgConfigHome()),
+		config.underTargetRoot(filepath.Join(homeDir, ".local", "bin")),
+	}
+	
+	var orphaned []string
+	for _, dir := range scanDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		
+		for _, entry := range entries {
+			if entry.Type()&os.ModeSymlink == 0 {
+				continue
+			}
+			
+			path := filepath.Join(dir, entry.Name())
+			if managed[path] || isManagedTreeLeaf(config, path) {
+				continue
+			}
+			
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				continue
+			}
+			if isWithinDir(resolveLinkTarget(path, linkTarget), config.DotfilesDir) {
+				orphaned = append(orphaned, path)
+			}
+		}
+	}
+	
+	return orphaned
+}
+
+// isDanglingDotfilesLink reports whether path is a symlink into DotfilesDir
+// whose destination is missing
+func isDanglingDotfilesLink(config *Config, path string) bool {
+	linkTarget, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+	
+	resolved := resolveLinkTarget(path, linkTarget)
+	if !isWithinDir(resolved, config.DotfilesDir) {
+		return false
+	}
+	
+	_, err = os.Stat(resolved)
+	return os.IsNotExist(err)
+}
+
+// isManagedTreeLeaf reports whether path lies inside a tree-linked target
+func isManagedTreeLeaf(config *Config, path string) bool {
+	for _, file := range config.Files {
+		if file.effectiveLinkStrategy() != LinkStrategyTree {
+			continue
+		}
+		if target, err := resolveTarget(config, &file); err == nil && isWithinDir(path, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// findOrphanedSources returns files and directories in DotfilesDir that no
+// managed file uses as its source. A referenced directory (tree-mode or
+// whole-directory sources) is kept with everything in it; directories on the
+// way to a source and category directories are kept but their other contents
+// are checked. An orphaned directory is reported once, not entry by entry.
+func findOrphanedSources(config *Config) ([]string, error) {
+	root := filepath.Clean(config.DotfilesDir)
+	referenced := make(map[string]bool)
+	ancestors := make(map[string]bool)
+	for _, file := range config.Files {
+		if file.Source == "" {
+			continue
+		}
+		sourcePath := filepath.Join(root, file.Source)
+		referenced[sourcePath] = true
+		for dir := filepath.Dir(sourcePath); dir != root && isWithinDir(dir, root); dir = filepath.Dir(dir) {
+			ancestors[dir] = true
+		}
+	}
+	for _, category := range config.Categories {
+		ancestors[filepath.Join(root, category)] = true
+	}
+	
+	// Config manager's own files, in case DotfilesDir is the config directory itself
+	preserved := map[string]bool{
+		filepath.Clean(config.GetBackupDir()): true,
+	}
+	preserved[config.inventoryPath()] = true
+	for _, name := range []string{"config.json", localConfigName, ".lock", "ignore", verboseLogName, operationsLogName, operationsLogName + ".1", "templates", "backups", snapshotsDirName, journalDirName} {
+		preserved[filepath.Join(config.ConfigDir, name)] = true
+	}
+	
+	var orphaned []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		
+		// Version control metadata (.git, .gitignore, ...) belongs to the repo, not to a file
+		if preserved[path] || strings.HasPrefix(entry.Name(), ".git") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if referenced[path] {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() && ancestors[path] {
+			return nil
+		}
+		
+		orphaned = append(orphaned, path)
+		if entry.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewConfigError("scan dotfiles directory", config.DotfilesDir, err)
+	}
+	
+	sort.Strings(orphaned)
+	return orphaned, nil
+}
+
+// removeSources deletes orphaned sources, refusing anything outside DotfilesDir
+func removeSources(config *Config, paths []string) error {
+	var multiErr MultiError
+	multiErr.Op = "remove sources"
+	
+	for _, path := range paths {
+		if !isWithinDir(path, config.DotfilesDir) || filepath.Clean(path) == filepath.Clean(config.DotfilesDir) {
+			multiErr.Add(NewConfigError("remove source", path, os.ErrInvalid))
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			multiErr.Add(NewConfigError("remove source", path, err))
+		}
+	}
+	
+	if multiErr.HasErrors() {
+		return &multiErr
+	}
+	
+	return nil
+}
+
+// removeLinks deletes the given symlinks, refusing to touch anything that isn't one
+func removeLinks(paths []string) error {
+	var multiErr MultiError
+	multiErr.Op = "remove links"
+	
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				multiErr.Add(NewConfigError("stat link", path, err))
+			}
+			continue
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			multiErr.Add(NewConfigError("remove link", path, os.ErrInvalid))
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			multiErr.Add(NewConfigError("remove link", path, err))
+		}
+	}
+	
+	if multiErr.HasErrors() {
+		return &multiErr
+	}
+	
+	return nil
+}
+
+// findUnexecutableInGit lists the linked executables (see linkedExecutables)
+// that git would check out without the executable bit, each with how to fix
+// it. Sources that already lack the bit are left to validation.
+func findUnexecutableInGit(config *Config) []string {
+	if !isGitManaged(config.DotfilesDir) {
+		return nil
+	}
+	
+	var problems []string
+	for _, i := range config.linkedExecutables() {
+		file := config.Files[i]
+		sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+		if info, err := os.Stat(sourcePath); err != nil || info.Mode()&0111 == 0 {
+			continue
+		}
+		if problem := gitExecutableProblem(sourcePath); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", file.Source, problem))
+		}
+	}
+	return problems
+}
+
+// gitExecutableProblem explains why git would check out the executable file
+// at path without its executable bit and how to fix it, or returns "" if it
+// wouldn't. Tracked
+// files are checked out with the mode in the index; untracked ones are added
+// with the working tree's mode unless core.fileMode is off.
+func gitExecutableProblem(path string) string {
+	if _, err := runner.LookPath("git"); err != nil {
+		return ""
+	}
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	ctx, cancel := commandContext()
+	defer cancel()
+	
+	output, err := runner.Capture(ctx, "git", "-C", dir, "ls-files", "--stage", "--", name)
+	if err != nil {
+		return ""
+	}
+	if fields := strings.Fields(string(output)); len(fields) > 0 {
+		if fields[0] == "100644" {
+			return "git records it as not executable, so a fresh checkout loses the bit; run git update-index --chmod=+x on it and commit"
+		}
+		return ""
+	}
+	
+	fileMode, err := runner.Capture(ctx, "git", "-C", dir, "config", "--bool", "core.fileMode")
+	if err == nil && strings.TrimSpace(string(fileMode)) == "false" {
+		return "core.fileMode is off in its repository, so git would record it as not executable; add it with git add --chmod=+x"
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateDoesNotRunGit(t *testing.T) {
+	config, home := newTestConfig(t)
+	if err := os.Mkdir(filepath.Join(config.DotfilesDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(config.DotfilesDir, "bin", "deploy")
+	writeTestFile(t, source, "#!/bin/sh\n")
+	if err := os.Chmod(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config.Files = []ConfigFile{
+		{Name: "deploy", Source: "bin/deploy", Target: filepath.Join(home, ".local", "bin", "deploy"), Category: "misc", Perms: "755"},
+	}
+	scripted := &scriptedRunner{Available: map[string]bool{"git": true}}
+	useRunner(t, scripted)
+
+	config.Validate()
+
+	if len(scripted.Calls) != 0 {
+		t.Errorf("Validate ran %q, want no commands", scripted.Calls)
+	}
+
+	scripted.Responses = []scriptedResponse{{Output: "100644 e69de29 0\tdeploy\n"}}
+	problems := findUnexecutableInGit(config)
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "bin/deploy: ") {
+		t.Errorf("findUnexecutableInGit() = %q, want one problem with bin/deploy", problems)
+	}
+}
+
+func TestGitExecutableProblem(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []scriptedResponse
+		wantIssue bool
+	}{
+		{"tracked without exec bit", []scriptedResponse{{Output: "100644 e69de29 0\tdeploy\n"}}, true},
+		{"tracked with exec bit", []scriptedResponse{{Output: "100755 e69de29 0\tdeploy\n"}}, false},
+		{"untracked with fileMode off", []scriptedResponse{{}, {Output: "false\n"}}, true},
+		{"untracked with fileMode on", []scriptedResponse{{}, {Output: "true\n"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRunner(t, &scriptedRunner{Available: map[string]bool{"git": true}, Responses: tt.responses})
+
+			if got := gitExecutableProblem("/dotfiles/bin/deploy"); (got != "") != tt.wantIssue {
+				t.Errorf("gitExecutableProblem() = %q, want an issue: %v", got, tt.wantIssue)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxImportSize caps how much is read from a remote config
+const maxImportSize = 1 << 20
+
+// importFetchTimeout bounds a download or clone of a remote config when no
+// --timeout is given
+const importFetchTimeout = 30 * time.Second
+
+// importTimeout is how long a download or clone of a remote config may take:
+// --timeout when set, like other external tools, otherwise importFetchTimeout
+func importTimeout() time.Duration {
+	if commandTimeout > 0 {
+		return commandTimeout
+	}
+	return importFetchTimeout
+}
+
+// isRemoteImport reports whether ref names a config to download rather than a local file
+func isRemoteImport(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") || isGitImport(ref)
+}
+
+// isGitImport reports whether ref is a git repository to clone: a git:// URL
+// or an http(s) URL ending in .git
+func isGitImport(ref string) bool {
+	if strings.HasPrefix(ref, "git://") {
+		return true
+	}
+	return (strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://")) && strings.HasSuffix(ref, ".git")
+}
+
+// fetchImportData downloads an exported config, or shallow-clones a git
+// repository and reads its config.json. Network failures are ConfigErrors so
+// callers can report them and carry on.
+func fetchImportData(ref string) ([]byte, error) {
+	if isGitImport(ref) {
+		return fetchGitImport(ref)
+	}
+	return fetchHTTPImport(ref)
+}
+
+// fetchHTTPImport downloads a JSON config, refusing HTML pages and anything
+// larger than maxImportSize
+func fetchHTTPImport(url string) ([]byte, error) {
+	client := &http.Client{Timeout: importTimeout()}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, NewConfigError("download config", url, err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewConfigError("download config", url, fmt.Errorf("server returned %s", resp.Status))
+	}
+	
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !isImportMediaType(mediaType) {
+			return nil, NewConfigError("download config", url, fmt.Errorf("unexpected content type %q (expected JSON)", contentType))
+		}
+	}
+	
+	if resp.ContentLength > maxImportSize {
+		return nil, NewConfigError("download config", url, fmt.Errorf("config is %d bytes, more than the %d allowed", resp.ContentLength, maxImportSize))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		return nil, NewConfigError("download config", url, err)
+	}
+	if len(data) > maxImportSize {
+		return nil, NewConfigError("download config", url, fmt.Errorf("config is more than the %d bytes allowed", maxImportSize))
+	}
+	
+	return data, nil
+}
+
+// isImportMediaType accepts JSON and the generic types raw file hosts serve it as
+func isImportMediaType(mediaType string) bool {
+	switch mediaType {
+	case "application/json", "text/json", "text/plain", "application/octet-stream":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json")
+}
+
+// fetchGitImport shallow-clones a repository into a temporary directory and
+// reads the config.json at its root
+func fetchGitImport(url string) ([]byte, error) {
+	if _, err := runner.LookPath("git"); err != nil {
+		return nil, NewConfigError("clone config", url, fmt.Errorf("git not found in PATH"))
+	}
+	
+	cloneDir, err := os.MkdirTemp("", "config-manager-import-")
+	if err != nil {
+		return nil, NewConfigError("clone config", url, err)
+	}
+	defer os.RemoveAll(cloneDir)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), importTimeout())
+	defer cancel()
+	if _, err := runner.Capture(ctx, "git", "clone", "--depth", "1", "--quiet", url, cloneDir); err != nil {
+		if errors.Is(err, errCommandTimeout) {
+			err = fmt.Errorf("git clone %w after %s", errCommandTimeout, importTimeout())
+		}
+		return nil, NewConfigError("clone config", url, err)
+	}
+	
+	configPath := filepath.Join(cloneDir, "config.json")
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return nil, NewConfigError("clone config", url, fmt.Errorf("repository has no config.json"))
+	}
+	if info.Size() > maxImportSize {
+		return nil, NewConfigError("clone config", url, fmt.Errorf("config.json is %d bytes, more than the %d allowed", info.Size(), maxImportSize))
+	}
+	
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, NewConfigError("clone config", url, err)
+	}
+	return data, nil
+}
+
+// previewImport describes what importing data would change, without touching
+// config: the import runs on a copy and the two are diffed
+func previewImport(config *Config, data []byte, mergeMode bool) (string, error) {
+	current, err := config.ExportConfig()
+	if err != nil {
+		return "", NewConfigError("preview import", "", err)
+	}
+	
+	preview, err := parseExportedConfig(current)
+	if err != nil {
+		return "", NewConfigError("preview import", "", err)
+	}
+	preview.ConfigDir = config.ConfigDir
+	preview.DotfilesDir = config.DotfilesDir
+	
+	// Files a merge fails to add are left out of the preview just as they will be from the import
+	if result, err := preview.ImportConfigWithResult(data, mergeMode); err != nil && result == nil {
+		return "", err
+	}
+	
+	after, err := preview.ExportConfig()
+	if err != nil {
+		return "", NewConfigError("preview import", "", err)
+	}
+	return diffConfigs(current, after)
+}
+
+// importFromURL downloads a config from an http(s) or git URL, shows what it
+// would change and imports it into config once confirmed. The config is saved
+// only when the import was applied.
+func importFromURL(config *Config, url string, mergeMode bool) error {
+	infof("%sFetching %s...\n", decoration("🌐"), url)
+	data, err := fetchImportData(url)
+	if err != nil {
+		return err
+	}
+	return confirmAndImport(config, data, url, mergeMode)
+}
+
+// confirmAndImport prints the changes importing data would make, asks for
+// confirmation, then applies and saves them
+func confirmAndImport(config *Config, data []byte, from string, mergeMode bool) error {
+	diff, err := previewImport(config, data, mergeMode)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		infoln(glyphSuccess + " Nothing to import; the config already matches " + from)
+		return nil
+	}
+	
+	fmt.Printf("Importing %s would change:\n%s\n", from, diff)
+	confirmed, err := confirmAction("Apply these changes?")
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		infoln("Import cancelled")
+		return nil
+	}
+	
+	if err := config.ImportConfig(data, mergeMode); err != nil {
+		return err
+	}
+	if err := saveConfigSafe(config); err != nil {
+		return err
+	}
+	
+	infof("%s Imported %s\n", glyphSuccess, from)
+	return nil
+}
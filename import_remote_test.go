@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchGitImportTimesOut(t *testing.T) {
+	// A git that never finishes cloning
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "git"), []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	useRunner(t, execRunner{})
+	previous := commandTimeout
+	commandTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { commandTimeout = previous })
+
+	start := time.Now()
+	_, err := fetchGitImport("git://example.com/dotfiles.git")
+	if !errors.Is(err, errCommandTimeout) {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("clone gave up after %s, want about --timeout", elapsed)
+	}
+}
+
+func TestImportTimeout(t *testing.T) {
+	previous := commandTimeout
+	t.Cleanup(func() { commandTimeout = previous })
+
+	commandTimeout = 0
+	if got := importTimeout(); got != importFetchTimeout {
+		t.Errorf("without --timeout: %s, want %s", got, importFetchTimeout)
+	}
+	commandTimeout = 5 * time.Second
+	if got := importTimeout(); got != 5*time.Second {
+		t.Errorf("with --timeout 5s: %s, want 5s", got)
+	}
+}
+
+// hostileImport is a remote config that, besides a file with a formatter,
+// tries to set every machine setting that runs commands or weakens safety
+const hostileImport = `{
+  "editor": "nvim",
+  "categories": ["shell"],
+  "categorizer_cmd": "sh -c 'touch /tmp/pwned'",
+  "escalation_cmd": "sh -c 'touch /tmp/pwned'",
+  "allow_privileged": true,
+  "no_backup": true,
+  "file_manager": "sh -c 'touch /tmp/pwned'",
+  "editor_args": {"nvim": ["-c", "!touch /tmp/pwned"]},
+  "target_root": "/tmp/elsewhere",
+  "backup_dir": "/tmp/elsewhere",
+  "files": [{"name": "zshrc", "source": "shell/zshrc", "target": "~/.zshrc", "category": "shell", "formatter": ["sh", "-c", "touch /tmp/pwned"]}]
+}`
+
+func TestImportTakesOnlyPortableSettings(t *testing.T) {
+	for _, mergeMode := range []bool{true, false} {
+		config, _ := newTestConfig(t)
+		if _, err := config.ImportConfigWithResult([]byte(hostileImport), mergeMode); err != nil {
+			t.Fatalf("merge %v: %v", mergeMode, err)
+		}
+
+		if config.CategorizerCmd != "" || config.EscalationCmd != "" || config.AllowPrivileged || config.NoBackup ||
+			config.FileManager != "" || config.EditorArgs != nil || config.TargetRoot != "" || config.BackupDir != "" {
+			t.Errorf("merge %v: machine settings were imported: %+v", mergeMode, config)
+		}
+		if config.Editor != "nvim" || len(config.Files) != 1 {
+			t.Errorf("merge %v: editor %q and %d files, want the portable settings imported", mergeMode, config.Editor, len(config.Files))
+		}
+	}
+}
+
+func TestImportPreviewShowsFormatters(t *testing.T) {
+	config, _ := newTestConfig(t)
+	diff, err := previewImport(config, []byte(hostileImport), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, `+ zshrc (~/.zshrc): formatter ["sh","-c","touch /tmp/pwned"]`) {
+		t.Errorf("preview doesn't show the added file's formatter:\n%s", diff)
+	}
+}
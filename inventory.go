@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// inventoryFileName is the shared inventory in the dotfiles directory. Each
+// machine sharing the dotfiles repository keeps its own section up to date.
+const inventoryFileName = "inventory.json"
+
+// inventoryHost is one machine's section of the inventory
+type inventoryHost struct {
+	Updated time.Time       `json:"updated"`
+	Version string          `json:"version"`
+	Files   []inventoryFile `json:"files"`
+}
+
+// inventoryFile is the link status of one managed file on a machine
+type inventoryFile struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Status string `json:"status"`
+}
+
+// inventoryPath is where the shared inventory is kept
+func (c *Config) inventoryPath() string {
+	return filepath.Join(c.DotfilesDir, inventoryFileName)
+}
+
+// fileStatusName describes a file's link status in one word
+func fileStatusName(file ConfigFile) string {
+	switch {
+	case file.Disabled:
+		return "disabled"
+	case file.HasConflict:
+		return "conflict"
+	case file.Drifted:
+		return "drifted"
+	case file.NeedsRelink:
+		return "modified"
+	case file.IsLinked:
+		return "linked"
+	default:
+		return "unlinked"
+	}
+}
+
+// exportInventory returns the shared inventory with this machine's section
+// replaced by the current status of config's files. Sections of other hosts
+// are kept as they are. Statuses should be up to date (updateFileStatuses).
+func exportInventory(config *Config) ([]byte, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, NewConfigError("get hostname", "", err)
+	}
+	
+	inventory := make(map[string]inventoryHost)
+	path := config.inventoryPath()
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &inventory); err != nil {
+			return nil, NewConfigError("parse inventory", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, NewConfigError("read inventory", path, err)
+	}
+	
+	files := make([]inventoryFile, 0, len(config.Files))
+	for _, file := range config.Files {
+		files = append(files, inventoryFile{
+			Name:   file.Name,
+			Target: file.Target,
+			Status: fileStatusName(file),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Target < files[j].Target
+	})
+	
+	inventory[hostname] = inventoryHost{
+		Updated: time.Now().UTC().Truncate(time.Second),
+		Version: version,
+		Files:   files,
+	}
+	
+	// Map keys are marshalled sorted, so hosts keep a stable order in diffs
+	data, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return nil, NewConfigError("marshal inventory", path, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// summarizeInventoryHost counts a host's files by status, e.g. "3 linked, 1 conflict"
+func summarizeInventoryHost(host inventoryHost) string {
+	counts := make(map[string]int)
+	var order []string
+	for _, file := range host.Files {
+		if counts[file.Status] == 0 {
+			order = append(order, file.Status)
+		}
+		counts[file.Status]++
+	}
+	sort.Strings(order)
+	
+	summary := ""
+	for i, status := range order {
+		if i > 0 {
+			summary += ", "
+		}
+		summary += fmt.Sprintf("%d %s", counts[status], status)
+	}
+	if summary == "" {
+		summary = "no files"
+	}
+	return summary
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// journalDirName is the directory under ConfigDir holding one journal per
+// running transaction
+const journalDirName = "journal"
+
+// transactionJournalDir is where transactions record their progress. It is
+// set once the config directory is known; empty disables journaling.
+var transactionJournalDir string
+
+// Journal entry kinds, one per journaled operation type
+const (
+	journalKindLink     = "link"
+	journalKindCopy     = "copy"
+	journalKindUnlink   = "unlink"
+	journalKindTemplate = "template"
+	journalKindRemove   = "remove"
+)
+
+// journalEntry records what one operation did (or was about to do) so it can
+// be undone after a crash
+type journalEntry struct {
+	Kind       string    `json:"kind"`
+	Target     string    `json:"target"`
+	Backup     string    `json:"backup,omitempty"`      // where the previous target was moved
+	BackupStem string    `json:"backup_stem,omitempty"` // backups are named after this path instead of the target (redirected)
+	LinkValue  string    `json:"link_value,omitempty"`  // unlink: the symlink that was removed
+	Existed    bool      `json:"existed"`               // target existed before the operation ran
+	Created    bool      `json:"created"`               // target was created (or for unlink, removed)
+	Done       bool      `json:"done"`                  // Execute returned successfully
+	Started    time.Time `json:"started"`
+}
+
+// transactionJournal is the on-disk record of a transaction in progress
+type transactionJournal struct {
+	ID               string         `json:"id"`
+	BackupTimeFormat string         `json:"backup_time_format,omitempty"` // timestamp of the backups it makes
+	Entries          []journalEntry `json:"entries"`
+}
+
+// journaledOperation is implemented by operations that can be recovered after a crash
+type journaledOperation interface {
+	journalEntry() journalEntry
+}
+
+// journalPath returns the journal file for a transaction, or "" when journaling is off
+func (t *Transaction) journalPath() string {
+	if transactionJournalDir == "" {
+		return ""
+	}
+	return filepath.Join(transactionJournalDir, t.id+".json")
+}
+
+// recordOperation writes op's current state to the journal before (done=false)
+// or after (done=true) it runs
+func (t *Transaction) recordOperation(index int, op Operation, done bool) error {
+	path := t.journalPath()
+	journaled, ok := op.(journaledOperation)
+	if path == "" || !ok {
+		return nil
+	}
+	
+	entry := journaled.journalEntry()
+	entry.Done = done
+	if index < len(t.journal.Entries) {
+		entry.Started = t.journal.Entries[index].Started
+		entry.Existed = t.journal.Entries[index].Existed
+		t.journal.Entries[index] = entry
+	} else {
+		entry.Started = time.Now()
+		entry.Existed = fileExistsNoFollow(entry.Target)
+		if stem, ok := t.backups.redirected(entry.Target); ok {
+			entry.BackupStem = stem
+		}
+		t.journal.Entries = append(t.journal.Entries, entry)
+	}
+	
+	return writeJournal(path, &t.journal)
+}
+
+// clearJournal removes the journal once the transaction has fully completed or
+// been rolled back
+func (t *Transaction) clearJournal() {
+	if path := t.journalPath(); path != "" {
+		os.Remove(path)
+	}
+}
+
+// writeJournal atomically replaces a journal file
+func writeJournal(path string, journal *transactionJournal) error {
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return NewConfigError("encode journal", path, err)
+	}
+	return atomicWrite(path, data, 0644)
+}
+
+// findIncompleteJournals lists journals left behind by interrupted transactions
+func findIncompleteJournals(journalDir string) ([]string, error) {
+	entries, err := os.ReadDir(journalDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewConfigError("read journal directory", journalDir, err)
+	}
+	
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			paths = append(paths, filepath.Join(journalDir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// offerJournalRecovery checks for transactions interrupted by a crash and
+// offers to roll them back. It must run while holding the instance lock.
+func offerJournalRecovery(configDir string) {
+	journalDir := filepath.Join(configDir, journalDirName)
+	paths, err := findIncompleteJournals(journalDir)
+	if err != nil {
+		warnf("Warning: %v\n", err)
+		return
+	}
+	if len(paths) == 0 {
+		return
+	}
+	
+	warnf("%s Found %d interrupted operations from a previous run (see %s)\n", glyphWarning, len(paths), journalDir)
+	confirmed, err := confirmAction("Roll back the interrupted operations and restore backups?")
+	if err != nil || !confirmed {
+		warnf("Leaving them for now; you'll be asked again on the next start.\n")
+		return
+	}
+	
+	failed := 0
+	for _, path := range paths {
+		if err := recoverJournal(path); err != nil {
+			errorf("Failed to roll back %s: %v\n", filepath.Base(path), err)
+			failed++
+		}
+	}
+	if failed == 0 {
+		infof("%s Rolled back %d interrupted operations\n", glyphSuccess, len(paths))
+	}
+}
+
+// recoverJournal undoes a journaled transaction's operations in reverse order
+// and removes the journal once everything was restored
+func recoverJournal(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return NewConfigError("read journal", path, err)
+	}
+	
+	var journal transactionJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return NewConfigError("parse journal", path, err)
+	}
+	
+	var multiErr MultiError
+	multiErr.Op = fmt.Sprintf("recover transaction %s", journal.ID)
+	
+	for i := len(journal.Entries) - 1; i >= 0; i-- {
+		if err := recoverJournalEntry(journal.Entries[i], journal.BackupTimeFormat); err != nil {
+			multiErr.Add(err)
+		}
+	}
+	
+	if multiErr.HasErrors() {
+		return &multiErr
+	}
+	
+	return os.Remove(path)
+}
+
+// recoverJournalEntry undoes a single operation. An entry that never finished
+// may not know its backup yet, so the newest backup made since it started,
+// named with timeFormat, is used.
+func recoverJournalEntry(entry journalEntry, timeFormat string) error {
+	if entry.Kind == journalKindUnlink {
+		if !entry.Created || entry.LinkValue == "" {
+			return nil
+		}
+		// Leaf links are undone already; drop the empty directory left in the link's place
+		if info, err := os.Lstat(entry.Target); err == nil && info.IsDir() {
+			os.Remove(entry.Target)
+		}
+		if _, err := os.Lstat(entry.Target); os.IsNotExist(err) {
+			if err := os.Symlink(entry.LinkValue, entry.Target); err != nil {
+				return NewConfigError("restore symlink", entry.Target, err)
+			}
+		}
+		return nil
+	}
+	
+	backup := entry.Backup
+	if backup == "" && !entry.Done {
+		backup = findStrandedBackup(entry, timeFormat)
+	}
+	
+	// An unfinished operation only touched the target if it didn't exist
+	// beforehand or was already moved to a backup
+	created := entry.Created || (!entry.Done && (!entry.Existed || backup != ""))
+	if created {
+		var err error
+		if entry.Kind == journalKindCopy {
+			err = os.RemoveAll(entry.Target)
+		} else {
+			err = os.Remove(entry.Target)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return NewConfigError("remove "+entry.Kind+" target", entry.Target, err)
+		}
+	}
+	
+	if backup != "" && fileExistsNoFollow(backup) {
+		if err := moveFile(backup, entry.Target); err != nil {
+			return NewConfigError("restore backup", backup, err)
+		}
+	}
+	
+	return nil
+}
+
+// findStrandedBackup returns the newest target.backup.<timestamp> created
+// since entry started, or else the rolling target.backup if it was made
+// since, or "" if there is none. Backups redirected out of the dotfiles and
+// config directories are looked for where they were redirected to. Journals
+// written before the time format was recorded use the default one.
+func findStrandedBackup(entry journalEntry, timeFormat string) string {
+	target, since := entry.Target, entry.Started
+	base := target
+	if entry.BackupStem != "" {
+		base = entry.BackupStem
+	}
+	if timeFormat == "" {
+		timeFormat = defaultBackupTimeFormat
+	}
+	matches, err := filepath.Glob(base + ".backup.*")
+	if err != nil {
+		return ""
+	}
+	
+	newest := ""
+	var newestTime time.Time
+	for _, match := range matches {
+		stamp := strings.TrimPrefix(match, base+".backup.")
+		backupTime, err := parseBackupTimestamp(timeFormat, stamp)
+		if err != nil || backupTime.Before(since.Truncate(time.Second)) {
+			continue
+		}
+		if newest == "" || backupTime.After(newestTime) {
+			newest = match
+			newestTime = backupTime
+		}
+	}
+	if newest != "" {
+		return newest
+	}
+	
+	// A rolling backup has no timestamp. It belongs to the interrupted
+	// operation if the target has been moved away or recreated since it started.
+	rolling := base + rollingBackupSuffix
+	if !fileExistsNoFollow(rolling) {
+		return ""
+	}
+	if info, err := os.Lstat(target); err == nil && info.ModTime().Before(since.Truncate(time.Second)) {
+		return ""
+	}
+	return rolling
+}
+
+// fileExistsNoFollow checks if path exists without following symlinks
+func fileExistsNoFollow(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
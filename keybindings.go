@@ -4,14 +4,40 @@ import "github.com/charmbracelet/bubbles/key"
 
 // Key bindings
 type keyMap struct {
-	Enter   key.Binding
-	Add     key.Binding
-	Remove  key.Binding
-	Link    key.Binding
-	LinkAll key.Binding
-	Edit    key.Binding
-	Backup  key.Binding
-	Quit    key.Binding
+	Enter        key.Binding
+	Add          key.Binding
+	Remove       key.Binding
+	Link         key.Binding
+	LinkAll      key.Binding
+	LinkUnlinked key.Binding
+	Edit         key.Binding
+	EditTarget   key.Binding
+	Sync         key.Binding
+	Backup       key.Binding
+	Validate     key.Binding
+	Variables    key.Binding
+	Snapshots    key.Binding
+	History      key.Binding
+	Import       key.Binding
+	Open         key.Binding
+	Shell        key.Binding
+	Rename       key.Binding
+	Conflicts    key.Binding
+	Describe     key.Binding
+	Tag          key.Binding
+	Disable      key.Binding
+	Collapse     key.Binding
+	CollapseAll  key.Binding
+	MoveUp       key.Binding
+	MoveDown     key.Binding
+	Skip         key.Binding
+	MergeDir     key.Binding
+	Diff         key.Binding
+	Pager        key.Binding
+	Up           key.Binding
+	Down         key.Binding
+	Back         key.Binding
+	Quit         key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
@@ -20,8 +46,8 @@ func (k keyMap) ShortHelp() []key.Binding {
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Enter, k.Add, k.Remove, k.Edit},
-		{k.Link, k.LinkAll, k.Backup, k.Quit},
+		{k.Enter, k.Add, k.Remove, k.Edit, k.EditTarget},
+		{k.Link, k.LinkAll, k.LinkUnlinked, k.Sync, k.Backup, k.Validate, k.Variables, k.Snapshots, k.History, k.Import, k.Open, k.Shell, k.Rename, k.Conflicts, k.Describe, k.Tag, k.Disable, k.Collapse, k.CollapseAll, k.MoveUp, k.MoveDown, k.Quit},
 	}
 }
 
@@ -46,14 +72,118 @@ var keys = keyMap{
 		key.WithKeys("L"),
 		key.WithHelp("L", "link all"),
 	),
+	LinkUnlinked: key.NewBinding(
+		key.WithKeys("u"),
+		key.WithHelp("u", "link unlinked"),
+	),
 	Edit: key.NewBinding(
 		key.WithKeys("e"),
 		key.WithHelp("e", "edit"),
 	),
+	EditTarget: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "edit target"),
+	),
+	Sync: key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "sync to source"),
+	),
 	Backup: key.NewBinding(
 		key.WithKeys("b"),
 		key.WithHelp("b", "backup configs"),
 	),
+	Validate: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "validate"),
+	),
+	Variables: key.NewBinding(
+		key.WithKeys("V"),
+		key.WithHelp("V", "template variables"),
+	),
+	Snapshots: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "snapshots"),
+	),
+	History: key.NewBinding(
+		key.WithKeys("H"),
+		key.WithHelp("H", "operations log"),
+	),
+	Import: key.NewBinding(
+		key.WithKeys("I"),
+		key.WithHelp("I", "import config"),
+	),
+	Open: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "open in file manager"),
+	),
+	Shell: key.NewBinding(
+		key.WithKeys("O"),
+		key.WithHelp("O", "open shell"),
+	),
+	Rename: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "rename/move source"),
+	),
+	Conflicts: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "resolve conflicts"),
+	),
+	Describe: key.NewBinding(
+		key.WithKeys("D"),
+		key.WithHelp("D", "edit description"),
+	),
+	Tag: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "filter by tag"),
+	),
+	Disable: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "disable/enable"),
+	),
+	Collapse: key.NewBinding(
+		key.WithKeys("z"),
+		key.WithHelp("z", "collapse/expand category"),
+	),
+	CollapseAll: key.NewBinding(
+		key.WithKeys("Z"),
+		key.WithHelp("Z", "collapse/expand all"),
+	),
+	MoveUp: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "move file up"),
+	),
+	MoveDown: key.NewBinding(
+		key.WithKeys("J"),
+		key.WithHelp("J", "move file down"),
+	),
+	Skip: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "skip"),
+	),
+	MergeDir: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "merge into source"),
+	),
+	Diff: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "view diff"),
+	),
+	Pager: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "open in pager"),
+	),
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "down"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
 	Quit: key.NewBinding(
 		key.WithKeys("q", "ctrl+c"),
 		key.WithHelp("q", "quit"),
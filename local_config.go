@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// localConfigName is the machine-local overlay read next to config.json. It
+// is meant to stay out of version control while config.json is shared.
+const localConfigName = "config.local.json"
+
+// saveToLocal is set from --local: saves then go to config.local.json, and
+// config.json is left as it is
+var saveToLocal bool
+
+// localOverlay is what config.local.json holds; every field is optional
+type localOverlay struct {
+	Files        []ConfigFile      `json:"files,omitempty"`
+	Variables    map[string]string `json:"global_variables,omitempty"`
+	Categories   []string          `json:"categories,omitempty"`
+	TemplateExts []string          `json:"template_extensions,omitempty"`
+	Editor       string            `json:"editor,omitempty"`
+	Shell        string            `json:"shell,omitempty"`
+}
+
+// overlayState remembers the config.json and config.local.json a config was
+// merged from, so saving can split them apart again
+type overlayState struct {
+	base    *Config
+	overlay *localOverlay
+}
+
+// applyLocalOverlay merges config.local.json from the config's directory into
+// config the way an import is merged, except that the overlay wins: its files
+// replace base files with the same target or name and category, and its
+// variables, editor and shell take precedence. Without an overlay config is
+// unchanged, but the base is still remembered so --local can save one.
+func applyLocalOverlay(config *Config, overlayFile string) error {
+	base := copyConfig(config)
+	state := &overlayState{base: base, overlay: &localOverlay{}}
+	config.local = state
+	
+	data, err := os.ReadFile(overlayFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return NewConfigError("read local config", overlayFile, err)
+	}
+	overlay := &localOverlay{}
+	if err := json.Unmarshal(data, overlay); err != nil {
+		return NewConfigError("parse local config", overlayFile, err)
+	}
+	
+	result, err := config.mergeConfig(&Config{
+		Files:        overlay.Files,
+		Variables:    overlay.Variables,
+		Categories:   overlay.Categories,
+		TemplateExts: overlay.TemplateExts,
+	})
+	if err != nil {
+		return NewConfigError("merge local config", overlayFile, err)
+	}
+	for _, conflict := range result.Conflicts {
+		if err := config.ResolveImportConflict(conflict, true); err != nil {
+			return NewConfigError("merge local config", overlayFile, err)
+		}
+	}
+	for _, skipped := range result.Skipped {
+		if _, clashes := config.findClashingFile(skipped.File); !clashes {
+			warnf("Warning: %s: skipping %s: %s\n", localConfigName, skipped.File.Name, skipped.Reason)
+		}
+	}
+	
+	// mergeConfig only fills in a default editor and shell
+	config.Editor, config.Shell = base.Editor, base.Shell
+	if overlay.Editor != "" {
+		config.Editor = overlay.Editor
+	}
+	if overlay.Shell != "" {
+		config.Shell = overlay.Shell
+	}
+	
+	state.overlay = overlay
+	return nil
+}
+
+// savedBase is what saving writes to config.json: c without what the overlay
+// contributed. Overlay entries that are unchanged are left out, or put back to
+// the base value they replaced; anything changed since loading belongs to the
+// base. shadowed names changed entries the overlay will still override.
+func (c *Config) savedBase() (saved *Config, shadowed []string) {
+	if c.local == nil {
+		return c, nil
+	}
+	base, overlay := c.local.base, c.local.overlay
+	copied := *c
+	saved = &copied
+	
+	overlayFiles := make(map[string]ConfigFile, len(overlay.Files))
+	for _, file := range overlay.Files {
+		overlayFiles[file.Target] = file
+	}
+	saved.Files = make([]ConfigFile, 0, len(c.Files))
+	for _, file := range c.Files {
+		local, fromOverlay := overlayFiles[file.Target]
+		switch {
+		case !fromOverlay:
+			saved.Files = append(saved.Files, file)
+		case reflect.DeepEqual(exportFile(local), exportFile(file)):
+			if original, ok := base.findClashingFile(local); ok {
+				saved.Files = append(saved.Files, original)
+			}
+		default:
+			saved.Files = append(saved.Files, file)
+			shadowed = append(shadowed, file.Name)
+		}
+	}
+	
+	saved.Variables = make(map[string]string, len(c.Variables))
+	for key, value := range c.Variables {
+		local, fromOverlay := overlay.Variables[key]
+		switch {
+		case !fromOverlay:
+			saved.Variables[key] = value
+		case local == value:
+			if original, ok := base.Variables[key]; ok {
+				saved.Variables[key] = original
+			}
+		default:
+			saved.Variables[key] = value
+			shadowed = append(shadowed, "global variable "+key)
+		}
+	}
+	
+	saved.Categories = withoutAdded(c.Categories, overlay.Categories, base.Categories)
+	saved.TemplateExts = withoutAdded(c.TemplateExts, overlay.TemplateExts, base.TemplateExts)
+	if overlay.Editor != "" && c.Editor == overlay.Editor {
+		saved.Editor = base.Editor
+	}
+	if overlay.Shell != "" && c.Shell == overlay.Shell {
+		saved.Shell = base.Shell
+	}
+	return saved, shadowed
+}
+
+// savedLocal is what saving with --local writes to config.local.json:
+// everything that differs from the base config.json. Removing a file or
+// variable the base defines can't be expressed in an overlay.
+func (c *Config) savedLocal() *localOverlay {
+	base := c.local.base
+	local := &localOverlay{Variables: make(map[string]string)}
+	
+	for _, file := range c.Files {
+		original, err := base.GetConfigFileByTarget(file.Target)
+		if err != nil || !reflect.DeepEqual(exportFile(*original), exportFile(file)) {
+			local.Files = append(local.Files, file)
+		}
+	}
+	for key, value := range c.Variables {
+		if original, ok := base.Variables[key]; !ok || original != value {
+			local.Variables[key] = value
+		}
+	}
+	local.Categories = missingFrom(c.Categories, base.Categories)
+	local.TemplateExts = missingFrom(c.TemplateExts, base.TemplateExts)
+	if c.Editor != base.Editor {
+		local.Editor = c.Editor
+	}
+	if c.Shell != base.Shell {
+		local.Shell = c.Shell
+	}
+	return local
+}
+
+// withoutAdded returns values minus the entries of added that base doesn't have
+func withoutAdded(values, added, base []string) []string {
+	return missingFrom(values, missingFrom(added, base))
+}
+
+// missingFrom returns the entries of values that other doesn't have
+func missingFrom(values, other []string) []string {
+	skip := make(map[string]bool, len(other))
+	for _, value := range other {
+		skip[value] = true
+	}
+	
+	var kept []string
+	for _, value := range values {
+		if !skip[value] {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+// copyConfig returns a deep copy of config's persisted fields
+func copyConfig(config *Config) *Config {
+	copied := &Config{}
+	if data, err := json.Marshal(config); err == nil {
+		json.Unmarshal(data, copied)
+	}
+	return copied
+}
+
+// localConfigPath is where config's overlay is read from and saved to
+func (c *Config) localConfigPath() string {
+	return filepath.Join(c.ConfigDir, localConfigName)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readJSONFile decodes the JSON file at path into out
+func readJSONFile(t *testing.T, path string, out interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeJSONFile encodes value as JSON into path
+func writeJSONFile(t *testing.T, path string, value interface{}) {
+	t.Helper()
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, path, string(data))
+}
+
+func TestLocalOverlaySaveReloadRoundTrip(t *testing.T) {
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "shell", "zshrc"), "")
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "git", "gitconfig"), "")
+	config.Files = []ConfigFile{{Name: "zshrc", Source: "shell/zshrc", Target: filepath.Join(home, ".zshrc"), Category: "shell"}}
+	if err := saveConfigSafe(config); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(config.ConfigDir, "config.json")
+	writeJSONFile(t, config.localConfigPath(), localOverlay{
+		Files:     []ConfigFile{{Name: "gitconfig", Source: "git/gitconfig", Target: filepath.Join(home, ".gitconfig"), Category: "git"}},
+		Variables: map[string]string{"host": "laptop"},
+		Editor:    "nvim",
+	})
+
+	loaded, err := loadConfigFile(configFile, config.ConfigDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Editor != "nvim" || len(loaded.Files) != 2 || loaded.Variables["host"] != "laptop" {
+		t.Fatalf("overlay not merged: editor %q, %d files, variables %v", loaded.Editor, len(loaded.Files), loaded.Variables)
+	}
+
+	// A plain save keeps the overlay's contributions out of config.json
+	loaded.Variables["theme"] = "dark"
+	if err := saveConfigSafe(loaded); err != nil {
+		t.Fatal(err)
+	}
+	var base Config
+	readJSONFile(t, configFile, &base)
+	if base.Editor != "vim" || len(base.Files) != 1 || base.Variables["host"] != "" || base.Variables["theme"] != "dark" {
+		t.Errorf("config.json: editor %q, %d files, variables %v; want only the base plus theme", base.Editor, len(base.Files), base.Variables)
+	}
+
+	// A --local save writes only what differs from config.json
+	previous := saveToLocal
+	saveToLocal = true
+	t.Cleanup(func() { saveToLocal = previous })
+	reloaded, err := loadConfigFile(configFile, config.ConfigDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.Shell = "zsh"
+	if err := saveConfigSafe(reloaded); err != nil {
+		t.Fatal(err)
+	}
+	var overlay localOverlay
+	readJSONFile(t, config.localConfigPath(), &overlay)
+	if overlay.Shell != "zsh" || overlay.Editor != "nvim" || len(overlay.Files) != 1 || overlay.Variables["host"] != "laptop" || overlay.Variables["theme"] != "" {
+		t.Errorf("config.local.json: %+v; want the overlay plus the new shell", overlay)
+	}
+	readJSONFile(t, configFile, &base)
+	if base.Shell != "bash" {
+		t.Errorf("config.json shell = %q after a --local save, want it unchanged", base.Shell)
+	}
+
+	final, err := loadConfigFile(configFile, config.ConfigDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.Editor != "nvim" || final.Shell != "zsh" || len(final.Files) != 2 || final.Variables["theme"] != "dark" {
+		t.Errorf("reloaded: editor %q, shell %q, %d files, variables %v", final.Editor, final.Shell, len(final.Files), final.Variables)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// instanceLock is an advisory lock on ConfigDir/.lock that prevents two
+// instances from racing on saveConfigSafe and clobbering config.json
+type instanceLock struct {
+	path string
+	file *os.File
+}
+
+// acquireInstanceLock takes the lock for configDir or explains who holds it
+func acquireInstanceLock(configDir string) (*instanceLock, error) {
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, NewConfigError("create config directory", configDir, err)
+	}
+	
+	lockPath := filepath.Join(configDir, ".lock")
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, NewConfigError("open lock file", lockPath, err)
+	}
+	
+	holder := readLockPID(lockPath)
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, NewConfigError("acquire lock", lockPath, lockHeldError(holder))
+	}
+	
+	// Without flock support the PID is all we have; a dead holder means a stale lock
+	if !flockSupported && holder != 0 && holder != os.Getpid() && processAlive(holder) {
+		file.Close()
+		return nil, NewConfigError("acquire lock", lockPath, lockHeldError(holder))
+	}
+	
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	
+	return &instanceLock{path: lockPath, file: file}, nil
+}
+
+// Release drops the lock. It is safe to call more than once.
+func (l *instanceLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	
+	l.file.Truncate(0)
+	unlockFile(l.file)
+	err := l.file.Close()
+	l.file = nil
+	
+	if err != nil {
+		return NewConfigError("release lock", l.path, err)
+	}
+	return nil
+}
+
+// readLockPID returns the PID recorded in the lock file, or 0 if none
+func readLockPID(lockPath string) int {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// lockHeldError describes the instance holding the lock
+func lockHeldError(pid int) error {
+	if pid != 0 {
+		return fmt.Errorf("another config-manager instance (PID %d) is already running", pid)
+	}
+	return fmt.Errorf("another config-manager instance is already running")
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// flockSupported reports whether lockFile provides real advisory locking
+const flockSupported = false
+
+// lockFile is a no-op; acquireInstanceLock falls back to PID liveness checks
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op counterpart to lockFile
+func unlockFile(file *os.File) error {
+	return nil
+}
+
+// processAlive reports whether a process with pid exists
+func processAlive(pid int) bool {
+	_, err := os.FindProcess(pid)
+	return err == nil
+}
@@ -1,16 +1,109 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"os"
+	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// version is reported in the machine inventory; release builds set it with
+// -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error running program: %v", err)
+	configFlag := flag.String("config", "", "use an alternate config directory (overrides $"+configHomeEnv+")")
+	backupDirFlag := flag.String("backup-dir", "", "store backups in this directory instead of the configured location")
+	flag.BoolVar(&quietOutput, "quiet", false, "suppress informational output (errors are still printed to stderr)")
+	noColorFlag := flag.Bool("no-color", false, "disable colors and use ASCII status markers (also enabled by $NO_COLOR)")
+	noSummaryFlag := flag.Bool("no-summary", false, "don't list unlinked and conflicted files when the TUI exits")
+	targetRootFlag := flag.String("target-root", "", "place every target under this directory instead of its real location, e.g. to try changes in a sandbox")
+	flag.BoolVar(&noBackupOverride, "no-backup", false, "delete targets that linking replaces instead of backing them up (for disposable environments; rollback can't restore them)")
+	flag.BoolVar(&saveToLocal, "local", false, "save changes to "+localConfigName+" instead of config.json, leaving the shared config untouched")
+	flag.StringVar(&editorOverride, "editor", "", "open files in this editor for this run instead of $VISUAL, $EDITOR or the configured one")
+	flag.DurationVar(&commandTimeout, "timeout", 0, "give up on gum prompts and external tools such as diff after `duration`, e.g. 30s, so unattended runs can't hang (0 waits forever, or 30s for remote imports; editors are never timed out)")
+	flag.IntVar(&parallelismOverride, "parallel", 0, "link up to `N` files at once in link-all (1 links one after another; conflicts aren't asked about when N > 1)")
+	verboseFlag := flag.Bool("verbose", false, "trace conflict detection and transactions (stderr for commands, "+verboseLogName+" in the config directory for the TUI)")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	if noColorRequested(*noColorFlag) {
+		usePlainOutput()
+	}
+
+	if *backupDirFlag != "" {
+		backupDirOverride = absPath(*backupDirFlag)
+	}
+	if *targetRootFlag != "" {
+		targetRootOverride = absPath(*targetRootFlag)
+	}
+	if parallelismOverride < 0 {
+		errorf("Error: --parallel must not be negative\n")
+		os.Exit(2)
+	}
+	if commandTimeout < 0 {
+		errorf("Error: --timeout must not be negative\n")
+		os.Exit(2)
+	}
+	if fields := strings.Fields(editorOverride); len(fields) > 0 {
+		if err := checkEditorCommand(fields[0], "--editor"); err != nil {
+			errorf("Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	// Targets, discovery and the default config directory are all relative to
+	// the home directory; guessing one would put files in the wrong place
+	if _, err := mustHome(); err != nil {
+		errorf("Error: %v (set $HOME and try again)\n", err)
+		os.Exit(1)
+	}
+
+	configDir := resolveConfigDir(*configFlag)
+	transactionJournalDir = filepath.Join(configDir, journalDirName)
+	operationsLogPath = filepath.Join(configDir, operationsLogName)
+
+	// Subcommands run without the TUI
+	if flag.NArg() > 0 {
+		if *verboseFlag {
+			logger = writerLogger{w: os.Stderr}
+		}
+		os.Exit(runCommand(configDir, flag.Args()))
+	}
+
+	// The TUI owns the terminal, so tracing goes to a file instead
+	if *verboseFlag {
+		logFile, err := openVerboseLog(configDir)
+		if err != nil {
+			errorf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer logFile.Close()
+		logger = writerLogger{w: logFile}
+	}
+
+	lock, err := acquireInstanceLock(configDir)
+	if err != nil {
+		errorf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	// Deferred so the lock is also released while unwinding a panic
+	defer lock.Release()
+
+	offerJournalRecovery(configDir)
+
+	p := tea.NewProgram(initialModel(configDir), tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		errorf("Error running program: %v\n", err)
+		lock.Release()
+		os.Exit(1)
+	}
+
+	// Printed after the alternate screen is gone so it stays visible
+	if !*noSummaryFlag {
+		printExitSummary(final)
+	}
 }
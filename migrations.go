@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// currentSchemaVersion is the config.json schema this binary reads and writes
+const currentSchemaVersion = 1
+
+// errSchemaTooNew is returned for configs written by a newer config-manager
+var errSchemaTooNew = errors.New("config schema is newer than this version of config-manager supports")
+
+// configMigration upgrades raw config JSON from one schema version to the next
+type configMigration struct {
+	from        int
+	description string
+	migrate     func(raw map[string]json.RawMessage) error
+}
+
+// configMigrations is the registry of upgrades, applied in order. Append a new
+// entry (and bump currentSchemaVersion) whenever the config format changes.
+var configMigrations = []configMigration{
+	{
+		from:        0,
+		description: "introduce schema_version",
+		migrate: func(raw map[string]json.RawMessage) error {
+			// Unversioned configs already match version 1; later defaults are
+			// backfilled by loadConfigFile
+			return nil
+		},
+	},
+}
+
+// migrateConfigData upgrades config JSON to currentSchemaVersion, rejecting
+// configs newer than this binary understands
+func migrateConfigData(data []byte) ([]byte, error) {
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	
+	version := 0
+	if rawVersion, ok := raw["schema_version"]; ok {
+		if err := json.Unmarshal(rawVersion, &version); err != nil {
+			return nil, fmt.Errorf("invalid schema_version: %v", err)
+		}
+	}
+	
+	if version > currentSchemaVersion {
+		return nil, fmt.Errorf("%w (config is version %d, supported up to %d); please upgrade config-manager", 
+			errSchemaTooNew, version, currentSchemaVersion)
+	}
+	if version == currentSchemaVersion {
+		return data, nil
+	}
+	
+	for _, migration := range configMigrations {
+		if migration.from < version {
+			continue
+		}
+		if err := migration.migrate(raw); err != nil {
+			return nil, fmt.Errorf("migrate config from version %d (%s): %v", 
+				migration.from, migration.description, err)
+		}
+		version = migration.from + 1
+	}
+	
+	if version != currentSchemaVersion {
+		return nil, fmt.Errorf("no migration path from schema version %d to %d", version, currentSchemaVersion)
+	}
+	
+	versionData, _ := json.Marshal(currentSchemaVersion)
+	raw["schema_version"] = versionData
+	
+	return json.Marshal(raw)
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,19 +22,36 @@ type Transaction struct {
 	operations []Operation
 	executed   []Operation // Successfully executed operations (for rollback)
 	id         string
+	journal    transactionJournal // on-disk progress for crash recovery
+	backups    *backupPolicy      // how operations back up what they replace
 }
 
-// NewTransaction creates a new transaction
-func NewTransaction() *Transaction {
+// transactionCount numbers transactions so ids stay unique when parallel
+// link-all creates several in the same instant
+var transactionCount atomic.Int64
+
+// NewTransaction creates a new transaction backing up replaced targets the
+// way config says
+func NewTransaction(config *Config) *Transaction {
 	return &Transaction{
 		operations: make([]Operation, 0),
 		executed:   make([]Operation, 0),
-		id:         fmt.Sprintf("tx_%d", time.Now().Unix()),
+		id:         fmt.Sprintf("tx_%d_%d", time.Now().UnixNano(), transactionCount.Add(1)),
+		backups:    config.backupPolicy(),
 	}
 }
 
-// AddOperation adds an operation to the transaction
+// backupTaker is implemented by operations that back up what they replace
+type backupTaker interface {
+	useBackups(policy *backupPolicy)
+}
+
+// AddOperation adds an operation to the transaction, handing it the
+// transaction's backup policy
 func (t *Transaction) AddOperation(op Operation) {
+	if taker, ok := op.(backupTaker); ok {
+		taker.useBackups(t.backups)
+	}
 	t.operations = append(t.operations, op)
 }
 
@@ -41,15 +60,40 @@ func (t *Transaction) AddOperation(op Operation) {
 func (t *Transaction) Execute() error {
 	var multiErr MultiError
 	multiErr.Op = fmt.Sprintf("transaction %s", t.id)
+	t.journal = transactionJournal{ID: t.id, BackupTimeFormat: t.backups.timeFormat}
 	
 	for i, op := range t.operations {
-		if err := op.Execute(); err != nil {
+		// Journal the operation before touching anything so a crash can be undone
+		if err := t.recordOperation(i, op, false); err != nil {
+			if rollbackErr := t.rollback(); rollbackErr != nil {
+				logTransaction(t, operationsLogRollbackFailed, i, rollbackErr)
+				return rollbackErr
+			}
+			t.clearJournal()
+			logTransaction(t, operationsLogRolledBack, i, err)
+			return NewConfigError("write journal", t.journalPath(), err)
+		}
+		
+		logger.Debugf("%s: %s", t.id, op.Description())
+		err := executeWithRetry(op)
+		if err == nil {
+			err = t.recordOperation(i, op, true)
+		}
+		if err != nil {
+			// Roll this operation back too: it may have got as far as moving
+			// the target to its backup, or the journal can't vouch for it
+			t.executed = append(t.executed, op)
 			// Operation failed, rollback all previous operations
+			logger.Debugf("%s: operation %d failed, rolling back: %v", t.id, i, err)
 			rollbackErr := t.rollback()
 			if rollbackErr != nil {
+				// Keep the journal so the stranded operations can be recovered later
 				multiErr.Add(fmt.Errorf("operation %d failed: %v; rollback also failed: %v", i, err, rollbackErr))
+				logTransaction(t, operationsLogRollbackFailed, i, err)
 			} else {
+				t.clearJournal()
 				multiErr.Add(fmt.Errorf("operation %d failed: %v (rolled back successfully)", i, err))
+				logTransaction(t, operationsLogRolledBack, i, err)
 			}
 			
 			if multiErr.HasErrors() {
@@ -62,6 +106,8 @@ func (t *Transaction) Execute() error {
 		t.executed = append(t.executed, op)
 	}
 	
+	t.clearJournal()
+	logTransaction(t, operationsLogCommitted, -1, nil)
 	return nil
 }
 
@@ -107,6 +153,11 @@ type LinkOperation struct {
 	backupPath string
 	created    bool
 	backed     bool
+	relative   bool // link with a path relative to the target's directory
+	escalation []string    // command to retry through on permission errors; nil disables
+	askFirst   bool        // the user can be asked before escalating; never escalate otherwise
+	ops        linkFileOps // how the changes were made; privileged once escalated
+	backups    *backupPolicy
 	file       *ConfigFile
 }
 
@@ -115,49 +166,108 @@ func NewLinkOperation(sourcePath, targetPath string, file *ConfigFile) *LinkOper
 	return &LinkOperation{
 		sourcePath: sourcePath,
 		targetPath: targetPath,
+		backups:    defaultBackupPolicy(),
 		file:       file,
 	}
 }
 
+func (op *LinkOperation) useBackups(policy *backupPolicy) { op.backups = policy }
+
 func (op *LinkOperation) Execute() error {
+	// Nothing to do if the target already links to our source (mirrors detectConflict)
+	if linkTarget, err := os.Readlink(op.targetPath); err == nil && 
+		sameLinkDestination(resolveLinkTarget(op.targetPath, linkTarget), op.sourcePath) {
+		return nil
+	}
+	
+	err := op.apply(op.fileOps())
+	if err != nil && shouldEscalate(err, op.escalation, op.askFirst, op.targetPath) {
+		op.ops = privilegedFileOps{command: op.escalation}
+		err = op.apply(op.ops)
+	}
+	return err
+}
+
+// useEscalation lets the link retry through config's escalation command, if
+// any, once the user agrees
+func (op *LinkOperation) useEscalation(config *Config) {
+	op.escalation = config.escalationCommand()
+	op.askFirst = config.canPrompt()
+}
+
+// fileOps returns how this operation changes the filesystem
+func (op *LinkOperation) fileOps() linkFileOps {
+	if op.ops == nil {
+		return localFileOps{}
+	}
+	return op.ops
+}
+
+// apply makes whichever changes are still outstanding, so a privileged retry
+// picks up where a failed attempt stopped
+func (op *LinkOperation) apply(ops linkFileOps) error {
 	// Check if target already exists
-	if _, err := os.Lstat(op.targetPath); err == nil {
+	if _, err := os.Lstat(op.targetPath); err == nil && op.backups.skip {
+		if err := discardTarget(op.targetPath, ops.RemoveAll); err != nil {
+			return NewConfigError("remove existing file", op.targetPath, permissionHint(err, op.escalation, op.askFirst))
+		}
+	} else if err == nil && !op.backed {
 		// Target exists, create backup
-		op.backupPath = op.targetPath + ".backup." + time.Now().Format("20060102-150405")
-		if err := os.Rename(op.targetPath, op.backupPath); err != nil {
-			return NewConfigError("backup existing file", op.targetPath, err)
+		op.backupPath = op.backups.targetPath(op.targetPath)
+		if err := op.backups.makeRoom(op.backupPath, ops.RemoveAll); err != nil {
+			return NewConfigError("prepare backup", op.backupPath, permissionHint(err, op.escalation, op.askFirst))
+		}
+		if err := ops.Rename(op.targetPath, op.backupPath); err != nil {
+			return NewConfigError("backup existing file", op.targetPath, permissionHint(err, op.escalation, op.askFirst))
 		}
 		op.backed = true
 	}
 	
 	// Ensure target directory exists
-	if err := os.MkdirAll(filepath.Dir(op.targetPath), 0755); err != nil {
-		return NewConfigError("create target directory", filepath.Dir(op.targetPath), err)
+	if err := ops.MkdirAll(filepath.Dir(op.targetPath)); err != nil {
+		return NewConfigError("create target directory", filepath.Dir(op.targetPath), permissionHint(err, op.escalation, op.askFirst))
 	}
 	
 	// Create symlink
-	if err := os.Symlink(op.sourcePath, op.targetPath); err != nil {
-		return NewConfigError("create symlink", op.targetPath, err)
+	if err := ops.Symlink(op.linkValue(), op.targetPath); err != nil {
+		return NewConfigError("create symlink", op.targetPath, permissionHint(err, op.escalation, op.askFirst))
 	}
 	
 	op.created = true
 	return nil
 }
 
+// linkValue is what the symlink stores: the absolute source, or a path relative
+// to the target's directory for portable dotfiles repos
+func (op *LinkOperation) linkValue() string {
+	if !op.relative {
+		return op.sourcePath
+	}
+	
+	relPath, err := filepath.Rel(filepath.Dir(op.targetPath), op.sourcePath)
+	if err != nil {
+		return op.sourcePath
+	}
+	return relPath
+}
+
 func (op *LinkOperation) Rollback() error {
 	var multiErr MultiError
 	multiErr.Op = "rollback link operation"
 	
+	// Undo with the same privileges the changes were made with
+	ops := op.fileOps()
+	
 	// Remove symlink if we created it
 	if op.created {
-		if err := os.Remove(op.targetPath); err != nil && !os.IsNotExist(err) {
+		if err := ops.Remove(op.targetPath); err != nil && !os.IsNotExist(err) {
 			multiErr.Add(NewConfigError("remove symlink", op.targetPath, err))
 		}
 	}
 	
 	// Restore backup if we created one
 	if op.backed && op.backupPath != "" {
-		if err := os.Rename(op.backupPath, op.targetPath); err != nil {
+		if err := ops.Rename(op.backupPath, op.targetPath); err != nil {
 			multiErr.Add(NewConfigError("restore backup", op.backupPath, err))
 		}
 	}
@@ -169,6 +279,10 @@ func (op *LinkOperation) Rollback() error {
 	return nil
 }
 
+func (op *LinkOperation) journalEntry() journalEntry {
+	return journalEntry{Kind: journalKindLink, Target: op.targetPath, Backup: op.backupPath, Created: op.created}
+}
+
 func (op *LinkOperation) Description() string {
 	return fmt.Sprintf("link %s -> %s", op.targetPath, op.sourcePath)
 }
@@ -180,6 +294,195 @@ func (op *LinkOperation) GetFile() string {
 	return filepath.Base(op.targetPath)
 }
 
+// UnlinkOperation removes an existing symlink, restoring it on rollback
+type UnlinkOperation struct {
+	targetPath string
+	linkValue  string
+	removed    bool
+	file       *ConfigFile
+}
+
+// NewUnlinkOperation creates a new unlink operation
+func NewUnlinkOperation(targetPath string, file *ConfigFile) *UnlinkOperation {
+	return &UnlinkOperation{
+		targetPath: targetPath,
+		file:       file,
+	}
+}
+
+func (op *UnlinkOperation) Execute() error {
+	linkValue, err := os.Readlink(op.targetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return NewConfigError("read symlink", op.targetPath, err)
+	}
+	
+	if err := os.Remove(op.targetPath); err != nil {
+		return NewConfigError("remove symlink", op.targetPath, err)
+	}
+	
+	op.linkValue = linkValue
+	op.removed = true
+	return nil
+}
+
+func (op *UnlinkOperation) Rollback() error {
+	if !op.removed {
+		return nil
+	}
+	
+	// Leaf links created after us are already rolled back; drop the now-empty
+	// directory left in the symlink's place
+	if info, err := os.Lstat(op.targetPath); err == nil && info.IsDir() {
+		os.Remove(op.targetPath)
+	}
+	
+	if err := os.Symlink(op.linkValue, op.targetPath); err != nil {
+		return NewConfigError("restore symlink", op.targetPath, err)
+	}
+	
+	return nil
+}
+
+func (op *UnlinkOperation) journalEntry() journalEntry {
+	linkValue := op.linkValue
+	if linkValue == "" {
+		// Not run yet - record the link it is about to remove
+		linkValue, _ = os.Readlink(op.targetPath)
+	}
+	return journalEntry{Kind: journalKindUnlink, Target: op.targetPath, LinkValue: linkValue, Created: op.removed}
+}
+
+func (op *UnlinkOperation) Description() string {
+	return fmt.Sprintf("unlink %s", op.targetPath)
+}
+
+func (op *UnlinkOperation) GetFile() string {
+	if op.file != nil {
+		return op.file.Name
+	}
+	return filepath.Base(op.targetPath)
+}
+
+// RemoveOperation moves a target out of the way, restoring it on rollback
+type RemoveOperation struct {
+	targetPath string
+	backupPath string
+	backed     bool
+	backups    *backupPolicy
+	file       *ConfigFile
+}
+
+// NewRemoveOperation creates a new remove operation
+func NewRemoveOperation(targetPath string, file *ConfigFile) *RemoveOperation {
+	return &RemoveOperation{
+		targetPath: targetPath,
+		backups:    defaultBackupPolicy(),
+		file:       file,
+	}
+}
+
+func (op *RemoveOperation) useBackups(policy *backupPolicy) { op.backups = policy }
+
+func (op *RemoveOperation) Execute() error {
+	if _, err := os.Lstat(op.targetPath); os.IsNotExist(err) {
+		return nil
+	}
+	
+	// Keep the removed target as a backup rather than deleting it
+	op.backupPath = op.backups.targetPath(op.targetPath)
+	if err := op.backups.makeRoom(op.backupPath, os.RemoveAll); err != nil {
+		return NewConfigError("prepare backup", op.backupPath, err)
+	}
+	if err := moveFile(op.targetPath, op.backupPath); err != nil {
+		return NewConfigError("backup existing file", op.targetPath, err)
+	}
+	op.backed = true
+	return nil
+}
+
+func (op *RemoveOperation) Rollback() error {
+	if !op.backed {
+		return nil
+	}
+	
+	if err := moveFile(op.backupPath, op.targetPath); err != nil {
+		return NewConfigError("restore backup", op.backupPath, err)
+	}
+	return nil
+}
+
+func (op *RemoveOperation) journalEntry() journalEntry {
+	return journalEntry{Kind: journalKindRemove, Target: op.targetPath, Backup: op.backupPath}
+}
+
+func (op *RemoveOperation) Description() string {
+	return fmt.Sprintf("remove %s", op.targetPath)
+}
+
+func (op *RemoveOperation) GetFile() string {
+	if op.file != nil {
+		return op.file.Name
+	}
+	return filepath.Base(op.targetPath)
+}
+
+// MoveOperation moves a file or directory to a new path, moving it back on rollback
+type MoveOperation struct {
+	sourcePath string
+	targetPath string
+	moved      bool
+	file       *ConfigFile
+}
+
+// NewMoveOperation creates a new move operation
+func NewMoveOperation(sourcePath, targetPath string, file *ConfigFile) *MoveOperation {
+	return &MoveOperation{
+		sourcePath: sourcePath,
+		targetPath: targetPath,
+		file:       file,
+	}
+}
+
+func (op *MoveOperation) Execute() error {
+	if fileExistsNoFollow(op.targetPath) {
+		return NewConfigError("move", op.targetPath, fmt.Errorf("destination already exists"))
+	}
+	if err := ensureDir(filepath.Dir(op.targetPath)); err != nil {
+		return err
+	}
+	
+	if err := moveFile(op.sourcePath, op.targetPath); err != nil {
+		return NewConfigError("move", op.sourcePath, err)
+	}
+	op.moved = true
+	return nil
+}
+
+func (op *MoveOperation) Rollback() error {
+	if !op.moved {
+		return nil
+	}
+	
+	if err := moveFile(op.targetPath, op.sourcePath); err != nil {
+		return NewConfigError("move back", op.targetPath, err)
+	}
+	return nil
+}
+
+func (op *MoveOperation) Description() string {
+	return fmt.Sprintf("move %s -> %s", op.sourcePath, op.targetPath)
+}
+
+func (op *MoveOperation) GetFile() string {
+	if op.file != nil {
+		return op.file.Name
+	}
+	return filepath.Base(op.sourcePath)
+}
+
 // CopyOperation handles copying files/directories with backup
 type CopyOperation struct {
 	sourcePath string
@@ -188,6 +491,9 @@ type CopyOperation struct {
 	copied     bool
 	backed     bool
 	isDir      bool
+	linkValue  string // when set, create a symlink with this value instead of copying
+	exclude    []string // directory copies skip paths matching these patterns
+	backups    *backupPolicy
 	file       *ConfigFile
 }
 
@@ -202,23 +508,33 @@ func NewCopyOperation(sourcePath, targetPath string, file *ConfigFile) *CopyOper
 		sourcePath: sourcePath,
 		targetPath: targetPath,
 		isDir:      isDir,
+		backups:    defaultBackupPolicy(),
 		file:       file,
 	}
 }
 
+func (op *CopyOperation) useBackups(policy *backupPolicy) { op.backups = policy }
+
 func (op *CopyOperation) Execute() error {
 	// Check if target already exists
-	if _, err := os.Lstat(op.targetPath); err == nil {
+	if _, err := os.Lstat(op.targetPath); err == nil && op.backups.skip {
+		if err := discardTarget(op.targetPath, os.RemoveAll); err != nil {
+			return NewConfigError("remove existing file", op.targetPath, err)
+		}
+	} else if err == nil {
 		// Target exists, create backup
-		op.backupPath = op.targetPath + ".backup." + time.Now().Format("20060102-150405")
-		if err := os.Rename(op.targetPath, op.backupPath); err != nil {
+		op.backupPath = op.backups.targetPath(op.targetPath)
+		if err := op.backups.makeRoom(op.backupPath, os.RemoveAll); err != nil {
+			return NewConfigError("prepare backup", op.backupPath, err)
+		}
+		if err := moveFile(op.targetPath, op.backupPath); err != nil {
 			return NewConfigError("backup existing file", op.targetPath, err)
 		}
 		op.backed = true
 	}
 	
 	// Ensure target directory exists
-	if err := os.MkdirAll(filepath.Dir(op.targetPath), 0755); err != nil {
+	if err := mkdirAllSerialized(filepath.Dir(op.targetPath)); err != nil {
 		return NewConfigError("create target directory", filepath.Dir(op.targetPath), err)
 	}
 	
@@ -227,17 +543,26 @@ func (op *CopyOperation) Execute() error {
 		// Create a basic config file
 		basicContent := fmt.Sprintf("# %s configuration\n# Generated by config-manager\n# Please customize as needed\n", 
 			filepath.Base(op.targetPath))
-		if err := os.WriteFile(op.targetPath, []byte(basicContent), 0644); err != nil {
+		if err := writeGeneratedFile(op.file, op.targetPath, []byte(basicContent)); err != nil {
 			return NewConfigError("create basic file", op.targetPath, err)
 		}
 		op.copied = true
 		return nil
 	}
 	
+	// Recreate a symlink rather than copying what it points to
+	if op.linkValue != "" {
+		if err := os.Symlink(op.linkValue, op.targetPath); err != nil {
+			return NewConfigError("create symlink", op.targetPath, err)
+		}
+		op.copied = true
+		return nil
+	}
+	
 	// Copy file or directory
 	var err error
 	if op.isDir {
-		err = copyDirectory(op.sourcePath, op.targetPath)
+		err = copyDirectoryExcluding(op.sourcePath, op.targetPath, op.exclude)
 	} else {
 		err = copyFile(op.sourcePath, op.targetPath)
 	}
@@ -247,7 +572,12 @@ func (op *CopyOperation) Execute() error {
 	}
 	
 	op.copied = true
-	return nil
+	
+	// Directories keep their per-file modes
+	if op.isDir {
+		return nil
+	}
+	return applyPerms(op.file, op.targetPath)
 }
 
 func (op *CopyOperation) Rollback() error {
@@ -263,7 +593,7 @@ func (op *CopyOperation) Rollback() error {
 	
 	// Restore backup if we created one
 	if op.backed && op.backupPath != "" {
-		if err := os.Rename(op.backupPath, op.targetPath); err != nil {
+		if err := moveFile(op.backupPath, op.targetPath); err != nil {
 			multiErr.Add(NewConfigError("restore backup", op.backupPath, err))
 		}
 	}
@@ -275,7 +605,14 @@ func (op *CopyOperation) Rollback() error {
 	return nil
 }
 
+func (op *CopyOperation) journalEntry() journalEntry {
+	return journalEntry{Kind: journalKindCopy, Target: op.targetPath, Backup: op.backupPath, Created: op.copied}
+}
+
 func (op *CopyOperation) Description() string {
+	if op.linkValue != "" {
+		return fmt.Sprintf("recreate symlink %s -> %s", op.targetPath, op.linkValue)
+	}
 	if op.isDir {
 		return fmt.Sprintf("copy directory %s -> %s", op.sourcePath, op.targetPath)
 	}
@@ -298,6 +635,7 @@ type TemplateOperation struct {
 	created      bool
 	backupPath   string
 	backed       bool
+	backups      *backupPolicy
 }
 
 // NewTemplateOperation creates a new template operation
@@ -307,15 +645,25 @@ func NewTemplateOperation(config *Config, file *ConfigFile, templatePath, output
 		file:         file,
 		templatePath: templatePath,
 		outputPath:   outputPath,
+		backups:      defaultBackupPolicy(),
 	}
 }
 
+func (op *TemplateOperation) useBackups(policy *backupPolicy) { op.backups = policy }
+
 func (op *TemplateOperation) Execute() error {
 	// Check if output already exists
-	if _, err := os.Lstat(op.outputPath); err == nil {
+	if _, err := os.Lstat(op.outputPath); err == nil && op.backups.skip {
+		if err := discardTarget(op.outputPath, os.RemoveAll); err != nil {
+			return NewConfigError("remove existing template output", op.outputPath, err)
+		}
+	} else if err == nil {
 		// Output exists, create backup
-		op.backupPath = op.outputPath + ".backup." + time.Now().Format("20060102-150405")
-		if err := os.Rename(op.outputPath, op.backupPath); err != nil {
+		op.backupPath = op.backups.targetPath(op.outputPath)
+		if err := op.backups.makeRoom(op.backupPath, os.RemoveAll); err != nil {
+			return NewConfigError("prepare backup", op.backupPath, err)
+		}
+		if err := moveFile(op.outputPath, op.backupPath); err != nil {
 			return NewConfigError("backup existing template output", op.outputPath, err)
 		}
 		op.backed = true
@@ -343,7 +691,7 @@ func (op *TemplateOperation) Rollback() error {
 	
 	// Restore backup if we created one
 	if op.backed && op.backupPath != "" {
-		if err := os.Rename(op.backupPath, op.outputPath); err != nil {
+		if err := moveFile(op.backupPath, op.outputPath); err != nil {
 			multiErr.Add(NewConfigError("restore backup", op.backupPath, err))
 		}
 	}
@@ -355,6 +703,10 @@ func (op *TemplateOperation) Rollback() error {
 	return nil
 }
 
+func (op *TemplateOperation) journalEntry() journalEntry {
+	return journalEntry{Kind: journalKindTemplate, Target: op.outputPath, Backup: op.backupPath, Created: op.created}
+}
+
 func (op *TemplateOperation) Description() string {
 	return fmt.Sprintf("process template %s -> %s", op.templatePath, op.outputPath)
 }
@@ -365,14 +717,26 @@ func (op *TemplateOperation) GetFile() string {
 
 // Helper function to create atomic link operation for a config file
 func createAtomicLinkOperation(config *Config, file *ConfigFile) (*Transaction, error) {
-	tx := NewTransaction()
-	
-	sourceDir := filepath.Dir(filepath.Join(config.DotfilesDir, file.Source))
-	if err := os.MkdirAll(sourceDir, 0755); err != nil {
-		return nil, NewConfigError("create source directory", sourceDir, err)
+	// Refuse up front rather than failing half-way through the transaction
+	if err := checkTargetWritable(config, file); err != nil {
+		return nil, err
 	}
 	
+	tx := NewTransaction(config)
+	
 	sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSourceAvailable(file, sourcePath, target); err != nil {
+		return nil, err
+	}
+	
+	sourceDir := filepath.Dir(sourcePath)
+	if err := mkdirAllSerialized(sourceDir); err != nil {
+		return nil, NewConfigError("create source directory", sourceDir, err)
+	}
 	
 	// If source doesn't exist and it's a template, create from template first
 	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
@@ -389,56 +753,418 @@ func createAtomicLinkOperation(config *Config, file *ConfigFile) (*Transaction,
 			}
 		} else {
 			// For non-templates, we might want to copy existing file if it exists
-			if _, err := os.Stat(file.Target); err == nil {
+			if _, err := os.Stat(target); err == nil {
 				// Target exists, copy it to source first
-				copyOp := NewCopyOperation(file.Target, sourcePath, file)
+				copyOp, err := newSeedSourceOperation(config, file, sourcePath)
+				if err != nil {
+					return nil, err
+				}
 				tx.AddOperation(copyOp)
 			}
 		}
 	}
 	
+	if file.effectiveLinkStrategy() == LinkStrategyTree {
+		if err := addTreeLinkOperations(tx, config, file, sourcePath); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+	
+	if file.LinkStrategy == LinkStrategyCopy {
+		// Copy the source over the target unless it was just seeded from it or
+		// already matches; an existing target is backed up by the copy
+		if _, err := os.Stat(sourcePath); err == nil && !isCopyInSync(sourcePath, target) {
+			tx.AddOperation(NewCopyOperation(sourcePath, target, file))
+		}
+		return tx, nil
+	}
+	
 	// Add link operation
-	linkOp := NewLinkOperation(sourcePath, file.Target, file)
+	linkOp := NewLinkOperation(sourcePath, target, file)
+	linkOp.relative = config.RelativeLinks
+	linkOp.useEscalation(config)
 	tx.AddOperation(linkOp)
 	
 	return tx, nil
 }
 
-// atomicLinkAllConfigs creates atomic transactions for linking all configs
-func atomicLinkAllConfigs(config *Config) error {
-	var allResults []OperationResult
-	var failedFiles []string
+// checkSourceAvailable rejects linking a plain file whose source is missing
+// when there is no target to copy into it either: the link would dangle.
+// Templates are rendered, or given a placeholder, so they always have one.
+func checkSourceAvailable(file *ConfigFile, sourcePath, target string) error {
+	if file.Template {
+		return nil
+	}
+	if _, err := os.Stat(sourcePath); !os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		return nil
+	}
+	return NewConfigError("link", file.Name, 
+		fmt.Errorf("source %s doesn't exist and there is no target to adopt it from; create the source or point the file at an existing one", file.Source))
+}
+
+// checkTargetWritable rejects linking file when the directory its target goes
+// in (or, for tree links, the target directory itself) can't be written. With
+// allow_privileged set the link is left to escalate instead.
+func checkTargetWritable(config *Config, file *ConfigFile) error {
+	if config.escalationCommand() != nil {
+		return nil
+	}
 	
-	for _, file := range config.Files {
-		tx, err := createAtomicLinkOperation(config, &file)
-		if err != nil {
-			result := OperationResult{
-				File:    file.Name,
-				Success: false,
-				Message: "Failed to create transaction",
-				Error:   err,
-			}
-			allResults = append(allResults, result)
-			failedFiles = append(failedFiles, file.Name)
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return err
+	}
+	dirs := []string{filepath.Dir(target)}
+	if file.effectiveLinkStrategy() == LinkStrategyTree {
+		dirs = append(dirs, target)
+	}
+	
+	for _, dir := range dirs {
+		// Missing directories are created inside the nearest one that exists
+		existing := nearestExistingDir(dir)
+		if existing == "" || dirWritable(existing) {
 			continue
 		}
+		return NewRecoverableError("check target directory", existing, 
+			fmt.Errorf("directory is not writable; fix its permissions (e.g. chmod u+w %s) or set allow_privileged in config.json to link with sudo", existing))
+	}
+	return nil
+}
+
+// nearestExistingDir returns dir or its closest existing ancestor, or "" when
+// that turns out not to be a directory
+func nearestExistingDir(dir string) string {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if info.IsDir() {
+				return dir
+			}
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// newSeedSourceOperation copies an existing target into the dotfiles directory.
+// A target that is itself a symlink is handled according to config.TargetSymlinks:
+// by default it is refused so another tool's file isn't silently absorbed.
+func newSeedSourceOperation(config *Config, file *ConfigFile, sourcePath string) (*CopyOperation, error) {
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return nil, err
+	}
+	copyOp := NewCopyOperation(target, sourcePath, file)
+	copyOp.exclude = file.ExcludePatterns // excluded files stay out of the dotfiles directory
+	
+	linkTarget, err := os.Readlink(target)
+	if err != nil {
+		// Not a symlink - copy as usual
+		return copyOp, nil
+	}
+	
+	switch config.TargetSymlinks {
+	case TargetSymlinksFollow:
+		return copyOp, nil
+	case TargetSymlinksPreserve:
+		// Store an absolute link so it still resolves from inside the dotfiles directory
+		copyOp.linkValue = resolveLinkTarget(target, linkTarget)
+		copyOp.isDir = false
+		return copyOp, nil
+	default:
+		return nil, NewConfigError("copy target into source", target, 
+			fmt.Errorf("target is a symlink to %s; set target_symlinks to %q or %q to manage it anyway", 
+				linkTarget, TargetSymlinksFollow, TargetSymlinksPreserve))
+	}
+}
+
+// isCopyInSync reports whether a copy-mode target is a real copy matching the source
+func isCopyInSync(sourcePath, targetPath string) bool {
+	if info, err := os.Lstat(targetPath); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return false
+	}
+	return contentsMatch(sourcePath, targetPath)
+}
+
+// syncTargetToSource pulls changes made to a copy-mode target back into the
+// dotfiles directory. It is a no-op when both sides already match; otherwise
+// the current source is backed up and replaced inside a transaction.
+func syncTargetToSource(config *Config, file *ConfigFile) error {
+	if file.LinkStrategy != LinkStrategyCopy {
+		return NewConfigError("sync to source", file.Name, 
+			fmt.Errorf("only files with the %q link strategy can be synced", LinkStrategyCopy))
+	}
+	
+	sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return err
+	}
+	if !isCopyInSync(sourcePath, target) {
+		if _, err := os.Stat(target); err != nil {
+			return NewConfigError("sync to source", target, err)
+		}
+		if err := ensureDir(filepath.Dir(sourcePath)); err != nil {
+			return err
+		}
 		
+		tx := NewTransaction(config)
+		tx.AddOperation(NewCopyOperation(target, sourcePath, file))
 		if err := tx.Execute(); err != nil {
-			result := OperationResult{
-				File:    file.Name,
-				Success: false,
-				Message: "Transaction failed",
-				Error:   err,
+			return err
+		}
+	}
+	
+	updateSingleFileStatus(config, file)
+	return nil
+}
+
+// addTreeLinkOperations links each file under sourcePath individually, creating
+// real directories at the target instead of one directory symlink
+func addTreeLinkOperations(tx *Transaction, config *Config, file *ConfigFile, sourcePath string) error {
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return err
+	}
+	
+	// When the source is about to be copied in from the target, walk the target instead
+	walkRoot := sourcePath
+	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+		walkRoot = target
+	}
+	
+	info, err := os.Stat(walkRoot)
+	if err != nil || !info.IsDir() {
+		// Nothing to walk (or a plain file) - a single link is equivalent
+		linkOp := NewLinkOperation(sourcePath, target, file)
+		linkOp.relative = config.RelativeLinks
+		linkOp.useEscalation(config)
+		tx.AddOperation(linkOp)
+		return nil
+	}
+	
+	leaves, err := treeLinkPaths(walkRoot, file.ExcludePatterns)
+	if err != nil {
+		return NewConfigError("scan source directory", walkRoot, err)
+	}
+	
+	// Replace a previous whole-directory symlink so leaf links land in a real directory
+	if targetInfo, err := os.Lstat(target); err == nil && targetInfo.Mode()&os.ModeSymlink != 0 {
+		tx.AddOperation(NewUnlinkOperation(target, file))
+	}
+	
+	for _, relPath := range leaves {
+		linkOp := NewLinkOperation(filepath.Join(sourcePath, relPath), filepath.Join(target, relPath), file)
+		linkOp.relative = config.RelativeLinks
+		linkOp.useEscalation(config)
+		tx.AddOperation(linkOp)
+	}
+	
+	return nil
+}
+
+// effectiveLinkStrategy is how a file is actually linked: exclusions can only
+// be honoured file by file, so they turn a whole-directory symlink into "tree"
+func (file ConfigFile) effectiveLinkStrategy() string {
+	if len(file.ExcludePatterns) > 0 && (file.LinkStrategy == "" || file.LinkStrategy == LinkStrategySymlink) {
+		return LinkStrategyTree
+	}
+	return file.LinkStrategy
+}
+
+// isExcludedPath reports whether relPath (relative to the linked directory)
+// matches one of the exclude patterns, either as a whole or by its base name
+func isExcludedPath(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, relPath); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, filepath.Base(relPath)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// treeLinkPaths lists the files (and symlinks) under root as paths relative to
+// root, leaving out anything matching exclude
+func treeLinkPaths(root string, exclude []string) ([]string, error) {
+	var leaves []string
+	
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if relPath != "." && isExcludedPath(exclude, relPath) {
+			if entry.IsDir() {
+				return filepath.SkipDir
 			}
-			allResults = append(allResults, result)
-			failedFiles = append(failedFiles, file.Name)
-		} else {
-			result := OperationResult{
-				File:    file.Name,
-				Success: true,
-				Message: "Successfully linked",
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		
+		leaves = append(leaves, relPath)
+		return nil
+	})
+	
+	return leaves, err
+}
+
+// createMoveSourceOperation moves file's source to newSourcePath and re-points
+// the links that used the old source. Copy-mode targets are real copies, so
+// only the source moves.
+func createMoveSourceOperation(config *Config, file *ConfigFile, newSourcePath string) (*Transaction, error) {
+	tx := NewTransaction(config)
+	oldSourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return nil, err
+	}
+	tx.AddOperation(NewMoveOperation(oldSourcePath, newSourcePath, file))
+	
+	if !file.IsLinked || file.LinkStrategy == LinkStrategyCopy {
+		return tx, nil
+	}
+	
+	links := []string{""}
+	if file.effectiveLinkStrategy() == LinkStrategyTree {
+		if info, err := os.Lstat(target); err == nil && info.IsDir() {
+			leaves, err := treeLinkPaths(oldSourcePath, file.ExcludePatterns)
+			if err != nil {
+				return nil, NewConfigError("scan source directory", oldSourcePath, err)
 			}
-			allResults = append(allResults, result)
+			links = leaves
+		}
+	}
+	
+	for _, relPath := range links {
+		targetPath := filepath.Join(target, relPath)
+		if !isLinkTo(targetPath, filepath.Join(oldSourcePath, relPath)) {
+			continue
+		}
+		tx.AddOperation(NewUnlinkOperation(targetPath, file))
+		linkOp := NewLinkOperation(filepath.Join(newSourcePath, relPath), targetPath, file)
+		linkOp.relative = config.RelativeLinks
+		linkOp.useEscalation(config)
+		tx.AddOperation(linkOp)
+	}
+	
+	return tx, nil
+}
+
+// createShareSourceOperation points file at sharedSourcePath, the source of
+// another file, leaving its old source where it is. Links to the old source
+// are replaced by links to the shared one; copy-mode targets and unlinked
+// files only need their entry changed.
+func createShareSourceOperation(config *Config, file *ConfigFile, sharedSourcePath string) (*Transaction, error) {
+	tx := NewTransaction(config)
+	if !file.IsLinked || file.LinkStrategy == LinkStrategyCopy {
+		return tx, nil
+	}
+	oldSourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return nil, err
+	}
+	
+	if file.effectiveLinkStrategy() != LinkStrategyTree {
+		if isLinkTo(target, oldSourcePath) {
+			tx.AddOperation(NewUnlinkOperation(target, file))
+			linkOp := NewLinkOperation(sharedSourcePath, target, file)
+			linkOp.relative = config.RelativeLinks
+			linkOp.useEscalation(config)
+			tx.AddOperation(linkOp)
+		}
+		return tx, nil
+	}
+	
+	// The shared source may hold other files than the old one, so the old
+	// leaf links go and the shared source is linked leaf by leaf afresh
+	links := []string{""}
+	if info, err := os.Lstat(target); err == nil && info.IsDir() {
+		leaves, err := treeLinkPaths(oldSourcePath, file.ExcludePatterns)
+		if err != nil {
+			return nil, NewConfigError("scan source directory", oldSourcePath, err)
+		}
+		links = leaves
+	}
+	unlinked := false
+	for _, relPath := range links {
+		targetPath := filepath.Join(target, relPath)
+		if isLinkTo(targetPath, filepath.Join(oldSourcePath, relPath)) {
+			tx.AddOperation(NewUnlinkOperation(targetPath, file))
+			unlinked = true
+		}
+	}
+	if unlinked {
+		if err := addTreeLinkOperations(tx, config, file, sharedSourcePath); err != nil {
+			return nil, err
+		}
+	}
+	
+	return tx, nil
+}
+
+// isLinkTo reports whether path is a symlink pointing at dest
+func isLinkTo(path, dest string) bool {
+	linkTarget, err := os.Readlink(path)
+	return err == nil && sameLinkDestination(resolveLinkTarget(path, linkTarget), dest)
+}
+
+// atomicLinkAllConfigs creates atomic transactions for linking all configs.
+// Conflicting targets are resolved up front; skipped files are left alone and
+// reported with Skipped set, and merged directories are adopted before linking.
+// Up to config.parallelism() files are linked at once, each in its own
+// transaction; results are returned in config order either way.
+func atomicLinkAllConfigs(config *Config) ([]OperationResult, error) {
+	var failedFiles []string
+	
+	answers, err := resolveBatchConflicts(config)
+	if err != nil {
+		return nil, err
+	}
+	
+	allResults := make([]OperationResult, len(config.Files))
+	scheduler := newLinkScheduler(config)
+	finished := make(chan int)
+	running := 0
+	for scheduler.remaining() || running > 0 {
+		for running < config.parallelism() {
+			index, ok := scheduler.next()
+			if !ok {
+				break
+			}
+			running++
+			go func(i int) {
+				file := config.Files[i]
+				allResults[i] = linkConfigWithAnswer(config, &file, answers[i])
+				finished <- i
+			}(index)
+		}
+		scheduler.done(<-finished)
+		running--
+	}
+	
+	for i, result := range allResults {
+		if !result.Success {
+			failedFiles = append(failedFiles, result.File)
+		} else if !result.Skipped {
+			config.markLinked(config.Files[i].Target)
 		}
 	}
 	
@@ -451,10 +1177,65 @@ func atomicLinkAllConfigs(config *Config) error {
 				multiErr.Add(fmt.Errorf("%s: %v", result.File, result.Error))
 			}
 		}
-		return &multiErr
+		return allResults, &multiErr
 	}
 	
-	return nil
+	return allResults, nil
+}
+
+// skippedConflictResult reports a file left alone because its conflict was
+// answered with "skip"
+func skippedConflictResult(file ConfigFile) OperationResult {
+	return OperationResult{
+		File:    file.Name,
+		Success: true,
+		Skipped: true,
+		Message: "Skipped (conflict)",
+	}
+}
+
+// linkConfigWithAnswer links a single config of a link-all batch the way its
+// conflict was answered; files without an answer are linked as usual
+func linkConfigWithAnswer(config *Config, file *ConfigFile, answer ConflictResolution) OperationResult {
+	if file.Disabled {
+		return OperationResult{File: file.Name, Success: true, Skipped: true, Message: "Skipped (disabled)"}
+	}
+	switch answer {
+	case ConflictSkip:
+		return skippedConflictResult(*file)
+	case ConflictMergeDirectory:
+		return mergeDirectoryWithResult(config, file)
+	}
+	return linkConfigWithResult(config, file)
+}
+
+// linkConfigWithResult links a single config in its own transaction and
+// reports the outcome instead of returning an error
+func linkConfigWithResult(config *Config, file *ConfigFile) OperationResult {
+	tx, err := createAtomicLinkOperation(config, file)
+	if err != nil {
+		return OperationResult{
+			File:    file.Name,
+			Success: false,
+			Message: "Failed to create transaction",
+			Error:   err,
+		}
+	}
+	
+	if err := tx.Execute(); err != nil {
+		return OperationResult{
+			File:    file.Name,
+			Success: false,
+			Message: "Transaction failed",
+			Error:   err,
+		}
+	}
+	
+	return OperationResult{
+		File:    file.Name,
+		Success: true,
+		Message: "Successfully linked",
+	}
 }
 
 // atomicLinkSingleConfig creates and executes atomic transaction for a single config
@@ -464,5 +1245,26 @@ func atomicLinkSingleConfig(config *Config, file *ConfigFile) error {
 		return NewConfigError("create transaction", file.Name, err)
 	}
 	
-	return tx.Execute()
+	if err := tx.Execute(); err != nil {
+		return err
+	}
+	config.markLinked(file.Target)
+	return nil
+}
+
+// markLinked records that the file managing target was just linked, so a
+// source modified afterwards can be flagged as needing a relink
+func (c *Config) markLinked(target string) {
+	if file, err := c.GetConfigFileByTarget(target); err == nil {
+		now := time.Now()
+		file.LinkedAt = &now
+	}
+}
+
+// saveLinkTimes persists the times markLinked recorded. Losing them only
+// makes the modified-source check fall back to the target's mtime.
+func saveLinkTimes(config *Config) {
+	if err := saveConfigSafe(config); err != nil {
+		logger.Debugf("could not save link times: %v", err)
+	}
 }
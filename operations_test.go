@@ -0,0 +1,297 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useJournalDir journals transactions into dir for the rest of the test
+func useJournalDir(t *testing.T, dir string) {
+	t.Helper()
+	previous := transactionJournalDir
+	transactionJournalDir = dir
+	t.Cleanup(func() { transactionJournalDir = previous })
+}
+
+// readDirNames lists dir, treating a missing directory as empty
+func readDirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestLinkingCorrectLinkAgainIsANoop(t *testing.T) {
+	config, home := newTestConfig(t)
+	journalDir := filepath.Join(config.ConfigDir, journalDirName)
+	useJournalDir(t, journalDir)
+	source := filepath.Join(config.DotfilesDir, "shell", "zshrc")
+	writeTestFile(t, source, "export EDITOR=vim\n")
+	target := filepath.Join(home, ".zshrc")
+	config.Files = []ConfigFile{{Name: "zshrc", Source: "shell/zshrc", Target: target, Category: "shell"}}
+
+	for i := 0; i < 2; i++ {
+		if err := atomicLinkSingleConfig(config, &config.Files[0]); err != nil {
+			t.Fatalf("link %d: %v", i+1, err)
+		}
+	}
+
+	if linkTarget, err := os.Readlink(target); err != nil || linkTarget != source {
+		t.Errorf("target links to %q (%v), want %q", linkTarget, err, source)
+	}
+	if backups, _ := filepath.Glob(target + ".backup*"); len(backups) != 0 {
+		t.Errorf("backups next to the target: %q", backups)
+	}
+	if names := readDirNames(t, config.GetBackupDir()); len(names) != 0 {
+		t.Errorf("backup directory holds %q, want nothing", names)
+	}
+	if names := readDirNames(t, journalDir); len(names) != 0 {
+		t.Errorf("journal directory holds %q, want nothing", names)
+	}
+}
+
+func TestLinkAbsoluteAndRelative(t *testing.T) {
+	for _, relative := range []bool{false, true} {
+		config, home := newTestConfig(t)
+		config.RelativeLinks = relative
+		source := filepath.Join(config.DotfilesDir, "shell", "zshrc")
+		writeTestFile(t, source, "")
+		target := filepath.Join(home, ".config", "zsh", ".zshrc")
+		config.Files = []ConfigFile{{Name: "zshrc", Source: "shell/zshrc", Target: target, Category: "shell"}}
+
+		if err := atomicLinkSingleConfig(config, &config.Files[0]); err != nil {
+			t.Fatalf("relative %v: %v", relative, err)
+		}
+		linkValue, err := os.Readlink(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := source
+		if relative {
+			want = filepath.Join("..", "config-manager", "dotfiles", "shell", "zshrc")
+		}
+		if linkValue != want {
+			t.Errorf("relative %v: link stores %q, want %q", relative, linkValue, want)
+		}
+
+		updateSingleFileStatus(config, &config.Files[0])
+		if !config.Files[0].IsLinked || config.Files[0].HasConflict {
+			t.Errorf("relative %v: status linked=%v conflict=%v, want linked", relative, config.Files[0].IsLinked, config.Files[0].HasConflict)
+		}
+	}
+}
+
+func TestReadOnlyTargetDirectoryRejected(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "misc", "app.conf"), "managed\n")
+	readOnly := filepath.Join(home, ".config", "locked")
+	if err := os.MkdirAll(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnly, 0755) })
+	file := ConfigFile{Name: "app.conf", Source: "misc/app.conf", Target: filepath.Join(readOnly, "app", "app.conf"), Category: "misc"}
+
+	_, err := createAtomicLinkOperation(config, &file)
+	if err == nil {
+		t.Fatal("linking into a read-only directory was accepted")
+	}
+	if !IsRecoverable(err) || !strings.Contains(err.Error(), readOnly) || !strings.Contains(err.Error(), "chmod u+w") {
+		t.Errorf("error = %v, want a recoverable error naming %s with a fix", err, readOnly)
+	}
+	if names := readDirNames(t, readOnly); len(names) != 0 {
+		t.Errorf("read-only directory gained %q", names)
+	}
+
+	// With privileged linking the write is escalated instead
+	config.AllowPrivileged = true
+	if _, err := createAtomicLinkOperation(config, &file); err != nil {
+		t.Errorf("with allow_privileged: %v", err)
+	}
+}
+
+func TestLinkRefusedWithoutSourceOrTarget(t *testing.T) {
+	config, home := newTestConfig(t)
+	target := filepath.Join(home, ".zshrc")
+	config.Files = []ConfigFile{{Name: "zshrc", Source: "shell/zshrc", Target: target, Category: "shell"}}
+
+	err := atomicLinkSingleConfig(config, &config.Files[0])
+	if err == nil || !strings.Contains(err.Error(), "no target to adopt it from") {
+		t.Fatalf("atomicLinkSingleConfig = %v, want the missing source refused", err)
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("target was created: %v", err)
+	}
+	if names := readDirNames(t, filepath.Join(config.DotfilesDir, "shell")); len(names) != 0 {
+		t.Errorf("source directory gained %q", names)
+	}
+}
+
+func TestLinkWithoutSourceAdoptsTargetOrRendersTemplate(t *testing.T) {
+	config, home := newTestConfig(t)
+	adopted := filepath.Join(home, ".zshrc")
+	writeTestFile(t, adopted, "export EDITOR=vim\n")
+	config.Files = []ConfigFile{
+		{Name: "zshrc", Source: "shell/zshrc", Target: adopted, Category: "shell"},
+		{Name: "gitconfig", Source: "git/gitconfig", Target: filepath.Join(home, ".gitconfig"), Category: "git", Template: true},
+	}
+
+	for i := range config.Files {
+		file := &config.Files[i]
+		if err := atomicLinkSingleConfig(config, file); err != nil {
+			t.Fatalf("link %s: %v", file.Name, err)
+		}
+		source := filepath.Join(config.DotfilesDir, file.Source)
+		if value, err := os.Readlink(file.Target); err != nil || value != source {
+			t.Errorf("%s links to %q (%v), want %q", file.Target, value, err, source)
+		}
+		if _, err := os.Stat(source); err != nil {
+			t.Errorf("%s wasn't produced: %v", source, err)
+		}
+	}
+}
+
+func TestTargetSymlinkModes(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		wantErr     bool
+		wantSymlink bool // the source is a symlink rather than a copy
+	}{
+		{"default", "", true, false},
+		{"refuse", TargetSymlinksRefuse, true, false},
+		{"follow", TargetSymlinksFollow, false, false},
+		{"preserve", TargetSymlinksPreserve, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, home := newTestConfig(t)
+			config.TargetSymlinks = tt.mode
+			external := filepath.Join(home, "other-tool", "gitconfig")
+			writeTestFile(t, external, "[user]\n")
+			target := filepath.Join(home, ".gitconfig")
+			if err := os.Symlink(external, target); err != nil {
+				t.Fatal(err)
+			}
+			source := filepath.Join(config.DotfilesDir, "git", "gitconfig")
+			config.Files = []ConfigFile{{Name: "gitconfig", Source: "git/gitconfig", Target: target, Category: "git"}}
+
+			err := atomicLinkSingleConfig(config, &config.Files[0])
+
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), external) {
+					t.Fatalf("error %v, want a refusal naming %s", err, external)
+				}
+				if value, err := os.Readlink(target); err != nil || value != external {
+					t.Errorf("target links to %q (%v), want it left pointing at %q", value, err, external)
+				}
+				if _, err := os.Lstat(source); !os.IsNotExist(err) {
+					t.Errorf("source was created (%v), want nothing absorbed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value, err := os.Readlink(target); err != nil || value != source {
+				t.Errorf("target links to %q (%v), want %q", value, err, source)
+			}
+			value, err := os.Readlink(source)
+			if tt.wantSymlink {
+				if err != nil || value != external {
+					t.Errorf("source links to %q (%v), want %q", value, err, external)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("source is a symlink to %q, want a copy", value)
+			}
+			if content, err := os.ReadFile(source); err != nil || string(content) != "[user]\n" {
+				t.Errorf("source holds %q (%v), want the external content", content, err)
+			}
+		})
+	}
+}
+
+func TestTargetSymlinkModesThroughSymlinkedParent(t *testing.T) {
+	// A symlinked parent (a ~/.config kept elsewhere) doesn't make the target a
+	// symlink: every mode copies the file itself
+	for _, mode := range []string{TargetSymlinksRefuse, TargetSymlinksFollow, TargetSymlinksPreserve} {
+		t.Run(mode, func(t *testing.T) {
+			config, home := newTestConfig(t)
+			config.TargetSymlinks = mode
+			realConfig := filepath.Join(home, "sync", "config")
+			writeTestFile(t, filepath.Join(realConfig, "kitty", "kitty.conf"), "font_size 12\n")
+			if err := os.Symlink(realConfig, filepath.Join(home, ".xdg")); err != nil {
+				t.Fatal(err)
+			}
+			target := filepath.Join(home, ".xdg", "kitty", "kitty.conf")
+			source := filepath.Join(config.DotfilesDir, "terminal", "kitty.conf")
+			config.Files = []ConfigFile{{Name: "kitty.conf", Source: "terminal/kitty.conf", Target: target, Category: "terminal"}}
+
+			if err := atomicLinkSingleConfig(config, &config.Files[0]); err != nil {
+				t.Fatal(err)
+			}
+
+			if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+				t.Fatalf("source is %v (%v), want a regular file", info, err)
+			}
+			if content, err := os.ReadFile(source); err != nil || string(content) != "font_size 12\n" {
+				t.Errorf("source holds %q (%v), want the target's content", content, err)
+			}
+			if value, err := os.Readlink(filepath.Join(realConfig, "kitty", "kitty.conf")); err != nil || value != source {
+				t.Errorf("target links to %q (%v), want %q", value, err, source)
+			}
+		})
+	}
+}
+
+func TestExcludedFilesStayOutOfTreeLinks(t *testing.T) {
+	config, home := newTestConfig(t)
+	target := filepath.Join(home, ".config", "app")
+	writeTestFile(t, filepath.Join(target, "config.toml"), "theme = \"dark\"\n")
+	writeTestFile(t, filepath.Join(target, "secrets.local"), "token\n")
+	writeTestFile(t, filepath.Join(target, "profiles", "work.local"), "token\n")
+	source := filepath.Join(config.DotfilesDir, "app")
+	config.Files = []ConfigFile{{Name: "app", Source: "app", Target: target, Category: "misc", ExcludePatterns: []string{"*.local"}}}
+
+	// Adoption copies the existing directory into the source without them
+	if err := atomicLinkSingleConfig(config, &config.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"secrets.local", filepath.Join("profiles", "work.local")} {
+		if _, err := os.Lstat(filepath.Join(source, rel)); !os.IsNotExist(err) {
+			t.Errorf("%s was adopted into the source (%v)", rel, err)
+		}
+		if info, err := os.Lstat(filepath.Join(target, rel)); err != nil || !info.Mode().IsRegular() {
+			t.Errorf("%s in the target is %v (%v), want the file left in place", rel, info, err)
+		}
+	}
+	if value, err := os.Readlink(filepath.Join(target, "config.toml")); err != nil || value != filepath.Join(source, "config.toml") {
+		t.Errorf("config.toml links to %q (%v), want the source", value, err)
+	}
+
+	// A .local file added to the source later isn't picked up by the walk either
+	writeTestFile(t, filepath.Join(source, "machine.local"), "")
+	leaves, err := treeLinkPaths(source, config.Files[0].ExcludePatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaves) != 1 || leaves[0] != "config.toml" {
+		t.Errorf("treeLinkPaths() = %q, want only config.toml", leaves)
+	}
+	updateSingleFileStatus(config, &config.Files[0])
+	if file := config.Files[0]; !file.IsLinked || file.HasConflict {
+		t.Errorf("linked %v, conflict %v; want linked with the excluded files ignored", file.IsLinked, file.HasConflict)
+	}
+}
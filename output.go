@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Informational output goes to stdout and is silenced by --quiet. Warnings
+// and errors always go to stderr so they survive redirection and quiet mode.
+
+// quietOutput suppresses informational output (set by --quiet)
+var quietOutput bool
+
+// infoOut is where informational output is written
+var infoOut io.Writer = os.Stdout
+
+// infof prints informational output unless --quiet is set
+func infof(format string, args ...interface{}) {
+	if quietOutput {
+		return
+	}
+	fmt.Fprintf(infoOut, format, args...)
+}
+
+// infoln prints a line of informational output unless --quiet is set
+func infoln(args ...interface{}) {
+	if quietOutput {
+		return
+	}
+	fmt.Fprintln(infoOut, args...)
+}
+
+// warnf prints a warning to stderr
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// errorf prints an error to stderr
+func errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// Logger receives verbose tracing, such as why a file was considered
+// conflicted or which operations a transaction ran
+type Logger interface {
+	Debugf(format string, args ...interface{})
+}
+
+// nopLogger discards tracing (the default)
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+
+// writerLogger writes tracing lines to w
+type writerLogger struct {
+	w io.Writer
+}
+
+func (l writerLogger) Debugf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, "[verbose] "+format+"\n", args...)
+}
+
+// logger is replaced by --verbose
+var logger Logger = nopLogger{}
+
+// verboseLogName is the file --verbose traces to while the TUI is running
+const verboseLogName = "verbose.log"
+
+// openVerboseLog opens (and truncates) the TUI's verbose log in configDir
+func openVerboseLog(configDir string) (*os.File, error) {
+	if err := ensureDir(configDir); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(configDir, verboseLogName)
+	logFile, err := os.Create(path)
+	if err != nil {
+		return nil, NewConfigError("open verbose log", path, err)
+	}
+	return logFile, nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// preserveOwner does nothing where files have no uid/gid owner
+func preserveOwner(dst string, info os.FileInfo) {}
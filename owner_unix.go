@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives dst the owner and group of the file described by info.
+// Only root may give files away, so failing is expected and ignored; the copy
+// then keeps belonging to the current user.
+func preserveOwner(dst string, info os.FileInfo) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	if err := os.Lchown(dst, int(stat.Uid), int(stat.Gid)); err != nil {
+		logger.Debugf("%s: keeping the current owner (%v)", dst, err)
+	}
+}
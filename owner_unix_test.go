@@ -0,0 +1,36 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestCopyFileKeepsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("only root can give files away")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "hosts")
+	writeTestFile(t, src, "127.0.0.1 localhost\n")
+	const uid, gid = 65534, 65534
+	if err := os.Chown(src, uid, gid); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "hosts.copy")
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+	if stat.Uid != uid || stat.Gid != gid {
+		t.Errorf("owner = %d:%d, want %d:%d", stat.Uid, stat.Gid, uid, gid)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// parallelismOverride is set from --parallel and takes precedence over the
+// config file without being persisted to it. 0 means not given.
+var parallelismOverride int
+
+// parallelism is how many files link-all links at once: --parallel, then the
+// configured Parallelism, then 1 (one file after another)
+func (c *Config) parallelism() int {
+	if parallelismOverride > 0 {
+		return parallelismOverride
+	}
+	if c.Parallelism > 0 {
+		return c.Parallelism
+	}
+	return 1
+}
+
+// linkScheduler hands out the files of a link-all run to workers. Files whose
+// targets are nested (one is inside the other, or they are the same path)
+// share a group, and only one file of a group runs at a time: linking the
+// outer target backs it up and replaces it, which would pull the inner one
+// out from under a concurrent link.
+type linkScheduler struct {
+	groupOf []int        // group of each file, by index
+	pending []int        // files not started yet, in apply order
+	busy    map[int]bool // groups with a file being linked
+}
+
+// newLinkScheduler groups config's files by overlapping targets. Files whose
+// target can't be resolved are left in groups of their own; linking them
+// fails on its own.
+func newLinkScheduler(config *Config) *linkScheduler {
+	count := len(config.Files)
+	s := &linkScheduler{
+		groupOf: make([]int, count),
+		pending: make([]int, count),
+		busy:    make(map[int]bool),
+	}
+	
+	targets := make([]string, count)
+	keys := make([]string, count)
+	copy(s.pending, config.applyOrder())
+	for i := range config.Files {
+		s.groupOf[i] = i
+		if target, err := resolveTarget(config, &config.Files[i]); err == nil {
+			targets[i] = target
+			// Sorting the separator first keeps "/a/b" right after "/a",
+			// ahead of "/a.b"
+			keys[i] = strings.ReplaceAll(target, string(filepath.Separator), "\x00")
+		}
+	}
+	
+	// Sorted, a target's nested targets follow it directly, so each one only
+	// needs comparing with the outermost target of the run before it
+	order := make([]int, count)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return keys[order[a]] < keys[order[b]]
+	})
+	outer := -1
+	for _, i := range order {
+		if targets[i] == "" {
+			continue
+		}
+		if outer >= 0 && isWithinDir(targets[i], targets[outer]) {
+			s.merge(outer, i)
+			continue
+		}
+		outer = i
+	}
+	return s
+}
+
+// merge puts b's group into a's
+func (s *linkScheduler) merge(a, b int) {
+	from, to := s.groupOf[b], s.groupOf[a]
+	for i, group := range s.groupOf {
+		if group == from {
+			s.groupOf[i] = to
+		}
+	}
+}
+
+// next returns the first pending file whose group is idle and marks the group
+// busy. ok is false when every pending file has to wait (or none is left).
+func (s *linkScheduler) next() (index int, ok bool) {
+	for i, index := range s.pending {
+		if s.busy[s.groupOf[index]] {
+			continue
+		}
+		s.busy[s.groupOf[index]] = true
+		s.pending = append(s.pending[:i], s.pending[i+1:]...)
+		return index, true
+	}
+	return 0, false
+}
+
+// done marks the file at index finished, freeing its group
+func (s *linkScheduler) done(index int) {
+	delete(s.busy, s.groupOf[index])
+}
+
+// remaining reports whether any file is still waiting to start
+func (s *linkScheduler) remaining() bool {
+	return len(s.pending) > 0
+}
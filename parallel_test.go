@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParallelLinkIntoNestedTargets(t *testing.T) {
+	config, home := newTestConfig(t)
+	previous := parallelismOverride
+	parallelismOverride = 8
+	t.Cleanup(func() { parallelismOverride = previous })
+
+	// Files share missing parents at several depths, so workers race to create them
+	for i := 0; i < 24; i++ {
+		source := fmt.Sprintf("misc/app%d/config", i)
+		writeTestFile(t, filepath.Join(config.DotfilesDir, source), "")
+		target := filepath.Join(home, ".config", "deep", fmt.Sprintf("level%d", i%3), fmt.Sprintf("app%d", i%6), fmt.Sprintf("config%d", i))
+		config.Files = append(config.Files, ConfigFile{Name: fmt.Sprintf("app%d", i), Source: source, Target: target, Category: "misc"})
+	}
+
+	results, err := atomicLinkAllConfigs(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, file := range config.Files {
+		if !results[i].Success {
+			t.Errorf("%s: %v", file.Name, results[i].Error)
+		}
+		want := filepath.Join(config.DotfilesDir, file.Source)
+		if linkTarget, err := os.Readlink(file.Target); err != nil || linkTarget != want {
+			t.Errorf("%s links to %q (%v), want %q", file.Target, linkTarget, err, want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// defaultEscalationCmd runs privileged link operations when
+// Config.EscalationCmd isn't set
+const defaultEscalationCmd = "sudo"
+
+// linkFileOps are the filesystem changes a link operation makes. They are
+// swapped for privileged versions when a target needs root.
+type linkFileOps interface {
+	Rename(oldPath, newPath string) error
+	MkdirAll(dir string) error
+	Symlink(value, path string) error
+	Remove(path string) error
+	RemoveAll(path string) error
+}
+
+// localFileOps changes the filesystem as the current user
+type localFileOps struct{}
+
+func (localFileOps) Rename(oldPath, newPath string) error { return moveFile(oldPath, newPath) }
+func (localFileOps) MkdirAll(dir string) error            { return mkdirAllSerialized(dir) }
+func (localFileOps) Symlink(value, path string) error     { return os.Symlink(value, path) }
+func (localFileOps) Remove(path string) error             { return os.Remove(path) }
+func (localFileOps) RemoveAll(path string) error          { return os.RemoveAll(path) }
+
+// privilegedFileOps runs each change as a command through the escalation
+// command, e.g. "sudo mv -- a b". They run as a session, never timed out, as
+// the command may wait on the user typing a password.
+type privilegedFileOps struct {
+	command []string
+}
+
+func (p privilegedFileOps) run(args ...string) error {
+	argv := append(append([]string{}, p.command[1:]...), args...)
+	if err := runner.Session(p.command[0], argv...); err != nil {
+		return fmt.Errorf("%s %s: %v", strings.Join(p.command, " "), strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func (p privilegedFileOps) Rename(oldPath, newPath string) error {
+	return p.run("mv", "--", oldPath, newPath)
+}
+
+func (p privilegedFileOps) MkdirAll(dir string) error {
+	return p.run("mkdir", "-p", "--", dir)
+}
+
+func (p privilegedFileOps) Symlink(value, path string) error {
+	return p.run("ln", "-s", "--", value, path)
+}
+
+func (p privilegedFileOps) Remove(path string) error {
+	return p.run("rm", "--", path)
+}
+
+func (p privilegedFileOps) RemoveAll(path string) error {
+	return p.run("rm", "-rf", "--", path)
+}
+
+// canPrompt reports whether the user can be asked while linking: not when
+// link-all links in parallel, where prompts would interleave, nor with nobody
+// to ask (serve)
+func (c *Config) canPrompt() bool {
+	return c.parallelism() <= 1 && !skipConflictPrompts
+}
+
+// escalationCommand returns the command privileged link operations run
+// through, or nil when allow_privileged is off
+func (c *Config) escalationCommand() []string {
+	if !c.AllowPrivileged {
+		return nil
+	}
+	if args := strings.Fields(c.EscalationCmd); len(args) > 0 {
+		return args
+	}
+	return []string{defaultEscalationCmd}
+}
+
+// isPermissionError reports whether err means the current user may not make
+// the change, as opposed to the change being impossible
+func isPermissionError(err error) bool {
+	return errors.Is(err, fs.ErrPermission)
+}
+
+// shouldEscalate decides whether a link that failed with err is retried with
+// elevated privileges: only for permission errors, only when escalation is
+// configured, and only once the user agrees. When nobody can be asked (ask
+// unset) it never escalates.
+func shouldEscalate(err error, command []string, ask bool, target string) bool {
+	if len(command) == 0 || !isPermissionError(err) {
+		return false
+	}
+	if !ask {
+		logger.Debugf("%s: needs elevated privileges, but nobody can be asked", target)
+		return false
+	}
+	
+	infof("%s%s needs elevated privileges (%v)\n", decoration("🔐"), target, err)
+	confirmed, confirmErr := confirmAction(fmt.Sprintf("Link %s using %s?", target, strings.Join(command, " ")))
+	if confirmErr != nil || !confirmed {
+		logger.Debugf("%s: escalation declined", target)
+		return false
+	}
+	return true
+}
+
+// permissionHint adds what to do about a permission error to err
+func permissionHint(err error, command []string, ask bool) error {
+	if !isPermissionError(err) {
+		return err
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("%w (set allow_privileged in config.json to link it with sudo)", err)
+	}
+	if !ask {
+		return fmt.Errorf("%w (link it on its own, outside parallel link-all or the API, to be asked about %s)", err, strings.Join(command, " "))
+	}
+	return err
+}
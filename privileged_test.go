@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// deniedFileOps refuses to create symlinks, as in a directory owned by root
+type deniedFileOps struct{ localFileOps }
+
+func (deniedFileOps) Symlink(value, path string) error {
+	return &os.LinkError{Op: "symlink", Old: value, New: path, Err: fs.ErrPermission}
+}
+
+func TestShouldEscalate(t *testing.T) {
+	denied := &os.PathError{Op: "symlink", Path: "/etc/hosts", Err: fs.ErrPermission}
+	tests := []struct {
+		name     string
+		err      error
+		command  []string
+		ask      bool
+		answer   error // gum confirm's result; nil is yes
+		want     bool
+		wantAsks int
+	}{
+		{"agreed", denied, []string{"sudo"}, true, nil, true, 1},
+		{"declined", denied, []string{"sudo"}, true, errors.New("exit status 1"), false, 1},
+		{"nobody to ask", denied, []string{"sudo"}, false, nil, false, 0},
+		{"not configured", denied, nil, true, nil, false, 0},
+		{"not a permission error", errors.New("file name too long"), []string{"sudo"}, true, nil, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &scriptedRunner{Available: map[string]bool{"gum": true}, Responses: []scriptedResponse{{Err: tt.answer}}}
+			useRunner(t, fake)
+
+			if got := shouldEscalate(tt.err, tt.command, tt.ask, "/etc/hosts"); got != tt.want {
+				t.Errorf("shouldEscalate() = %v, want %v", got, tt.want)
+			}
+			if len(fake.Calls) != tt.wantAsks {
+				t.Errorf("calls = %q, want %d prompts", fake.Calls, tt.wantAsks)
+			}
+		})
+	}
+}
+
+func TestCanPrompt(t *testing.T) {
+	config, _ := newTestConfig(t)
+	if !config.canPrompt() {
+		t.Error("can't prompt when linking one file at a time")
+	}
+	config.Parallelism = 4
+	if config.canPrompt() {
+		t.Error("prompts while linking in parallel")
+	}
+	config.Parallelism = 1
+	previous := skipConflictPrompts
+	skipConflictPrompts = true
+	t.Cleanup(func() { skipConflictPrompts = previous })
+	if config.canPrompt() {
+		t.Error("prompts with nobody to ask")
+	}
+}
+
+func TestDeniedLinkEscalates(t *testing.T) {
+	dir := t.TempDir()
+	source, target := filepath.Join(dir, "hosts"), filepath.Join(dir, "etc", "hosts")
+	fake := &scriptedRunner{Available: map[string]bool{"gum": true}, Responses: []scriptedResponse{{}, {}, {}}}
+	useRunner(t, fake)
+
+	op := NewLinkOperation(source, target, nil)
+	op.escalation, op.askFirst, op.ops = []string{"sudo"}, true, deniedFileOps{}
+	if err := op.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"gum", "confirm", "Link " + target + " using sudo?"},
+		{"sudo", "mkdir", "-p", "--", filepath.Dir(target)},
+		{"sudo", "ln", "-s", "--", source, target},
+	}
+	if !reflect.DeepEqual(fake.Calls, want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+}
+
+func TestDeniedLinkFailsWithHint(t *testing.T) {
+	tests := []struct {
+		name       string
+		escalation []string
+		ask        bool
+		wantHint   string
+	}{
+		{"escalation off", nil, true, "set allow_privileged"},
+		{"nobody to ask", []string{"sudo"}, false, "link it on its own"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &scriptedRunner{}
+			useRunner(t, fake)
+			dir := t.TempDir()
+
+			op := NewLinkOperation(filepath.Join(dir, "hosts"), filepath.Join(dir, "hosts.link"), nil)
+			op.escalation, op.askFirst, op.ops = tt.escalation, tt.ask, deniedFileOps{}
+			err := op.Execute()
+			if !errors.Is(err, fs.ErrPermission) || !strings.Contains(err.Error(), tt.wantHint) {
+				t.Errorf("Execute() = %v, want a permission error saying to %s", err, tt.wantHint)
+			}
+			if len(fake.Calls) != 0 {
+				t.Errorf("ran %q, want nothing escalated or asked", fake.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Built-in prompts used when gum isn't installed. They run as small bubbletea
+// programs drawing on stderr (like gum), so answers read the same either way.
+// When stdin isn't a terminal the numbered text prompts are used instead, so
+// scripts can still pipe answers in.
+
+// stdinIsTerminal reports whether the built-in prompts can read keys from stdin
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// choiceModel is a picker over a fixed list of options
+type choiceModel struct {
+	header    string
+	options   []string
+	cursor    int
+	done      bool
+	cancelled bool
+}
+
+func (m choiceModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m choiceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	
+	switch {
+	case key.Matches(keyMsg, keys.Quit), key.Matches(keyMsg, keys.Back):
+		m.cancelled = true
+		return m, tea.Quit
+		
+	case key.Matches(keyMsg, keys.Up):
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		
+	case key.Matches(keyMsg, keys.Down):
+		if m.cursor < len(m.options)-1 {
+			m.cursor++
+		}
+		
+	case key.Matches(keyMsg, keys.Enter):
+		m.done = true
+		return m, tea.Quit
+		
+	default:
+		// Digits pick an option directly, as in the numbered text prompts
+		if s := keyMsg.String(); len(s) == 1 && s[0] >= '1' && s[0] <= '9' {
+			if index := int(s[0] - '1'); index < len(m.options) {
+				m.cursor = index
+				m.done = true
+				return m, tea.Quit
+			}
+		}
+	}
+	
+	return m, nil
+}
+
+func (m choiceModel) View() string {
+	if m.done || m.cancelled {
+		return ""
+	}
+	
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(m.header) + "\n")
+	for i, option := range m.options {
+		if i == m.cursor {
+			b.WriteString(activeStyle.Render("> "+option) + "\n")
+		} else {
+			b.WriteString("  " + option + "\n")
+		}
+	}
+	b.WriteString(inactiveStyle.Render("↑/↓ move • enter select • esc cancel") + "\n")
+	return b.String()
+}
+
+// chooseOption asks the user to pick one of options, starting on selected,
+// and returns its index
+func chooseOption(header string, options []string, selected int) (int, error) {
+	if selected < 0 || selected >= len(options) {
+		selected = 0
+	}
+	
+	result, err := tea.NewProgram(choiceModel{header: header, options: options, cursor: selected}, tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		return -1, NewConfigError("choice", "", err)
+	}
+	
+	choice := result.(choiceModel)
+	if !choice.done {
+		return -1, NewConfigError("choice", "", fmt.Errorf("selection cancelled"))
+	}
+	return choice.cursor, nil
+}
+
+// inputModel reads one line of text
+type inputModel struct {
+	input     textinput.Model
+	done      bool
+	cancelled bool
+}
+
+func (m inputModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m inputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			m.cancelled = true
+			return m, tea.Quit
+		case tea.KeyEnter:
+			m.done = true
+			return m, tea.Quit
+		}
+	}
+	
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m inputModel) View() string {
+	if m.done || m.cancelled {
+		return ""
+	}
+	return m.input.View() + "\n" + inactiveStyle.Render("enter confirm • esc cancel") + "\n"
+}
+
+// readInput asks for a single line of text, pre-filled with value
+func readInput(prompt, placeholder, value string) (string, error) {
+	input := textinput.New()
+	input.Prompt = prompt
+	input.Placeholder = placeholder
+	input.SetValue(value)
+	input.Focus()
+	
+	result, err := tea.NewProgram(inputModel{input: input}, tea.WithOutput(os.Stderr)).Run()
+	if err != nil {
+		return "", NewConfigError("input", "", err)
+	}
+	
+	answer := result.(inputModel)
+	if !answer.done {
+		return "", NewConfigError("input", "", fmt.Errorf("input cancelled"))
+	}
+	return strings.TrimSpace(answer.input.Value()), nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// linkState is what relink finds where a link should be
+type linkState int
+
+const (
+	linkCorrect linkState = iota // a symlink to the expected source
+	linkMissing                  // nothing there
+	linkStale                    // a dangling symlink, or one to somewhere else in DotfilesDir
+	linkForeign                  // a real file or directory, or a working symlink out of DotfilesDir
+)
+
+// inspectLink classifies path against the source it should link to
+func inspectLink(config *Config, path, source string) linkState {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return linkMissing
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return linkForeign
+	}
+	linkValue, err := os.Readlink(path)
+	if err != nil {
+		return linkForeign
+	}
+	
+	resolved := resolveLinkTarget(path, linkValue)
+	if sameLinkDestination(resolved, source) {
+		return linkCorrect
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) || isWithinDir(resolved, config.DotfilesDir) {
+		return linkStale
+	}
+	return linkForeign
+}
+
+// relinkPlan re-points a file's stale links at its current source
+type relinkPlan struct {
+	tx        *Transaction
+	relinked  []string // links replaced, at the target
+	unchanged string   // why nothing is replaced, when relinked is empty
+}
+
+// planRelink finds file's stale links: the target itself or, for a tree-linked
+// directory, the links inside it. Stale links are removed rather than backed
+// up - a link holds no data - and a missing template source is rendered again.
+// Correct links, missing links and anything that isn't a link are left alone.
+func planRelink(config *Config, file *ConfigFile) (*relinkPlan, error) {
+	sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		return nil, err
+	}
+	
+	plan := &relinkPlan{tx: NewTransaction(config)}
+	state := inspectLink(config, target, sourcePath)
+	sourceInfo, sourceErr := os.Stat(sourcePath)
+	treeLinked := file.effectiveLinkStrategy() == LinkStrategyTree && sourceErr == nil && sourceInfo.IsDir()
+	
+	switch {
+	case state == linkCorrect:
+		plan.unchanged = "link is correct"
+		return plan, nil
+	case state == linkMissing:
+		plan.unchanged = "not linked"
+		return plan, nil
+	case state == linkForeign && !treeLinked:
+		plan.unchanged = "target is not a link into the dotfiles directory; link the file to replace it"
+		return plan, nil
+	}
+	
+	if os.IsNotExist(sourceErr) {
+		templatePath := ""
+		if file.Template {
+			templatePath = findTemplateFile(config, file.Name, file.Source, file.Category)
+		}
+		if templatePath == "" {
+			return nil, NewConfigError("relink", file.Name,
+				fmt.Errorf("source %s is missing; point the file at where its source moved to", file.Source))
+		}
+		plan.tx.AddOperation(NewTemplateOperation(config, file, templatePath, sourcePath))
+	}
+	
+	// A stale directory link is replaced by leaf links like any tree link
+	if treeLinked && state == linkStale {
+		if err := addTreeLinkOperations(plan.tx, config, file, sourcePath); err != nil {
+			return nil, err
+		}
+		plan.relinked = append(plan.relinked, target)
+		return plan, nil
+	}
+	
+	links := [][2]string{{sourcePath, target}} // source, link
+	if treeLinked {
+		leaves, err := treeLinkPaths(sourcePath, file.ExcludePatterns)
+		if err != nil {
+			return nil, NewConfigError("scan source directory", sourcePath, err)
+		}
+		links = nil
+		for _, relPath := range leaves {
+			leafSource, leafLink := filepath.Join(sourcePath, relPath), filepath.Join(target, relPath)
+			if inspectLink(config, leafLink, leafSource) == linkStale {
+				links = append(links, [2]string{leafSource, leafLink})
+			}
+		}
+	}
+	
+	for _, link := range links {
+		plan.tx.AddOperation(NewUnlinkOperation(link[1], file))
+		linkOp := NewLinkOperation(link[0], link[1], file)
+		linkOp.relative = config.RelativeLinks
+		linkOp.useEscalation(config)
+		plan.tx.AddOperation(linkOp)
+		plan.relinked = append(plan.relinked, link[1])
+	}
+	if len(plan.relinked) == 0 {
+		plan.unchanged = "links are correct"
+	}
+	return plan, nil
+}
+
+// relink re-points file's links at its current source when they were left
+// dangling or at an old source, e.g. after the source moved in the
+// repository. Unlike linking it never backs anything up. Skipped is set when
+// nothing needed changing.
+func relink(config *Config, file *ConfigFile) OperationResult {
+	switch {
+	case file.Disabled:
+		return OperationResult{File: file.Name, Success: true, Skipped: true, Message: "Skipped (disabled)"}
+	case file.LinkStrategy == LinkStrategyCopy:
+		return OperationResult{File: file.Name, Success: true, Skipped: true, Message: "Skipped (copy mode keeps no link)"}
+	}
+	
+	plan, err := planRelink(config, file)
+	if err != nil {
+		return OperationResult{
+			File:    file.Name,
+			Success: false,
+			Message: "Failed to plan relink",
+			Error:   err,
+		}
+	}
+	if len(plan.relinked) == 0 {
+		return OperationResult{File: file.Name, Success: true, Skipped: true, Message: "Unchanged (" + plan.unchanged + ")"}
+	}
+	
+	if err := plan.tx.Execute(); err != nil {
+		return OperationResult{
+			File:    file.Name,
+			Success: false,
+			Message: "Transaction failed",
+			Error:   err,
+		}
+	}
+	
+	return OperationResult{
+		File:    file.Name,
+		Success: true,
+		Message: "Relinked " + strings.Join(plan.relinked, ", "),
+	}
+}
+
+// relinkAll relinks every file; see relinkFiles
+func relinkAll(config *Config) ([]OperationResult, error) {
+	files := make([]*ConfigFile, len(config.Files))
+	for i := range config.Files {
+		files[i] = &config.Files[i]
+	}
+	return relinkFiles(config, files)
+}
+
+// relinkFiles relinks files one after another, returning their results in
+// order. Files whose links changed are marked linked now.
+func relinkFiles(config *Config, files []*ConfigFile) ([]OperationResult, error) {
+	var multiErr MultiError
+	multiErr.Op = "relink configs"
+	
+	results := make([]OperationResult, len(files))
+	for i, file := range files {
+		results[i] = relink(config, file)
+		switch {
+		case !results[i].Success:
+			multiErr.Add(fmt.Errorf("%s: %v", results[i].File, results[i].Error))
+		case !results[i].Skipped:
+			config.markLinked(file.Target)
+		}
+	}
+	
+	if multiErr.HasErrors() {
+		return results, &multiErr
+	}
+	return results, nil
+}
+
+// runRelink re-points links left dangling or at an old source, for the named
+// files or all of them, and lists the ones it changed
+func runRelink(config *Config, args []string) error {
+	flags := flag.NewFlagSet("relink", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	
+	var results []OperationResult
+	var err error
+	if flags.NArg() == 0 {
+		results, err = relinkAll(config)
+	} else {
+		var files []*ConfigFile
+		for _, ref := range flags.Args() {
+			file, findErr := findFileForCommand(config, ref)
+			if findErr != nil {
+				return findErr
+			}
+			files = append(files, file)
+		}
+		results, err = relinkFiles(config, files)
+	}
+	
+	changed, failed := 0, 0
+	for _, result := range results {
+		switch {
+		case !result.Success:
+			failed++
+			errorf("%s %s: %v\n", glyphError, result.File, result.Error)
+		case !result.Skipped:
+			changed++
+			fmt.Printf("%s %s: %s\n", glyphSuccess, result.File, result.Message)
+		case flags.NArg() > 0:
+			infof("%s: %s\n", result.File, result.Message)
+		}
+	}
+	
+	if changed > 0 {
+		saveLinkTimes(config)
+		infof("%sRelinked %d of %d files\n", decoration("🔗"), changed, len(results))
+	} else if err == nil {
+		infoln(glyphSuccess + " No stale links")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be relinked", failed, len(results))
+	}
+	return nil
+}
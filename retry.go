@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// defaultOperationRetries is how many times an operation that failed with a
+// transient error is retried when operation_retries isn't set
+const defaultOperationRetries = 2
+
+// operationRetries is the active retry count, set from the loaded config
+var operationRetries = defaultOperationRetries
+
+// retryBackoff is the wait before the first retry; it doubles after each one
+var retryBackoff = 100 * time.Millisecond
+
+// useOperationRetries activates a configured retry count, falling back to
+// the default when it is unset or negative (Validate reports negative ones)
+func useOperationRetries(retries *int) {
+	operationRetries = defaultOperationRetries
+	if retries != nil && *retries >= 0 {
+		operationRetries = *retries
+	}
+}
+
+// isTransientError reports whether err comes from contention that usually
+// clears up by itself, such as a busy executable or a locked file
+func isTransientError(err error) bool {
+	return errors.Is(err, syscall.ETXTBSY) ||
+		errors.Is(err, syscall.EBUSY) ||
+		errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EINTR)
+}
+
+// markTransient marks err recoverable when it is transient, wrapping errors
+// that aren't ConfigErrors yet
+func markTransient(err error, file string) error {
+	if err == nil || !isTransientError(err) {
+		return err
+	}
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		configErr.Recoverable = true
+		return err
+	}
+	return NewRecoverableError("execute", file, err)
+}
+
+// executeWithRetry runs op, retrying it with a doubling backoff while it
+// fails with a recoverable error, up to operationRetries times. Whatever a
+// failed attempt managed to do is rolled back before the next one.
+func executeWithRetry(op Operation) error {
+	err := markTransient(op.Execute(), op.GetFile())
+	for attempt := 1; err != nil && IsRecoverable(err) && attempt <= operationRetries; attempt++ {
+		if rollbackErr := op.Rollback(); rollbackErr != nil {
+			logger.Debugf("%s: not retrying, undoing the failed attempt failed: %v", op.Description(), rollbackErr)
+			return err
+		}
+		
+		delay := retryBackoff << (attempt - 1)
+		logger.Debugf("%s: %v; retrying in %s (attempt %d of %d)", op.Description(), err, delay, attempt, operationRetries)
+		time.Sleep(delay)
+		err = markTransient(op.Execute(), op.GetFile())
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyOperation fails its first failures attempts with err
+type flakyOperation struct {
+	failures  int
+	err       error
+	attempts  int
+	rollbacks int
+}
+
+func (op *flakyOperation) Execute() error {
+	op.attempts++
+	if op.attempts <= op.failures {
+		return op.err
+	}
+	return nil
+}
+
+func (op *flakyOperation) Rollback() error {
+	op.rollbacks++
+	return nil
+}
+
+func (op *flakyOperation) Description() string { return "flaky operation" }
+func (op *flakyOperation) GetFile() string     { return "flaky" }
+
+// useRetries sets the retry count and drops the backoff for the rest of the test
+func useRetries(t *testing.T, retries int) {
+	t.Helper()
+	previousRetries, previousBackoff := operationRetries, retryBackoff
+	operationRetries, retryBackoff = retries, time.Millisecond
+	t.Cleanup(func() { operationRetries, retryBackoff = previousRetries, previousBackoff })
+}
+
+// textFileBusy is the error replacing a running executable fails with
+func textFileBusy() error {
+	return &os.PathError{Op: "open", Path: "/usr/local/bin/tool", Err: syscall.ETXTBSY}
+}
+
+func TestRetrySucceedsOnSecondAttempt(t *testing.T) {
+	config, _ := newTestConfig(t)
+	useRetries(t, 2)
+	op := &flakyOperation{failures: 1, err: textFileBusy()}
+
+	tx := NewTransaction(config)
+	tx.AddOperation(op)
+	if err := tx.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if op.attempts != 2 || op.rollbacks != 1 {
+		t.Errorf("attempts = %d, rollbacks = %d; want 2 and 1", op.attempts, op.rollbacks)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	useRetries(t, 2)
+	op := &flakyOperation{failures: 10, err: textFileBusy()}
+
+	err := executeWithRetry(op)
+	if !errors.Is(err, syscall.ETXTBSY) || !IsRecoverable(err) {
+		t.Errorf("error = %v, want the recoverable busy error", err)
+	}
+	if op.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", op.attempts)
+	}
+}
+
+func TestRetrySkipsPermanentErrors(t *testing.T) {
+	useRetries(t, 2)
+	op := &flakyOperation{failures: 1, err: &os.PathError{Op: "open", Path: "/etc/hosts", Err: syscall.EACCES}}
+
+	if err := executeWithRetry(op); err == nil || IsRecoverable(err) {
+		t.Errorf("error = %v, want it returned unrecoverable", err)
+	}
+	if op.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", op.attempts)
+	}
+}
+
+func TestUseOperationRetries(t *testing.T) {
+	useRetries(t, 0)
+	for _, test := range []struct {
+		retries *int
+		want    int
+	}{
+		{nil, defaultOperationRetries},
+		{intPtr(-1), defaultOperationRetries},
+		{intPtr(0), 0},
+		{intPtr(5), 5},
+	} {
+		useOperationRetries(test.retries)
+		if operationRetries != test.want {
+			t.Errorf("useOperationRetries(%v) = %d, want %d", test.retries, operationRetries, test.want)
+		}
+	}
+}
+
+func intPtr(n int) *int { return &n }
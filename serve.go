@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// skipConflictPrompts is set when there is nobody to ask about conflicts
+// (serve); link-all then skips every conflicting target
+var skipConflictPrompts bool
+
+// apiServer answers the HTTP API. Every request reloads config.json, so edits
+// made while serving are picked up, and requests run one at a time.
+type apiServer struct {
+	configDir string
+	token     string
+	mu        sync.Mutex
+}
+
+// apiFileStatus is one file in GET /status
+type apiFileStatus struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Status string `json:"status"`
+}
+
+// apiStatusReport is the body of GET /status
+type apiStatusReport struct {
+	Summary string          `json:"summary"`
+	Files   []apiFileStatus `json:"files"`
+}
+
+// apiLinkResult is the outcome of linking one file
+type apiLinkResult struct {
+	File    string `json:"file"`
+	Success bool   `json:"success"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runServe serves the HTTP API on 127.0.0.1 until interrupted
+func runServe(config *Config, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	port := flags.Int("port", 0, "port to listen on (0 picks a free one)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("usage: config-manager serve [--port N]")
+	}
+	
+	token, err := newAPIToken()
+	if err != nil {
+		return err
+	}
+	
+	// Only ever reachable from this machine
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(*port)))
+	if err != nil {
+		return NewConfigError("listen", fmt.Sprintf("127.0.0.1:%d", *port), err)
+	}
+	
+	skipConflictPrompts = true
+	api := &apiServer{configDir: config.ConfigDir, token: token}
+	server := &http.Server{
+		Handler:           api.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	
+	// Printed on stdout even with --quiet: clients read it to connect
+	fmt.Printf("Listening on http://%s\n", listener.Addr())
+	fmt.Printf("Token: %s\n", token)
+	infof("Send it as \"Authorization: Bearer <token>\"; press Ctrl+C to stop\n")
+	
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	
+	select {
+	case err := <-served:
+		return NewConfigError("serve", listener.Addr().String(), err)
+	case <-ctx.Done():
+	}
+	
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return NewConfigError("stop server", listener.Addr().String(), err)
+	}
+	infoln("Stopped")
+	return nil
+}
+
+// newAPIToken returns a random token clients must present
+func newAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", NewConfigError("generate token", "", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handle(http.MethodGet, s.handleStatus))
+	mux.HandleFunc("/files", s.handle(http.MethodGet, s.handleFiles))
+	mux.HandleFunc("/link/", s.handle(http.MethodPost, s.handleLink))
+	mux.HandleFunc("/link-all", s.handle(http.MethodPost, s.handleLinkAll))
+	return mux
+}
+
+// handle wraps an endpoint with the token check, the method check and a fresh
+// config, and writes what it returns as JSON
+func (s *apiServer) handle(method string, endpoint func(*Config, *http.Request) (int, interface{})) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			writeAPIJSON(w, http.StatusUnauthorized, apiError("missing or wrong token"))
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeAPIJSON(w, http.StatusMethodNotAllowed, apiError("use "+method))
+			return
+		}
+		
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		
+		config, err := loadConfigForCommand(s.configDir)
+		if err != nil {
+			writeAPIJSON(w, http.StatusInternalServerError, apiError(err.Error()))
+			return
+		}
+		status, body := endpoint(config, r)
+		writeAPIJSON(w, status, body)
+	}
+}
+
+// authorized checks the bearer token in constant time. The Bearer scheme is
+// required; a bare token is refused.
+func (s *apiServer) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// handleStatus reports every file's link status, like the inventory does
+func (s *apiServer) handleStatus(config *Config, r *http.Request) (int, interface{}) {
+	report := apiStatusReport{Files: make([]apiFileStatus, 0, len(config.Files))}
+	host := inventoryHost{}
+	for _, file := range config.Files {
+		status := apiFileStatus{Name: file.Name, Target: file.Target, Status: fileStatusName(file)}
+		report.Files = append(report.Files, status)
+		host.Files = append(host.Files, inventoryFile{Name: status.Name, Target: status.Target, Status: status.Status})
+	}
+	report.Summary = summarizeInventoryHost(host)
+	return http.StatusOK, report
+}
+
+// handleFiles lists the managed files as they are in config.json
+func (s *apiServer) handleFiles(config *Config, r *http.Request) (int, interface{}) {
+	files := make([]ConfigFile, len(config.Files))
+	for i, file := range config.Files {
+		files[i] = exportFile(file)
+	}
+	return http.StatusOK, files
+}
+
+// handleLink links the file named in the path, e.g. POST /link/nvim. A
+// conflicting target is skipped, as in link-all, and answered with 409.
+func (s *apiServer) handleLink(config *Config, r *http.Request) (int, interface{}) {
+	name := strings.TrimPrefix(r.URL.Path, "/link/")
+	matches := config.GetConfigFilesByName(name)
+	switch len(matches) {
+	case 0:
+		return http.StatusNotFound, apiError(fmt.Sprintf("no file named %q", name))
+	case 1:
+	default:
+		targets := make([]string, len(matches))
+		for i, file := range matches {
+			targets[i] = file.Target
+		}
+		return http.StatusConflict, apiError(fmt.Sprintf("%d files are named %q: %s", len(matches), name, strings.Join(targets, ", ")))
+	}
+	
+	file := matches[0]
+	conflict, err := batchConflict(config, file)
+	if err != nil {
+		return http.StatusInternalServerError, apiLinkResult{File: file.Name, Message: "Failed to link", Error: err.Error()}
+	}
+	if conflict != nil {
+		return http.StatusConflict, newAPILinkResult(skippedConflictResult(*file))
+	}
+	if _, err := linkConfigFile(config, file); err != nil {
+		return http.StatusInternalServerError, apiLinkResult{File: file.Name, Message: "Failed to link", Error: err.Error()}
+	}
+	saveLinkTimes(config)
+	return http.StatusOK, apiLinkResult{File: file.Name, Success: true, Message: "Successfully linked"}
+}
+
+// handleLinkAll links every file; conflicting targets are skipped, as there
+// is nobody to ask about them
+func (s *apiServer) handleLinkAll(config *Config, r *http.Request) (int, interface{}) {
+	results, err := applyAllConfigs(config)
+	if err != nil {
+		return http.StatusInternalServerError, apiError(err.Error())
+	}
+	saveLinkTimes(config)
+	
+	status := http.StatusOK
+	body := make([]apiLinkResult, len(results))
+	for i, result := range results {
+		body[i] = newAPILinkResult(result)
+		if !result.Success {
+			status = http.StatusMultiStatus
+		}
+	}
+	return status, body
+}
+
+// newAPILinkResult reports the outcome of linking one file
+func newAPILinkResult(result OperationResult) apiLinkResult {
+	body := apiLinkResult{File: result.File, Success: result.Success, Skipped: result.Skipped, Message: result.Message}
+	if result.Error != nil {
+		body.Error = result.Error.Error()
+	}
+	return body
+}
+
+// apiError is the body of a failed request
+func apiError(message string) map[string]string {
+	return map[string]string{"error": message}
+}
+
+// writeAPIJSON writes body as an indented JSON response
+func writeAPIJSON(w http.ResponseWriter, status int, body interface{}) {
+	data, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(apiError("encode response: " + err.Error()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
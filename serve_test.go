@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestAPI serves the API for a config with a file whose target is free
+// ("clean") and one whose target is an unrelated file ("conflicted")
+func newTestAPI(t *testing.T) (*httptest.Server, *Config) {
+	t.Helper()
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "shell", "zshrc"), "from dotfiles\n")
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "git", "gitconfig"), "from dotfiles\n")
+	writeTestFile(t, filepath.Join(home, ".gitconfig"), "local edits\n")
+	config.Files = []ConfigFile{
+		{Name: "clean", Source: "shell/zshrc", Target: filepath.Join(home, ".zshrc"), Category: "shell"},
+		{Name: "conflicted", Source: "git/gitconfig", Target: filepath.Join(home, ".gitconfig"), Category: "git"},
+	}
+	if err := saveConfigSafe(config); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := skipConflictPrompts
+	skipConflictPrompts = true
+	t.Cleanup(func() { skipConflictPrompts = previous })
+
+	server := httptest.NewServer((&apiServer{configDir: config.ConfigDir, token: "secret"}).routes())
+	t.Cleanup(server.Close)
+	return server, config
+}
+
+// apiRequest sends a request with the given Authorization header and returns
+// the response status, decoding the body into out when it is non-nil
+func apiRequest(t *testing.T, server *httptest.Server, method, path, authorization string, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// assertUntouched fails unless path is still a regular file holding content
+func assertUntouched(t *testing.T, path, content string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if info, lerr := os.Lstat(path); lerr != nil || info.Mode()&os.ModeSymlink != 0 || err != nil || string(data) != content {
+		t.Errorf("%s was replaced, want it left holding %q", path, content)
+	}
+	if backups, _ := filepath.Glob(path + ".backup*"); len(backups) != 0 {
+		t.Errorf("%s was backed up to %q", path, backups)
+	}
+}
+
+func TestAPIAuthorization(t *testing.T) {
+	server, _ := newTestAPI(t)
+
+	tests := []struct {
+		authorization string
+		want          int
+	}{
+		{"", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Basic secret", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := apiRequest(t, server, http.MethodGet, "/status", tt.authorization, nil); got != tt.want {
+			t.Errorf("Authorization %q: status %d, want %d", tt.authorization, got, tt.want)
+		}
+	}
+}
+
+func TestAPILinkSkipsConflicts(t *testing.T) {
+	server, config := newTestAPI(t)
+
+	var result apiLinkResult
+	if status := apiRequest(t, server, http.MethodPost, "/link/conflicted", "Bearer secret", &result); status != http.StatusConflict {
+		t.Errorf("conflicting target: status %d, want %d", status, http.StatusConflict)
+	}
+	if !result.Skipped {
+		t.Errorf("conflicting target: result %+v, want it skipped", result)
+	}
+	assertUntouched(t, config.Files[1].Target, "local edits\n")
+
+	if status := apiRequest(t, server, http.MethodPost, "/link/clean", "Bearer secret", &result); status != http.StatusOK || !result.Success {
+		t.Errorf("free target: status %d, result %+v", status, result)
+	}
+	if _, err := os.Readlink(config.Files[0].Target); err != nil {
+		t.Errorf("free target wasn't linked: %v", err)
+	}
+}
+
+func TestAPILinkAllSkipsConflicts(t *testing.T) {
+	server, config := newTestAPI(t)
+
+	var results []apiLinkResult
+	if status := apiRequest(t, server, http.MethodPost, "/link-all", "Bearer secret", &results); status != http.StatusOK {
+		t.Errorf("status %d, want %d", status, http.StatusOK)
+	}
+	if len(results) != 2 || !results[0].Success || results[0].Skipped || !results[1].Skipped {
+		t.Errorf("results %+v, want clean linked and conflicted skipped", results)
+	}
+	assertUntouched(t, config.Files[1].Target, "local edits\n")
+	if _, err := os.Readlink(config.Files[0].Target); err != nil {
+		t.Errorf("free target wasn't linked: %v", err)
+	}
+}
@@ -1,64 +1,57 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 // Initial setup wizard using Gum with fallback
-func runSetupWizard() (*Config, error) {
-	homeDir, _ := os.UserHomeDir()
-	configDir := filepath.Join(homeDir, ".config", "config-manager")
-	
-	fmt.Println("🎉 Welcome to Config Manager!")
-	fmt.Println("Let's set up your configuration management...")
-	fmt.Println()
+func runSetupWizard(configDir string) (*Config, error) {
+	infoln(decoration("🎉") + "Welcome to Config Manager!")
+	infoln("Let's set up your configuration management...")
+	infoln()
 	
 	// Check if we're in an interactive terminal and gum works
 	useGum := false
-	if _, err := exec.LookPath("gum"); err == nil {
+	if _, err := runner.LookPath("gum"); err == nil {
 		// Test if gum works in this environment
-		testCmd := exec.Command("gum", "choose", "--timeout=1s", "test")
-		testCmd.Stdin = os.Stdin
-		if err := testCmd.Run(); err == nil || strings.Contains(err.Error(), "timeout") {
+		if err := runner.Run("gum", "choose", "--timeout=1s", "test"); err == nil || strings.Contains(err.Error(), "timeout") {
 			useGum = true
 		}
 	}
 	
 	if !useGum {
-		fmt.Println("Using text-based setup (Gum not available or not working in this environment)")
+		infoln("Using text-based setup (Gum not available or not working in this environment)")
 		return runTextSetup(configDir)
 	}
 	
 	// Step 1: Choose preferred tools with Gum
-	fmt.Println("🛠️  Step 1: Tool Preferences")
+	infoln(decoration("🛠️ ") + "Step 1: Tool Preferences")
 	
 	// Editor selection
 	editor := selectEditor()
-	fmt.Printf("✅ Editor: %s\n\n", editor)
+	infof("%s Editor: %s\n\n", glyphSuccess, editor)
 	
 	// Shell selection
 	shell := selectShell()
-	fmt.Printf("✅ Shell: %s\n\n", shell)
+	infof("%s Shell: %s\n\n", glyphSuccess, shell)
 	
 	// Step 2: Discover and choose configs to manage
-	fmt.Println("📁 Step 2: Configuration Discovery")
-	fmt.Println("Scanning for configuration files and directories...")
+	infoln(decoration("📁") + "Step 2: Configuration Discovery")
+	infoln("Scanning for configuration files and directories...")
 	
-	selectedConfigs := selectConfigs()
+	selectedConfigs := selectConfigs(configDir)
 	
 	return createConfigFromSetup(configDir, editor, shell, selectedConfigs)
 }
 
 func selectEditor() string {
 	fmt.Println("Choose your preferred editor (use arrow keys and enter):")
-	editorCmd := exec.Command("gum", "choose", "vim", "nvim", "emacs", "nano", "code", "other")
-	editorCmd.Stdin = os.Stdin
-	editorOutput, err := editorCmd.Output()
+	editorOutput, err := runner.Output("gum", "choose", "vim", "nvim", "emacs", "nano", "code", "other")
 	if err != nil {
 		return "vim"
 	}
@@ -70,9 +63,7 @@ func selectEditor() string {
 	
 	if editor == "other" {
 		fmt.Print("Enter your editor command: ")
-		customEditorCmd := exec.Command("gum", "input", "--placeholder", "editor command")
-		customEditorCmd.Stdin = os.Stdin
-		customOutput, err := customEditorCmd.Output()
+		customOutput, err := runner.Output("gum", "input", "--placeholder", "editor command")
 		if err == nil && strings.TrimSpace(string(customOutput)) != "" {
 			editor = strings.TrimSpace(string(customOutput))
 		} else {
@@ -85,9 +76,7 @@ func selectEditor() string {
 
 func selectShell() string {
 	fmt.Println("Choose your preferred shell (use arrow keys and enter):")
-	shellCmd := exec.Command("gum", "choose", "bash", "zsh", "fish", "other")
-	shellCmd.Stdin = os.Stdin
-	shellOutput, err := shellCmd.Output()
+	shellOutput, err := runner.Output("gum", "choose", "bash", "zsh", "fish", "other")
 	if err != nil {
 		return "bash"
 	}
@@ -99,9 +88,7 @@ func selectShell() string {
 	
 	if shell == "other" {
 		fmt.Print("Enter your shell name: ")
-		customShellCmd := exec.Command("gum", "input", "--placeholder", "shell name")
-		customShellCmd.Stdin = os.Stdin
-		customOutput, err := customShellCmd.Output()
+		customOutput, err := runner.Output("gum", "input", "--placeholder", "shell name")
 		if err == nil && strings.TrimSpace(string(customOutput)) != "" {
 			shell = strings.TrimSpace(string(customOutput))
 		} else {
@@ -112,27 +99,24 @@ func selectShell() string {
 	return shell
 }
 
-func selectConfigs() []string {
-	configChoices := discoverAllConfigs()
-	fmt.Printf("Found %d potential configurations\n", len(configChoices))
+func selectConfigs(configDir string) []string {
+	configChoices := discoverAllConfigs(createMinimalConfig(configDir))
+	infof("Found %d potential configurations\n", len(configChoices))
 	
 	var selectedConfigs []string
 	
 	if len(configChoices) == 0 {
-		fmt.Println("No configuration files found. You can add them later using 'a' in the application.")
+		infoln("No configuration files found. You can add them later using 'a' in the application.")
 		return []string{}
 	}
 	
 	fmt.Println("\nSelect configurations to manage (use space to select, enter to confirm):")
 	
-	chooseCmd := exec.Command("gum", "choose", "--no-limit")
-	chooseCmd.Args = append(chooseCmd.Args, configChoices...)
-	chooseCmd.Stdin = os.Stdin
-	
-	chosenOutput, err := chooseCmd.Output()
+	args := append([]string{"choose", "--no-limit"}, configChoices...)
+	chosenOutput, err := runner.Output("gum", args...)
 	if err != nil {
-		fmt.Printf("❌ Config selection cancelled or failed: %v\n", err)
-		fmt.Println("Continuing with empty configuration. You can add configs later with 'a'.")
+		warnf("%s Config selection cancelled or failed: %v\n", glyphError, err)
+		warnf("Continuing with empty configuration. You can add configs later with 'a'.\n")
 		return []string{}
 	}
 	
@@ -144,26 +128,26 @@ func selectConfigs() []string {
 		}
 	}
 	selectedConfigs = filtered
-	fmt.Printf("✅ Selected %d configurations\n", len(selectedConfigs))
+	infof("%s Selected %d configurations\n", glyphSuccess, len(selectedConfigs))
 	
 	return selectedConfigs
 }
 
 // Text-based setup fallback
 func runTextSetup(configDir string) (*Config, error) {
-	fmt.Println("\n📝 Text-based Setup")
+	infoln("\n" + decoration("📝") + "Text-based Setup")
 	
 	// Editor selection
-	fmt.Println("\n🛠️  Step 1: Tool Preferences")
+	infoln("\n" + decoration("🛠️ ") + "Step 1: Tool Preferences")
 	editor := selectEditorText()
-	fmt.Printf("✅ Editor: %s\n", editor)
+	infof("%s Editor: %s\n", glyphSuccess, editor)
 	
 	// Shell selection
 	shell := selectShellText()
-	fmt.Printf("✅ Shell: %s\n", shell)
+	infof("%s Shell: %s\n", glyphSuccess, shell)
 	
 	// Config discovery
-	selectedConfigs := selectConfigsText()
+	selectedConfigs := selectConfigsText(configDir)
 	
 	return createConfigFromSetup(configDir, editor, shell, selectedConfigs)
 }
@@ -201,7 +185,7 @@ func selectEditorText() string {
 		}
 		return editor
 	default:
-		fmt.Println("Invalid choice, using vim")
+		warnf("Invalid choice, using vim\n")
 		return "vim"
 	}
 }
@@ -233,20 +217,20 @@ func selectShellText() string {
 		}
 		return shell
 	default:
-		fmt.Println("Invalid choice, using bash")
+		warnf("Invalid choice, using bash\n")
 		return "bash"
 	}
 }
 
-func selectConfigsText() []string {
-	fmt.Println("\n📁 Step 2: Configuration Discovery")
-	fmt.Println("Scanning for configuration files and directories...")
+func selectConfigsText(configDir string) []string {
+	infoln("\n" + decoration("📁") + "Step 2: Configuration Discovery")
+	infoln("Scanning for configuration files and directories...")
 	
-	configChoices := discoverAllConfigs()
-	fmt.Printf("Found %d potential configurations\n", len(configChoices))
+	configChoices := discoverAllConfigs(createMinimalConfig(configDir))
+	infof("Found %d potential configurations\n", len(configChoices))
 	
 	if len(configChoices) == 0 {
-		fmt.Println("No configuration files found. You can add them later using 'a' in the application.")
+		infoln("No configuration files found. You can add them later using 'a' in the application.")
 		return []string{}
 	}
 	
@@ -274,10 +258,113 @@ func selectConfigsText() []string {
 		}
 	}
 	
-	fmt.Printf("✅ Selected %d configurations\n", len(selectedConfigs))
+	infof("%s Selected %d configurations\n", glyphSuccess, len(selectedConfigs))
 	return selectedConfigs
 }
 
+// stringListFlag collects the values of a flag that may be given repeatedly
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// runSetup creates the configuration. Without --non-interactive it runs the
+// setup wizard; with it, nothing is asked and the editor, shell and managed
+// files come from flags, so a provisioning script can configure a new machine.
+func runSetup(config *Config, args []string) error {
+	flags := flag.NewFlagSet("setup", flag.ContinueOnError)
+	nonInteractive := flags.Bool("non-interactive", false, "don't prompt; configure from --editor, --shell and --adopt")
+	editor := flags.String("editor", "vim", "editor to use")
+	shell := flags.String("shell", "bash", "shell to use")
+	var adopt stringListFlag
+	flags.Var(&adopt, "adopt", "manage the discovered configs matching this `glob`, e.g. '.config/nvim' or '.*rc' (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("usage: config-manager setup [--non-interactive [--editor E] [--shell S] [--adopt <glob>]...]")
+	}
+	
+	// Setting up again would throw away the managed files
+	configFile := filepath.Join(config.ConfigDir, "config.json")
+	if _, err := os.Stat(configFile); err == nil {
+		return NewConfigError("setup", configFile, fmt.Errorf("a configuration already exists"))
+	}
+	
+	if !*nonInteractive {
+		var given []string
+		flags.Visit(func(f *flag.Flag) {
+			given = append(given, "--"+f.Name)
+		})
+		if len(given) > 0 {
+			return fmt.Errorf("use --non-interactive with %s", strings.Join(given, ", "))
+		}
+		_, err := runSetupWizard(config.ConfigDir)
+		return err
+	}
+	
+	if strings.TrimSpace(*editor) == "" || strings.TrimSpace(*shell) == "" {
+		return fmt.Errorf("--editor and --shell cannot be empty")
+	}
+	for _, pattern := range adopt {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --adopt pattern %q: %v", pattern, err)
+		}
+	}
+	
+	selected := adoptDiscoveredConfigs(config, adopt)
+	_, err := createConfigFromSetup(config.ConfigDir, *editor, *shell, selected)
+	return err
+}
+
+// adoptDiscoveredConfigs returns the discovered configs matching any of the
+// patterns, in the wizard's selection format. Like apply --only, a pattern is
+// matched against the path as listed (relative to the home directory), its
+// base name and the full target path.
+func adoptDiscoveredConfigs(config *Config, patterns []string) []string {
+	if len(patterns) == 0 {
+		return nil
+	}
+	
+	var selected []string
+	used := make([]bool, len(patterns))
+	for _, choice := range discoverAllConfigs(config) {
+		path := strings.TrimSpace(choice)
+		if i := strings.LastIndex(path, " ("); i >= 0 {
+			path = path[:i]
+		}
+		candidates := []string{path, filepath.Base(path), resolveHomePath(path)}
+		
+		matched := false
+		for i, pattern := range patterns {
+			for _, candidate := range candidates {
+				if ok, _ := filepath.Match(pattern, candidate); ok {
+					used[i] = true
+					matched = true
+					break
+				}
+			}
+		}
+		if matched {
+			selected = append(selected, choice)
+		}
+	}
+	
+	for i, pattern := range patterns {
+		if !used[i] {
+			warnf("%s --adopt %s matched no discovered config\n", glyphWarning, pattern)
+		}
+	}
+	infof("%s Adopting %d configurations\n", glyphSuccess, len(selected))
+	return selected
+}
+
 // Common config creation logic
 func createConfigFromSetup(configDir, editor, shell string, selectedConfigs []string) (*Config, error) {
 	config := &Config{
@@ -289,6 +376,7 @@ func createConfigFromSetup(configDir, editor, shell string, selectedConfigs []st
 		Editor:       editor,
 		Shell:        shell,
 		Files:        []ConfigFile{},
+		DiscoveryDepth: defaultDiscoveryDepth,
 	}
 	
 	// Convert selected configs to ConfigFile structs
@@ -302,32 +390,32 @@ func createConfigFromSetup(configDir, editor, shell string, selectedConfigs []st
 			config.Files = append(config.Files, configFile)
 			successCount++
 		} else {
-			fmt.Printf("⚠️  Failed to add %s: %v\n", selected, err)
+			warnf("%s Failed to add %s: %v\n", glyphWarning, selected, err)
 		}
 	}
 	
 	// Create directories and save config
-	os.MkdirAll(configDir, 0755)
-	saveConfig(config)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, NewConfigError("create config directory", configDir, err)
+	}
+	if err := saveConfigSafe(config); err != nil {
+		return nil, err
+	}
 	
-	fmt.Printf("\n🎉 Setup complete! Managing %d configurations.\n", successCount)
+	infof("\n%sSetup complete! Managing %d configurations.\n", decoration("🎉"), successCount)
 	if successCount == 0 {
-		fmt.Println("You can add configurations later using 'a' in the application.")
+		infoln("You can add configurations later using 'a' in the application.")
 	} else {
-		fmt.Println("Use 'l' to link your configurations when ready.")
+		infoln("Use 'l' to link your configurations when ready.")
 	}
-	fmt.Println("Starting Config Manager...")
-	fmt.Println()
 	
 	return config, nil
 }
 
 // Create ConfigFile from user selection
 func createConfigFileFromSelection(selection string, config *Config) (ConfigFile, error) {
-	homeDir, _ := os.UserHomeDir()
-	
-	// Parse selection format: "path (type)"
-	parts := strings.Split(selection, " (")
+	// Parse selection format: "path (type)", ignoring any depth indentation
+	parts := strings.Split(strings.TrimSpace(selection), " (")
 	if len(parts) != 2 {
 		return ConfigFile{}, fmt.Errorf("invalid selection format")
 	}
@@ -335,40 +423,12 @@ func createConfigFileFromSelection(selection string, config *Config) (ConfigFile
 	path := parts[0]
 	fileType := strings.TrimSuffix(parts[1], ")")
 	
-	targetPath := filepath.Join(homeDir, path)
+	targetPath := resolveHomePath(path)
 	fileName := filepath.Base(path)
 	
 	// Auto-categorize
-	category := categorizeDotfile(fileName, config.Categories)
-	
-	// Check if it might be a template (only for files, not directories)
-	isTemplate := false
-	if fileType == "file" {
-		if data, err := os.ReadFile(targetPath); err == nil {
-			content := strings.ToLower(string(data))
-			if strings.Contains(content, "{{") || 
-			   strings.Contains(content, "$user") || 
-			   strings.Contains(content, "$email") ||
-			   strings.Contains(content, "$editor") {
-				isTemplate = true
-			}
-		}
-	}
-	
-	// Determine source path in dotfiles directory
-	sourcePath := filepath.Join(category, strings.TrimPrefix(fileName, "."))
-	if strings.Contains(path, ".config/") {
-		// For .config subdirectories, preserve the structure
-		relativePath := strings.TrimPrefix(path, ".config/")
-		sourcePath = filepath.Join("config", relativePath)
-	}
+	category := categorizeDotfile(config, fileName)
 	
-	return ConfigFile{
-		Name:      fileName,
-		Source:    sourcePath,
-		Target:    targetPath,
-		Category:  category,
-		Template:  isTemplate,
-		Variables: make(map[string]string),
-	}, nil
+	// Same source layout and template detection as the main add flow
+	return buildConfigFile(targetPath, fileName, category, fileType == "directory"), nil
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectEditorAsksForOther(t *testing.T) {
+	fake := &scriptedRunner{Responses: []scriptedResponse{
+		{Output: "other\n"},
+		{Output: "hx\n"},
+	}}
+	useRunner(t, fake)
+
+	if editor := selectEditor(); editor != "hx" {
+		t.Errorf("selectEditor() = %q, want %q", editor, "hx")
+	}
+	if len(fake.Calls) != 2 || fake.Calls[1][1] != "input" {
+		t.Errorf("calls = %q, want gum choose then gum input", fake.Calls)
+	}
+}
+
+func TestSelectShellDefaultsWhenGumFails(t *testing.T) {
+	useRunner(t, &scriptedRunner{Responses: []scriptedResponse{
+		{Err: errors.New("interrupted")},
+	}})
+
+	if shell := selectShell(); shell != "bash" {
+		t.Errorf("selectShell() = %q, want %q", shell, "bash")
+	}
+}
+
+func TestSelectConfigsSplitsChoices(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	writeTestFile(t, home+"/.bashrc", "")
+	writeTestFile(t, home+"/.vimrc", "")
+
+	fake := &scriptedRunner{Responses: []scriptedResponse{
+		{Output: "~/.bashrc\n\n~/.vimrc\n"},
+	}}
+	useRunner(t, fake)
+
+	selected := selectConfigs(home + "/.config/config-manager")
+	if len(selected) != 2 || selected[0] != "~/.bashrc" || selected[1] != "~/.vimrc" {
+		t.Errorf("selectConfigs() = %q, want the two chosen configs", selected)
+	}
+	if len(fake.Calls) != 1 || fake.Calls[0][1] != "choose" {
+		t.Errorf("calls = %q, want one gum choose", fake.Calls)
+	}
+}
+
+func TestNonInteractiveSetupFailsWhenSaveFails(t *testing.T) {
+	config, _ := newTestConfig(t)
+	configFile := filepath.Join(config.ConfigDir, "config.json")
+	// A directory in the way of the temporary file makes the save fail
+	if err := os.MkdirAll(configFile+".tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runSetup(config, []string{"--non-interactive"}); err == nil {
+		t.Fatal("setup succeeded without saving the configuration")
+	}
+	if _, err := os.Stat(configFile); !os.IsNotExist(err) {
+		t.Errorf("config.json exists (%v), want nothing saved", err)
+	}
+
+	if err := os.Remove(configFile + ".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSetup(config, []string{"--non-interactive", "--editor", "nvim"}); err != nil {
+		t.Fatal(err)
+	}
+	var saved Config
+	readJSONFile(t, configFile, &saved)
+	if saved.Editor != "nvim" {
+		t.Errorf("saved editor %q, want nvim", saved.Editor)
+	}
+}
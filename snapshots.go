@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotsDirName is the directory under ConfigDir holding named snapshots
+const snapshotsDirName = "snapshots"
+
+// Kinds of target recorded in a snapshot
+const (
+	snapshotKindSymlink   = "symlink"
+	snapshotKindFile      = "file"
+	snapshotKindDirectory = "directory"
+	snapshotKindMissing   = "missing"
+)
+
+// snapshotEntry records the state of one target when the snapshot was taken
+type snapshotEntry struct {
+	Target    string `json:"target"`
+	Kind      string `json:"kind"`
+	LinkValue string `json:"link_value,omitempty"` // symlinks: what the link stored
+	Stored    string `json:"stored,omitempty"`     // files and directories: copy inside the snapshot
+}
+
+// snapshotInfo is the manifest of a snapshot
+type snapshotInfo struct {
+	Name    string          `json:"name"`
+	Created time.Time       `json:"created"`
+	Entries []snapshotEntry `json:"entries"`
+	// LocalConfig is snapshotKindFile when config.local.json was copied and
+	// snapshotKindMissing when there was none; empty in snapshots taken before
+	// the overlay was recorded
+	LocalConfig string `json:"local_config,omitempty"`
+}
+
+// snapshotDir returns where the named snapshot is stored
+func snapshotDir(config *Config, name string) string {
+	return filepath.Join(config.ConfigDir, snapshotsDirName, name)
+}
+
+// validateSnapshotName rejects names that can't be used as a directory name
+func validateSnapshotName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return NewValidationError("snapshot", name, "snapshot name must be a non-empty name without slashes", "")
+	}
+	return nil
+}
+
+// createSnapshot records every managed target plus config.json and
+// config.local.json under
+// ConfigDir/snapshots/<name>. Symlinks are recorded by what they point to;
+// files and directories are copied. Dotfiles sources are not copied.
+func createSnapshot(config *Config, name string) error {
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+	
+	dir := snapshotDir(config, name)
+	if fileExists(dir) {
+		return NewConfigError("create snapshot", name, fmt.Errorf("a snapshot with this name already exists"))
+	}
+	
+	info := snapshotInfo{Name: name, Created: time.Now()}
+	seen := make(map[string]bool)
+	
+	for _, file := range config.Files {
+		resolved, err := resolveTarget(config, &file)
+		if err != nil {
+			return err
+		}
+		targets := []string{resolved}
+		
+		// Tree-linked directories are captured link by link; untracked files in them are left alone
+		if targetInfo, err := os.Lstat(resolved); err == nil && targetInfo.IsDir() && file.effectiveLinkStrategy() == LinkStrategyTree {
+			targets = nil
+			filepath.Walk(resolved, func(path string, walkInfo os.FileInfo, err error) error {
+				if err == nil && walkInfo.Mode()&os.ModeSymlink != 0 {
+					targets = append(targets, path)
+				}
+				return nil
+			})
+		}
+		
+		for _, target := range targets {
+			if seen[target] {
+				continue
+			}
+			seen[target] = true
+			
+			entry, err := snapshotTarget(dir, target, len(info.Entries))
+			if err != nil {
+				os.RemoveAll(dir)
+				return err
+			}
+			info.Entries = append(info.Entries, entry)
+		}
+	}
+	
+	// Keep the config exactly as it is on disk
+	configFile := filepath.Join(config.ConfigDir, "config.json")
+	if err := ensureDir(dir); err != nil {
+		return err
+	}
+	if err := copyFile(configFile, filepath.Join(dir, "config.json")); err != nil {
+		os.RemoveAll(dir)
+		return NewConfigError("snapshot config", configFile, err)
+	}
+	info.LocalConfig = snapshotKindMissing
+	if localFile := config.localConfigPath(); fileExists(localFile) {
+		if err := copyFile(localFile, filepath.Join(dir, localConfigName)); err != nil {
+			os.RemoveAll(dir)
+			return NewConfigError("snapshot local config", localFile, err)
+		}
+		info.LocalConfig = snapshotKindFile
+	}
+	
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		os.RemoveAll(dir)
+		return NewConfigError("encode snapshot", name, err)
+	}
+	if err := atomicWrite(filepath.Join(dir, "snapshot.json"), data, 0644); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	
+	return nil
+}
+
+// snapshotTarget records a single target, copying its content into dir when
+// it is a real file or directory
+func snapshotTarget(dir, target string, index int) (snapshotEntry, error) {
+	entry := snapshotEntry{Target: target}
+	
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		entry.Kind = snapshotKindMissing
+		return entry, nil
+	}
+	if err != nil {
+		return entry, NewConfigError("snapshot target", target, err)
+	}
+	
+	if info.Mode()&os.ModeSymlink != 0 {
+		linkValue, err := os.Readlink(target)
+		if err != nil {
+			return entry, NewConfigError("snapshot target", target, err)
+		}
+		entry.Kind = snapshotKindSymlink
+		entry.LinkValue = linkValue
+		return entry, nil
+	}
+	
+	entry.Stored = filepath.Join("targets", fmt.Sprintf("%d", index))
+	storedPath := filepath.Join(dir, entry.Stored)
+	if err := ensureDir(filepath.Dir(storedPath)); err != nil {
+		return entry, err
+	}
+	
+	if info.IsDir() {
+		entry.Kind = snapshotKindDirectory
+		err = copyDirectory(target, storedPath)
+	} else {
+		entry.Kind = snapshotKindFile
+		err = copyFile(target, storedPath)
+	}
+	if err != nil {
+		return entry, NewConfigError("snapshot target", target, err)
+	}
+	
+	return entry, nil
+}
+
+// listSnapshots returns the available snapshots, newest first
+func listSnapshots(config *Config) ([]snapshotInfo, error) {
+	snapshotsDir := filepath.Join(config.ConfigDir, snapshotsDirName)
+	entries, err := os.ReadDir(snapshotsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewConfigError("list snapshots", snapshotsDir, err)
+	}
+	
+	var snapshots []snapshotInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := readSnapshot(config, entry.Name())
+		if err != nil {
+			// Skip anything that isn't a complete snapshot
+			continue
+		}
+		snapshots = append(snapshots, *info)
+	}
+	
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.After(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+// readSnapshot loads the manifest of the named snapshot
+func readSnapshot(config *Config, name string) (*snapshotInfo, error) {
+	manifestPath := filepath.Join(snapshotDir(config, name), "snapshot.json")
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, NewConfigError("read snapshot", name, err)
+	}
+	
+	var info snapshotInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, NewConfigError("parse snapshot", manifestPath, err)
+	}
+	return &info, nil
+}
+
+// restoreSnapshot puts every recorded target back the way it was in one
+// transaction, then restores config.json and reloads it into config. Targets
+// that get replaced are kept as .backup.<timestamp> files next to them.
+func restoreSnapshot(config *Config, name string) error {
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+	
+	info, err := readSnapshot(config, name)
+	if err != nil {
+		return err
+	}
+	dir := snapshotDir(config, name)
+	
+	tx := NewTransaction(config)
+	for _, entry := range info.Entries {
+		switch entry.Kind {
+		case snapshotKindSymlink:
+			linkOp := NewLinkOperation(resolveLinkTarget(entry.Target, entry.LinkValue), entry.Target, nil)
+			linkOp.relative = !filepath.IsAbs(entry.LinkValue)
+			tx.AddOperation(linkOp)
+		case snapshotKindFile, snapshotKindDirectory:
+			storedPath := filepath.Join(dir, entry.Stored)
+			if !isCopyInSync(storedPath, entry.Target) {
+				tx.AddOperation(NewCopyOperation(storedPath, entry.Target, nil))
+			}
+		case snapshotKindMissing:
+			if fileExistsNoFollow(entry.Target) {
+				tx.AddOperation(NewRemoveOperation(entry.Target, nil))
+			}
+		}
+	}
+	
+	if err := tx.Execute(); err != nil {
+		return NewConfigError("restore snapshot", name, err)
+	}
+	
+	// Targets are restored; now bring back the matching config
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return NewConfigError("restore snapshot config", name, err)
+	}
+	configFile := filepath.Join(config.ConfigDir, "config.json")
+	if err := atomicWrite(configFile, data, 0644); err != nil {
+		return err
+	}
+	if err := restoreSnapshotLocalConfig(config, dir, info.LocalConfig); err != nil {
+		return err
+	}
+	
+	restored, err := loadConfigFile(configFile, config.ConfigDir)
+	if err != nil {
+		return NewConfigError("reload restored config", configFile, err)
+	}
+	*config = *restored
+	updateFileStatuses(config)
+	
+	return nil
+}
+
+// restoreSnapshotLocalConfig puts config.local.json back the way the snapshot
+// in dir recorded it: copied back, or removed if there was none. An overlay
+// that differs is backed up first. Older snapshots that didn't record it
+// leave it alone.
+func restoreSnapshotLocalConfig(config *Config, dir, recorded string) error {
+	localFile := config.localConfigPath()
+	stored := filepath.Join(dir, localConfigName)
+	switch {
+	case recorded == "":
+		return nil
+	case recorded == snapshotKindFile && isCopyInSync(stored, localFile):
+		return nil
+	}
+	
+	if fileExists(localFile) {
+		backups := config.backupPolicy()
+		backupPath := backups.targetPath(localFile)
+		if err := backups.makeRoom(backupPath, os.RemoveAll); err != nil {
+			return NewConfigError("prepare backup", backupPath, err)
+		}
+		if err := moveFile(localFile, backupPath); err != nil {
+			return NewConfigError("backup local config", localFile, err)
+		}
+	}
+	if recorded == snapshotKindFile {
+		if err := copyFile(stored, localFile); err != nil {
+			return NewConfigError("restore snapshot local config", localFile, err)
+		}
+	}
+	return nil
+}
+
+// deleteSnapshot removes a snapshot and everything stored in it
+func deleteSnapshot(config *Config, name string) error {
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+	
+	if err := os.RemoveAll(snapshotDir(config, name)); err != nil {
+		return NewConfigError("delete snapshot", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRestoresLocalConfig(t *testing.T) {
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "shell", "zshrc"), "")
+	config.Files = []ConfigFile{{Name: "zshrc", Source: "shell/zshrc", Target: filepath.Join(home, ".zshrc"), Category: "shell"}}
+	if err := saveConfigSafe(config); err != nil {
+		t.Fatal(err)
+	}
+	localFile := config.localConfigPath()
+	writeTestFile(t, localFile, `{"editor": "nvim"}`)
+
+	if err := createSnapshot(config, "with-overlay"); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(localFile)
+	if err := createSnapshot(config, "without-overlay"); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, localFile, `{"editor": "emacs"}`)
+	if err := restoreSnapshot(config, "with-overlay"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(localFile); string(data) != `{"editor": "nvim"}` {
+		t.Errorf("config.local.json = %q after restoring, want the snapshot's", data)
+	}
+	if config.Editor != "nvim" {
+		t.Errorf("restored config uses editor %q, want the overlay's nvim", config.Editor)
+	}
+
+	if err := restoreSnapshot(config, "without-overlay"); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(localFile) {
+		t.Error("config.local.json added after the snapshot was kept")
+	}
+	if config.Editor != "vim" {
+		t.Errorf("restored config uses editor %q, want the base vim", config.Editor)
+	}
+}
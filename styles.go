@@ -1,6 +1,11 @@
 package main
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"io"
+	"os"
+	
+	"github.com/charmbracelet/lipgloss"
+)
 
 // Styles
 var (
@@ -46,4 +51,86 @@ var (
 	
 	helpSeparatorStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#6C7086"))
+			
+	// Diff view lines
+	diffDeleteStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F38BA8"))
+			
+	diffInsertStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#A6E3A1"))
+			
+	diffHunkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#89B4FA"))
+)
+
+// Status glyphs shared by the TUI and command output. usePlainOutput swaps
+// them for ASCII markers.
+var (
+	glyphLinked    = "✓"
+	glyphUnlinked  = "✗"
+	glyphConflict  = "⚠️"
+	glyphDrifted   = "≠"
+	glyphModified  = "↻"
+	glyphDisabled  = "⏸"
+	glyphExpanded  = "▾"
+	glyphCollapsed = "▸"
+	glyphSuccess   = "✅"
+	glyphError     = "❌"
+	glyphWarning   = "⚠️ "
+	glyphSeparator = " • "
+	
+	// plainOutput drops colors and decorative emoji
+	plainOutput bool
 )
+
+// noColorRequested reports whether --no-color was given or NO_COLOR is set
+// (to any value, per no-color.org)
+func noColorRequested(flagSet bool) bool {
+	_, envSet := os.LookupEnv("NO_COLOR")
+	return flagSet || envSet
+}
+
+// usePlainOutput switches lipgloss to a colorless profile and every status
+// glyph to ASCII. It must run before any TUI components are created.
+func usePlainOutput() {
+	// A renderer writing to a non-terminal detects the plain ASCII profile
+	lipgloss.SetColorProfile(lipgloss.NewRenderer(io.Discard).ColorProfile())
+	
+	glyphLinked = "[OK]"
+	glyphUnlinked = "[X]"
+	glyphConflict = "[!]"
+	glyphDrifted = "[~]"
+	glyphModified = "[*]"
+	glyphDisabled = "[-]"
+	glyphExpanded = "v"
+	glyphCollapsed = ">"
+	glyphSuccess = "[OK]"
+	glyphError = "[X]"
+	glyphWarning = "[!]"
+	glyphSeparator = " | "
+	plainOutput = true
+}
+
+// fileStatusGlyph returns the marker for a file's link status
+func fileStatusGlyph(file ConfigFile) string {
+	switch {
+	case file.Disabled:
+		return glyphDisabled
+	case file.IsLinked:
+		return glyphLinked
+	case file.HasConflict:
+		return glyphConflict
+	case file.Drifted:
+		return glyphDrifted
+	default:
+		return glyphUnlinked
+	}
+}
+
+// decoration returns a decorative emoji followed by a space, or nothing in plain mode
+func decoration(emoji string) string {
+	if plainOutput {
+		return ""
+	}
+	return emoji + " "
+}
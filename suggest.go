@@ -0,0 +1,217 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// suggestSampleLimit bounds how many files of a directory are looked at when
+// judging whether it holds configuration
+const suggestSampleLimit = 200
+
+// suggestLargeFile is the size past which a file is unlikely to be hand-written
+// configuration
+const suggestLargeFile = 1 << 20
+
+// configSuffixes are name endings typical of configuration files
+var configSuffixes = []string{"rc", ".conf", "config", ".cfg", ".ini", ".toml", ".yaml", ".yml", ".json"}
+
+// suggestion is an unmanaged dotfile or config directory worth adopting
+type suggestion struct {
+	Path      string // as shown, relative to the home directory where possible
+	Target    string
+	Directory bool
+	Category  string
+	Score     int
+	Reasons   []string
+}
+
+// runSuggest lists unmanaged dotfiles and config directories, most
+// config-like first, with the category each would be added to
+func runSuggest(config *Config, args []string) error {
+	flags := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	limit := flags.Int("n", 20, "number of suggestions to show (0 for all)")
+	all := flags.Bool("all", false, "also list candidates that don't look like configuration")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("usage: config-manager suggest [-n 20] [--all]")
+	}
+	
+	suggestions := suggestConfigs(config)
+	shown := 0
+	for _, s := range suggestions {
+		if s.Score <= 0 && !*all {
+			continue
+		}
+		if *limit > 0 && shown == *limit {
+			break
+		}
+		kind := "file"
+		if s.Directory {
+			kind = "directory"
+		}
+		fmt.Printf("%3d  %-32s %-10s %-9s %s\n", s.Score, s.Path, s.Category, kind, strings.Join(s.Reasons, ", "))
+		shown++
+	}
+	
+	if shown == 0 {
+		infoln("Nothing left to suggest; every config found is managed or ignored")
+		return nil
+	}
+	infof("\nAdd them with 'a' in the TUI. Hide candidates you never want suggested with the ignore file.\n")
+	return nil
+}
+
+// suggestConfigs scores every unmanaged candidate from discovery, highest
+// score first
+func suggestConfigs(config *Config) []suggestion {
+	homeDir, _ := mustHome()
+	
+	var managed []string
+	for i := range config.Files {
+		if target, err := resolveTarget(config, &config.Files[i]); err == nil {
+			managed = append(managed, target)
+		}
+	}
+	isManaged := func(target string) bool {
+		for _, m := range managed {
+			if isWithinDir(target, m) {
+				return true
+			}
+		}
+		return false
+	}
+	
+	// Discovery narrates its scan for the setup wizard; only the ranking matters here
+	quiet := quietOutput
+	quietOutput = true
+	candidates := findUnmanagedDotfiles(config)
+	for _, choice := range discoverAllConfigs(config) {
+		path := strings.TrimSpace(choice)
+		if i := strings.LastIndex(path, " ("); i >= 0 {
+			path = path[:i]
+		}
+		candidates = append(candidates, path)
+	}
+	quietOutput = quiet
+	
+	seen := make(map[string]bool)
+	var suggestions []suggestion
+	for _, path := range candidates {
+		target := resolveHomePath(path)
+		if seen[target] || isManaged(target) {
+			continue
+		}
+		seen[target] = true
+		
+		info, err := os.Stat(target)
+		if err != nil {
+			continue
+		}
+		if relPath, err := filepath.Rel(homeDir, target); err == nil && isWithinDir(target, homeDir) {
+			path = filepath.ToSlash(relPath)
+		}
+		suggestions = append(suggestions, scoreSuggestion(config, path, target, info))
+	}
+	
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Path < suggestions[j].Path
+	})
+	return suggestions
+}
+
+// scoreSuggestion rates how much target looks like configuration: a name the
+// categorizer recognizes, a config-like name, and text rather than binary
+// contents count for it; binary, large or sprawling contents count against it
+func scoreSuggestion(config *Config, path, target string, info os.FileInfo) suggestion {
+	name := filepath.Base(target)
+	s := suggestion{
+		Path:      path,
+		Target:    target,
+		Directory: info.IsDir(),
+		Category:  categorizeDotfile(config, name),
+	}
+	
+	if s.Category != "misc" {
+		s.Score += 3
+		s.Reasons = append(s.Reasons, "known "+s.Category+" config")
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range configSuffixes {
+		if strings.HasSuffix(lower, suffix) && lower != suffix {
+			s.Score++
+			s.Reasons = append(s.Reasons, "config-like name")
+			break
+		}
+	}
+	
+	if !s.Directory {
+		switch {
+		case !isTextFile(target):
+			s.Score -= 3
+			s.Reasons = append(s.Reasons, "binary")
+		case info.Size() > suggestLargeFile:
+			s.Score -= 2
+			s.Reasons = append(s.Reasons, "large")
+		default:
+			s.Score += 2
+			s.Reasons = append(s.Reasons, "text")
+		}
+		return s
+	}
+	
+	text, total, truncated := sampleDirectory(target)
+	switch {
+	case total == 0:
+		s.Score--
+		s.Reasons = append(s.Reasons, "empty")
+	case text*5 >= total*4:
+		s.Score += 2
+		s.Reasons = append(s.Reasons, "mostly text")
+	case text*2 < total:
+		s.Score -= 2
+		s.Reasons = append(s.Reasons, "mostly binary")
+	}
+	if truncated {
+		s.Score--
+		s.Reasons = append(s.Reasons, fmt.Sprintf("over %d files", suggestSampleLimit))
+	}
+	return s
+}
+
+// sampleDirectory counts the text files among the first suggestSampleLimit
+// files under dir, skipping noisy directories such as caches. truncated
+// reports whether there were more.
+func sampleDirectory(dir string) (text, total int, truncated bool) {
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != dir && isNoisyDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if total == suggestSampleLimit {
+			truncated = true
+			return filepath.SkipAll
+		}
+		total++
+		if entry.Type().IsRegular() && isTextFile(path) {
+			text++
+		}
+		return nil
+	})
+	return text, total, truncated
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateLintIssue is a single finding from lintTemplates
+type templateLintIssue struct {
+	Template string // path relative to the templates directory
+	IsError  bool   // errors break rendering, everything else is a warning
+	Message  string
+}
+
+// templateRefs are the variables a template reads
+type templateRefs struct {
+	variables map[string]bool // keys read via .Variables.X or index .Variables "X"
+	usesAll   bool            // .Variables is used as a whole (e.g. ranged over)
+	rebound   int             // depth of with/range blocks, where dot isn't the context
+}
+
+// builtinTemplateFields are the TemplateContext fields besides Variables
+var builtinTemplateFields = map[string]bool{
+	"User":     true,
+	"Hostname": true,
+	"Editor":   true,
+	"Shell":    true,
+	"Data":     true,
+}
+
+// lintTemplates parses every template under ConfigDir/templates and compares
+// the variables each one references with what's defined globally and on the
+// managed files that render it
+func lintTemplates(config *Config) ([]templateLintIssue, error) {
+	templatesDir := filepath.Join(config.ConfigDir, "templates")
+	paths, err := findTemplatePaths(config, templatesDir)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := loadSharedVariables(config)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Which managed files render each template
+	users := make(map[string][]ConfigFile)
+	for _, file := range config.Files {
+		if !file.Template {
+			continue
+		}
+		if templatePath := findTemplateFile(config, file.Name, file.Source, file.Category); templatePath != "" {
+			users[templatePath] = append(users[templatePath], file)
+		}
+	}
+	
+	var issues []templateLintIssue
+	usedGlobals := make(map[string]bool)
+	usesAllGlobals := false
+	
+	for _, path := range paths {
+		relPath, _ := filepath.Rel(templatesDir, path)
+		add := func(isError bool, format string, args ...interface{}) {
+			issues = append(issues, templateLintIssue{Template: relPath, IsError: isError, Message: fmt.Sprintf(format, args...)})
+		}
+		
+		content, err := os.ReadFile(path)
+		if err != nil {
+			add(true, "cannot read template: %v", err)
+			continue
+		}
+		
+		tmpl, err := template.New(filepath.Base(path)).Funcs(getTemplateFunctions(nil)).Parse(string(content))
+		if err != nil {
+			add(true, "parse error: %v", err)
+			continue
+		}
+		
+		refs := templateRefs{variables: make(map[string]bool)}
+		var unknownFields []string
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				collectTemplateRefs(t.Tree.Root, &refs, &unknownFields)
+			}
+		}
+		
+		for _, field := range uniqueSorted(unknownFields) {
+			add(true, "unknown field .%s (available: .User, .Hostname, .Editor, .Shell, .Variables, .Data)", field)
+		}
+		
+		if refs.usesAll {
+			usesAllGlobals = true
+		}
+		for name := range refs.variables {
+			usedGlobals[name] = true
+		}
+		
+		files := users[path]
+		if len(files) == 0 {
+			add(false, "not used by any managed file")
+			for _, name := range sortedRefNames(refs.variables) {
+				_, global := config.Variables[name]
+				_, isShared := shared[name]
+				if !global && !isShared {
+					add(true, "undefined variable %q (not a global or shared variable)", name)
+				}
+			}
+			continue
+		}
+		
+		for _, file := range files {
+			for _, name := range sortedRefNames(refs.variables) {
+				_, global := config.Variables[name]
+				_, local := file.Variables[name]
+				_, isShared := shared[name]
+				if !global && !local && !isShared {
+					add(true, "undefined variable %q for %s (set it globally, on the file or in %s)", name, file.Name, templateContextFile)
+				}
+			}
+			
+			if refs.usesAll {
+				continue
+			}
+			for _, name := range sortedKeys(file.Variables) {
+				if !refs.variables[name] {
+					add(false, "variable %q set on %s is never used", name, file.Name)
+				}
+			}
+		}
+	}
+	
+	// Globals only count as unused when no template could be reading them
+	if !usesAllGlobals {
+		for _, name := range sortedKeys(config.Variables) {
+			if !usedGlobals[name] {
+				issues = append(issues, templateLintIssue{
+					Template: "(global)",
+					Message:  fmt.Sprintf("global variable %q is not used by any template", name),
+				})
+			}
+		}
+	}
+	
+	return issues, nil
+}
+
+// findTemplatePaths lists files in templatesDir with a configured template extension
+func findTemplatePaths(config *Config, templatesDir string) ([]string, error) {
+	var paths []string
+	
+	err := filepath.Walk(templatesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == templatesDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		
+		for _, ext := range config.TemplateExts {
+			if strings.HasSuffix(info.Name(), ext) {
+				paths = append(paths, path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, NewConfigError("scan templates", templatesDir, err)
+	}
+	
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// collectTemplateRefs walks a parse tree recording variable references and
+// top-level fields that don't exist on TemplateContext
+func collectTemplateRefs(node parse.Node, refs *templateRefs, unknownFields *[]string) {
+	switch n := node.(type) {
+	case nil:
+		return
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateRefs(child, refs, unknownFields)
+		}
+	case *parse.ActionNode:
+		collectTemplateRefs(n.Pipe, refs, unknownFields)
+	case *parse.IfNode:
+		collectBranchRefs(&n.BranchNode, false, refs, unknownFields)
+	case *parse.RangeNode:
+		collectBranchRefs(&n.BranchNode, true, refs, unknownFields)
+	case *parse.WithNode:
+		collectBranchRefs(&n.BranchNode, true, refs, unknownFields)
+	case *parse.TemplateNode:
+		collectTemplateRefs(n.Pipe, refs, unknownFields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectTemplateRefs(cmd, refs, unknownFields)
+		}
+	case *parse.CommandNode:
+		// index .Variables "name"
+		if len(n.Args) >= 3 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "index" {
+				if field, ok := n.Args[1].(*parse.FieldNode); ok && refs.rebound == 0 && len(field.Ident) == 1 && field.Ident[0] == "Variables" {
+					if key, ok := n.Args[2].(*parse.StringNode); ok {
+						refs.variables[key.Text] = true
+						for _, arg := range n.Args[3:] {
+							collectTemplateRefs(arg, refs, unknownFields)
+						}
+						return
+					}
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			collectTemplateRefs(arg, refs, unknownFields)
+		}
+	case *parse.ChainNode:
+		collectTemplateRefs(n.Node, refs, unknownFields)
+	case *parse.FieldNode:
+		if refs.rebound == 0 {
+			collectFieldRef(n.Ident, refs, unknownFields)
+		}
+	case *parse.VariableNode:
+		// $.Variables.X always refers to the top-level context
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			collectFieldRef(n.Ident[1:], refs, unknownFields)
+		}
+	}
+}
+
+// collectBranchRefs handles the shared parts of if/range/with. With and range
+// rebind dot inside their body, so plain fields there aren't context fields.
+func collectBranchRefs(n *parse.BranchNode, rebindsDot bool, refs *templateRefs, unknownFields *[]string) {
+	collectTemplateRefs(n.Pipe, refs, unknownFields)
+	if rebindsDot {
+		refs.rebound++
+	}
+	collectTemplateRefs(n.List, refs, unknownFields)
+	if rebindsDot {
+		refs.rebound--
+	}
+	if n.ElseList != nil {
+		collectTemplateRefs(n.ElseList, refs, unknownFields)
+	}
+}
+
+// collectFieldRef records a field chain such as .Variables.email or .User
+func collectFieldRef(ident []string, refs *templateRefs, unknownFields *[]string) {
+	if len(ident) == 0 {
+		return
+	}
+	
+	switch {
+	case ident[0] == "Variables" && len(ident) == 1:
+		refs.usesAll = true
+	case ident[0] == "Variables":
+		refs.variables[ident[1]] = true
+	case !builtinTemplateFields[ident[0]]:
+		*unknownFields = append(*unknownFields, ident[0])
+	}
+}
+
+// sortedRefNames returns the referenced variable names in a stable order
+func sortedRefNames(names map[string]bool) []string {
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// uniqueSorted removes duplicates from values and sorts them
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
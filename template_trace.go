@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateBranch is one if/with/range condition reached while rendering
+type templateBranch struct {
+	Location  string // template:line:col of the action
+	Keyword   string // "if", "with" or "range"
+	Condition string // the pipeline as written
+	Taken     bool   // the body ran (for range: there was something to range over)
+	Err       error  // the condition couldn't be evaluated on its own
+}
+
+// traceTemplateBranches reports the conditions rendering templatePath with
+// context evaluates, in order. Only branches that actually run are followed;
+// the bodies of with and range blocks rebind dot, so conditions inside them
+// aren't traced.
+func traceTemplateBranches(templatePath string, context *TemplateContext) ([]templateBranch, error) {
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, NewConfigError("read template", templatePath, err)
+	}
+	
+	tmpl, err := template.New(filepath.Base(templatePath)).
+		Funcs(getTemplateFunctions(context)).
+		Parse(string(content))
+	if err != nil {
+		return nil, NewConfigError("parse template", templatePath, err)
+	}
+	
+	tracer := branchTracer{tree: tmpl.Tree, context: context}
+	if tmpl.Tree != nil {
+		tracer.walk(tmpl.Tree.Root)
+	}
+	return tracer.branches, nil
+}
+
+// branchTracer walks a parsed template, evaluating each condition it meets
+type branchTracer struct {
+	tree     *parse.Tree
+	context  *TemplateContext
+	branches []templateBranch
+}
+
+func (t *branchTracer) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			t.walk(child)
+		}
+	case *parse.IfNode:
+		t.walkBranch("if", n, &n.BranchNode, true)
+	case *parse.WithNode:
+		t.walkBranch("with", n, &n.BranchNode, false)
+	case *parse.RangeNode:
+		t.walkBranch("range", n, &n.BranchNode, false)
+	}
+}
+
+// walkBranch records a condition and follows whichever side of it runs.
+// followBody is false for with and range, whose bodies run with a different dot.
+func (t *branchTracer) walkBranch(keyword string, node parse.Node, branch *parse.BranchNode, followBody bool) {
+	location, _ := t.tree.ErrorContext(node)
+	result := templateBranch{
+		Location:  location,
+		Keyword:   keyword,
+		Condition: pipelineExpression(branch.Pipe),
+	}
+	result.Taken, result.Err = t.evaluate(result.Condition)
+	t.branches = append(t.branches, result)
+	
+	if result.Err != nil {
+		return
+	}
+	if result.Taken {
+		if followBody {
+			t.walk(branch.List)
+		}
+	} else if branch.ElseList != nil {
+		t.walk(branch.ElseList)
+	}
+}
+
+// evaluate runs a condition against the context on its own. Emptiness
+// decides if, with and range alike, so one probe covers all three.
+func (t *branchTracer) evaluate(condition string) (bool, error) {
+	probe, err := template.New("condition").
+		Funcs(getTemplateFunctions(t.context)).
+		Parse("{{if " + condition + "}}1{{end}}")
+	if err != nil {
+		return false, err
+	}
+	
+	var out strings.Builder
+	if err := probe.Execute(&out, t.context); err != nil {
+		return false, err
+	}
+	return out.String() == "1", nil
+}
+
+// pipelineExpression is the pipeline without any variable declarations, so
+// "range $i, $line := .X" evaluates as ".X"
+func pipelineExpression(pipe *parse.PipeNode) string {
+	commands := make([]string, len(pipe.Cmds))
+	for i, cmd := range pipe.Cmds {
+		commands[i] = cmd.String()
+	}
+	return strings.Join(commands, " | ")
+}
+
+// printTemplateTrace lists the traced branches on stderr, so the rendered
+// output on stdout can still be redirected on its own
+func printTemplateTrace(branches []templateBranch) {
+	if len(branches) == 0 {
+		fmt.Fprintln(os.Stderr, "No conditions reached")
+		return
+	}
+	
+	fmt.Fprintln(os.Stderr, "Conditions reached:")
+	for _, branch := range branches {
+		outcome := glyphLinked + " taken"
+		switch {
+		case branch.Err != nil:
+			outcome = fmt.Sprintf("%s could not evaluate: %v", glyphWarning, branch.Err)
+		case !branch.Taken:
+			outcome = glyphUnlinked + " not taken"
+		}
+		fmt.Fprintf(os.Stderr, "  %s  {{%s %s}}  %s\n", branch.Location, branch.Keyword, branch.Condition, outcome)
+	}
+}
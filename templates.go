@@ -1,13 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 )
 
+// formatterTimeout bounds each run of a template's formatter
+const formatterTimeout = 30 * time.Second
+
 // TemplateContext holds all variables available to templates
 type TemplateContext struct {
 	// Built-in system variables
@@ -18,6 +26,17 @@ type TemplateContext struct {
 	
 	// Custom variables (merged from global and file-specific)
 	Variables map[string]string `json:"variables"`
+	
+	// Structured variables (merged from global_data and the file's data)
+	Data map[string]interface{} `json:"data"`
+	
+	// Where include reads from: relative paths are resolved against
+	// includeBase (the dotfiles directory) and must stay inside includeRoots
+	includeBase  string
+	includeRoots []string
+	
+	// Validation renders a missing include as empty instead of failing
+	includeMissingOK bool
 }
 
 // TemplateResult represents the result of template processing
@@ -28,8 +47,13 @@ type TemplateResult struct {
 	Variables  map[string]string
 }
 
-// Enhanced template functions
-func getTemplateFunctions() template.FuncMap {
+// Enhanced template functions. The shell helpers follow shell, the
+// configured .Shell (a name or path); code that only parses can pass "".
+func getTemplateFunctions(context *TemplateContext) template.FuncMap {
+	shellName := ""
+	if context != nil && context.Shell != "" {
+		shellName = filepath.Base(context.Shell)
+	}
 	return template.FuncMap{
 		"env": func(key string) string {
 			return os.Getenv(key)
@@ -46,6 +70,83 @@ func getTemplateFunctions() template.FuncMap {
 		"replace": strings.ReplaceAll,
 		"join": strings.Join,
 		"split": strings.Split,
+		"isBash": func() bool { return shellName == "bash" },
+		"isZsh": func() bool { return shellName == "zsh" },
+		"isFish": func() bool { return shellName == "fish" },
+		"isNu": func() bool { return shellName == "nu" || shellName == "nushell" },
+		"shellExport": func(name, value string) string {
+			return shellExport(shellName, name, value)
+		},
+		"include": func(path string) (string, error) {
+			return includeFile(context, path)
+		},
+	}
+}
+
+// includeFile returns the contents of path for the include template function.
+// Relative paths are read from the dotfiles directory; absolute ones must be
+// in it too, also after following symlinks, so a template can't pull in
+// arbitrary files such as ~/.ssh keys.
+func includeFile(context *TemplateContext, path string) (string, error) {
+	if context == nil || context.includeBase == "" {
+		if context != nil && context.includeMissingOK {
+			return "", nil
+		}
+		return "", fmt.Errorf("include %s: no dotfiles directory to read from", path)
+	}
+	
+	fullPath := filepath.Join(context.includeBase, path)
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "~/") {
+		fullPath = filepath.Clean(absPath(path))
+	}
+	if !withinAnyDir(fullPath, context.includeRoots) {
+		return "", fmt.Errorf("include %s: outside the dotfiles directory", path)
+	}
+	
+	resolved, err := filepath.EvalSymlinks(fullPath)
+	if os.IsNotExist(err) && context.includeMissingOK {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("include %s: %v", path, err)
+	}
+	if !withinAnyDir(resolved, resolvedDirs(context.includeRoots)) {
+		return "", fmt.Errorf("include %s: links outside the dotfiles directory", path)
+	}
+	
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", fmt.Errorf("include %s: %v", path, err)
+	}
+	return string(data), nil
+}
+
+// resolvedDirs returns dirs with symlinks resolved, keeping the ones that
+// can't be resolved as they are
+func resolvedDirs(dirs []string) []string {
+	resolved := make([]string, len(dirs))
+	for i, dir := range dirs {
+		resolved[i] = dir
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			resolved[i] = real
+		}
+	}
+	return resolved
+}
+
+// shellExport returns the line that sets environment variable name to value
+// in shell: set -gx for fish, $env for nushell and export for everything
+// else. The value is double-quoted, so variables like $HOME still expand in
+// the shells that expand them.
+func shellExport(shell, name, value string) string {
+	quoted := `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	switch shell {
+	case "fish":
+		return fmt.Sprintf("set -gx %s %s", name, quoted)
+	case "nu", "nushell":
+		return fmt.Sprintf("$env.%s = %s", name, quoted)
+	default:
+		return fmt.Sprintf("export %s=%s", name, quoted)
 	}
 }
 
@@ -116,7 +217,7 @@ func getDefaultTemplateContent() map[string]string {
     # Configuration for {{ .User }} on {{ .Hostname }}`,
 		
 		"zshrc": `# {{ .User }}'s {{ .Shell }} configuration on {{ .Hostname }}
-export EDITOR="{{ .Editor }}"
+{{ shellExport "EDITOR" .Editor }}
 export PATH="$HOME/bin:$PATH"
 
 # Work-specific settings
@@ -153,7 +254,7 @@ autoload -U colors && colors
 PS1="%{$fg[blue]%}%n@%m%{$reset_color%}:%{$fg[green]%}%~%{$reset_color%}$ "`,
 
 		"bashrc": `# {{ .User }}'s {{ .Shell }} configuration on {{ .Hostname }}
-export EDITOR="{{ .Editor }}"
+{{ shellExport "EDITOR" .Editor }}
 export PATH="$HOME/bin:$PATH"
 
 # Work-specific settings
@@ -265,19 +366,19 @@ func createFromTemplate(config *Config, file *ConfigFile, outputPath string) err
 		return createBasicConfigFile(file, outputPath)
 	}
 	
-	// Validate template before processing
-	if err := validateTemplateFileContent(templatePath); err != nil {
-		return NewConfigError("validate template", templatePath, err)
-	}
-	
 	// Create template context
 	context, err := createTemplateContext(config, file)
 	if err != nil {
 		return NewConfigError("create template context", file.Name, err)
 	}
 	
+	// Validate template before processing
+	if err := validateTemplateFileContent(templatePath, context); err != nil {
+		return NewConfigError("validate template", templatePath, err)
+	}
+	
 	// Process template
-	result, err := processTemplate(templatePath, context, outputPath)
+	result, err := processTemplate(templatePath, context, outputPath, file)
 	if err != nil {
 		return err
 	}
@@ -314,6 +415,36 @@ func findTemplateFile(config *Config, fileName, source, category string) string
 	return ""
 }
 
+// templateContextFile holds variable defaults shared by every machine. It
+// lives in the templates directory so it can be versioned with the templates.
+const templateContextFile = "context.json"
+
+// sharedVariablesPath is where the shared template variables are read from
+func sharedVariablesPath(config *Config) string {
+	return filepath.Join(config.ConfigDir, "templates", templateContextFile)
+}
+
+// loadSharedVariables reads templates/context.json, a JSON object of string
+// variables. A missing file means no shared variables; one that doesn't parse
+// is an error, so templates aren't rendered with defaults silently missing.
+func loadSharedVariables(config *Config) (map[string]string, error) {
+	path := sharedVariablesPath(config)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, NewConfigError("read shared template variables", path, err)
+	}
+	
+	variables := make(map[string]string)
+	if err := json.Unmarshal(data, &variables); err != nil {
+		return nil, NewConfigError("parse shared template variables", path, 
+			fmt.Errorf("expected a JSON object of string values: %v", err))
+	}
+	return variables, nil
+}
+
 // createTemplateContext builds the context for template execution
 func createTemplateContext(config *Config, file *ConfigFile) (*TemplateContext, error) {
 	context := &TemplateContext{
@@ -335,8 +466,18 @@ func createTemplateContext(config *Config, file *ConfigFile) (*TemplateContext,
 	
 	context.Editor = config.Editor
 	context.Shell = config.Shell
+	context.includeBase = config.DotfilesDir
+	context.includeRoots = []string{config.DotfilesDir}
+	
+	// Merge variables: shared defaults < global < file-specific
+	shared, err := loadSharedVariables(config)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range shared {
+		context.Variables[k] = v
+	}
 	
-	// Merge variables: global < file-specific
 	for k, v := range config.Variables {
 		context.Variables[k] = v
 	}
@@ -345,29 +486,46 @@ func createTemplateContext(config *Config, file *ConfigFile) (*TemplateContext,
 		context.Variables[k] = v
 	}
 	
+	// Structured variables: global < file-specific, merged key by key
+	context.Data = mergeStructuredVariables(nil, config.StructuredVariables)
+	context.Data = mergeStructuredVariables(context.Data, file.StructuredVariables)
+	
 	return context, nil
 }
 
-// processTemplate executes the template with the given context
-func processTemplate(templatePath string, context *TemplateContext, outputPath string) (*TemplateResult, error) {
+// mergeStructuredVariables returns base with overrides applied. Nested
+// objects are merged key by key, so a file can override .Data.git.email
+// without repeating the rest of .Data.git; any other value replaces what
+// was there. Neither argument is modified.
+func mergeStructuredVariables(base, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		if nested, ok := v.(map[string]interface{}); ok {
+			existing, _ := merged[k].(map[string]interface{})
+			merged[k] = mergeStructuredVariables(existing, nested)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// processTemplate executes the template with the given context and writes it
+// with file's permissions. With a formatter the rendered output is piped
+// through it before anything is written, so a failing formatter leaves
+// outputPath untouched.
+func processTemplate(templatePath string, context *TemplateContext, outputPath string, file *ConfigFile) (*TemplateResult, error) {
 	result := &TemplateResult{
 		OutputPath: outputPath,
 		Variables:  context.Variables,
 	}
 	
-	// Read template content
-	content, err := os.ReadFile(templatePath)
+	data, err := renderTemplate(templatePath, context, file.Formatter)
 	if err != nil {
-		result.Error = NewConfigError("read template", templatePath, err)
-		return result, result.Error
-	}
-	
-	// Create template with functions
-	tmpl, err := template.New(filepath.Base(templatePath)).
-		Funcs(getTemplateFunctions()).
-		Parse(string(content))
-	if err != nil {
-		result.Error = NewConfigError("parse template", templatePath, err)
+		result.Error = err
 		return result, result.Error
 	}
 	
@@ -377,22 +535,68 @@ func processTemplate(templatePath string, context *TemplateContext, outputPath s
 		return result, result.Error
 	}
 	
-	// Create output file
-	output, err := os.Create(outputPath)
-	if err != nil {
+	if err := writeGeneratedFile(file, outputPath, data); err != nil {
 		result.Error = NewConfigError("create output file", outputPath, err)
 		return result, result.Error
 	}
-	defer output.Close()
+	
+	result.Success = true
+	return result, nil
+}
+
+// renderTemplate executes the template with the given context and pipes the
+// result through formatter, if any, without writing anything
+func renderTemplate(templatePath string, context *TemplateContext, formatter []string) ([]byte, error) {
+	// Read template content
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, NewConfigError("read template", templatePath, err)
+	}
+	
+	// Create template with functions
+	tmpl, err := template.New(filepath.Base(templatePath)).
+		Funcs(getTemplateFunctions(context)).
+		Parse(string(content))
+	if err != nil {
+		return nil, NewConfigError("parse template", templatePath, err)
+	}
 	
 	// Execute template
-	if err := tmpl.Execute(output, context); err != nil {
-		result.Error = NewConfigError("execute template", templatePath, err)
-		return result, result.Error
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, context); err != nil {
+		return nil, NewConfigError("execute template", templatePath, err)
 	}
 	
-	result.Success = true
-	return result, nil
+	data := rendered.Bytes()
+	if len(formatter) > 0 {
+		data, err = runFormatter(formatter, data)
+		if err != nil {
+			return nil, NewConfigError("format template output", templatePath, err)
+		}
+	}
+	return data, nil
+}
+
+// runFormatter pipes rendered template output through a formatter command
+// and returns what it printed. A failure reports the formatter's stderr.
+func runFormatter(formatter []string, data []byte) ([]byte, error) {
+	timeout := toolTimeout(formatterTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	name := formatter[0]
+	if strings.HasPrefix(name, "~/") {
+		name = absPath(name)
+	}
+	
+	output, err := runner.Filter(ctx, data, name, formatter[1:]...)
+	if errors.Is(err, errCommandTimeout) {
+		return nil, fmt.Errorf("%s timed out after %s", formatter[0], timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", formatter[0], err)
+	}
+	return output, nil
 }
 
 // createBasicConfigFile creates a basic config file when no template is found
@@ -403,15 +607,16 @@ func createBasicConfigFile(file *ConfigFile, outputPath string) error {
 		return NewConfigError("create output directory", filepath.Dir(outputPath), err)
 	}
 	
-	if err := os.WriteFile(outputPath, []byte(basicContent), 0644); err != nil {
+	if err := writeGeneratedFile(file, outputPath, []byte(basicContent)); err != nil {
 		return NewConfigError("write basic config", outputPath, err)
 	}
 	
 	return nil
 }
 
-// validateTemplateFileContent checks template syntax and common issues
-func validateTemplateFileContent(templatePath string) error {
+// validateTemplateFileContent checks template syntax and common issues by
+// executing it with context, or with representative dummy data when nil
+func validateTemplateFileContent(templatePath string, context *TemplateContext) error {
 	content, err := os.ReadFile(templatePath)
 	if err != nil {
 		return NewConfigError("read template", templatePath, err)
@@ -419,7 +624,7 @@ func validateTemplateFileContent(templatePath string) error {
 	
 	// Parse template to check syntax
 	tmpl, err := template.New(filepath.Base(templatePath)).
-		Funcs(getTemplateFunctions()).
+		Funcs(getTemplateFunctions(nil)).
 		Parse(string(content))
 	if err != nil {
 		return NewConfigError("parse template", templatePath, err)
@@ -435,7 +640,21 @@ func validateTemplateFileContent(templatePath string) error {
 			"email_domain": "example.com",
 			"environment": "test",
 		},
+		Data: map[string]interface{}{
+			"git": map[string]interface{}{
+				"name":       "Test User",
+				"email":      "testuser@example.com",
+				"signingkey": "0000000000000000",
+			},
+		},
+	}
+	if context != nil {
+		copied := *context
+		dummyContext = &copied
 	}
+	// Fragments may not exist yet, e.g. before the first sync of a new machine
+	dummyContext.includeMissingOK = true
+	tmpl.Funcs(getTemplateFunctions(dummyContext))
 	
 	// Execute to a dummy writer to validate template logic
 	var buf strings.Builder
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// formattedTemplate writes a gitconfig template and returns a file rendering
+// it through formatter, and the output path
+func formattedTemplate(t *testing.T, config *Config, home string, formatter []string) (*ConfigFile, string) {
+	t.Helper()
+	writeTestFile(t, filepath.Join(config.ConfigDir, "templates", "gitconfig.tmpl"), "[user]\n\tname = {{ .User }}\n")
+	file := &ConfigFile{Name: "gitconfig", Source: "git/gitconfig", Target: "~/.gitconfig", Category: "git", Template: true, Formatter: formatter}
+	return file, filepath.Join(home, ".gitconfig")
+}
+
+func TestTemplateFormatterPassThrough(t *testing.T) {
+	config, home := newTestConfig(t)
+	file, output := formattedTemplate(t, config, home, []string{"cat"})
+
+	tx := NewTransaction(config)
+	tx.AddOperation(NewTemplateOperation(config, file, "", output))
+	if err := tx.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "[user]\n\tname = ") {
+		t.Errorf("output = %q, want the rendered template unchanged", data)
+	}
+}
+
+func TestTemplateFormatterFailureRollsBack(t *testing.T) {
+	config, home := newTestConfig(t)
+	file, output := formattedTemplate(t, config, home, []string{"sh", "-c", "echo 'unexpected token' >&2; exit 1"})
+	writeTestFile(t, output, "existing\n")
+
+	tx := NewTransaction(config)
+	tx.AddOperation(NewTemplateOperation(config, file, "", output))
+	err := tx.Execute()
+	if err == nil {
+		t.Fatal("transaction succeeded with a failing formatter")
+	}
+	if !strings.Contains(err.Error(), "unexpected token") {
+		t.Errorf("error %q doesn't carry the formatter's stderr", err)
+	}
+	assertUntouched(t, output, "existing\n")
+	if backups, _ := filepath.Glob(filepath.Join(config.GetBackupDir(), "*")); len(backups) != 0 {
+		t.Errorf("backups left behind: %q", backups)
+	}
+}
+
+func TestValidateTemplateSkipsFormatter(t *testing.T) {
+	config, home := newTestConfig(t)
+	file, _ := formattedTemplate(t, config, home, []string{"false"})
+	context, err := createTemplateContext(config, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateTemplateFileContent(filepath.Join(config.ConfigDir, "templates", "gitconfig.tmpl"), context); err != nil {
+		t.Errorf("validation ran the failing formatter: %v", err)
+	}
+}
+
+func TestRunFormatterRunsThroughRunner(t *testing.T) {
+	fake := &scriptedRunner{Responses: []scriptedResponse{{Output: "formatted\n"}}}
+	useRunner(t, fake)
+
+	output, err := runFormatter([]string{"shfmt", "-i", "2"}, []byte("raw\n"))
+	if err != nil || string(output) != "formatted\n" {
+		t.Errorf("runFormatter = %q, %v; want the runner's output", output, err)
+	}
+	if len(fake.Calls) != 1 || strings.Join(fake.Calls[0], " ") != "shfmt -i 2" {
+		t.Errorf("calls = %q, want shfmt -i 2", fake.Calls)
+	}
+}
+
+func TestTemplateOutputHasPermsFromTheStart(t *testing.T) {
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(config.ConfigDir, "templates", "ssh_config.tmpl"), "Host *\n\tUser {{ .User }}\n")
+	output := filepath.Join(home, ".ssh", "config")
+	// An existing output with a looser mode is replaced, not rewritten in place
+	writeTestFile(t, output, "Host old\n")
+	file := &ConfigFile{Name: "ssh_config", Source: "misc/ssh_config", Target: "~/.ssh/config", Category: "misc", Template: true, Perms: "600"}
+	missing := &ConfigFile{Name: "netrc", Source: "misc/netrc", Target: "~/.netrc", Category: "misc", Template: true, Perms: "600"}
+
+	for _, test := range []struct {
+		file   *ConfigFile
+		output string
+	}{
+		{file, output},
+		{missing, filepath.Join(home, ".netrc")}, // no template: a placeholder is written
+	} {
+		if err := createFromTemplate(config, test.file, test.output); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(test.output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, want -rw-------", test.output, info.Mode().Perm())
+		}
+	}
+	if names := readDirNames(t, filepath.Dir(output)); len(names) != 1 {
+		t.Errorf("%s holds %q, want no temporary files left", filepath.Dir(output), names)
+	}
+}
+
+func TestIncludeFragmentIntoGitconfig(t *testing.T) {
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "fragments", "git-aliases"), "[alias]\n\tst = status\n")
+	writeTestFile(t, filepath.Join(config.ConfigDir, "templates", "gitconfig.tmpl"),
+		"[user]\n\tname = {{ .User }}\n{{ include \"fragments/git-aliases\" }}")
+	file := &ConfigFile{Name: "gitconfig", Source: "git/gitconfig", Target: "~/.gitconfig", Category: "git", Template: true}
+	output := filepath.Join(home, ".gitconfig")
+
+	if err := createFromTemplate(config, file, output); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "[alias]\n\tst = status\n") {
+		t.Errorf("output = %q, want the fragment included", data)
+	}
+}
+
+func TestIncludeRejectsPathsOutsideDotfiles(t *testing.T) {
+	config, home := newTestConfig(t)
+	key := filepath.Join(home, ".ssh", "id_rsa")
+	writeTestFile(t, key, "PRIVATE KEY\n")
+	if err := os.Symlink(key, filepath.Join(config.DotfilesDir, "key")); err != nil {
+		t.Fatal(err)
+	}
+	context, err := createTemplateContext(config, &ConfigFile{Name: "gitconfig", Source: "git/gitconfig", Target: "~/.gitconfig", Category: "git"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"~/.ssh/id_rsa", // in home, an allowed target root
+		key,             // the same, absolute
+		"/etc/hostname", // in /etc, also a target root
+		"../../../.ssh/id_rsa",
+		"key", // inside, but links out
+	} {
+		if content, err := includeFile(context, path); err == nil {
+			t.Errorf("include %q = %q, want it refused", path, content)
+		}
+	}
+}
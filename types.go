@@ -1,42 +1,137 @@
 package main
 
 import (
+	"time"
+	
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/viewport"
 )
 
 // Data structures
 type ConfigFile struct {
 	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"` // Free-form note on what the file is for
 	Source      string            `json:"source"`      // Path in dotfiles repo
 	Target      string            `json:"target"`      // Path where it should be linked
 	Category    string            `json:"category"`
+	Tags        []string          `json:"tags,omitempty"`          // Labels grouping files across categories, e.g. "work" or "minimal"
+	Disabled    bool              `json:"disabled,omitempty"`      // Kept in the config but not linked for now
+	Order       int               `json:"order,omitempty"`         // Link-all applies lower orders first; equal orders keep config order
 	Template    bool              `json:"template"`
+	TemplateManual bool           `json:"template_manual,omitempty"` // Template was set by hand; refresh leaves it alone
 	Variables   map[string]string `json:"variables,omitempty"`
+	StructuredVariables map[string]interface{} `json:"data,omitempty"` // Nested values templates read as .Data, e.g. .Data.git.signingkey
+	LinkStrategy string           `json:"link_strategy,omitempty"` // "symlink" (default), "tree" or "copy"
+	Perms       string            `json:"perms,omitempty"`         // Octal mode for copied/generated files, e.g. "600"
+	OriginalLink string           `json:"original_link,omitempty"` // Where the target linked to before it was adopted, for restoring
+	ExcludePatterns []string      `json:"exclude_patterns,omitempty"` // Paths inside a directory that aren't linked; implies "tree"
+	Formatter   []string          `json:"formatter,omitempty"`     // Command rendered template output is piped through, e.g. ["shfmt"]
+	LinkedAt    *time.Time        `json:"linked_at,omitempty"`     // When the file was last linked
 	IsLinked    bool              `json:"-"`
 	HasConflict bool              `json:"-"`
+	Drifted     bool              `json:"-"` // copy-mode target no longer matches its source
+	NeedsRelink bool              `json:"-"` // source modified since the file was last linked
 }
 
+// Link strategies for ConfigFile.LinkStrategy
+const (
+	LinkStrategySymlink = "symlink" // one symlink for the whole file or directory
+	LinkStrategyTree    = "tree"    // real directories with a symlink per file (GNU Stow style)
+	LinkStrategyCopy    = "copy"    // target is a plain copy of the source, no symlinks
+)
+
 type Config struct {
+	SchemaVersion    int               `json:"schema_version"`
 	Files            []ConfigFile      `json:"files"`
 	ConfigDir        string            `json:"config_dir"`
 	DotfilesDir      string            `json:"dotfiles_dir"`
 	Variables        map[string]string `json:"global_variables"`
+	StructuredVariables map[string]interface{} `json:"global_data,omitempty"` // Nested values for every template, overridden per file by "data"
 	Categories       []string          `json:"categories"`
 	TemplateExts     []string          `json:"template_extensions"`
 	Editor           string            `json:"editor"`
+	EditorArgs       map[string][]string `json:"editor_args,omitempty"` // Arguments per editor; {file} is replaced with the path being edited
 	Shell            string            `json:"shell"`
+	DiscoveryDepth   int               `json:"discovery_depth,omitempty"` // How many levels of .config to scan
+	BackupDir        string            `json:"backup_dir,omitempty"`      // Defaults to ConfigDir/backups
+	RelativeLinks    bool              `json:"relative_links,omitempty"`  // Create symlinks relative to the target's directory
+	TargetSymlinks   string            `json:"target_symlinks,omitempty"` // How to bring a symlinked target into the source: "refuse" (default), "follow" or "preserve"
+	BackupTimeFormat string            `json:"backup_time_format,omitempty"` // Go time layout naming backups; defaults to 2006-01-02_15-04-05
+	BackupStyle      string            `json:"backup_style,omitempty"`    // "timestamped" (default) or "rolling": one .backup per target, overwritten each time
+	FileManager      string            `json:"file_manager,omitempty"`    // Command for browsing the dotfiles directory; defaults to open/xdg-open
+	CategorizerCmd   string            `json:"categorizer_cmd,omitempty"` // Command given a filename on stdin that prints its category
+	AllowPrivileged  bool              `json:"allow_privileged,omitempty"` // Offer to retry links that fail with permission denied through EscalationCmd
+	EscalationCmd    string            `json:"escalation_cmd,omitempty"`   // Command privileged links run through; defaults to sudo
+	AllowedTargetRoots []string        `json:"allowed_target_roots,omitempty"` // Absolute directories targets may be in besides home and /etc
+	OperationRetries *int              `json:"operation_retries,omitempty"` // Retries for operations failing with transient errors (e.g. text file busy); defaults to 2
+	GitKeep          bool              `json:"gitkeep,omitempty"`          // In a git-managed dotfiles dir, create every category dir up front with a .gitkeep
+	TargetRoot       string            `json:"target_root,omitempty"`      // Sandbox directory every target is placed under instead of its real location
+	Parallelism      int               `json:"parallelism,omitempty"`      // How many files link-all links at once; defaults to 1
+	NoBackup         bool              `json:"no_backup,omitempty"`        // Delete replaced targets instead of backing them up (disposable environments only)
+	local            *overlayState     // config.local.json merged in when loading; nil for configs that weren't loaded from disk
 }
 
+// Handling for Config.TargetSymlinks when a target being copied into the
+// dotfiles directory is itself a symlink
+const (
+	TargetSymlinksRefuse   = "refuse"   // fail rather than absorb another tool's file
+	TargetSymlinksFollow   = "follow"   // copy the content the symlink points to
+	TargetSymlinksPreserve = "preserve" // recreate the symlink itself in the source
+)
+
 // Application state
 type model struct {
 	config       *Config
 	currentView  string
 	fileList     list.Model
+	tagFilter    string // only files with this tag are listed; "" lists every file
+	collapsedCategories map[string]bool // categories whose files are hidden under their header
 	selectedFile *ConfigFile
 	message      string
 	messageType  string // "success", "error", "warning"
 	width        int
 	height       int
+	
+	// Link-all progress state
+	progress     progress.Model
+	linkIndex    int                        // how many files have finished
+	linkResults  []OperationResult          // results collected so far
+	linkAnswers  map[int]ConflictResolution // how conflicting files were answered (skip or merge)
+	linkQueue    *linkScheduler             // files not started yet, kept apart when their targets overlap
+	linkRunning  int                        // files being linked right now
+	
+	// Validation view state
+	validationErrors []ValidationError
+	validationCursor int
+	
+	// Template variables view state
+	variablesCursor    int
+	variablesFileIndex int // file whose variables are shown alongside globals, -1 for none
+	
+	// Snapshots view state
+	snapshots       []snapshotInfo
+	snapshotsCursor int
+	
+	// Operations log view state
+	operationsLog       []operationLogEntry // newest first
+	operationsLogCursor int
+	
+	// Import view state
+	importResult *ImportResult
+	importTaken  []bool // per conflict: the imported file replaced the existing one
+	importCursor int
+	
+	// Conflicts view state
+	conflictFiles   []ConfigFile
+	conflictChoices map[int]ConflictResolution // by index into conflictFiles; unanswered conflicts are absent
+	conflictCursor  int
+	
+	// Diff view state
+	diffViewport viewport.Model
+	diffTitle    string
+	diffPaths    [2]string // target and source, for opening them in the pager
+	diffReturn   string    // view to go back to
 }
 
 // List items for bubbles/list
@@ -5,60 +5,87 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 // fileItem methods for bubbles/list interface (unchanged)
-func (i fileItem) FilterValue() string { return i.file.Name }
+func (i fileItem) FilterValue() string {
+	return strings.TrimSpace(i.file.Name + " " + i.file.Description + " " + strings.Join(i.file.Tags, " "))
+}
 
 func (i fileItem) Title() string {
-	status := "✗"
-	if i.file.IsLinked {
-		status = "✓"
-	} else if i.file.HasConflict {
-		status = "⚠️"
+	title := fmt.Sprintf("%s %s", fileStatusGlyph(i.file), i.file.Name)
+	if i.file.Disabled {
+		return inactiveStyle.Render(title + " (disabled)")
+	}
+	if i.file.NeedsRelink {
+		title += " " + glyphModified
 	}
-	return fmt.Sprintf("%s %s", status, i.file.Name)
+	return title
 }
 
 func (i fileItem) Description() string {
-	return fmt.Sprintf("%s → %s", i.file.Target, i.file.Source)
+	paths := fmt.Sprintf("%s → %s", i.file.Target, i.file.Source)
+	if len(i.file.Tags) > 0 {
+		paths += " #" + strings.Join(i.file.Tags, " #")
+	}
+	if i.file.Description == "" {
+		return paths
+	}
+	return i.file.Description + " · " + paths
 }
 
 // Initialize application with enhanced error handling
-func initialModel() model {
-	config := loadConfig()
+func initialModel(configDir string) model {
+	config := loadConfig(configDir)
 	
 	// Create initial file list with default dimensions
 	var fileList list.Model
+	message := "Welcome to Config Manager! Use 'a' to add configs, 'l' to link them."
+	messageType := "success"
 	if config != nil {
 		// Ensure directories exist
 		if err := config.EnsureDirectoriesExist(); err != nil {
-			fmt.Printf("Warning: failed to create directories: %v\n", err)
+			warnf("Warning: failed to create directories: %v\n", err)
 		}
 		
 		// Create default templates if they don't exist
 		if err := createDefaultTemplates(config); err != nil {
-			fmt.Printf("Warning: failed to create default templates: %v", err)
+			warnf("Warning: failed to create default templates: %v", err)
+		}
+		
+		if dangling := findDanglingLinks(config); len(dangling) > 0 {
+			message = fmt.Sprintf("Found %d dangling links - run 'config-manager doctor' to clean up", len(dangling))
+			messageType = "warning"
+		}
+		
+		// Make sure backups can actually be written before the user relies on them
+		if err := checkDirWritable(config.GetBackupDir()); err != nil {
+			warnf("Warning: backup directory is not writable: %v\n", err)
+			message = fmt.Sprintf("Warning: backup directory is not writable: %v", err)
+			messageType = "warning"
 		}
 		
 		updateFileStatuses(config)
-		fileList = createFileList(config.Files, 76, 14) // Default size
+		fileList = createFileList(groupByCategory(fileListItems(config), config.Categories, nil), 76, 14) // Default size
 	} else {
-		fileList = createFileList([]ConfigFile{}, 76, 14)
+		fileList = createFileList(nil, 76, 14)
 	}
 	
 	return model{
 		config:      config,
 		currentView: "main",
+		variablesFileIndex: -1,
 		fileList:    fileList,
-		message:     "Welcome to Config Manager! Use 'a' to add configs, 'l' to link them.",
-		messageType: "success",
+		progress:    progress.New(progress.WithDefaultGradient()),
+		message:     message,
+		messageType: messageType,
 		width:       80,  // Default width
 		height:      20,  // Default height
 	}
@@ -86,16 +113,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		
 		m.fileList.SetSize(listWidth, listHeight)
+		m.progress.Width = listWidth
+		m.diffViewport.Width, m.diffViewport.Height = m.diffViewportSize()
+		
+	case linkFileDoneMsg:
+		return m.handleLinkFileDone(msg)
+		
+	case progress.FrameMsg:
+		progressModel, cmd := m.progress.Update(msg)
+		m.progress = progressModel.(progress.Model)
+		return m, cmd
+		
+	case conflictDiffDoneMsg:
+		m = m.conflictsChanged()
+		if msg.err != nil {
+			m.message = fmt.Sprintf("Cannot show diff: %v", msg.err)
+			m.messageType = "error"
+		}
 		
 	case editorFinishedMsg:
-		// Handle the editor finishing
+		// A non-zero exit only means the editor was quit without saving or
+		// the like; an error is reserved for failing to start it
+		exitNote := ""
+		if status, exited := editorExitStatus(msg.err); exited {
+			exitNote = fmt.Sprintf(" (%s %s)", msg.toolName(), status)
+			msg.err = nil
+		}
+		
 		if msg.err != nil {
 			if IsConfigError(msg.err) {
-				m.message = fmt.Sprintf("Editor error: %v", msg.err)
+				m.message = fmt.Sprintf("Error from %s: %v", msg.toolName(), msg.err)
 			} else {
-				m.message = fmt.Sprintf("Failed to open editor: %v", msg.err)
+				m.message = fmt.Sprintf("Failed to open %s: %v", msg.toolName(), msg.err)
 			}
 			m.messageType = "error"
+			
+			// The files may still have been touched before the failure
+			updateFileStatuses(m.config)
+			m.fileList.SetItems(m.listItems())
 		} else {
 			// After editing, update file statuses and remove duplicates
 			updateFileStatuses(m.config)
@@ -111,19 +166,62 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			
 			// Completely recreate the file list to ensure clean display
-			m.fileList = createFileList(m.config.Files, listWidth, listHeight)
+			m.fileList = createFileList(m.listItems(), listWidth, listHeight)
+			if m.tagFilter != "" {
+				m.fileList.Title += " #" + m.tagFilter
+			}
 			
 			// Save config to persist any changes
+			verb := "editing"
+			if msg.tool != "" {
+				verb = "browsing"
+			}
 			if err := saveConfigSafe(m.config); err != nil {
-				m.message = fmt.Sprintf("Finished editing %s (warning: failed to save config: %v)", msg.fileName, err)
+				m.message = fmt.Sprintf("Finished %s %s (warning: failed to save config: %v)", verb, msg.fileName, err)
 				m.messageType = "warning"
 			} else {
-				m.message = fmt.Sprintf("Finished editing %s", msg.fileName)
+				m.message = fmt.Sprintf("Finished %s %s%s", verb, msg.fileName, exitNote)
 				m.messageType = "success"
 			}
+			
+			// An edited target that isn't linked to its source has drifted from it
+			if msg.syncSource != "" {
+				m.message, m.messageType = syncEditedTarget(msg)
+			}
 		}
 		
 	case tea.KeyMsg:
+		// Ignore input other than quit while a link-all is in progress
+		if m.currentView == "linking" {
+			if key.Matches(msg, keys.Quit) {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		
+		if m.currentView == "validation" {
+			return m.updateValidationView(msg)
+		}
+		if m.currentView == "variables" {
+			return m.updateVariablesView(msg)
+		}
+		if m.currentView == "snapshots" {
+			return m.updateSnapshotsView(msg)
+		}
+		if m.currentView == "log" {
+			return m.updateOperationsLogView(msg)
+		}
+		if m.currentView == "import" {
+			return m.updateImportView(msg)
+		}
+		if m.currentView == "conflicts" {
+			return m.updateConflictsView(msg)
+		}
+		
+		if m.currentView == "diff" {
+			return m.updateDiffView(msg)
+		}
+		
 		switch {
 		case key.Matches(msg, keys.Quit):
 			return m, tea.Quit
@@ -140,11 +238,64 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, keys.LinkAll):
 			return m.handleLinkAll()
 			
+		case key.Matches(msg, keys.LinkUnlinked):
+			return m.handleLinkUnlinked()
+			
 		case key.Matches(msg, keys.Edit):
 			return m.handleEdit()
+		case key.Matches(msg, keys.EditTarget):
+			return m.handleEditTarget()
+			
+		case key.Matches(msg, keys.Sync):
+			return m.handleSyncToSource()
 			
 		case key.Matches(msg, keys.Backup):
 			return m.handleBackup()
+			
+		case key.Matches(msg, keys.Validate):
+			return m.handleValidate()
+			
+		case key.Matches(msg, keys.Variables):
+			return m.handleVariables()
+			
+		case key.Matches(msg, keys.Snapshots):
+			return m.handleSnapshots()
+			
+		case key.Matches(msg, keys.History):
+			return m.handleOperationsLog()
+			
+		case key.Matches(msg, keys.Import):
+			return m.handleImport()
+			
+		case key.Matches(msg, keys.Open):
+			return m.handleOpenDirectory(false)
+			
+		case key.Matches(msg, keys.Shell):
+			return m.handleOpenDirectory(true)
+			
+		case key.Matches(msg, keys.Rename):
+			return m.handleRename()
+			
+		case key.Matches(msg, keys.Conflicts):
+			return m.handleConflicts()
+			
+		case key.Matches(msg, keys.Tag):
+			return m.handleTagFilter()
+			
+		case key.Matches(msg, keys.Describe):
+			return m.handleDescribe()
+		case key.Matches(msg, keys.Disable):
+			return m.handleToggleDisabled()
+			
+		case key.Matches(msg, keys.Collapse):
+			return m.handleToggleCategory()
+		case key.Matches(msg, keys.CollapseAll):
+			return m.handleToggleAllCategories()
+			
+		case key.Matches(msg, keys.MoveUp):
+			return m.handleMoveFile(-1)
+		case key.Matches(msg, keys.MoveDown):
+			return m.handleMoveFile(1)
 		}
 	}
 	
@@ -161,8 +312,25 @@ func (m model) View() string {
 		fmt.Sprintf(" (%d files, %d linked, %d conflicts)", 
 			stats["total_files"], stats["linked_files"], stats["conflicted_files"]) + "\n\n"
 	
-	// Main content - the file list
+	// Main content - the file list, or link progress while linking
 	content := m.fileList.View()
+	if m.currentView == "linking" {
+		content = m.linkProgressView()
+	} else if m.currentView == "validation" {
+		content = m.validationView()
+	} else if m.currentView == "variables" {
+		content = m.variablesView()
+	} else if m.currentView == "snapshots" {
+		content = m.snapshotsView()
+	} else if m.currentView == "log" {
+		content = m.operationsLogView()
+	} else if m.currentView == "import" {
+		content = m.importView()
+	} else if m.currentView == "conflicts" {
+		content = m.conflictsView()
+	} else if m.currentView == "diff" {
+		content = m.diffView()
+	}
 	
 	// Status/message bar with enhanced styling
 	statusStyle := successStyle
@@ -179,13 +347,82 @@ func (m model) View() string {
 		helpKeyStyle.Render("a") + helpDescStyle.Render(" add"),
 		helpKeyStyle.Render("r") + helpDescStyle.Render(" remove"),
 		helpKeyStyle.Render("e") + helpDescStyle.Render(" edit"),
+		helpKeyStyle.Render("E") + helpDescStyle.Render(" edit target"),
 		helpKeyStyle.Render("l") + helpDescStyle.Render(" link selected"),
 		helpKeyStyle.Render("L") + helpDescStyle.Render(" link all"),
+		helpKeyStyle.Render("u") + helpDescStyle.Render(" link unlinked"),
+		helpKeyStyle.Render("S") + helpDescStyle.Render(" sync to source"),
 		helpKeyStyle.Render("b") + helpDescStyle.Render(" backup"),
+		helpKeyStyle.Render("v") + helpDescStyle.Render(" validate"),
+		helpKeyStyle.Render("V") + helpDescStyle.Render(" variables"),
+		helpKeyStyle.Render("s") + helpDescStyle.Render(" snapshots"),
+		helpKeyStyle.Render("H") + helpDescStyle.Render(" operations log"),
+		helpKeyStyle.Render("I") + helpDescStyle.Render(" import"),
+		helpKeyStyle.Render("o/O") + helpDescStyle.Render(" file manager/shell"),
+		helpKeyStyle.Render("n") + helpDescStyle.Render(" rename/move"),
+		helpKeyStyle.Render("c") + helpDescStyle.Render(" conflicts"),
+		helpKeyStyle.Render("D") + helpDescStyle.Render(" describe"),
+		helpKeyStyle.Render("t") + helpDescStyle.Render(" filter by tag"),
+		helpKeyStyle.Render("x") + helpDescStyle.Render(" disable/enable"),
+		helpKeyStyle.Render("z/Z") + helpDescStyle.Render(" collapse category/all"),
+		helpKeyStyle.Render("K/J") + helpDescStyle.Render(" move up/down"),
 		helpKeyStyle.Render("q") + helpDescStyle.Render(" quit"),
 	}
+	if m.currentView == "validation" {
+		helpItems = []string{
+			helpKeyStyle.Render("↑/↓") + helpDescStyle.Render(" move"),
+			helpKeyStyle.Render("enter") + helpDescStyle.Render(" go to file"),
+			helpKeyStyle.Render("v") + helpDescStyle.Render(" re-run"),
+			helpKeyStyle.Render("esc") + helpDescStyle.Render(" back"),
+		}
+	} else if m.currentView == "variables" {
+		helpItems = []string{
+			helpKeyStyle.Render("↑/↓") + helpDescStyle.Render(" move"),
+			helpKeyStyle.Render("a") + helpDescStyle.Render(" add"),
+			helpKeyStyle.Render("e") + helpDescStyle.Render(" edit"),
+			helpKeyStyle.Render("r") + helpDescStyle.Render(" delete"),
+			helpKeyStyle.Render("esc") + helpDescStyle.Render(" back"),
+		}
+	} else if m.currentView == "snapshots" {
+		helpItems = []string{
+			helpKeyStyle.Render("↑/↓") + helpDescStyle.Render(" move"),
+			helpKeyStyle.Render("a") + helpDescStyle.Render(" take snapshot"),
+			helpKeyStyle.Render("enter") + helpDescStyle.Render(" restore"),
+			helpKeyStyle.Render("r") + helpDescStyle.Render(" delete"),
+			helpKeyStyle.Render("esc") + helpDescStyle.Render(" back"),
+		}
+	} else if m.currentView == "log" {
+		helpItems = []string{
+			helpKeyStyle.Render("↑/↓") + helpDescStyle.Render(" move"),
+			helpKeyStyle.Render("H") + helpDescStyle.Render(" reload"),
+			helpKeyStyle.Render("esc") + helpDescStyle.Render(" back"),
+		}
+	} else if m.currentView == "import" {
+		helpItems = []string{
+			helpKeyStyle.Render("↑/↓") + helpDescStyle.Render(" move"),
+			helpKeyStyle.Render("enter") + helpDescStyle.Render(" keep existing / take imported"),
+			helpKeyStyle.Render("esc") + helpDescStyle.Render(" back"),
+		}
+	} else if m.currentView == "conflicts" {
+		helpItems = []string{
+			helpKeyStyle.Render("↑/↓") + helpDescStyle.Render(" move"),
+			helpKeyStyle.Render("b") + helpDescStyle.Render(" backup and replace"),
+			helpKeyStyle.Render("s") + helpDescStyle.Render(" skip"),
+			helpKeyStyle.Render("m") + helpDescStyle.Render(" merge dir into source"),
+			helpKeyStyle.Render("d") + helpDescStyle.Render(" view diff"),
+			helpKeyStyle.Render("enter") + helpDescStyle.Render(" apply"),
+			helpKeyStyle.Render("esc") + helpDescStyle.Render(" back"),
+		}
+	} else if m.currentView == "diff" {
+		helpItems = []string{
+			helpKeyStyle.Render("↑/↓") + helpDescStyle.Render(" scroll"),
+			helpKeyStyle.Render("pgup/pgdn") + helpDescStyle.Render(" page"),
+			helpKeyStyle.Render("p") + helpDescStyle.Render(" open in pager"),
+			helpKeyStyle.Render("esc") + helpDescStyle.Render(" back"),
+		}
+	}
 	
-	helpContent := strings.Join(helpItems, helpSeparatorStyle.Render(" • "))
+	helpContent := strings.Join(helpItems, helpSeparatorStyle.Render(glyphSeparator))
 	helpBar := "\n" + helpBarStyle.Render(helpContent)
 	
 	return header + content + status + helpBar
@@ -220,10 +457,15 @@ func (m model) handleAdd() (tea.Model, tea.Cmd) {
 	}
 	
 	// Create ConfigFile from selected path
-	newFile, err := createConfigFileFromPath(selectedPath, m.config)
+	newFile, err := createConfigFileFromPath(selectedPath, m.config, true)
 	if err != nil {
-		m.message = fmt.Sprintf("Failed to create config entry: %v", err)
-		m.messageType = "error"
+		if strings.Contains(err.Error(), "cancelled") {
+			m.message = "Add operation cancelled"
+			m.messageType = "warning"
+		} else {
+			m.message = fmt.Sprintf("Failed to create config entry: %v", err)
+			m.messageType = "error"
+		}
 		return m, tea.Batch(
 			tea.HideCursor,
 			func() tea.Msg {
@@ -232,6 +474,26 @@ func (m model) handleAdd() (tea.Model, tea.Cmd) {
 		)
 	}
 	
+	// A link into another dotfiles setup: offer to take over the real file
+	absorbed := ""
+	if linkTarget, resolved, ok := externalSymlink(m.config, &newFile); ok {
+		confirmed, err := confirmAction(fmt.Sprintf("%s is a symlink to %s (resolving to %s). Copy the real file into your dotfiles?", 
+			newFile.Target, linkTarget, resolved))
+		if err == nil && confirmed {
+			if err := absorbSymlinkTarget(m.config, &newFile); err != nil {
+				m.message = fmt.Sprintf("Failed to absorb %s: %v", newFile.Name, err)
+				m.messageType = "error"
+				return m, tea.Batch(
+					tea.HideCursor,
+					func() tea.Msg {
+						return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+					},
+				)
+			}
+			absorbed = fmt.Sprintf(" (copied from %s; link it to replace the old symlink)", resolved)
+		}
+	}
+	
 	// Add file using the safe method
 	if err := m.config.AddConfigFile(newFile); err != nil {
 		if IsValidationError(err) {
@@ -249,14 +511,9 @@ func (m model) handleAdd() (tea.Model, tea.Cmd) {
 	}
 	
 	// Update the list items properly
-	fileItems := make([]list.Item, len(m.config.Files))
-	for i, file := range m.config.Files {
-		fileItems[i] = fileItem{file: file}
-	}
-	
-	m.fileList.SetItems(fileItems)
+	m.fileList.SetItems(m.listItems())
 	
-	m.message = fmt.Sprintf("Added %s to configuration", newFile.Name)
+	m.message = fmt.Sprintf("Added %s to configuration", newFile.Name) + absorbed
 	m.messageType = "success"
 	
 	// Save config safely
@@ -274,8 +531,22 @@ func (m model) handleAdd() (tea.Model, tea.Cmd) {
 }
 
 func (m model) handleRemove() (tea.Model, tea.Cmd) {
-	if selected := m.fileList.SelectedItem(); selected != nil {
-		selectedFileItem := selected.(fileItem)
+	if selectedFileItem, ok := m.fileList.SelectedItem().(fileItem); ok {
+		
+		// Adopted targets can go back to the link they replaced
+		restored := ""
+		restoreFailed := false
+		if file := selectedFileItem.file; file.OriginalLink != "" {
+			confirmed, err := confirmAction(fmt.Sprintf("Point %s back to %s?", file.Target, file.OriginalLink))
+			if err == nil && confirmed {
+				if err := restoreOriginalLink(m.config, &file); err != nil {
+					restored = fmt.Sprintf(" (warning: failed to restore original link: %v)", err)
+					restoreFailed = true
+				} else {
+					restored = fmt.Sprintf(" and restored link to %s", file.OriginalLink)
+				}
+			}
+		}
 		
 		// Remove file using the safe method
 		if err := m.config.RemoveConfigFile(selectedFileItem.file.Target); err != nil {
@@ -283,15 +554,13 @@ func (m model) handleRemove() (tea.Model, tea.Cmd) {
 			m.messageType = "error"
 		} else {
 			// Update the list items properly
-			fileItems := make([]list.Item, len(m.config.Files))
-			for i, file := range m.config.Files {
-				fileItems[i] = fileItem{file: file}
-			}
+			m.fileList.SetItems(m.listItems())
 			
-			m.fileList.SetItems(fileItems)
-			
-			m.message = fmt.Sprintf("Removed %s from configuration", selectedFileItem.file.Name)
+			m.message = fmt.Sprintf("Removed %s from configuration", selectedFileItem.file.Name) + restored
 			m.messageType = "success"
+			if restoreFailed {
+				m.messageType = "warning"
+			}
 			
 			// Save config safely
 			if err := saveConfigSafe(m.config); err != nil {
@@ -304,14 +573,16 @@ func (m model) handleRemove() (tea.Model, tea.Cmd) {
 		m.messageType = "warning"
 	}
 	
-	return m, func() tea.Msg {
-		return tea.WindowSizeMsg{Width: m.width, Height: m.height}
-	}
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
 }
 
 func (m model) handleLinkSelected() (tea.Model, tea.Cmd) {
-	if selected := m.fileList.SelectedItem(); selected != nil {
-		selectedFileItem := selected.(fileItem)
+	if selectedFileItem, ok := m.fileList.SelectedItem().(fileItem); ok {
 		
 		// Use atomic linking operation
 		msg, err := linkConfigFile(m.config, &selectedFileItem.file)
@@ -323,16 +594,13 @@ func (m model) handleLinkSelected() (tea.Model, tea.Cmd) {
 			}
 			m.messageType = "error"
 		} else {
+			saveLinkTimes(m.config)
+			
 			// Update file statuses
 			updateFileStatuses(m.config)
 			
 			// Update the list items with new statuses
-			fileItems := make([]list.Item, len(m.config.Files))
-			for i, file := range m.config.Files {
-				fileItems[i] = fileItem{file: file}
-			}
-			
-			m.fileList.SetItems(fileItems)
+			m.fileList.SetItems(m.listItems())
 			
 			m.message = msg
 			m.messageType = "success"
@@ -348,8 +616,38 @@ func (m model) handleLinkSelected() (tea.Model, tea.Cmd) {
 }
 
 func (m model) handleLinkAll() (tea.Model, tea.Cmd) {
+	// Large sets are linked one file per command so progress can be shown
+	if len(m.config.Files) >= linkProgressThreshold {
+		if err := validateForApply(m.config); err != nil {
+			m.message = fmt.Sprintf("Configuration error: %v", err)
+			m.messageType = "error"
+			return m, nil
+		}
+		
+		answers, err := resolveBatchConflicts(m.config)
+		if err != nil {
+			m.message = fmt.Sprintf("Link all cancelled: %v", err)
+			m.messageType = "warning"
+			return m, tea.Batch(tea.HideCursor, func() tea.Msg {
+				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+			})
+		}
+		
+		m.currentView = "linking"
+		m.linkIndex = 0
+		m.linkResults = nil
+		m.linkAnswers = answers
+		m.linkQueue = newLinkScheduler(m.config)
+		m.linkRunning = 0
+		m.message = fmt.Sprintf("Linking %d configuration files...", len(m.config.Files))
+		m.messageType = "success"
+		var startCmds []tea.Cmd
+		m, startCmds = m.startLinkWorkers()
+		return m, tea.Batch(append([]tea.Cmd{tea.HideCursor, m.progress.SetPercent(0)}, startCmds...)...)
+	}
+	
 	// Use atomic operations for linking all configs
-	messages, err := applyAllConfigs(m.config)
+	results, err := applyAllConfigs(m.config)
 	if err != nil {
 		if IsConfigError(err) || IsValidationError(err) {
 			m.message = fmt.Sprintf("Configuration error: %v", err)
@@ -358,44 +656,59 @@ func (m model) handleLinkAll() (tea.Model, tea.Cmd) {
 		}
 		m.messageType = "error"
 	} else {
+		saveLinkTimes(m.config)
+		
 		// Update file statuses
 		updateFileStatuses(m.config)
 		
 		// Update the list items with new statuses
-		fileItems := make([]list.Item, len(m.config.Files))
-		for i, file := range m.config.Files {
-			fileItems[i] = fileItem{file: file}
-		}
-		
-		m.fileList.SetItems(fileItems)
+		m.fileList.SetItems(m.listItems())
 		
-		// Show summary of what was done
-		if len(messages) > 0 {
-			summary := fmt.Sprintf("✅ Successfully processed %d files", len(m.config.Files))
-			if len(messages) <= 3 {
-				if len(messages) == 1 {
-					summary = messages[0]
-				} else {
-					summary += ": " + strings.Join(messages[:2], ", ")
-				}
-			} else {
-				summary += fmt.Sprintf(" (%d operations completed)", len(messages))
-			}
-			m.message = summary
-		} else {
-			m.message = fmt.Sprintf("✅ Successfully linked %d configuration files", len(m.config.Files))
-		}
-		m.messageType = "success"
+		// Show how many files linked, failed or were skipped
+		m.message, m.messageType = summarizeLinkResults(results)
 	}
 	
-	return m, func() tea.Msg {
-		return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// handleLinkUnlinked links the files that have nothing at their target yet,
+// leaving linked, conflicted and drifted files alone
+func (m model) handleLinkUnlinked() (tea.Model, tea.Cmd) {
+	linked, left, err := linkUnlinked(m.config)
+	if err != nil {
+		m.message = fmt.Sprintf("Linking unlinked files failed, nothing was changed: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	saveLinkTimes(m.config)
+	updateFileStatuses(m.config)
+	m.fileList.SetItems(m.listItems())
+	
+	switch {
+	case linked == 0 && left == 0:
+		m.message = "Nothing to link - every file is already linked"
+		m.messageType = "success"
+	case linked == 0:
+		m.message = fmt.Sprintf("Nothing linked - %d conflicted or drifted files need c or S", left)
+		m.messageType = "warning"
+	case left > 0:
+		m.message = fmt.Sprintf("%s Linked %d files; %d conflicted or drifted files left alone", glyphSuccess, linked, left)
+		m.messageType = "warning"
+	default:
+		m.message = fmt.Sprintf("%s Linked %d files", glyphSuccess, linked)
+		m.messageType = "success"
 	}
+	return m, nil
 }
 
 func (m model) handleEdit() (tea.Model, tea.Cmd) {
-	if selected := m.fileList.SelectedItem(); selected != nil {
-		selectedFileItem := selected.(fileItem)
+	if selectedFileItem, ok := m.fileList.SelectedItem().(fileItem); ok {
 		
 		// Use enhanced editor opening with better error handling
 		sourcePath := filepath.Join(m.config.DotfilesDir, selectedFileItem.file.Source)
@@ -407,6 +720,12 @@ func (m model) handleEdit() (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		
+		if err := m.config.checkEditor(); err != nil {
+			m.message = fmt.Sprintf("Can't edit: %v", err)
+			m.messageType = "error"
+			return m, nil
+		}
+		
 		// Check if it's a directory
 		if info, err := os.Stat(sourcePath); err == nil && info.IsDir() {
 			// Handle directory selection first
@@ -430,12 +749,12 @@ func (m model) handleEdit() (tea.Model, tea.Cmd) {
 			
 			// Open the selected file from the directory
 			fullPath := filepath.Join(sourcePath, selectedFile)
-			return m, tea.ExecProcess(createSingleFileEditorCommand(m.config.Editor, fullPath), func(err error) tea.Msg {
+			return m, tea.ExecProcess(createSingleFileEditorCommand(m.config, fullPath), func(err error) tea.Msg {
 				return editorFinishedMsg{err: err, fileName: selectedFile}
 			})
 		} else {
 			// Single file - open directly
-			return m, tea.ExecProcess(createSingleFileEditorCommand(m.config.Editor, sourcePath), func(err error) tea.Msg {
+			return m, tea.ExecProcess(createSingleFileEditorCommand(m.config, sourcePath), func(err error) tea.Msg {
 				return editorFinishedMsg{err: err, fileName: selectedFileItem.file.Name}
 			})
 		}
@@ -446,6 +765,271 @@ func (m model) handleEdit() (tea.Model, tea.Cmd) {
 	}
 }
 
+// handleEditTarget opens the live target instead of the source. For linked
+// files this is the same file; for anything else the edit happens in place
+// and the user is offered to copy it back into the dotfiles directory.
+func (m model) handleEditTarget() (tea.Model, tea.Cmd) {
+	selected, ok := m.fileList.SelectedItem().(fileItem)
+	if !ok {
+		m.message = "No file selected to edit"
+		m.messageType = "warning"
+		return m, nil
+	}
+	
+	file := selected.file
+	sourcePath := filepath.Join(m.config.DotfilesDir, file.Source)
+	
+	target, err := resolveTarget(m.config, &file)
+	if err != nil {
+		m.message = fmt.Sprintf("Can't edit target: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	info, err := os.Stat(target)
+	if err != nil {
+		m.message = fmt.Sprintf("Target file/directory does not exist: %s", target)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	if err := m.config.checkEditor(); err != nil {
+		m.message = fmt.Sprintf("Can't edit target: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	editPath := target
+	editSource := sourcePath
+	fileName := file.Name
+	if info.IsDir() {
+		selectedFile, err := handleDirectorySelection(target)
+		if err != nil {
+			if IsConfigError(err) && strings.Contains(err.Error(), "cancelled") {
+				m.message = "Edit operation cancelled"
+				m.messageType = "warning"
+			} else {
+				m.message = fmt.Sprintf("File selection failed: %v", err)
+				m.messageType = "error"
+			}
+			
+			return m, tea.Batch(
+				tea.HideCursor,
+				func() tea.Msg {
+					return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+				},
+			)
+		}
+		
+		editPath = filepath.Join(target, selectedFile)
+		editSource = filepath.Join(sourcePath, selectedFile)
+		fileName = selectedFile
+	}
+	
+	// Only offer a sync back when the edited file isn't the source itself
+	finished := editorFinishedMsg{fileName: fileName}
+	if !sameFile(editPath, editSource) {
+		finished.syncTarget = editPath
+		finished.syncSource = editSource
+	}
+	
+	return m, tea.ExecProcess(createSingleFileEditorCommand(m.config, editPath), func(err error) tea.Msg {
+		finished.err = err
+		return finished
+	})
+}
+
+// syncEditedTarget offers to copy an edited target back over its source
+func syncEditedTarget(msg editorFinishedMsg) (string, string) {
+	if filesEqual(msg.syncTarget, msg.syncSource) {
+		return fmt.Sprintf("Finished editing %s (source already up to date)", msg.fileName), "success"
+	}
+	
+	confirm, err := confirmAction(fmt.Sprintf("Copy changes to %s back into the source?", msg.fileName))
+	if err != nil || !confirm {
+		return fmt.Sprintf("Finished editing %s (source not updated)", msg.fileName), "warning"
+	}
+	
+	if err := ensureDir(filepath.Dir(msg.syncSource)); err != nil {
+		return fmt.Sprintf("Failed to update source: %v", err), "error"
+	}
+	if err := copyFile(msg.syncTarget, msg.syncSource); err != nil {
+		return fmt.Sprintf("Failed to update source: %v", err), "error"
+	}
+	
+	return fmt.Sprintf("Finished editing %s and copied changes to %s", msg.fileName, msg.syncSource), "success"
+}
+
+// handleSyncToSource copies a drifted copy-mode target back into the dotfiles directory
+func (m model) handleSyncToSource() (tea.Model, tea.Cmd) {
+	if _, ok := m.fileList.SelectedItem().(fileItem); !ok {
+		m.message = "No file selected to sync"
+		m.messageType = "warning"
+		return m, nil
+	}
+	
+	index := m.selectedFileIndex()
+	if index < 0 {
+		return m, nil
+	}
+	file := &m.config.Files[index]
+	
+	if !file.Drifted {
+		if file.LinkStrategy == LinkStrategyCopy {
+			m.message = fmt.Sprintf("%s is already in sync with its source", file.Name)
+		} else {
+			m.message = fmt.Sprintf("Only drifted %q files can be synced to source", LinkStrategyCopy)
+		}
+		m.messageType = "warning"
+		return m, nil
+	}
+	
+	if err := syncTargetToSource(m.config, file); err != nil {
+		m.message = fmt.Sprintf("Sync failed for %s: %v", file.Name, err)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	m.fileList.SetItem(m.fileList.Index(), fileItem{file: cloneConfigFile(*file)})
+	m.message = fmt.Sprintf("Copied %s back into %s", file.Target, file.Source)
+	m.messageType = "success"
+	return m, nil
+}
+
+// handleRename prompts for a new name and source for the selected file. Either
+// can be left as it is; a new source is moved on disk and its links re-pointed.
+func (m model) handleRename() (tea.Model, tea.Cmd) {
+	index := m.selectedFileIndex()
+	if index < 0 {
+		m.message = "No file selected to rename"
+		m.messageType = "warning"
+		return m, nil
+	}
+	file := m.config.Files[index]
+	
+	done := func(m model) (tea.Model, tea.Cmd) {
+		return m, tea.Batch(
+			tea.HideCursor,
+			func() tea.Msg {
+				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+			},
+		)
+	}
+	
+	newName, err := promptForInput("New name: ", file.Name, file.Name)
+	if err != nil || newName == "" {
+		m.message = "Rename cancelled"
+		m.messageType = "warning"
+		return done(m)
+	}
+	newSource, err := promptForInput("New source (relative to dotfiles): ", file.Source, file.Source)
+	if err != nil || newSource == "" {
+		m.message = "Rename cancelled"
+		m.messageType = "warning"
+		return done(m)
+	}
+	
+	if err := m.config.RenameFile(file.Target, newName); err != nil {
+		m.message = fmt.Sprintf("Failed to rename %s: %v", file.Name, err)
+		m.messageType = "error"
+		return done(m)
+	}
+	if err := m.config.MoveSource(file.Target, newSource); err != nil {
+		// A failed move leaves the entry as it was, name included
+		m.config.RenameFile(file.Target, file.Name)
+		m.message = fmt.Sprintf("Failed to move source of %s: %v", file.Name, err)
+		m.messageType = "error"
+		return done(m)
+	}
+	
+	if err := saveConfigSafe(m.config); err != nil {
+		m.message = fmt.Sprintf("Renamed %s but failed to save config: %v", file.Name, err)
+		m.messageType = "error"
+		return done(m)
+	}
+	
+	updated := m.config.Files[index]
+	m.fileList.SetItem(m.fileList.Index(), fileItem{file: cloneConfigFile(updated)})
+	m.message = fmt.Sprintf("%s %s now %s (source %s)", glyphSuccess, file.Name, updated.Name, updated.Source)
+	m.messageType = "success"
+	return done(m)
+}
+
+// handleToggleDisabled disables the selected file, or enables it again. A
+// disabled file stays in the config but is left out of every link-all.
+func (m model) handleToggleDisabled() (tea.Model, tea.Cmd) {
+	index := m.selectedFileIndex()
+	if index < 0 {
+		m.message = "No file selected to disable"
+		m.messageType = "warning"
+		return m, nil
+	}
+	file := m.config.Files[index]
+	
+	if err := m.config.SetDisabled(file.Target, !file.Disabled); err != nil {
+		m.message = fmt.Sprintf("Failed to update %s: %v", file.Name, err)
+		m.messageType = "error"
+		return m, nil
+	}
+	if err := saveConfigSafe(m.config); err != nil {
+		m.message = fmt.Sprintf("Updated %s but failed to save config: %v", file.Name, err)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	updateSingleFileStatus(m.config, &m.config.Files[index])
+	m.fileList.SetItem(m.fileList.Index(), fileItem{file: cloneConfigFile(m.config.Files[index])})
+	if m.config.Files[index].Disabled {
+		m.message = fmt.Sprintf("Disabled %s; link-all will skip it", file.Name)
+	} else {
+		m.message = fmt.Sprintf("Enabled %s", file.Name)
+	}
+	m.messageType = "success"
+	return m, nil
+}
+
+// handleDescribe edits the note shown under the selected file
+func (m model) handleDescribe() (tea.Model, tea.Cmd) {
+	index := m.selectedFileIndex()
+	if index < 0 {
+		m.message = "No file selected to describe"
+		m.messageType = "warning"
+		return m, nil
+	}
+	file := m.config.Files[index]
+	
+	done := func(m model) (tea.Model, tea.Cmd) {
+		return m, tea.Batch(
+			tea.HideCursor,
+			func() tea.Msg {
+				return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+			},
+		)
+	}
+	
+	description, err := promptForInput("Description: ", "what this file is for (empty to clear)", file.Description)
+	if err != nil {
+		m.message = "Description unchanged"
+		m.messageType = "warning"
+		return done(m)
+	}
+	if err := m.config.SetDescription(file.Target, description); err != nil {
+		m.message = fmt.Sprintf("Failed to set description of %s: %v", file.Name, err)
+		m.messageType = "error"
+		return done(m)
+	}
+	if err := saveConfigSafe(m.config); err != nil {
+		m.message = fmt.Sprintf("Updated %s but failed to save config: %v", file.Name, err)
+		m.messageType = "error"
+		return done(m)
+	}
+	
+	m.fileList.SetItem(m.fileList.Index(), fileItem{file: cloneConfigFile(m.config.Files[index])})
+	m.message = fmt.Sprintf("Updated description of %s", file.Name)
+	m.messageType = "success"
+	return done(m)
+}
+
 func (m model) handleBackup() (tea.Model, tea.Cmd) {
 	// Create enhanced backup
 	backupDir := createBackupWithStats(m.config)
@@ -454,7 +1038,7 @@ func (m model) handleBackup() (tea.Model, tea.Cmd) {
 		m.messageType = "error"
 	} else {
 		stats := m.config.GetStats()
-		m.message = fmt.Sprintf("Backed up %d files to %s", stats["total_files"], filepath.Base(backupDir))
+		m.message = fmt.Sprintf("Backed up %d files to %s", stats["total_files"], backupDir)
 		m.messageType = "success"
 	}
 	
@@ -463,7 +1047,7 @@ func (m model) handleBackup() (tea.Model, tea.Cmd) {
 
 // Enhanced backup creation with statistics
 func createBackupWithStats(config *Config) string {
-	backupDir := fmt.Sprintf("%s/backups/%s", config.ConfigDir, time.Now().Format("2006-01-02_15-04-05"))
+	backupDir := filepath.Join(config.GetBackupDir(), config.backupPolicy().timestamp())
 	backedUp := createBackupInDir(config, backupDir)
 	
 	if backedUp == 0 {
@@ -473,10 +1057,111 @@ func createBackupWithStats(config *Config) string {
 	return backupDir
 }
 
+// linkProgressThreshold is the number of files at which link-all switches
+// from a single blocking call to per-file progress updates
+const linkProgressThreshold = 5
+
+// linkFileDoneMsg is emitted when one file of a link-all batch completes
+type linkFileDoneMsg struct {
+	index  int
+	result OperationResult
+}
+
+// linkFileCmd links the file at index the way its conflict was answered and
+// reports the result; a file answered with "skip" is reported without being
+// touched
+func linkFileCmd(config *Config, index int, answer ConflictResolution) tea.Cmd {
+	return func() tea.Msg {
+		file := config.Files[index]
+		return linkFileDoneMsg{
+			index:  index,
+			result: linkConfigWithAnswer(config, &file, answer),
+		}
+	}
+}
+
+// startLinkWorkers starts linking as many waiting files as the configured
+// parallelism allows
+func (m model) startLinkWorkers() (model, []tea.Cmd) {
+	var cmds []tea.Cmd
+	for m.linkRunning < m.config.parallelism() {
+		index, ok := m.linkQueue.next()
+		if !ok {
+			break
+		}
+		m.linkRunning++
+		cmds = append(cmds, linkFileCmd(m.config, index, m.linkAnswers[index]))
+	}
+	return m, cmds
+}
+
+// handleLinkFileDone records a finished file and starts the next ones
+func (m model) handleLinkFileDone(msg linkFileDoneMsg) (tea.Model, tea.Cmd) {
+	m.linkResults = append(m.linkResults, msg.result)
+	m.linkIndex++
+	m.linkRunning--
+	m.linkQueue.done(msg.index)
+	if msg.result.Success && !msg.result.Skipped {
+		// Recorded here rather than in the command, which runs off the UI goroutine
+		m.config.markLinked(m.config.Files[msg.index].Target)
+	}
+	
+	total := len(m.config.Files)
+	progressCmd := m.progress.SetPercent(float64(m.linkIndex) / float64(total))
+	
+	if m.linkIndex < total {
+		var startCmds []tea.Cmd
+		m, startCmds = m.startLinkWorkers()
+		return m, tea.Batch(append([]tea.Cmd{progressCmd}, startCmds...)...)
+	}
+	
+	// Batch finished - refresh statuses and summarize
+	m.currentView = "main"
+	saveLinkTimes(m.config)
+	updateFileStatuses(m.config)
+	
+	m.fileList.SetItems(m.listItems())
+	
+	m.message, m.messageType = summarizeLinkResults(m.linkResults)
+	
+	return m, progressCmd
+}
+
+// linkProgressView renders the progress bar with the count and the file that
+// finished last
+func (m model) linkProgressView() string {
+	total := len(m.config.Files)
+	current := ""
+	if len(m.linkResults) > 0 {
+		current = m.linkResults[len(m.linkResults)-1].File
+	}
+	
+	return fmt.Sprintf("\n%s\n\n%s\n\n%s\n",
+		activeStyle.Render("Linking configuration files"),
+		m.progress.View(),
+		inactiveStyle.Render(fmt.Sprintf("(%d/%d) %s", m.linkIndex, total, current)))
+}
+
 // Message type for when editor finishes (unchanged)
 type editorFinishedMsg struct {
-	err      error
-	fileName string
+	err        error
+	fileName   string
+	tool       string // what was run, for messages; "" means the editor
+	syncTarget string // set when a target was edited in place
+	syncSource string
+}
+
+// conflictDiffDoneMsg reports that the diff pager opened from the conflicts view exited
+type conflictDiffDoneMsg struct {
+	err error
+}
+
+// toolName names the program that was run for status messages
+func (msg editorFinishedMsg) toolName() string {
+	if msg.tool == "" {
+		return "editor"
+	}
+	return msg.tool
 }
 
 // Enhanced directory selection handling
@@ -517,29 +1202,205 @@ func handleDirectorySelection(dirPath string) (string, error) {
 	return selectFileToEdit(editableFiles)
 }
 
-// Create command for editing a single file (unchanged)
-func createSingleFileEditorCommand(editor, filePath string) *exec.Cmd {
-	// Handle different editors that might need special arguments
-	switch editor {
-	case "code", "vscode":
-		return exec.Command("code", "--wait", filePath)
-	case "subl", "sublime":
-		return exec.Command("subl", "--wait", filePath)
-	case "atom":
-		return exec.Command("atom", "--wait", filePath)
+// handleOpenDirectory opens the selected file's source directory (or the whole
+// dotfiles directory) in a shell or the configured file manager, returning to
+// the TUI when it exits
+func (m model) handleOpenDirectory(inShell bool) (tea.Model, tea.Cmd) {
+	dir := m.config.DotfilesDir
+	if selected, ok := m.fileList.SelectedItem().(fileItem); ok {
+		sourcePath := filepath.Join(m.config.DotfilesDir, selected.file.Source)
+		if info, err := os.Stat(sourcePath); err == nil && info.IsDir() {
+			dir = sourcePath
+		} else if err == nil {
+			dir = filepath.Dir(sourcePath)
+		}
+	}
+	
+	var cmd *exec.Cmd
+	tool := "file manager"
+	if inShell {
+		cmd = shellCommand(m.config)
+		tool = "shell"
+	} else {
+		cmd = fileManagerCommand(m.config, dir)
+	}
+	
+	// runner.Command records a failed PATH lookup in cmd.Err
+	if cmd.Err != nil {
+		m.message = fmt.Sprintf("Cannot open %s: %s not found", tool, cmd.Args[0])
+		if !inShell {
+			m.message += " (set file_manager in config.json)"
+		}
+		m.messageType = "warning"
+		return m, nil
+	}
+	cmd.Dir = dir
+	
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{err: err, fileName: dir, tool: tool}
+	})
+}
+
+// shellCommand starts an interactive $SHELL, falling back to the configured shell
+func shellCommand(config *Config) *exec.Cmd {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = config.Shell
+	}
+	return runner.Command(shell)
+}
+
+// fileManagerCommand opens dir with the configured file manager (which may
+// include arguments) or the platform's default opener
+func fileManagerCommand(config *Config, dir string) *exec.Cmd {
+	args := strings.Fields(config.FileManager)
+	if len(args) == 0 {
+		args = []string{defaultFileManager()}
+	}
+	return runner.Command(args[0], append(args[1:], dir)...)
+}
+
+// defaultFileManager returns the usual command for opening a directory on this OS
+func defaultFileManager() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "open"
+	case "windows":
+		return "explorer"
 	default:
-		// For vim, nvim, emacs, nano, etc.
-		return exec.Command(editor, filePath)
+		return "xdg-open"
 	}
 }
 
-// Enhanced file list creation with better sizing
-func createFileList(files []ConfigFile, width, height int) list.Model {
-	fileItems := make([]list.Item, len(files))
-	for i, file := range files {
-		fileItems[i] = fileItem{file: file}
+// Create command for editing a single file
+func createSingleFileEditorCommand(config *Config, filePath string) *exec.Cmd {
+	args := config.editorInvocation(filePath)
+	return runner.Command(args[0], args[1:]...)
+}
+
+// printExitSummary reminds the user of files left unlinked or conflicted
+// when the TUI exits. Nothing is printed when everything is linked.
+func printExitSummary(final tea.Model) {
+	m, ok := final.(model)
+	if !ok || m.config == nil {
+		return
+	}
+	
+	updateFileStatuses(m.config)
+	unlinked := m.config.GetUnlinkedFiles()
+	conflicted := m.config.GetConflictedFiles()
+	if len(unlinked) == 0 {
+		return
 	}
 	
+	infof("%s %d of %d files not linked (%d conflicted):\n", glyphWarning, len(unlinked), len(m.config.Files), len(conflicted))
+	for _, file := range unlinked {
+		note := ""
+		if file.HasConflict {
+			note = " (conflict)"
+		} else if file.Drifted {
+			note = " (drifted)"
+		}
+		infof("  - %s%s\n", file.Name, note)
+	}
+	infoln("Run config-manager again and press L to link them, or c to resolve conflicts.")
+}
+
+// fileListItems builds list items from a snapshot of config's files, so items
+// never share maps or slices with entries that are changed afterwards. They
+// are listed in apply order.
+func fileListItems(config *Config) []list.Item {
+	files := config.SnapshotFiles()
+	items := make([]list.Item, len(files))
+	for i, index := range config.applyOrder() {
+		items[i] = fileItem{file: files[index]}
+	}
+	return items
+}
+
+// listItems is fileListItems narrowed to the tag being filtered on, if any,
+// and grouped under category headers
+func (m model) listItems() []list.Item {
+	items := fileListItems(m.config)
+	if m.tagFilter != "" {
+		var tagged []list.Item
+		for _, item := range items {
+			if file := item.(fileItem).file; file.HasTag(m.tagFilter) {
+				tagged = append(tagged, item)
+			}
+		}
+		items = tagged
+	}
+	return groupByCategory(items, m.config.Categories, m.collapsedCategories)
+}
+
+// selectedFileIndex is the index in config.Files of the selected list item, or
+// -1 when no file is selected (a category header may be). List positions and
+// config indexes differ, as files are grouped by category.
+func (m model) selectedFileIndex() int {
+	selected, ok := m.fileList.SelectedItem().(fileItem)
+	if !ok {
+		return -1
+	}
+	target := selected.file.Target
+	for i, file := range m.config.Files {
+		if file.Target == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// handleTagFilter lists only the files carrying the next tag in turn, and all
+// files again after the last one
+func (m model) handleTagFilter() (tea.Model, tea.Cmd) {
+	tags := m.config.Tags()
+	if len(tags) == 0 {
+		m.tagFilter = ""
+		m.message = "No files are tagged - add \"tags\" to files in config.json to group them"
+		m.messageType = "warning"
+		return m, nil
+	}
+	
+	next := tags[0]
+	for i, tag := range tags {
+		if tag == m.tagFilter {
+			next = ""
+			if i+1 < len(tags) {
+				next = tags[i+1]
+			}
+			break
+		}
+	}
+	if next == "" {
+		m = m.clearTagFilter()
+		m.message = fmt.Sprintf("Showing all %d files", len(m.config.Files))
+		m.messageType = "success"
+		return m, nil
+	}
+	
+	m.tagFilter = next
+	m.fileList.SetItems(m.listItems())
+	m.fileList.Select(0)
+	m.fileList.Title = "Managed Configuration Files #" + next
+	m.message = fmt.Sprintf("Showing %d files tagged %s (t for the next tag)", len(m.config.GetFilesByTag(next)), next)
+	m.messageType = "success"
+	return m, nil
+}
+
+// clearTagFilter lists every file again
+func (m model) clearTagFilter() model {
+	if m.tagFilter == "" {
+		return m
+	}
+	m.tagFilter = ""
+	m.fileList.SetItems(m.listItems())
+	m.fileList.Title = "Managed Configuration Files"
+	return m
+}
+
+// Enhanced file list creation with better sizing
+func createFileList(items []list.Item, width, height int) list.Model {
 	// Ensure minimum dimensions
 	if width < 40 {
 		width = 40
@@ -548,7 +1409,7 @@ func createFileList(files []ConfigFile, width, height int) list.Model {
 		height = 5
 	}
 	
-	fileList := list.New(fileItems, list.NewDefaultDelegate(), width, height)
+	fileList := list.New(items, newGroupedDelegate(), width, height)
 	fileList.Title = "Managed Configuration Files"
 	fileList.SetShowStatusBar(false)
 	fileList.SetShowHelp(false) // We'll show our own help
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestShellAndFileManagerCommands(t *testing.T) {
+	t.Setenv("SHELL", "")
+	fake := &scriptedRunner{Available: map[string]bool{"fish": true}}
+	useRunner(t, fake)
+	config := &Config{Shell: "fish", FileManager: "yazi --cwd-file /tmp/cwd"}
+
+	if cmd := shellCommand(config); cmd.Err != nil || cmd.Args[0] != "fish" {
+		t.Errorf("shellCommand: args %q, err %v; want fish from config", cmd.Args, cmd.Err)
+	}
+
+	cmd := fileManagerCommand(config, "/home/user/.config")
+	want := []string{"yazi", "--cwd-file", "/tmp/cwd", "/home/user/.config"}
+	if len(cmd.Args) != len(want) {
+		t.Fatalf("fileManagerCommand args = %q, want %q", cmd.Args, want)
+	}
+	for i := range want {
+		if cmd.Args[i] != want[i] {
+			t.Fatalf("fileManagerCommand args = %q, want %q", cmd.Args, want)
+		}
+	}
+	if cmd.Err == nil {
+		t.Error("a file manager missing from PATH should leave cmd.Err set")
+	}
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
@@ -18,15 +18,36 @@ func (c *Config) Validate() []ValidationError {
 	// Validate files
 	errors = append(errors, c.validateFiles()...)
 	
+	// Validate targets inside other targets
+	errors = append(errors, c.validateNestedTargets()...)
+	
 	// Validate templates
 	errors = append(errors, c.validateTemplates()...)
 	
+	// Validate template flags against their contents
+	errors = append(errors, c.validateTemplateMarkers()...)
+	
+	// Validate executable sources
+	errors = append(errors, c.validateExecutables()...)
+	
 	// Validate editor
 	errors = append(errors, c.validateEditor()...)
 	
 	return errors
 }
 
+// BlockingErrors returns the issues found by Validate that stop the
+// configuration from being saved or linked; warnings and info are left out
+func (c *Config) BlockingErrors() []ValidationError {
+	var blocking []ValidationError
+	for _, err := range c.Validate() {
+		if err.IsBlocking() {
+			blocking = append(blocking, err)
+		}
+	}
+	return blocking
+}
+
 func (c *Config) validateBasicConfig() []ValidationError {
 	var errors []ValidationError
 	
@@ -43,6 +64,52 @@ func (c *Config) validateBasicConfig() []ValidationError {
 		errors = append(errors, *NewValidationError("dotfiles_dir", c.DotfilesDir, "must be absolute path", ""))
 	}
 	
+	if c.BackupDir != "" && !filepath.IsAbs(c.BackupDir) {
+		errors = append(errors, *NewValidationError("backup_dir", c.BackupDir, "must be absolute path", ""))
+	}
+	
+	if c.TargetRoot != "" && !filepath.IsAbs(c.TargetRoot) {
+		errors = append(errors, *NewValidationError("target_root", c.TargetRoot, "must be absolute path", ""))
+	}
+	
+	for _, root := range c.AllowedTargetRoots {
+		if !filepath.IsAbs(root) {
+			errors = append(errors, *NewValidationError("allowed_target_roots", root, "must be absolute path", ""))
+		}
+	}
+	
+	switch c.TargetSymlinks {
+	case "", TargetSymlinksRefuse, TargetSymlinksFollow, TargetSymlinksPreserve:
+	default:
+		errors = append(errors, *NewValidationError("target_symlinks", c.TargetSymlinks, 
+			fmt.Sprintf("unknown value (use %q, %q or %q)", TargetSymlinksRefuse, TargetSymlinksFollow, TargetSymlinksPreserve), ""))
+	}
+	
+	switch c.BackupStyle {
+	case "", BackupStyleTimestamped, BackupStyleRolling:
+	default:
+		errors = append(errors, *NewValidationError("backup_style", c.BackupStyle, 
+			fmt.Sprintf("unknown value (use %q or %q)", BackupStyleTimestamped, BackupStyleRolling), ""))
+	}
+	
+	if c.BackupTimeFormat != "" {
+		if err := validateBackupTimeFormat(c.BackupTimeFormat); err != nil {
+			errors = append(errors, *NewValidationError("backup_time_format", c.BackupTimeFormat, err.Error(), ""))
+		}
+	}
+	
+	if c.OperationRetries != nil && *c.OperationRetries < 0 {
+		errors = append(errors, *NewValidationError("operation_retries", fmt.Sprintf("%d", *c.OperationRetries), "must not be negative", ""))
+	}
+	
+	if c.Parallelism < 0 {
+		errors = append(errors, *NewValidationError("parallelism", fmt.Sprintf("%d", c.Parallelism), "must not be negative", ""))
+	}
+	
+	if c.DiscoveryDepth < 0 {
+		errors = append(errors, *NewValidationError("discovery_depth", fmt.Sprintf("%d", c.DiscoveryDepth), "must not be negative", ""))
+	}
+	
 	// Validate categories
 	if len(c.Categories) == 0 {
 		errors = append(errors, *NewValidationError("categories", "", "no categories defined", ""))
@@ -63,8 +130,9 @@ func (c *Config) validateBasicConfig() []ValidationError {
 func (c *Config) validateFiles() []ValidationError {
 	var errors []ValidationError
 	
-	// Track targets to detect duplicates
+	// Track targets to detect duplicates, also by case for case-insensitive filesystems
 	targetsSeen := make(map[string]string)
+	foldedSeen := make(map[string]string)
 	
 	for i, file := range c.Files {
 		fileContext := fmt.Sprintf("files[%d]", i)
@@ -80,17 +148,21 @@ func (c *Config) validateFiles() []ValidationError {
 		
 		if file.Target == "" {
 			errors = append(errors, *NewValidationError("target", "", "target path cannot be empty", fileContext))
+		} else if target, err := resolveTarget(c, &file); err != nil {
+			errors = append(errors, *NewValidationError("target", file.Target, "must be absolute path", fileContext))
 		} else {
-			// Check for duplicate targets
-			if existingFile, exists := targetsSeen[file.Target]; exists {
+			// Duplicates are compared on disk, so /a/b and /a/b/ collide
+			if existingFile, exists := targetsSeen[target]; exists {
 				errors = append(errors, *NewValidationError("target", file.Target, 
 					fmt.Sprintf("duplicate target (also used by %s)", existingFile), fileContext))
+			} else if other, exists := foldedSeen[strings.ToLower(target)]; exists && sameTargetPath(other, target) {
+				// A warning, like nested targets: blocking would lock the whole config
+				errors = append(errors, *NewValidationIssue(SeverityWarning, "target", file.Target, 
+					fmt.Sprintf("differs from the target of %s (%s) only by case, so both are the same file on this case-insensitive filesystem", targetsSeen[other], other), fileContext))
 			}
-			targetsSeen[file.Target] = file.Name
-			
-			// Validate target path is absolute
-			if !filepath.IsAbs(file.Target) {
-				errors = append(errors, *NewValidationError("target", file.Target, "must be absolute path", fileContext))
+			targetsSeen[target] = file.Name
+			if _, exists := foldedSeen[strings.ToLower(target)]; !exists {
+				foldedSeen[strings.ToLower(target)] = target
 			}
 		}
 		
@@ -108,11 +180,70 @@ func (c *Config) validateFiles() []ValidationError {
 			}
 		}
 		
-		// Validate source path doesn't escape dotfiles directory
+		// Validate link strategy
+		switch file.LinkStrategy {
+		case "", LinkStrategySymlink, LinkStrategyTree, LinkStrategyCopy:
+		default:
+			errors = append(errors, *NewValidationError("link_strategy", file.LinkStrategy, 
+				fmt.Sprintf("unknown link strategy (use %q, %q or %q)", LinkStrategySymlink, LinkStrategyTree, LinkStrategyCopy), fileContext))
+		}
+		
+		// Validate exclude patterns
+		for _, pattern := range file.ExcludePatterns {
+			if pattern == "" || filepath.IsAbs(pattern) {
+				errors = append(errors, *NewValidationError("exclude_patterns", pattern, 
+					"exclude patterns must be non-empty and relative to the linked directory", fileContext))
+			} else if _, err := filepath.Match(pattern, ""); err != nil {
+				errors = append(errors, *NewValidationError("exclude_patterns", pattern, "invalid pattern: "+err.Error(), fileContext))
+			}
+		}
+		if len(file.ExcludePatterns) > 0 && file.LinkStrategy == LinkStrategyCopy {
+			errors = append(errors, *NewValidationError("exclude_patterns", strings.Join(file.ExcludePatterns, ","), 
+				"exclude patterns need link_strategy \"tree\" (or the default, which switches to tree)", fileContext))
+		}
+		
+		// Tags are typed on the command line (--tag), so keep them to one word
+		seenTags := make(map[string]bool)
+		for _, tag := range file.Tags {
+			if tag == "" || strings.ContainsAny(tag, " \t,") {
+				errors = append(errors, *NewValidationError("tags", tag, "tags must be non-empty and contain no spaces or commas", fileContext))
+			} else if seenTags[tag] {
+				errors = append(errors, *NewValidationError("tags", tag, "tag is listed more than once", fileContext))
+			}
+			seenTags[tag] = true
+		}
+		
+		// A formatter only runs on rendered templates
+		if len(file.Formatter) > 0 && !file.Template {
+			errors = append(errors, *NewValidationError("formatter", strings.Join(file.Formatter, " "), 
+				"formatter is only used for templates", fileContext))
+		}
+		if len(file.Formatter) > 0 && strings.TrimSpace(file.Formatter[0]) == "" {
+			errors = append(errors, *NewValidationError("formatter", strings.Join(file.Formatter, " "), 
+				"formatter command cannot be empty", fileContext))
+		}
+		
+		// Validate permissions
+		if file.Perms != "" {
+			if _, err := parsePerms(file.Perms); err != nil {
+				errors = append(errors, *NewValidationError("perms", file.Perms, err.Error(), fileContext))
+			}
+		}
+		
+		// Validate source path doesn't escape dotfiles directory. Compare by
+		// path components: a prefix check passes "../dotfiles-evil" and the
+		// like. A symlinked directory on the way can lead out as well.
 		if file.Source != "" {
 			sourcePath := filepath.Join(c.DotfilesDir, file.Source)
-			if !strings.HasPrefix(sourcePath, c.DotfilesDir) {
+			switch {
+			case filepath.IsAbs(file.Source):
+				errors = append(errors, *NewValidationError("source", file.Source, "source must be relative to the dotfiles directory", fileContext))
+			case !isWithinDir(sourcePath, c.DotfilesDir):
 				errors = append(errors, *NewValidationError("source", file.Source, "source path escapes dotfiles directory", fileContext))
+			case filepath.Clean(sourcePath) == filepath.Clean(c.DotfilesDir):
+				errors = append(errors, *NewValidationError("source", file.Source, "source must be inside the dotfiles directory, not the directory itself", fileContext))
+			case parentLeavesDir(sourcePath, c.DotfilesDir):
+				errors = append(errors, *NewValidationError("source", file.Source, "source path escapes dotfiles directory through a symlinked directory", fileContext))
 			}
 		}
 	}
@@ -120,9 +251,66 @@ func (c *Config) validateFiles() []ValidationError {
 	return errors
 }
 
+// validateNestedTargets reports files whose target is inside another file's
+// target. A directory linked as one symlink (or copied) replaces everything in
+// it, so the inner file would be linked into the outer file's source instead.
+// That only works when the outer directory is linked file by file ("tree")
+// and leaves the inner path out with exclude_patterns.
+func (c *Config) validateNestedTargets() []ValidationError {
+	var errors []ValidationError
+	
+	targets := make([]string, len(c.Files))
+	for i := range c.Files {
+		// Unresolvable targets are reported by validateFiles
+		if target, err := resolveTarget(c, &c.Files[i]); err == nil {
+			targets[i] = target
+		}
+	}
+	
+	for i, inner := range c.Files {
+		for j, outer := range c.Files {
+			if i == j || targets[i] == "" || targets[j] == "" || targets[i] == targets[j] || !isWithinDir(targets[i], targets[j]) {
+				continue
+			}
+			
+			relPath, err := filepath.Rel(targets[j], targets[i])
+			if err != nil {
+				continue
+			}
+			relPath = filepath.ToSlash(relPath)
+			if outer.effectiveLinkStrategy() == LinkStrategyTree && isExcludedPath(outer.ExcludePatterns, relPath) {
+				continue
+			}
+			
+			fileContext := fmt.Sprintf("files[%d]", i)
+			problem := fmt.Sprintf("target is inside the target of %s (%s), which links the whole directory, so this file would end up in %s's source", 
+				outer.Name, targets[j], outer.Name)
+			suggestion := fmt.Sprintf("set \"link_strategy\": \"tree\" on %s and add %q to its exclude_patterns", outer.Name, relPath)
+			if outer.effectiveLinkStrategy() == LinkStrategyTree {
+				problem = fmt.Sprintf("target is inside the target of %s (%s), which links its files one by one, so both would link %s", 
+					outer.Name, targets[j], relPath)
+				suggestion = fmt.Sprintf("add %q to the exclude_patterns of %s", relPath, outer.Name)
+			}
+			// A warning: managing a directory and a file inside it is common,
+			// and blocking would keep the whole config from saving or linking
+			errors = append(errors, *NewValidationIssue(SeverityWarning, "target", inner.Target, problem+"; "+suggestion, fileContext))
+		}
+	}
+	
+	return errors
+}
+
 func (c *Config) validateTemplates() []ValidationError {
 	var errors []ValidationError
 	
+	// Shared defaults must parse, or every template fails to render
+	shared, err := loadSharedVariables(c)
+	if err != nil {
+		errors = append(errors, *NewValidationError("template_context", sharedVariablesPath(c), err.Error(), ""))
+	}
+	
+	errors = append(errors, c.validateTemplateOutputs()...)
+	
 	for i, file := range c.Files {
 		if !file.Template {
 			continue
@@ -137,15 +325,17 @@ func (c *Config) validateTemplates() []ValidationError {
 			continue
 		}
 		
-		// Validate template syntax using the function from templates.go
-		if err := validateTemplateFileContent(templatePath); err != nil {
+		// Validate template syntax using the function from templates.go,
+		// with the file's real variables when they can be loaded
+		context, _ := createTemplateContext(c, &file)
+		if err := validateTemplateFileContent(templatePath, context); err != nil {
 			errors = append(errors, *NewValidationError("template", templatePath, 
 				fmt.Sprintf("template syntax error: %v", err), fileContext))
 		}
 		
 		// Validate template variables
-		if err := c.validateTemplateVariables(file, templatePath); err != nil {
-			errors = append(errors, *NewValidationError("template_variables", file.Name, 
+		if err := c.validateTemplateVariables(file, templatePath, shared); err != nil {
+			errors = append(errors, *NewValidationIssue(SeverityWarning, "template_variables", file.Name, 
 				fmt.Sprintf("template variable error: %v", err), fileContext))
 		}
 	}
@@ -153,26 +343,162 @@ func (c *Config) validateTemplates() []ValidationError {
 	return errors
 }
 
+// validateTemplateOutputs reports files sharing a source with a template. A
+// template is rendered to its source, so rendering it would overwrite the other
+// file's source, or another template's output, whichever is linked last.
+// Plain files sharing a source are fine: they link the same file twice.
+func (c *Config) validateTemplateOutputs() []ValidationError {
+	var errors []ValidationError
+	
+	// First file using each source, and the first template using it if any
+	firstUser := make(map[string]int)
+	firstTemplate := make(map[string]int)
+	for i, file := range c.Files {
+		if file.Source == "" {
+			continue
+		}
+		sourcePath := filepath.Clean(filepath.Join(c.DotfilesDir, file.Source))
+		fileContext := fmt.Sprintf("files[%d]", i)
+		
+		if other, exists := firstTemplate[sourcePath]; exists {
+			if file.Template {
+				errors = append(errors, *NewValidationError("source", file.Source, 
+					fmt.Sprintf("template output collides with template %s, which renders to the same source", c.Files[other].Name), fileContext))
+			} else {
+				errors = append(errors, *NewValidationError("source", file.Source, 
+					fmt.Sprintf("source is the output of template %s, which would overwrite it", c.Files[other].Name), fileContext))
+			}
+		} else if other, exists := firstUser[sourcePath]; exists && file.Template {
+			errors = append(errors, *NewValidationError("source", file.Source, 
+				fmt.Sprintf("template output would overwrite the source of %s", c.Files[other].Name), fileContext))
+		}
+		
+		if _, exists := firstUser[sourcePath]; !exists {
+			firstUser[sourcePath] = i
+		}
+		if _, exists := firstTemplate[sourcePath]; !exists && file.Template {
+			firstTemplate[sourcePath] = i
+		}
+	}
+	
+	return errors
+}
+
+// validateTemplateMarkers compares each file's template flag with its
+// contents: a plain source with {{ }} markers is linked with the braces left
+// in, and a template without any renders to an unchanged copy. Files whose
+// flag was set by hand (template_manual) are left alone. Unrendered braces
+// are a warning; an unchanged copy is only info.
+func (c *Config) validateTemplateMarkers() []ValidationError {
+	var warnings []ValidationError
+	
+	for i, file := range c.Files {
+		if file.TemplateManual || file.Source == "" {
+			continue
+		}
+		fileContext := fmt.Sprintf("files[%d]", i)
+		
+		if !file.Template {
+			sourcePath := filepath.Join(c.DotfilesDir, file.Source)
+			if hasTemplateMarkers(sourcePath) {
+				warnings = append(warnings, *NewValidationIssue(SeverityWarning, "template_flag", file.Source, 
+					"source contains {{ }} template syntax but isn't a template, so it is linked with the braces unrendered; move it into templates/ and set \"template\": true, or set \"template_manual\": true to keep it as is", fileContext))
+			}
+			continue
+		}
+		
+		// A template's source is its rendered output, so look at the template
+		templatePath := c.findTemplateFile(file.Name, file.Source, file.Category)
+		if templatePath != "" && !hasTemplateMarkers(templatePath) {
+			warnings = append(warnings, *NewValidationIssue(SeverityInfo, "template_flag", templatePath, 
+				"template has no {{ }} markers, so it renders to an unchanged copy; set \"template\": false unless you plan to add some", fileContext))
+		}
+	}
+	
+	return warnings
+}
+
+// hasTemplateMarkers reports whether the regular file at path contains a
+// {{ ... }} action
+func hasTemplateMarkers(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	open := bytes.Index(data, []byte("{{"))
+	return open >= 0 && bytes.Contains(data[open:], []byte("}}"))
+}
+
+// validateEditor checks the editor can be started. A missing editor only
+// matters when editing, so it is a warning rather than an error.
 func (c *Config) validateEditor() []ValidationError {
 	var errors []ValidationError
 	
 	if c.Editor == "" {
-		errors = append(errors, *NewValidationError("editor", "", "editor not configured", ""))
+		errors = append(errors, *NewValidationIssue(SeverityWarning, "editor", "", "editor not configured", ""))
 		return errors
 	}
 	
 	// Check if editor is available in PATH
-	if _, err := exec.LookPath(c.Editor); err != nil {
-		errors = append(errors, *NewValidationError("editor", c.Editor, 
+	if _, err := runner.LookPath(c.Editor); err != nil {
+		errors = append(errors, *NewValidationIssue(SeverityWarning, "editor", c.Editor, 
 			fmt.Sprintf("editor not found in PATH: %v", err), ""))
 	}
 	
 	return errors
 }
 
+// validateExecutables warns about linked files whose perms make them
+// executable (scripts in ~/.local/bin get 755 when added) but whose source
+// isn't. Perms are only applied to files config-manager writes, so a symlink
+// or tree link runs with the source's own mode: the source must be executable
+// on disk. Whether git records it so is left to doctor, as that runs git for
+// every file and validation runs on every save.
+func (c *Config) validateExecutables() []ValidationError {
+	var errors []ValidationError
+	
+	for _, i := range c.linkedExecutables() {
+		file := c.Files[i]
+		info, err := os.Stat(filepath.Join(c.DotfilesDir, file.Source))
+		if err != nil || info.Mode()&0111 != 0 {
+			continue
+		}
+		errors = append(errors, *NewValidationIssue(SeverityWarning, "perms", file.Perms, 
+			fmt.Sprintf("source %s isn't executable, so the linked file isn't either; run chmod +x on it", file.Source), 
+			fmt.Sprintf("files[%d]", i)))
+	}
+	
+	return errors
+}
+
+// linkedExecutables returns the indexes of the enabled, linked (not copied)
+// files whose perms make them executable and whose source is a regular file
+func (c *Config) linkedExecutables() []int {
+	var indexes []int
+	for i, file := range c.Files {
+		if file.Disabled || file.Source == "" || file.LinkStrategy == LinkStrategyCopy || file.Perms == "" {
+			continue
+		}
+		mode, err := parsePerms(file.Perms)
+		if err != nil || mode&0111 == 0 {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(c.DotfilesDir, file.Source))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
 // Remove the duplicate validateTemplateFileContent function since it's in templates.go
 
-func (c *Config) validateTemplateVariables(file ConfigFile, templatePath string) error {
+func (c *Config) validateTemplateVariables(file ConfigFile, templatePath string, shared map[string]string) error {
 	content, err := os.ReadFile(templatePath)
 	if err != nil {
 		return err
@@ -202,10 +528,12 @@ func (c *Config) validateTemplateVariables(file ConfigFile, templatePath string)
 			continue // Built-in variables
 		}
 		
-		// Check if variable is defined in global or file-specific variables
+		// Check if variable is defined in shared, global or file-specific variables
 		if _, exists := c.Variables[varName]; !exists {
 			if _, exists := file.Variables[varName]; !exists {
-				return fmt.Errorf("undefined variable: %s", varName)
+				if _, exists := shared[varName]; !exists {
+					return fmt.Errorf("undefined variable: %s", varName)
+				}
 			}
 		}
 	}
@@ -236,9 +564,10 @@ func (c *Config) findTemplateFile(fileName, source, category string) string {
 	return ""
 }
 
-// ValidateBeforeSave performs validation before saving config
+// ValidateBeforeSave performs validation before saving config; only errors
+// block the save
 func (c *Config) ValidateBeforeSave() error {
-	errors := c.Validate()
+	errors := c.BlockingErrors()
 	if len(errors) > 0 {
 		var messages []string
 		for _, err := range errors {
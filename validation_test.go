@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsWithinDir(t *testing.T) {
+	tests := []struct {
+		path string
+		dir  string
+		want bool
+	}{
+		{"/home/user", "/home/user", true},
+		{"/home/user/.bashrc", "/home/user", true},
+		{"/home/user/a/../.bashrc", "/home/user", true},
+		{"/home/user/../../etc/passwd", "/home/user", false},
+		{"/etc/passwd", "/home/user", false},
+		{"/home/user2", "/home/user", false},
+		{"/home/user2/.bashrc", "/home/user", false},
+		{"/home/user-evil/.bashrc", "/home/user", false},
+	}
+	for _, tt := range tests {
+		if got := isWithinDir(tt.path, tt.dir); got != tt.want {
+			t.Errorf("isWithinDir(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestValidateFilesSourceEscapes(t *testing.T) {
+	base := t.TempDir()
+	dotfiles := filepath.Join(base, "user")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{dotfiles, filepath.Join(base, "user2"), outside, filepath.Join(dotfiles, "shell")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(dotfiles, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{"parent traversal", "../../etc/passwd", "source path escapes dotfiles directory"},
+		{"absolute path outside root", "/etc/passwd", "source must be relative to the dotfiles directory"},
+		{"sibling prefix", "../user2/.bashrc", "source path escapes dotfiles directory"},
+		{"symlinked parent", "escape/passwd", "source path escapes dotfiles directory through a symlinked directory"},
+		{"dotfiles directory itself", "shell/..", "source must be inside the dotfiles directory, not the directory itself"},
+		{"nested source", "shell/zshrc", ""},
+		{"name sharing the sibling prefix", "user2file", ""},
+		{"missing parent", "new/dir/file", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				DotfilesDir: dotfiles,
+				Files: []ConfigFile{
+					{Name: "file", Source: tt.source, Target: filepath.Join(base, "home", ".file")},
+				},
+			}
+
+			var got []string
+			for _, issue := range config.validateFiles() {
+				if issue.Field == "source" {
+					got = append(got, issue.Message)
+				}
+			}
+			if tt.wantErr == "" {
+				if len(got) != 0 {
+					t.Errorf("source %q: unexpected issues %q", tt.source, got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0] != tt.wantErr {
+				t.Errorf("source %q: got issues %q, want %q", tt.source, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNestedTargetsWarnWithoutBlocking(t *testing.T) {
+	config, home := newTestConfig(t)
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "config", "starship.toml"), "")
+	writeTestFile(t, filepath.Join(config.DotfilesDir, "editor", "nvim", "init.lua"), "")
+	config.Files = []ConfigFile{
+		{Name: "config", Source: "config", Target: filepath.Join(home, ".config"), Category: "misc"},
+		{Name: "nvim", Source: "editor/nvim", Target: filepath.Join(home, ".config", "nvim"), Category: "editor"},
+	}
+
+	issues := config.validateNestedTargets()
+	if len(issues) != 1 || issues[0].Severity != SeverityWarning {
+		t.Fatalf("validateNestedTargets = %+v, want one warning", issues)
+	}
+	if err := config.ValidateBeforeSave(); err != nil {
+		t.Errorf("ValidateBeforeSave = %v, want nested targets not to block saving", err)
+	}
+}
+
+func TestTemplateFlagMismatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		template bool
+		manual   bool
+		source   string // contents of the source
+		tmpl     string // contents of templates/gitconfig.tmpl; none when empty
+		want     Severity
+		wantWarn bool
+	}{
+		{"plain source with markers", false, false, "[user]\n\tname = {{ .User }}\n", "", SeverityWarning, true},
+		{"template without markers", true, false, "[user]\n", "[user]\n\tname = me\n", SeverityInfo, true},
+		{"plain source without markers", false, false, "[user]\n", "", 0, false},
+		{"template with markers", true, false, "[user]\n\tname = me\n", "[user]\n\tname = {{ .User }}\n", 0, false},
+		{"flag set by hand", false, true, "{{ not a template }}\n", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, home := newTestConfig(t)
+			writeTestFile(t, filepath.Join(config.DotfilesDir, "git", "gitconfig"), tt.source)
+			if tt.tmpl != "" {
+				writeTestFile(t, filepath.Join(config.ConfigDir, "templates", "gitconfig.tmpl"), tt.tmpl)
+			}
+			config.Files = []ConfigFile{{Name: "gitconfig", Source: "git/gitconfig", Target: filepath.Join(home, ".gitconfig"),
+				Category: "git", Template: tt.template, TemplateManual: tt.manual}}
+
+			issues := config.validateTemplateMarkers()
+
+			if !tt.wantWarn {
+				if len(issues) != 0 {
+					t.Errorf("unexpected issues %v", issues)
+				}
+				return
+			}
+			if len(issues) != 1 || issues[0].Field != "template_flag" || issues[0].Severity != tt.want {
+				t.Fatalf("issues = %v, want one template_flag %s", issues, tt.want)
+			}
+			if blocking := config.BlockingErrors(); len(blocking) != 0 {
+				t.Errorf("a template flag mismatch blocks saving: %v", blocking)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Per-file outcomes reported by verify
+const (
+	verifyOK      = "OK"
+	verifyDrift   = "DRIFT"
+	verifyMissing = "MISSING"
+)
+
+// verifyResult is the verify outcome for one managed file
+type verifyResult struct {
+	File    *ConfigFile
+	Status  string
+	Detail  string
+	Fixable bool // a symlink pointing somewhere else, which --fix relinks
+}
+
+// verifyFile checks that a file's target matches its source and, for
+// templates, that the source still matches a fresh render
+func verifyFile(config *Config, file *ConfigFile) verifyResult {
+	result := verifyResult{File: file, Status: verifyOK}
+	updateSingleFileStatus(config, file)
+	
+	sourcePath := filepath.Join(config.DotfilesDir, file.Source)
+	if !fileExistsNoFollow(sourcePath) {
+		result.Status = verifyMissing
+		result.Detail = "source " + sourcePath + " does not exist"
+		return result
+	}
+	
+	target, err := resolveTarget(config, file)
+	if err != nil {
+		result.Status = verifyDrift
+		result.Detail = err.Error()
+		return result
+	}
+	
+	targetInfo, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		result.Status = verifyMissing
+		result.Detail = "target does not exist"
+		return result
+	}
+	
+	switch {
+	case file.Drifted:
+		result.Status = verifyDrift
+		result.Detail = "copy differs from source"
+		return result
+	case file.HasConflict && file.effectiveLinkStrategy() == LinkStrategyTree:
+		result.Status = verifyDrift
+		result.Detail = "some files in the directory aren't linked to the source"
+		return result
+	case file.HasConflict && err == nil && targetInfo.Mode()&os.ModeSymlink != 0:
+		linkTarget, _ := os.Readlink(target)
+		result.Status = verifyDrift
+		result.Detail = "symlink points to " + resolveLinkTarget(target, linkTarget)
+		result.Fixable = file.LinkStrategy != LinkStrategyCopy
+		return result
+	case file.HasConflict:
+		result.Status = verifyDrift
+		result.Detail = "target is not linked to the source"
+		return result
+	case !file.IsLinked:
+		result.Status = verifyMissing
+		result.Detail = "not linked"
+		return result
+	}
+	
+	if file.Template {
+		stale, err := templateIsStale(config, file, sourcePath)
+		if err != nil {
+			result.Status = verifyDrift
+			result.Detail = "cannot re-render template: " + err.Error()
+		} else if stale {
+			result.Status = verifyDrift
+			result.Detail = "source no longer matches a fresh render of its template"
+		}
+	}
+	
+	return result
+}
+
+// templateIsStale renders a file's template to a temporary file and reports
+// whether the rendered source differs from it. Files without a template file
+// are never stale.
+func templateIsStale(config *Config, file *ConfigFile, sourcePath string) (bool, error) {
+	templatePath := findTemplateFile(config, file.Name, file.Source, file.Category)
+	if templatePath == "" {
+		return false, nil
+	}
+	
+	tempDir, err := os.MkdirTemp("", "config-manager-verify-")
+	if err != nil {
+		return false, NewConfigError("create temp directory", "", err)
+	}
+	defer os.RemoveAll(tempDir)
+	
+	context, err := createTemplateContext(config, file)
+	if err != nil {
+		return false, err
+	}
+	
+	rendered := filepath.Join(tempDir, filepath.Base(sourcePath))
+	if _, err := processTemplate(templatePath, context, rendered, file); err != nil {
+		return false, err
+	}
+	
+	return !filesEqual(rendered, sourcePath), nil
+}
+
+// verifyConfig checks every managed file, in config order
+func verifyConfig(config *Config) []verifyResult {
+	results := make([]verifyResult, len(config.Files))
+	for i := range config.Files {
+		results[i] = verifyFile(config, &config.Files[i])
+	}
+	return results
+}
@@ -0,0 +1,1051 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Secondary TUI views reachable from the main file list
+
+// handleValidate runs validation live and switches to the validation view
+func (m model) handleValidate() (tea.Model, tea.Cmd) {
+	m.validationErrors = m.config.Validate()
+	m.validationCursor = 0
+	m.currentView = "validation"
+	
+	if len(m.validationErrors) == 0 {
+		m.message = glyphSuccess + " Configuration is valid"
+		m.messageType = "success"
+		return m, nil
+	}
+	
+	blocking := 0
+	for _, err := range m.validationErrors {
+		if err.IsBlocking() {
+			blocking++
+		}
+	}
+	m.message = fmt.Sprintf("Found %d validation issues (%d blocking)", len(m.validationErrors), blocking)
+	m.messageType = "warning"
+	if blocking > 0 {
+		m.messageType = "error"
+	}
+	
+	return m, nil
+}
+
+// updateValidationView handles key presses while the validation view is shown
+func (m model) updateValidationView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+		
+	case key.Matches(msg, keys.Back):
+		m.currentView = "main"
+		m.message = "Returned to file list"
+		m.messageType = "success"
+		
+	case key.Matches(msg, keys.Up):
+		if m.validationCursor > 0 {
+			m.validationCursor--
+		}
+		
+	case key.Matches(msg, keys.Down):
+		if m.validationCursor < len(m.validationErrors)-1 {
+			m.validationCursor++
+		}
+		
+	case key.Matches(msg, keys.Validate):
+		return m.handleValidate()
+		
+	case key.Matches(msg, keys.Enter):
+		return m.jumpToValidationError()
+	}
+	
+	return m, nil
+}
+
+// jumpToValidationError selects the file referenced by the highlighted error
+func (m model) jumpToValidationError() (tea.Model, tea.Cmd) {
+	if len(m.validationErrors) == 0 {
+		return m, nil
+	}
+	
+	validationErr := m.validationErrors[m.validationCursor]
+	index, ok := validationFileIndex(validationErr)
+	if !ok || index >= len(m.config.Files) {
+		m.message = "This issue is not tied to a specific file"
+		m.messageType = "warning"
+		return m, nil
+	}
+	
+	// The file may be hidden by the tag filter or a collapsed category
+	m = m.clearTagFilter()
+	m = m.selectFile(index)
+	m.currentView = "main"
+	m.message = fmt.Sprintf("%s: %s", m.config.Files[index].Name, validationErr.Message)
+	m.messageType = "warning"
+	
+	return m, nil
+}
+
+// validationFileIndex extracts the file index from a "files[N]" error context
+func validationFileIndex(err ValidationError) (int, bool) {
+	var index int
+	if _, scanErr := fmt.Sscanf(err.File, "files[%d]", &index); scanErr != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// validationView renders each validation error with its field, value and context
+func (m model) validationView() string {
+	var b strings.Builder
+	b.WriteString(activeStyle.Render("Configuration Validation") + "\n\n")
+	
+	if len(m.validationErrors) == 0 {
+		b.WriteString(successStyle.Render(glyphLinked + " No validation issues found") + "\n")
+		return b.String()
+	}
+	
+	for i, validationErr := range m.validationErrors {
+		cursor := "  "
+		if i == m.validationCursor {
+			cursor = activeStyle.Render("> ")
+		}
+		
+		location := "config"
+		if index, ok := validationFileIndex(validationErr); ok && index < len(m.config.Files) {
+			location = fmt.Sprintf("%s (%s)", m.config.Files[index].Name, validationErr.File)
+		}
+		
+		style := errorStyle
+		switch validationErr.Severity {
+		case SeverityWarning:
+			style = warningStyle
+		case SeverityInfo:
+			style = inactiveStyle
+		}
+		
+		b.WriteString(cursor + style.Render(validationErr.Severity.String()+": "+validationErr.Message) + "\n")
+		b.WriteString("    " + inactiveStyle.Render(fmt.Sprintf("%s: %s=%q", location, validationErr.Field, validationErr.Value)) + "\n")
+	}
+	
+	return b.String()
+}
+
+// variableRow is a single entry in the template variables view
+type variableRow struct {
+	global bool
+	key    string
+	value  string
+}
+
+// handleVariables opens the template variables view for globals and the selected file
+func (m model) handleVariables() (tea.Model, tea.Cmd) {
+	m.variablesFileIndex = m.selectedFileIndex()
+	m.variablesCursor = 0
+	m.currentView = "variables"
+	m.message = "Manage template variables"
+	m.messageType = "success"
+	
+	return m, nil
+}
+
+// variablesFile returns the file whose variables are shown, if any
+func (m model) variablesFile() *ConfigFile {
+	if m.variablesFileIndex < 0 || m.variablesFileIndex >= len(m.config.Files) {
+		return nil
+	}
+	return &m.config.Files[m.variablesFileIndex]
+}
+
+// variableRows lists global variables followed by the selected file's variables
+func (m model) variableRows() []variableRow {
+	var rows []variableRow
+	
+	for _, key := range sortedKeys(m.config.Variables) {
+		rows = append(rows, variableRow{global: true, key: key, value: m.config.Variables[key]})
+	}
+	
+	if file := m.variablesFile(); file != nil {
+		for _, key := range sortedKeys(file.Variables) {
+			rows = append(rows, variableRow{key: key, value: file.Variables[key]})
+		}
+	}
+	
+	return rows
+}
+
+// sortedKeys returns the keys of a variable map in a stable order
+func sortedKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// updateVariablesView handles key presses while the variables view is shown
+func (m model) updateVariablesView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := m.variableRows()
+	
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+		
+	case key.Matches(msg, keys.Back):
+		m.currentView = "main"
+		m.message = "Returned to file list"
+		m.messageType = "success"
+		
+	case key.Matches(msg, keys.Up):
+		if m.variablesCursor > 0 {
+			m.variablesCursor--
+		}
+		
+	case key.Matches(msg, keys.Down):
+		if m.variablesCursor < len(rows)-1 {
+			m.variablesCursor++
+		}
+		
+	case key.Matches(msg, keys.Add):
+		return m.addVariable()
+		
+	case key.Matches(msg, keys.Edit), key.Matches(msg, keys.Enter):
+		if len(rows) > 0 {
+			return m.editVariable(rows[m.variablesCursor])
+		}
+		
+	case key.Matches(msg, keys.Remove):
+		if len(rows) > 0 {
+			return m.deleteVariable(rows[m.variablesCursor])
+		}
+	}
+	
+	return m, nil
+}
+
+// addVariable prompts for scope, name and value of a new variable
+func (m model) addVariable() (tea.Model, tea.Cmd) {
+	global := true
+	if file := m.variablesFile(); file != nil {
+		scope, err := promptForChoice("Variable scope:", []string{"Global", "File: " + file.Name})
+		if err != nil {
+			return m.variablesPromptFailed(err)
+		}
+		global = scope == "Global"
+	}
+	
+	name, err := promptForInput("Variable name: ", "email_domain", "")
+	if err != nil {
+		return m.variablesPromptFailed(err)
+	}
+	if name == "" {
+		return m.variablesPromptFailed(NewValidationError("variable", "", "variable name cannot be empty", ""))
+	}
+	
+	value, err := promptForInput(fmt.Sprintf("Value for %s: ", name), "value", "")
+	if err != nil {
+		return m.variablesPromptFailed(err)
+	}
+	
+	return m.setVariable(variableRow{global: global, key: name, value: value})
+}
+
+// editVariable prompts for a new value of an existing variable
+func (m model) editVariable(row variableRow) (tea.Model, tea.Cmd) {
+	value, err := promptForInput(fmt.Sprintf("Value for %s: ", row.key), "value", row.value)
+	if err != nil {
+		return m.variablesPromptFailed(err)
+	}
+	
+	row.value = value
+	return m.setVariable(row)
+}
+
+// setVariable stores a variable in its scope and persists the configuration
+func (m model) setVariable(row variableRow) (tea.Model, tea.Cmd) {
+	if row.global {
+		m.config.SetGlobalVariable(row.key, row.value)
+	} else if file := m.variablesFile(); file != nil {
+		if file.Variables == nil {
+			file.Variables = make(map[string]string)
+		}
+		file.Variables[row.key] = row.value
+	}
+	
+	return m.variablesChanged(row, fmt.Sprintf("Set %s variable %s", variableScopeName(row), row.key))
+}
+
+// deleteVariable removes a variable from its scope and persists the configuration
+func (m model) deleteVariable(row variableRow) (tea.Model, tea.Cmd) {
+	if row.global {
+		m.config.RemoveGlobalVariable(row.key)
+	} else if file := m.variablesFile(); file != nil {
+		delete(file.Variables, row.key)
+	}
+	
+	if m.variablesCursor > 0 && m.variablesCursor >= len(m.variableRows()) {
+		m.variablesCursor--
+	}
+	
+	return m.variablesChanged(row, fmt.Sprintf("Deleted %s variable %s", variableScopeName(row), row.key))
+}
+
+// variablesChanged saves the config and re-validates the templates affected by row
+func (m model) variablesChanged(row variableRow, message string) (tea.Model, tea.Cmd) {
+	m.message = message
+	m.messageType = "success"
+	
+	if err := saveConfigSafe(m.config); err != nil {
+		m.message += fmt.Sprintf(" (warning: failed to save: %v)", err)
+		m.messageType = "warning"
+	}
+	
+	var affected []ConfigFile
+	if row.global {
+		affected = m.config.GetTemplateFiles()
+	} else if file := m.variablesFile(); file != nil && file.Template {
+		affected = []ConfigFile{*file}
+	}
+	
+	var warnings []string
+	shared, err := loadSharedVariables(m.config)
+	if err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	for _, file := range affected {
+		templatePath := m.config.findTemplateFile(file.Name, file.Source, file.Category)
+		if templatePath == "" {
+			continue
+		}
+		if err := m.config.validateTemplateVariables(file, templatePath, shared); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", file.Name, err))
+		}
+	}
+	if len(warnings) > 0 {
+		m.message += " (template warnings: " + strings.Join(warnings, "; ") + ")"
+		m.messageType = "warning"
+	}
+	
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// variablesPromptFailed reports a cancelled or failed variable prompt
+func (m model) variablesPromptFailed(err error) (tea.Model, tea.Cmd) {
+	if strings.Contains(err.Error(), "cancelled") {
+		m.message = "Variable edit cancelled"
+		m.messageType = "warning"
+	} else {
+		m.message = fmt.Sprintf("Variable edit failed: %v", err)
+		m.messageType = "error"
+	}
+	
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// variableScopeName describes the scope a variable row belongs to
+func variableScopeName(row variableRow) string {
+	if row.global {
+		return "global"
+	}
+	return "file"
+}
+
+// variablesView renders global and file-specific variables grouped by scope
+func (m model) variablesView() string {
+	var b strings.Builder
+	b.WriteString(activeStyle.Render("Template Variables") + "\n\n")
+	
+	rows := m.variableRows()
+	file := m.variablesFile()
+	
+	renderSection := func(title string, global bool) {
+		b.WriteString(titleStyle.Render(title) + "\n")
+		empty := true
+		for i, row := range rows {
+			if row.global != global {
+				continue
+			}
+			empty = false
+			cursor := "  "
+			if i == m.variablesCursor {
+				cursor = activeStyle.Render("> ")
+			}
+			b.WriteString(fmt.Sprintf("%s%s = %s\n", cursor, row.key, inactiveStyle.Render(row.value)))
+		}
+		if empty {
+			b.WriteString(inactiveStyle.Render("  (none)") + "\n")
+		}
+		b.WriteString("\n")
+	}
+	
+	renderSection("Global", true)
+	if file != nil {
+		renderSection("File: "+file.Name, false)
+	}
+	
+	return b.String()
+}
+
+// handleSnapshots opens the snapshots view
+func (m model) handleSnapshots() (tea.Model, tea.Cmd) {
+	m.currentView = "snapshots"
+	m.snapshotsCursor = 0
+	m = m.reloadSnapshots()
+	
+	if m.messageType != "error" {
+		m.message = fmt.Sprintf("%d snapshots", len(m.snapshots))
+		m.messageType = "success"
+	}
+	
+	return m, nil
+}
+
+// reloadSnapshots re-reads the snapshot list, keeping the cursor in range
+func (m model) reloadSnapshots() model {
+	snapshots, err := listSnapshots(m.config)
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to list snapshots: %v", err)
+		m.messageType = "error"
+	}
+	m.snapshots = snapshots
+	
+	if m.snapshotsCursor >= len(m.snapshots) {
+		m.snapshotsCursor = len(m.snapshots) - 1
+	}
+	if m.snapshotsCursor < 0 {
+		m.snapshotsCursor = 0
+	}
+	return m
+}
+
+// updateSnapshotsView handles key presses while the snapshots view is shown
+func (m model) updateSnapshotsView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+		
+	case key.Matches(msg, keys.Back):
+		m.currentView = "main"
+		m.message = "Returned to file list"
+		m.messageType = "success"
+		
+	case key.Matches(msg, keys.Up):
+		if m.snapshotsCursor > 0 {
+			m.snapshotsCursor--
+		}
+		
+	case key.Matches(msg, keys.Down):
+		if m.snapshotsCursor < len(m.snapshots)-1 {
+			m.snapshotsCursor++
+		}
+		
+	case key.Matches(msg, keys.Add):
+		return m.takeSnapshot()
+		
+	case key.Matches(msg, keys.Enter):
+		if len(m.snapshots) > 0 {
+			return m.restoreSelectedSnapshot()
+		}
+		
+	case key.Matches(msg, keys.Remove):
+		if len(m.snapshots) > 0 {
+			return m.deleteSelectedSnapshot()
+		}
+	}
+	
+	return m, nil
+}
+
+// takeSnapshot prompts for a name and snapshots the current state
+func (m model) takeSnapshot() (tea.Model, tea.Cmd) {
+	name, err := promptForInput("Snapshot name: ", "before-upgrade", m.config.backupPolicy().timestamp())
+	if err != nil {
+		return m.snapshotPromptFailed(err)
+	}
+	
+	if err := createSnapshot(m.config, name); err != nil {
+		m.message = fmt.Sprintf("Snapshot failed: %v", err)
+		m.messageType = "error"
+	} else {
+		m.message = fmt.Sprintf("Saved snapshot %s", name)
+		m.messageType = "success"
+	}
+	
+	m = m.reloadSnapshots()
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// restoreSelectedSnapshot confirms, then restores the highlighted snapshot
+func (m model) restoreSelectedSnapshot() (tea.Model, tea.Cmd) {
+	snapshot := m.snapshots[m.snapshotsCursor]
+	
+	confirmed, err := confirmAction(fmt.Sprintf("Restore snapshot %s? Current targets are kept as .backup files.", snapshot.Name))
+	if err != nil {
+		return m.snapshotPromptFailed(err)
+	}
+	if !confirmed {
+		return m.snapshotPromptFailed(fmt.Errorf("cancelled"))
+	}
+	
+	if err := restoreSnapshot(m.config, snapshot.Name); err != nil {
+		m.message = fmt.Sprintf("Restore failed: %v", err)
+		m.messageType = "error"
+	} else {
+		m.message = fmt.Sprintf("Restored snapshot %s", snapshot.Name)
+		m.messageType = "success"
+	}
+	
+	// The config may have been replaced
+	m.fileList.SetItems(m.listItems())
+	
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// deleteSelectedSnapshot confirms, then deletes the highlighted snapshot
+func (m model) deleteSelectedSnapshot() (tea.Model, tea.Cmd) {
+	snapshot := m.snapshots[m.snapshotsCursor]
+	
+	confirmed, err := confirmAction(fmt.Sprintf("Delete snapshot %s?", snapshot.Name))
+	if err != nil {
+		return m.snapshotPromptFailed(err)
+	}
+	if !confirmed {
+		return m.snapshotPromptFailed(fmt.Errorf("cancelled"))
+	}
+	
+	if err := deleteSnapshot(m.config, snapshot.Name); err != nil {
+		m.message = fmt.Sprintf("Delete failed: %v", err)
+		m.messageType = "error"
+	} else {
+		m.message = fmt.Sprintf("Deleted snapshot %s", snapshot.Name)
+		m.messageType = "success"
+	}
+	
+	m = m.reloadSnapshots()
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// snapshotPromptFailed reports a cancelled or failed snapshot prompt
+func (m model) snapshotPromptFailed(err error) (tea.Model, tea.Cmd) {
+	if strings.Contains(err.Error(), "cancelled") {
+		m.message = "Snapshot operation cancelled"
+		m.messageType = "warning"
+	} else {
+		m.message = fmt.Sprintf("Snapshot operation failed: %v", err)
+		m.messageType = "error"
+	}
+	
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// snapshotsView renders the saved snapshots, newest first
+func (m model) snapshotsView() string {
+	var b strings.Builder
+	b.WriteString(activeStyle.Render("Snapshots") + "\n\n")
+	
+	if len(m.snapshots) == 0 {
+		b.WriteString(inactiveStyle.Render("No snapshots yet - press a to take one") + "\n")
+		return b.String()
+	}
+	
+	for i, snapshot := range m.snapshots {
+		cursor := "  "
+		if i == m.snapshotsCursor {
+			cursor = activeStyle.Render("> ")
+		}
+		details := fmt.Sprintf("%s, %d targets", snapshot.Created.Format("2006-01-02 15:04"), len(snapshot.Entries))
+		b.WriteString(fmt.Sprintf("%s%s %s\n", cursor, snapshot.Name, inactiveStyle.Render(details)))
+	}
+	
+	return b.String()
+}
+
+// operationsLogViewLimit is how many transactions the operations log view loads
+const operationsLogViewLimit = 200
+
+// handleOperationsLog opens the operations log view on the most recent transactions
+func (m model) handleOperationsLog() (tea.Model, tea.Cmd) {
+	m.currentView = "log"
+	m.operationsLogCursor = 0
+	
+	entries, err := readOperationsLog(operationsLogViewLimit)
+	if err != nil {
+		m.operationsLog = nil
+		m.message = fmt.Sprintf("Failed to read operations log: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	m.operationsLog = make([]operationLogEntry, len(entries))
+	for i, entry := range entries {
+		m.operationsLog[len(entries)-1-i] = entry
+	}
+	m.message = fmt.Sprintf("%d recent transactions", len(m.operationsLog))
+	m.messageType = "success"
+	return m, nil
+}
+
+// updateOperationsLogView handles key presses while the operations log is shown
+func (m model) updateOperationsLogView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+		
+	case key.Matches(msg, keys.Back):
+		m.currentView = "main"
+		m.message = "Returned to file list"
+		m.messageType = "success"
+		
+	case key.Matches(msg, keys.Up):
+		if m.operationsLogCursor > 0 {
+			m.operationsLogCursor--
+		}
+		
+	case key.Matches(msg, keys.Down):
+		if m.operationsLogCursor < len(m.operationsLog)-1 {
+			m.operationsLogCursor++
+		}
+		
+	case key.Matches(msg, keys.History):
+		return m.handleOperationsLog()
+	}
+	
+	return m, nil
+}
+
+// operationsLogView lists logged transactions, newest first, with the
+// operations of the highlighted one
+func (m model) operationsLogView() string {
+	var b strings.Builder
+	b.WriteString(activeStyle.Render("Operations Log") + "\n\n")
+	
+	if len(m.operationsLog) == 0 {
+		b.WriteString(inactiveStyle.Render("Nothing has been logged yet") + "\n")
+		return b.String()
+	}
+	
+	for i, entry := range m.operationsLog {
+		style := successStyle
+		if !strings.Contains(entry.Header, " "+operationsLogCommitted) {
+			style = errorStyle
+		}
+		
+		if i != m.operationsLogCursor {
+			b.WriteString("  " + style.Render(entry.Header) + "\n")
+			continue
+		}
+		b.WriteString(activeStyle.Render("> ") + style.Render(entry.Header) + "\n")
+		for _, op := range entry.Operations {
+			b.WriteString("      " + inactiveStyle.Render(op) + "\n")
+		}
+	}
+	
+	return b.String()
+}
+
+// handleImport merges an exported config into the current one and shows what
+// was added, skipped and conflicted
+func (m model) handleImport() (tea.Model, tea.Cmd) {
+	path, err := promptForInput("Import config from (file or URL): ", "~/config-export.json", "")
+	if err != nil {
+		return m.importPromptFailed(err)
+	}
+	if path == "" {
+		return m.importPromptFailed(fmt.Errorf("cancelled"))
+	}
+	
+	var data []byte
+	if isRemoteImport(path) {
+		data, err = fetchImportData(path)
+		if err != nil {
+			return m.importPromptFailed(err)
+		}
+		
+		// Downloaded configs are shown before anything is merged
+		diff, err := previewImport(m.config, data, true)
+		if err != nil {
+			return m.importPromptFailed(err)
+		}
+		if diff == "" {
+			return m.importPromptFailed(fmt.Errorf("nothing to import; the config already matches %s", path))
+		}
+		fmt.Printf("\nImporting %s would change:\n%s\n", path, diff)
+		confirmed, err := confirmAction("Apply these changes?")
+		if err != nil {
+			return m.importPromptFailed(err)
+		}
+		if !confirmed {
+			return m.importPromptFailed(fmt.Errorf("cancelled"))
+		}
+	} else {
+		data, err = os.ReadFile(resolveHomePath(path))
+		if err != nil {
+			return m.importPromptFailed(NewConfigError("read import file", path, err))
+		}
+	}
+	
+	result, err := m.config.ImportConfigWithResult(data, true)
+	if result == nil {
+		return m.importPromptFailed(err)
+	}
+	
+	m.importResult = result
+	m.importTaken = make([]bool, len(result.Conflicts))
+	m.importCursor = 0
+	m.currentView = "import"
+	
+	m.message = fmt.Sprintf("Imported %d files, skipped %d, %d conflicts",
+		len(result.Added), len(result.Skipped), len(result.Conflicts))
+	m.messageType = "success"
+	if err != nil {
+		m.message += fmt.Sprintf(" (errors: %v)", err)
+		m.messageType = "warning"
+	} else if len(result.Conflicts) > 0 {
+		m.messageType = "warning"
+	}
+	
+	m = m.importChanged()
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// updateImportView handles key presses while the import result is shown
+func (m model) updateImportView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+		
+	case key.Matches(msg, keys.Back):
+		m.currentView = "main"
+		m.message = "Returned to file list"
+		m.messageType = "success"
+		
+	case key.Matches(msg, keys.Up):
+		if m.importCursor > 0 {
+			m.importCursor--
+		}
+		
+	case key.Matches(msg, keys.Down):
+		if m.importCursor < len(m.importResult.Conflicts)-1 {
+			m.importCursor++
+		}
+		
+	case key.Matches(msg, keys.Enter):
+		if len(m.importResult.Conflicts) > 0 {
+			return m.toggleImportConflict()
+		}
+	}
+	
+	return m, nil
+}
+
+// toggleImportConflict switches the highlighted conflict between keeping the
+// existing file and taking the imported one
+func (m model) toggleImportConflict() (tea.Model, tea.Cmd) {
+	conflict := m.importResult.Conflicts[m.importCursor]
+	takeImported := !m.importTaken[m.importCursor]
+	
+	if err := m.config.ResolveImportConflict(conflict, takeImported); err != nil {
+		m.message = fmt.Sprintf("Cannot resolve conflict: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	m.importTaken[m.importCursor] = takeImported
+	if takeImported {
+		m.message = fmt.Sprintf("Using imported %s", conflict.Imported.Name)
+	} else {
+		m.message = fmt.Sprintf("Keeping existing %s", conflict.Existing.Name)
+	}
+	m.messageType = "success"
+	
+	m = m.importChanged()
+	return m, nil
+}
+
+// importChanged saves the config and refreshes the file list after an import change
+func (m model) importChanged() model {
+	if err := saveConfigSafe(m.config); err != nil {
+		m.message += fmt.Sprintf(" (warning: failed to save: %v)", err)
+		m.messageType = "warning"
+	}
+	
+	m.fileList.SetItems(m.listItems())
+	
+	return m
+}
+
+// importPromptFailed reports a cancelled or failed import
+func (m model) importPromptFailed(err error) (tea.Model, tea.Cmd) {
+	if strings.Contains(err.Error(), "cancelled") {
+		m.message = "Import cancelled"
+		m.messageType = "warning"
+	} else {
+		m.message = fmt.Sprintf("Import failed: %v", err)
+		m.messageType = "error"
+	}
+	
+	return m, tea.Batch(
+		tea.HideCursor,
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+// importView renders the outcome of an import and each conflict's decision
+func (m model) importView() string {
+	var b strings.Builder
+	b.WriteString(activeStyle.Render("Import Result") + "\n\n")
+	
+	b.WriteString(titleStyle.Render(fmt.Sprintf("Added (%d)", len(m.importResult.Added))) + "\n")
+	for _, file := range m.importResult.Added {
+		b.WriteString("  " + successStyle.Render("+ "+file.Name) + " " + inactiveStyle.Render(file.Target) + "\n")
+	}
+	
+	b.WriteString("\n" + titleStyle.Render(fmt.Sprintf("Skipped (%d)", len(m.importResult.Skipped))) + "\n")
+	for _, skipped := range m.importResult.Skipped {
+		b.WriteString("  " + skipped.File.Name + " " + inactiveStyle.Render(skipped.Reason) + "\n")
+	}
+	
+	b.WriteString("\n" + titleStyle.Render(fmt.Sprintf("Conflicts (%d)", len(m.importResult.Conflicts))) + "\n")
+	for i, conflict := range m.importResult.Conflicts {
+		cursor := "  "
+		if i == m.importCursor {
+			cursor = activeStyle.Render("> ")
+		}
+		
+		decision := successStyle.Render("keep existing")
+		if m.importTaken[i] {
+			decision = warningStyle.Render("take imported")
+		}
+		b.WriteString(fmt.Sprintf("%s%s [%s] %s\n", cursor, conflict.Imported.Name, decision, inactiveStyle.Render(conflict.Reason)))
+		
+		for _, change := range diffFileFields(exportFile(conflict.Existing), exportFile(conflict.Imported)) {
+			b.WriteString("    " + inactiveStyle.Render(change) + "\n")
+		}
+	}
+	
+	return b.String()
+}
+
+// handleConflicts opens the conflicts view, listing every file whose target
+// is in the way of its link
+func (m model) handleConflicts() (tea.Model, tea.Cmd) {
+	updateFileStatuses(m.config)
+	m.conflictFiles = m.config.GetConflictedFiles()
+	m.conflictChoices = make(map[int]ConflictResolution)
+	m.conflictCursor = 0
+	
+	if len(m.conflictFiles) == 0 {
+		m.message = "No conflicts to resolve"
+		m.messageType = "success"
+		return m, nil
+	}
+	
+	m.currentView = "conflicts"
+	return m.conflictsChanged(), nil
+}
+
+// updateConflictsView handles key presses while the conflicts view is shown.
+// Answers are only recorded here; enter applies them all at once.
+func (m model) updateConflictsView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, keys.Quit):
+		return m, tea.Quit
+		
+	case key.Matches(msg, keys.Back):
+		m.currentView = "main"
+		m.message = "Conflicts left unresolved"
+		m.messageType = "warning"
+		
+	case key.Matches(msg, keys.Up):
+		if m.conflictCursor > 0 {
+			m.conflictCursor--
+		}
+		
+	case key.Matches(msg, keys.Down):
+		if m.conflictCursor < len(m.conflictFiles)-1 {
+			m.conflictCursor++
+		}
+		
+	case key.Matches(msg, keys.Backup):
+		return m.chooseConflictResolution(ConflictBackupAndReplace), nil
+		
+	case key.Matches(msg, keys.Skip):
+		return m.chooseConflictResolution(ConflictSkip), nil
+		
+	case key.Matches(msg, keys.MergeDir):
+		file := m.conflictFiles[m.conflictCursor]
+		target, err := resolveTarget(m.config, &file)
+		if err != nil {
+			m.message = fmt.Sprintf("Can't merge: %v", err)
+			m.messageType = "error"
+			return m, nil
+		}
+		conflict := &ConflictInfo{File: &file, TargetPath: target, SourcePath: filepath.Join(m.config.DotfilesDir, file.Source)}
+		if !canMergeDirectory(conflict) {
+			m.message = fmt.Sprintf("%s: only a directory target with a directory source can be merged", file.Name)
+			m.messageType = "warning"
+			return m, nil
+		}
+		return m.chooseConflictResolution(ConflictMergeDirectory), nil
+		
+	case key.Matches(msg, keys.Diff):
+		file := m.conflictFiles[m.conflictCursor]
+		sourcePath := filepath.Join(m.config.DotfilesDir, file.Source)
+		target, err := resolveTarget(m.config, &file)
+		if err != nil {
+			m.message = fmt.Sprintf("Can't show diff: %v", err)
+			m.messageType = "error"
+			return m, nil
+		}
+		return m.showDiff(file.Name+": target vs source", target, sourcePath)
+		
+	case key.Matches(msg, keys.Enter):
+		return m.applyConflictChoices()
+	}
+	
+	return m, nil
+}
+
+// chooseConflictResolution records the answer for the highlighted conflict
+// and moves on to the next one. Choosing the same answer again clears it.
+func (m model) chooseConflictResolution(resolution ConflictResolution) model {
+	if current, ok := m.conflictChoices[m.conflictCursor]; ok && current == resolution {
+		delete(m.conflictChoices, m.conflictCursor)
+	} else {
+		m.conflictChoices[m.conflictCursor] = resolution
+		if m.conflictCursor < len(m.conflictFiles)-1 {
+			m.conflictCursor++
+		}
+	}
+	return m.conflictsChanged()
+}
+
+// conflictsChanged shows how many conflicts are still unanswered
+func (m model) conflictsChanged() model {
+	remaining := len(m.conflictFiles) - len(m.conflictChoices)
+	m.message = fmt.Sprintf("%d of %d conflicts unresolved", remaining, len(m.conflictFiles))
+	m.messageType = "warning"
+	if remaining == 0 {
+		m.message = fmt.Sprintf("All %d conflicts resolved - press enter to apply", len(m.conflictFiles))
+		m.messageType = "success"
+	}
+	return m
+}
+
+// applyConflictChoices replaces every target answered with backup-and-replace,
+// and merges every directory answered with merge, in one transaction and
+// returns to the file list
+func (m model) applyConflictChoices() (tea.Model, tea.Cmd) {
+	var replace, merge []ConfigFile
+	for i, file := range m.conflictFiles {
+		resolution, ok := m.conflictChoices[i]
+		if ok && resolution == ConflictBackupAndReplace {
+			replace = append(replace, file)
+		} else if ok && resolution == ConflictMergeDirectory {
+			merge = append(merge, file)
+		}
+	}
+	if len(replace) == 0 && len(merge) == 0 {
+		m.message = "Nothing to apply - choose backup and replace (b) or merge (m) for at least one conflict"
+		m.messageType = "warning"
+		return m, nil
+	}
+	
+	summaries, err := replaceConflictedTargets(m.config, replace, merge)
+	updateFileStatuses(m.config)
+	m.fileList.SetItems(m.listItems())
+	if err != nil {
+		m.message = fmt.Sprintf("Resolving conflicts failed, nothing was changed: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	
+	saveLinkTimes(m.config)
+	m.currentView = "main"
+	kept := "backups kept"
+	if m.config.backupPolicy().skip {
+		kept = "backups off"
+	}
+	m.message = fmt.Sprintf("Replaced %d and merged %d conflicting targets (%s), %d conflicts remain",
+		len(replace), len(merge), kept, len(m.config.GetConflictedFiles()))
+	if len(summaries) > 0 {
+		m.message += " - " + strings.Join(summaries, "; ")
+	}
+	m.messageType = "success"
+	return m, nil
+}
+
+// conflictsView lists the conflicted files and the answer chosen for each
+func (m model) conflictsView() string {
+	var b strings.Builder
+	b.WriteString(activeStyle.Render(fmt.Sprintf("Conflicts (%d)", len(m.conflictFiles))) + "\n\n")
+	
+	for i, file := range m.conflictFiles {
+		cursor := "  "
+		if i == m.conflictCursor {
+			cursor = activeStyle.Render("> ")
+		}
+		
+		decision := inactiveStyle.Render("unresolved")
+		if resolution, ok := m.conflictChoices[i]; ok {
+			switch resolution {
+			case ConflictBackupAndReplace:
+				decision = warningStyle.Render("backup and replace")
+			case ConflictSkip:
+				decision = successStyle.Render("skip")
+			case ConflictMergeDirectory:
+				decision = warningStyle.Render("merge into source")
+			}
+		}
+		b.WriteString(fmt.Sprintf("%s%s [%s] %s\n", cursor, file.Name, decision, inactiveStyle.Render(file.Target)))
+	}
+	
+	return b.String()
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// dirWritable reports whether the current user may create entries in dir,
+// by creating and removing a probe file
+func dirWritable(dir string) bool {
+	probe, err := os.CreateTemp(dir, ".config-manager-probe-")
+	if err != nil {
+		return !os.IsPermission(err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return true
+}
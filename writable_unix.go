@@ -0,0 +1,13 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// accessWriteOK is W_OK from <unistd.h>
+const accessWriteOK = 0x2
+
+// dirWritable reports whether the current user may create entries in dir
+func dirWritable(dir string) bool {
+	return syscall.Access(dir, accessWriteOK) == nil
+}